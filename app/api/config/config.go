@@ -0,0 +1,529 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"shofy/utils/ratelimit"
+	"strings"
+	"time"
+)
+
+// Config is the typed application configuration. Values are resolved in
+// order: struct defaults, the optional YAML file, then environment
+// variables (including .env).
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Log         LogConfig         `yaml:"log"`
+	Telemetry   TelemetryConfig   `yaml:"telemetry"`
+	Database    DatabaseConfig    `yaml:"database"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Auth        AuthConfig        `yaml:"auth"`
+	DeepInfra   DeepInfraConfig   `yaml:"deepinfra"`
+	Azure       AzureConfig       `yaml:"azure"`
+	WhatsApp    WhatsAppConfig    `yaml:"whatsapp"`
+	SMTP        SMTPConfig        `yaml:"smtp"`
+	Seed        SeedConfig        `yaml:"seed"`
+	Health      HealthConfig      `yaml:"health"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Chat        ChatConfig        `yaml:"chat"`
+	Knowledge   KnowledgeConfig   `yaml:"knowledge"`
+	LLM         LLMConfig         `yaml:"llm"`
+	Guardrail   GuardrailConfig   `yaml:"guardrail"`
+}
+
+type ServerConfig struct {
+	Port            int           `yaml:"port" env:"PORT" default:"8080"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"5s"`
+	// TrustedProxies is a comma-separated list of proxy IPs or CIDRs whose
+	// X-Forwarded-For header is believed. Empty trusts no proxy, so client
+	// IPs, and with them per-IP rate limits, come from the connection.
+	TrustedProxies string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// TrustedProxyList splits TrustedProxies, or returns nil when it is empty.
+func (c ServerConfig) TrustedProxyList() []string {
+	var list []string
+	for _, entry := range strings.Split(c.TrustedProxies, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	Format string `yaml:"format" env:"LOG_FORMAT" default:"json"`
+}
+
+// TelemetryConfig controls OpenTelemetry tracing. Leave Enabled off for
+// local runs without a collector.
+type TelemetryConfig struct {
+	Enabled     bool    `yaml:"enabled" env:"OTEL_ENABLED" default:"false"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE" default:"true"`
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME" default:"shofy"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLE_RATIO" default:"1"`
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port            int           `yaml:"port" env:"DB_PORT" default:"5432"`
+	User            string        `yaml:"user" env:"DB_USER"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD"`
+	Name            string        `yaml:"name" env:"DB_NAME"`
+	SSLMode         string        `yaml:"sslmode" env:"DB_SSLMODE" default:"disable"`
+	MaxConns        int32         `yaml:"max_conns" env:"DB_MAX_CONNS" default:"10"`
+	MinConns        int32         `yaml:"min_conns" env:"DB_MIN_CONNS" default:"0"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" default:"1h"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" default:"30m"`
+	// AutoMigrate applies pending migrations before serving.
+	AutoMigrate bool `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE" default:"false"`
+}
+
+type JWTConfig struct {
+	SecretKey string `yaml:"secret_key" env:"JWT_SECRET_KEY"`
+}
+
+type AuthConfig struct {
+	OTPSecretKey        string        `yaml:"otp_secret_key" env:"OTP_SECRET_KEY"`
+	OTPMaxAttempts      int32         `yaml:"otp_max_attempts" env:"OTP_MAX_ATTEMPTS" default:"5"`
+	OTPResendCooldown   time.Duration `yaml:"otp_resend_cooldown" env:"OTP_RESEND_COOLDOWN" default:"60s"`
+	OTPMaxSendsPerPhone int64         `yaml:"otp_max_sends_per_phone" env:"OTP_MAX_SENDS_PER_PHONE" default:"5"`
+	OTPMaxSendsPerIP    int64         `yaml:"otp_max_sends_per_ip" env:"OTP_MAX_SENDS_PER_IP" default:"20"`
+	MagicLinkURL        string        `yaml:"magic_link_url" env:"MAGIC_LINK_URL"`
+	MagicLinkSecretKey  string        `yaml:"magic_link_secret_key" env:"MAGIC_LINK_SECRET_KEY"`
+	MagicLinkTTL        time.Duration `yaml:"magic_link_ttl" env:"MAGIC_LINK_TTL" default:"15m"`
+	DefaultCustomerRole string        `yaml:"default_customer_role" env:"DEFAULT_CUSTOMER_ROLE" default:"CUSTOMER"`
+	// DevLogOTPs logs OTP codes that cannot be delivered because WhatsApp
+	// or SMTP is disabled. For local development only; codes are never
+	// returned in API responses.
+	DevLogOTPs bool `yaml:"dev_log_otps" env:"OTP_DEV_LOG_CODES" default:"false"`
+}
+
+type DeepInfraConfig struct {
+	Enabled bool   `yaml:"enabled" env:"DEEPINFRA_ENABLED" default:"true"`
+	URL     string `yaml:"url" env:"DEEPINFRA_URL" default:"https://api.deepinfra.com/v1/openai"`
+	APIKey  string `yaml:"api_key" env:"DI_API_KEY"`
+	Model   string `yaml:"model" env:"DEEPINFRA_MODEL" default:"meta-llama/Llama-4-Maverick-17B-128E-Instruct-FP8"`
+	// EmbeddingModel embeds knowledge base chunks and chat questions.
+	EmbeddingModel string `yaml:"embedding_model" env:"DEEPINFRA_EMBEDDING_MODEL" default:"BAAI/bge-m3"`
+}
+
+type AzureConfig struct {
+	Enabled    bool   `yaml:"enabled" env:"AZURE_OPENAI_ENABLED" default:"false"`
+	Endpoint   string `yaml:"endpoint" env:"AZURE_OPENAI_ENDPOINT"`
+	APIKey     string `yaml:"api_key" env:"AZURE_OPENAI_API_KEY"`
+	Deployment string `yaml:"deployment" env:"AZURE_OPENAI_DEPLOYMENT" default:"gpt-35-turbo"`
+}
+
+// WhatsAppConfig controls OTP delivery over the WhatsApp Cloud API. When
+// disabled, codes are stored but not sent.
+type WhatsAppConfig struct {
+	Enabled       bool   `yaml:"enabled" env:"OTP_DELIVERY_ENABLED" default:"false"`
+	AccessToken   string `yaml:"access_token" env:"WHATSAPP_ACCESS_TOKEN"`
+	PhoneNumberID string `yaml:"phone_number_id" env:"WHATSAPP_PHONE_NUMBER_ID"`
+}
+
+// SMTPConfig is enabled by setting Host.
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+}
+
+// SeedConfig describes the super admin created by the seed command. Set
+// either AdminEmail or AdminPhone; the first shop is used, or ShopName is
+// created when there are no shops yet.
+type SeedConfig struct {
+	AdminEmail    string `yaml:"admin_email" env:"SEED_ADMIN_EMAIL"`
+	AdminPhone    string `yaml:"admin_phone" env:"SEED_ADMIN_PHONE"`
+	AdminCodeArea string `yaml:"admin_code_area" env:"SEED_ADMIN_CODE_AREA" default:"62"`
+	ShopName      string `yaml:"shop_name" env:"SEED_SHOP_NAME" default:"Shofy"`
+}
+
+// HealthConfig controls /readyz. The database and migration version are
+// always checked; LLM and notification providers only when enabled here.
+type HealthConfig struct {
+	Timeout            time.Duration `yaml:"timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	CheckLLM           bool          `yaml:"check_llm" env:"HEALTH_CHECK_LLM" default:"false"`
+	CheckNotifications bool          `yaml:"check_notifications" env:"HEALTH_CHECK_NOTIFICATIONS" default:"false"`
+}
+
+// IdempotencyConfig controls how long Idempotency-Key responses are kept
+// for replay and how often expired keys are deleted.
+type IdempotencyConfig struct {
+	TTL           time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL" default:"24h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"IDEMPOTENCY_PURGE_INTERVAL" default:"1h"`
+}
+
+// RateLimitConfig controls the request rate limiter. Policies are written
+// as "limit/period by key", e.g. "5/10m by ip", where key is ip, user or
+// shop; an empty policy turns that limit off. Use the postgres backend when
+// running more than one instance.
+type RateLimitConfig struct {
+	Enabled       bool          `yaml:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	Backend       string        `yaml:"backend" env:"RATE_LIMIT_BACKEND" default:"memory"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"RATE_LIMIT_PURGE_INTERVAL" default:"10m"`
+	// API applies to every /v1 route.
+	API string `yaml:"api" env:"RATE_LIMIT_API" default:"600/1m by ip"`
+	// OTPSend applies to the endpoints that send an OTP or login link.
+	OTPSend string `yaml:"otp_send" env:"RATE_LIMIT_OTP_SEND" default:"5/10m by ip"`
+	// Chat applies to the LLM-backed chat endpoints.
+	Chat string `yaml:"chat" env:"RATE_LIMIT_CHAT" default:"20/1m by user"`
+}
+
+// Policies parses the configured policies by name. Empty ones are left out.
+func (c RateLimitConfig) Policies() (map[string]ratelimit.Policy, error) {
+	specs := [][2]string{{"api", c.API}, {"otp_send", c.OTPSend}, {"chat", c.Chat}}
+	policies := make(map[string]ratelimit.Policy, len(specs))
+	var errs []error
+	for _, s := range specs {
+		name, spec := s[0], s[1]
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		p, err := ratelimit.ParsePolicy(name, spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_%s: %w", strings.ToUpper(name), err))
+			continue
+		}
+		policies[name] = p
+	}
+	return policies, errors.Join(errs...)
+}
+
+// ChatConfig controls access to the chat endpoints. Signed-in users can
+// always chat; anonymous visitors need a guest token when AllowGuests is on.
+type ChatConfig struct {
+	AllowGuests   bool          `yaml:"allow_guests" env:"CHAT_ALLOW_GUESTS" default:"true"`
+	GuestTokenTTL time.Duration `yaml:"guest_token_ttl" env:"CHAT_GUEST_TOKEN_TTL" default:"168h"`
+	// CacheTTL is how long replies to catalog questions are reused; 0
+	// turns the response cache off.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"CHAT_CACHE_TTL" default:"15m"`
+	// WebFormat is how replies are rendered for the web widget: html, which
+	// the widget inserts as is, or markdown for clients that render it
+	// themselves.
+	WebFormat string `yaml:"web_format" env:"CHAT_WEB_FORMAT" default:"html"`
+	// HandoffTimeout is how long an escalated session waits for staff to
+	// take it before the assistant answers again; 0 waits forever.
+	HandoffTimeout time.Duration `yaml:"handoff_timeout" env:"CHAT_HANDOFF_TIMEOUT" default:"30m"`
+	// StoreReasoning keeps the reasoning of reasoning models for staff to
+	// debug replies. It is never shown to customers.
+	StoreReasoning bool `yaml:"store_reasoning" env:"CHAT_STORE_REASONING" default:"false"`
+}
+
+// KnowledgeConfig controls the shop knowledge base. Chunks are embedded
+// with DeepInfra, so retrieval only runs when DeepInfra is enabled too.
+type KnowledgeConfig struct {
+	Enabled bool `yaml:"enabled" env:"KNOWLEDGE_ENABLED" default:"true"`
+	// TopK chunks scoring at least MinScore are added to the chat prompt.
+	TopK      int     `yaml:"top_k" env:"KNOWLEDGE_TOP_K" default:"4"`
+	MinScore  float64 `yaml:"min_score" env:"KNOWLEDGE_MIN_SCORE" default:"0.35"`
+	ChunkSize int     `yaml:"chunk_size" env:"KNOWLEDGE_CHUNK_SIZE" default:"1200"`
+	// MaxUploadBytes caps the size of one uploaded document.
+	MaxUploadBytes int64 `yaml:"max_upload_bytes" env:"KNOWLEDGE_MAX_UPLOAD_BYTES" default:"5242880"`
+	// MaxChunks caps the chunks of all of a shop's documents, which
+	// Retrieve scores on every chat message.
+	MaxChunks int `yaml:"max_chunks" env:"KNOWLEDGE_MAX_CHUNKS" default:"2000"`
+	// CacheTTL is how long a shop's chunk embeddings are kept in memory.
+	// Uploads and deletes on this instance refresh them at once.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"KNOWLEDGE_CACHE_TTL" default:"5m"`
+	// MaxInflatedBytes caps the decompressed size of all the streams of
+	// one PDF; documents that inflate past it are rejected.
+	MaxInflatedBytes int64 `yaml:"max_inflated_bytes" env:"KNOWLEDGE_MAX_INFLATED_BYTES" default:"52428800"`
+}
+
+// GuardrailConfig controls the checks on customer messages and assistant
+// replies. Blocked events are always logged while Enabled is on.
+type GuardrailConfig struct {
+	Enabled bool `yaml:"enabled" env:"GUARDRAIL_ENABLED" default:"true"`
+	// BlockedTerms adds comma-separated words or phrases to the built-in
+	// list of disallowed content.
+	BlockedTerms string `yaml:"blocked_terms" env:"GUARDRAIL_BLOCKED_TERMS"`
+}
+
+// LLMConfig controls retries, circuit breaking and fallbacks around chat
+// completions. MaxAttempts applies to each provider in turn: the DeepInfra
+// model first, then Fallbacks in order.
+type LLMConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts" env:"LLM_MAX_ATTEMPTS" default:"3"`
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay" env:"LLM_RETRY_BASE_DELAY" default:"300ms"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay" env:"LLM_RETRY_MAX_DELAY" default:"3s"`
+	AttemptTimeout time.Duration `yaml:"attempt_timeout" env:"LLM_ATTEMPT_TIMEOUT" default:"30s"`
+	// A provider is skipped for BreakerCooldown after BreakerThreshold
+	// failures in a row.
+	BreakerThreshold int           `yaml:"breaker_threshold" env:"LLM_BREAKER_THRESHOLD" default:"5"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown" env:"LLM_BREAKER_COOLDOWN" default:"30s"`
+	// Fallbacks is a comma-separated list of provider:model entries, e.g.
+	// "deepinfra:Qwen/Qwen2.5-72B-Instruct, azure". Azure uses its
+	// configured deployment when no model is given.
+	Fallbacks string `yaml:"fallbacks" env:"LLM_FALLBACKS"`
+	// Classifier is the provider:model of a small model that finds the
+	// intent of chat messages the built-in rules do not recognise. Empty
+	// leaves those messages to the chat model.
+	Classifier string `yaml:"classifier" env:"LLM_CLASSIFIER"`
+	// ClassifierTimeout bounds the single classifier attempt; messages it
+	// cannot classify in time go to the chat model.
+	ClassifierTimeout time.Duration `yaml:"classifier_timeout" env:"LLM_CLASSIFIER_TIMEOUT" default:"3s"`
+}
+
+// LLMFallback is one entry of LLMConfig.Fallbacks.
+type LLMFallback struct {
+	Provider string
+	Model    string
+}
+
+// FallbackList parses Fallbacks.
+func (c LLMConfig) FallbackList() ([]LLMFallback, error) {
+	var (
+		list []LLMFallback
+		errs []error
+	)
+	for _, entry := range strings.Split(c.Fallbacks, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		f, err := ParseLLMProvider(entry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("LLM_FALLBACKS: %w", err))
+			continue
+		}
+		list = append(list, f)
+	}
+	return list, errors.Join(errs...)
+}
+
+// ParseLLMProvider parses one provider:model entry, e.g.
+// "deepinfra:Qwen/Qwen2.5-72B-Instruct" or "azure".
+func ParseLLMProvider(entry string) (LLMFallback, error) {
+	entry = strings.TrimSpace(entry)
+	provider, model, _ := strings.Cut(entry, ":")
+	provider, model = strings.TrimSpace(provider), strings.TrimSpace(model)
+	switch {
+	case provider == "deepinfra" && model == "":
+		return LLMFallback{}, fmt.Errorf("%q needs a model", entry)
+	case provider != "deepinfra" && provider != "azure":
+		return LLMFallback{}, fmt.Errorf("unknown provider %q", provider)
+	}
+	return LLMFallback{Provider: provider, Model: model}, nil
+}
+
+// ValidationError lists every configuration problem found by Load.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration:\n  - %s", strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks the loaded values. Credentials are only required for
+// providers that are enabled.
+func (c *Config) Validate() error {
+	var problems []string
+	require := func(value string, name string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		problems = append(problems, "PORT must be between 1 and 65535")
+	}
+	for _, proxy := range c.Server.TrustedProxyList() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES: %q is not an IP or CIDR", proxy))
+		}
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL %q must be one of debug, info, warn, error", c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT %q must be json or text", c.Log.Format))
+	}
+
+	if c.Telemetry.Enabled {
+		require(c.Telemetry.Endpoint, "OTEL_EXPORTER_OTLP_ENDPOINT")
+		require(c.Telemetry.ServiceName, "OTEL_SERVICE_NAME")
+		if c.Telemetry.SampleRatio < 0 || c.Telemetry.SampleRatio > 1 {
+			problems = append(problems, "OTEL_TRACES_SAMPLE_RATIO must be between 0 and 1")
+		}
+	}
+
+	require(c.Database.User, "DB_USER")
+	require(c.Database.Name, "DB_NAME")
+	require(c.Database.Host, "DB_HOST")
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		problems = append(problems, fmt.Sprintf("DB_SSLMODE %q is not a valid sslmode", c.Database.SSLMode))
+	}
+	if c.Database.MaxConns < 1 {
+		problems = append(problems, "DB_MAX_CONNS must be at least 1")
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		problems = append(problems, "DB_MIN_CONNS must be between 0 and DB_MAX_CONNS")
+	}
+
+	require(c.JWT.SecretKey, "JWT_SECRET_KEY")
+
+	if c.Auth.OTPMaxAttempts < 1 {
+		problems = append(problems, "OTP_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.Auth.OTPMaxSendsPerPhone < 1 || c.Auth.OTPMaxSendsPerIP < 1 {
+		problems = append(problems, "OTP_MAX_SENDS_PER_PHONE and OTP_MAX_SENDS_PER_IP must be at least 1")
+	}
+	if c.Auth.MagicLinkURL != "" {
+		if u, err := url.Parse(c.Auth.MagicLinkURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, "MAGIC_LINK_URL must be an absolute URL")
+		}
+	}
+	require(c.Auth.DefaultCustomerRole, "DEFAULT_CUSTOMER_ROLE")
+
+	if c.DeepInfra.Enabled {
+		require(c.DeepInfra.URL, "DEEPINFRA_URL")
+		require(c.DeepInfra.APIKey, "DI_API_KEY")
+		require(c.DeepInfra.Model, "DEEPINFRA_MODEL")
+	}
+
+	if c.Azure.Enabled {
+		require(c.Azure.Endpoint, "AZURE_OPENAI_ENDPOINT")
+		require(c.Azure.APIKey, "AZURE_OPENAI_API_KEY")
+		require(c.Azure.Deployment, "AZURE_OPENAI_DEPLOYMENT")
+	}
+
+	if c.WhatsApp.Enabled {
+		require(c.WhatsApp.AccessToken, "WHATSAPP_ACCESS_TOKEN")
+		require(c.WhatsApp.PhoneNumberID, "WHATSAPP_PHONE_NUMBER_ID")
+	}
+
+	if c.SMTP.Host != "" {
+		require(c.SMTP.From, "SMTP_FROM")
+		if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+			problems = append(problems, "SMTP_PORT must be between 1 and 65535")
+		}
+	}
+
+	if c.Health.Timeout <= 0 {
+		problems = append(problems, "HEALTH_CHECK_TIMEOUT must be positive")
+	}
+
+	if c.Idempotency.TTL <= 0 || c.Idempotency.PurgeInterval <= 0 {
+		problems = append(problems, "IDEMPOTENCY_TTL and IDEMPOTENCY_PURGE_INTERVAL must be positive")
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "postgres" {
+			problems = append(problems, fmt.Sprintf("RATE_LIMIT_BACKEND %q must be memory or postgres", c.RateLimit.Backend))
+		}
+		if c.RateLimit.PurgeInterval <= 0 {
+			problems = append(problems, "RATE_LIMIT_PURGE_INTERVAL must be positive")
+		}
+		if _, err := c.RateLimit.Policies(); err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				problems = append(problems, line)
+			}
+		}
+	}
+
+	if c.Chat.AllowGuests && c.Chat.GuestTokenTTL <= 0 {
+		problems = append(problems, "CHAT_GUEST_TOKEN_TTL must be positive")
+	}
+	if c.Chat.CacheTTL < 0 {
+		problems = append(problems, "CHAT_CACHE_TTL must not be negative")
+	}
+	if c.Chat.HandoffTimeout < 0 {
+		problems = append(problems, "CHAT_HANDOFF_TIMEOUT must not be negative")
+	}
+	switch c.Chat.WebFormat {
+	case "markdown", "html":
+	default:
+		problems = append(problems, fmt.Sprintf("CHAT_WEB_FORMAT %q must be markdown or html", c.Chat.WebFormat))
+	}
+
+	if c.Knowledge.Enabled {
+		if c.Knowledge.TopK < 1 || c.Knowledge.TopK > 20 {
+			problems = append(problems, "KNOWLEDGE_TOP_K must be between 1 and 20")
+		}
+		if c.Knowledge.MinScore < 0 || c.Knowledge.MinScore > 1 {
+			problems = append(problems, "KNOWLEDGE_MIN_SCORE must be between 0 and 1")
+		}
+		if c.Knowledge.ChunkSize < 200 || c.Knowledge.ChunkSize > 8000 {
+			problems = append(problems, "KNOWLEDGE_CHUNK_SIZE must be between 200 and 8000")
+		}
+		if c.Knowledge.MaxUploadBytes < 1 {
+			problems = append(problems, "KNOWLEDGE_MAX_UPLOAD_BYTES must be positive")
+		}
+		if c.Knowledge.MaxChunks < 1 {
+			problems = append(problems, "KNOWLEDGE_MAX_CHUNKS must be positive")
+		}
+		if c.Knowledge.CacheTTL < 0 {
+			problems = append(problems, "KNOWLEDGE_CACHE_TTL must not be negative")
+		}
+		if c.Knowledge.MaxInflatedBytes < 1 {
+			problems = append(problems, "KNOWLEDGE_MAX_INFLATED_BYTES must be positive")
+		}
+		if c.DeepInfra.Enabled {
+			require(c.DeepInfra.EmbeddingModel, "DEEPINFRA_EMBEDDING_MODEL")
+		}
+	}
+
+	if c.LLM.MaxAttempts < 1 {
+		problems = append(problems, "LLM_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.LLM.RetryBaseDelay <= 0 || c.LLM.RetryMaxDelay < c.LLM.RetryBaseDelay {
+		problems = append(problems, "LLM_RETRY_BASE_DELAY must be positive and at most LLM_RETRY_MAX_DELAY")
+	}
+	if c.LLM.AttemptTimeout <= 0 || c.LLM.BreakerCooldown <= 0 {
+		problems = append(problems, "LLM_ATTEMPT_TIMEOUT and LLM_BREAKER_COOLDOWN must be positive")
+	}
+	if c.LLM.BreakerThreshold < 1 {
+		problems = append(problems, "LLM_BREAKER_THRESHOLD must be at least 1")
+	}
+	fallbacks, err := c.LLM.FallbackList()
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			problems = append(problems, line)
+		}
+	}
+	for _, f := range fallbacks {
+		if f.Provider == "azure" && !c.Azure.Enabled {
+			problems = append(problems, "LLM_FALLBACKS uses azure but AZURE_OPENAI_ENABLED is off")
+		}
+		if f.Provider == "deepinfra" && !c.DeepInfra.Enabled {
+			problems = append(problems, "LLM_FALLBACKS uses deepinfra but DEEPINFRA_ENABLED is off")
+		}
+	}
+	if strings.TrimSpace(c.LLM.Classifier) != "" {
+		classifier, err := ParseLLMProvider(c.LLM.Classifier)
+		switch {
+		case err != nil:
+			problems = append(problems, "LLM_CLASSIFIER: "+err.Error())
+		case classifier.Provider == "azure" && !c.Azure.Enabled:
+			problems = append(problems, "LLM_CLASSIFIER uses azure but AZURE_OPENAI_ENABLED is off")
+		case classifier.Provider == "deepinfra" && !c.DeepInfra.Enabled:
+			problems = append(problems, "LLM_CLASSIFIER uses deepinfra but DEEPINFRA_ENABLED is off")
+		}
+		if c.LLM.ClassifierTimeout <= 0 {
+			problems = append(problems, "LLM_CLASSIFIER_TIMEOUT must be positive")
+		}
+	}
+
+	if c.Seed.AdminEmail != "" && c.Seed.AdminPhone != "" {
+		problems = append(problems, "set only one of SEED_ADMIN_EMAIL and SEED_ADMIN_PHONE")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
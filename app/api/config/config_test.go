@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "shofy")
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	t.Setenv("DI_API_KEY", "key")
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != 8080 {
+		t.Errorf("Server.Port = %d, want 8080", cfg.Server.Port)
+	}
+	if got := cfg.Database.DSN(); got != "postgres://postgres:@localhost:5432/shofy?sslmode=disable" {
+		t.Errorf("DSN() = %q", got)
+	}
+	if cfg.Azure.Enabled {
+		t.Error("Azure should be disabled by default")
+	}
+}
+
+func TestLoad_ReportsAllProblems(t *testing.T) {
+	t.Setenv("PORT", "abc")
+	t.Setenv("DB_SSLMODE", "sometimes")
+	t.Setenv("AZURE_OPENAI_ENABLED", "true")
+
+	_, err := Load()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load() error = %v, want *ValidationError", err)
+	}
+
+	msg := err.Error()
+	for _, want := range []string{"PORT", "DB_SSLMODE", "DB_USER", "JWT_SECRET_KEY", "AZURE_OPENAI_ENDPOINT"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %s", msg, want)
+		}
+	}
+	if strings.Contains(msg, "WHATSAPP_ACCESS_TOKEN") {
+		t.Errorf("disabled WhatsApp should not be required: %q", msg)
+	}
+}
+
+func TestLoad_YAMLOverriddenByEnv(t *testing.T) {
+	setRequiredEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "server:\n  port: 9000\ndatabase:\n  host: db.internal\n  max_conns: 20\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "9100")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != 9100 {
+		t.Errorf("Server.Port = %d, want env value 9100", cfg.Server.Port)
+	}
+	if cfg.Database.Host != "db.internal" || cfg.Database.MaxConns != 20 {
+		t.Errorf("Database = %+v, want YAML values", cfg.Database)
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/subosito/gotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads .env, the YAML file named by CONFIG_FILE (if any) and the
+// environment into a Config. Every parse and validation problem is
+// reported together in a single *ValidationError.
+func Load() (*Config, error) {
+	// A missing .env is fine; real environment variables take precedence.
+	_ = gotenv.Load()
+
+	cfg := &Config{}
+	var problems []string
+
+	if err := applyTags(reflect.ValueOf(cfg).Elem(), "default", func(key string) (string, bool) {
+		return key, key != ""
+	}); err != nil {
+		problems = append(problems, err...)
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadYAML(path, cfg); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if err := applyTags(reflect.ValueOf(cfg).Elem(), "env", func(key string) (string, bool) {
+		value, ok := os.LookupEnv(key)
+		return value, ok && value != ""
+	}); err != nil {
+		problems = append(problems, err...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			problems = append(problems, validationErr.Problems...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func loadYAML(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("CONFIG_FILE: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("CONFIG_FILE %s: %v", path, err)
+	}
+	return nil
+}
+
+// applyTags walks the struct and sets each field whose tag resolves to a
+// value. It returns one problem per field that could not be parsed.
+func applyTags(v reflect.Value, tag string, lookup func(string) (string, bool)) []string {
+	var problems []string
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			problems = append(problems, applyTags(value, tag, lookup)...)
+			continue
+		}
+
+		key := field.Tag.Get(tag)
+		raw, ok := lookup(key)
+		if key == "" || !ok {
+			continue
+		}
+
+		name := key
+		if tag == "default" {
+			name = field.Tag.Get("env")
+		}
+		if err := setField(value, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return problems
+}
+
+func setField(value reflect.Value, raw string) error {
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		value.SetFloat(f)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", value.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"shofy/app/api/config"
+	"shofy/db/migration"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+var ErrSchemaMismatch = errors.New("database schema version mismatch")
+
+// Migrator applies the migrations embedded from db/migration.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+type MigrationInfo struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+type MigrationStatus struct {
+	Current    uint
+	Latest     uint
+	Dirty      bool
+	Migrations []MigrationInfo
+}
+
+func NewMigrator(cfg config.DatabaseConfig) (*Migrator, error) {
+	src, err := iofs.New(migration.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	dsn := "pgx5" + strings.TrimPrefix(cfg.DSN(), "postgres")
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init migrations: %w", err)
+	}
+	return &Migrator{m: m}, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Down rolls back the given number of migrations.
+func (m *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1")
+	}
+	if err := m.m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+func (m *Migrator) Status() (*MigrationStatus, error) {
+	current, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{Current: current, Dirty: dirty}
+	for i := range migrations {
+		migrations[i].Applied = migrations[i].Version <= current
+		status.Latest = migrations[i].Version
+	}
+	status.Migrations = migrations
+	return status, nil
+}
+
+// CheckVersion returns ErrSchemaMismatch unless the database is clean and at
+// the latest embedded version.
+func (m *Migrator) CheckVersion() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w: version %d is dirty", ErrSchemaMismatch, status.Current)
+	}
+	if status.Current != status.Latest {
+		return fmt.Errorf("%w: database is at %d, binary expects %d", ErrSchemaMismatch, status.Current, status.Latest)
+	}
+	return nil
+}
+
+// embeddedMigrations lists the up migrations in version order.
+func embeddedMigrations() ([]MigrationInfo, error) {
+	entries, err := fs.ReadDir(migration.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	var migrations []MigrationInfo
+	for _, entry := range entries {
+		parsed, err := source.DefaultParse(entry.Name())
+		if err != nil || parsed.Direction != source.Up {
+			continue
+		}
+		migrations = append(migrations, MigrationInfo{
+			Version: parsed.Version,
+			Name:    parsed.Identifier,
+		})
+	}
+	return migrations, nil
+}
+
+// LatestVersion is the highest embedded migration version, i.e. the schema
+// version this binary expects.
+func LatestVersion() (uint, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"io/fs"
+	"shofy/db/migration"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+func TestEmbeddedMigrations_HaveUpAndDown(t *testing.T) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		t.Fatalf("embeddedMigrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	downs := map[uint]bool{}
+	entries, _ := fs.ReadDir(migration.FS, ".")
+	for _, entry := range entries {
+		if parsed, err := source.DefaultParse(entry.Name()); err == nil && parsed.Direction == source.Down {
+			downs[parsed.Version] = true
+		}
+	}
+
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("migration %d is out of order", m.Version)
+		}
+		if !downs[m.Version] {
+			t.Errorf("migration %d has no down file", m.Version)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shofy/app/api/config"
+	db "shofy/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Channels allowed by the channel.name CHECK constraint.
+var defaultChannels = []string{"whatsapp", "facebook", "instagram", "telegram", "sms", "system"}
+
+const superAdminRole = "SUPER_ADMIN"
+
+// Seed inserts reference data and, when configured, a super admin. It is
+// safe to run more than once.
+func Seed(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(tx)
+
+	for _, name := range defaultChannels {
+		if err := q.EnsureChannel(ctx, name); err != nil {
+			return fmt.Errorf("failed to seed channel %s: %w", name, err)
+		}
+	}
+
+	for _, name := range []string{superAdminRole, "ADMIN", cfg.Auth.DefaultCustomerRole} {
+		if err := q.EnsureRole(ctx, name); err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", name, err)
+		}
+	}
+
+	if cfg.Seed.AdminEmail != "" || cfg.Seed.AdminPhone != "" {
+		if err := seedSuperAdmin(ctx, q, cfg.Seed); err != nil {
+			return err
+		}
+	} else {
+		slog.InfoContext(ctx, "SEED_ADMIN_EMAIL and SEED_ADMIN_PHONE are not set, skipping super admin")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	return nil
+}
+
+func seedSuperAdmin(ctx context.Context, q *db.Queries, cfg config.SeedConfig) error {
+	shop, err := q.GetFirstShop(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		shop, err = q.CreateShops(ctx, db.CreateShopsParams{
+			Name:        cfg.ShopName,
+			Description: cfg.ShopName,
+			Address:     "-",
+			City:        "-",
+			State:       "-",
+			ZipCode:     "-",
+			Country:     "-",
+			IsActive:    true,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to seed shop: %w", err)
+	}
+
+	var user db.User
+	if cfg.AdminEmail != "" {
+		email := pgtype.Text{String: cfg.AdminEmail, Valid: true}
+		user, err = q.FindUserByShopAndEmail(ctx, db.FindUserByShopAndEmailParams{ShopID: shop.ID, Email: email})
+		if errors.Is(err, pgx.ErrNoRows) {
+			user, err = q.CreateUser(ctx, db.CreateUserParams{
+				ShopID:   shop.ID,
+				Email:    email,
+				IsActive: pgtype.Bool{Bool: true, Valid: true},
+			})
+		}
+	} else {
+		phone := pgtype.Text{String: cfg.AdminPhone, Valid: true}
+		user, err = q.FindUserByShopAndPhone(ctx, db.FindUserByShopAndPhoneParams{ShopID: shop.ID, Phone: phone})
+		if errors.Is(err, pgx.ErrNoRows) {
+			user, err = q.CreateUser(ctx, db.CreateUserParams{
+				ShopID:   shop.ID,
+				Phone:    phone,
+				CodeArea: pgtype.Text{String: cfg.AdminCodeArea, Valid: true},
+				IsActive: pgtype.Bool{Bool: true, Valid: true},
+			})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to seed super admin: %w", err)
+	}
+
+	role, err := q.GetRoleByName(ctx, superAdminRole)
+	if err != nil {
+		return fmt.Errorf("failed to find role %s: %w", superAdminRole, err)
+	}
+
+	if err := q.CreateUserRole(ctx, db.CreateUserRoleParams{UserID: user.ID, RoleID: role.ID}); err != nil {
+		return fmt.Errorf("failed to assign role %s: %w", superAdminRole, err)
+	}
+
+	slog.InfoContext(ctx, "Super admin is ready", "user_id", user.ID, "shop_id", shop.ID)
+	return nil
+}
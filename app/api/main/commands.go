@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"shofy/app/api/config"
+	"shofy/app/api/database"
+	"strconv"
+)
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n\n%s", usage)
+	}
+
+	migrator, err := database.NewMigrator(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], usage)
+	}
+
+	return printStatus(migrator)
+}
+
+func printStatus(migrator *database.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range status.Migrations {
+		state := "pending"
+		if m.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%06d  %-8s %s\n", m.Version, state, m.Name)
+	}
+
+	fmt.Printf("\ncurrent version: %d, latest: %d", status.Current, status.Latest)
+	if status.Dirty {
+		fmt.Print(" (dirty)")
+	}
+	fmt.Println()
+	return nil
+}
+
+func runSeed(ctx context.Context) error {
+	pool, err := config.LoadDbConfig(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	if err := database.Seed(ctx, pool, cfg); err != nil {
+		return err
+	}
+	fmt.Println("seed completed")
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"shofy/app/api/config"
+	db "shofy/db/sqlc"
+	chatService "shofy/modules/chat/service"
+	evalModel "shofy/modules/eval/model"
+	evalService "shofy/modules/eval/service"
+	guardrailService "shofy/modules/guardrail/service"
+	knowledgeService "shofy/modules/knowledge/service"
+	languageService "shofy/modules/language/service"
+	llmService "shofy/modules/llm/service"
+	promptService "shofy/modules/prompt/service"
+	"strings"
+	"time"
+)
+
+const evalUsage = `usage:
+  shofy eval run [flags]          run a suite against the seeded test catalog
+  shofy eval compare <base> <new> compare two reports
+
+run flags:`
+
+func runEval(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing eval command\n\n%s", evalUsage)
+	}
+	switch args[0] {
+	case "run":
+		return runEvalSuite(ctx, args[1:])
+	case "compare":
+		if len(args) != 3 {
+			return fmt.Errorf("compare needs two reports\n\n%s", evalUsage)
+		}
+		base, err := evalService.LoadReport(args[1])
+		if err != nil {
+			return err
+		}
+		next, err := evalService.LoadReport(args[2])
+		if err != nil {
+			return err
+		}
+		evalService.WriteComparison(os.Stdout, base, next)
+		return nil
+	}
+	return fmt.Errorf("unknown eval command %q\n\n%s", args[0], evalUsage)
+}
+
+func runEvalSuite(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("eval run", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), evalUsage)
+		flags.PrintDefaults()
+	}
+	var (
+		suitePath    = flags.String("suite", "", "JSONL suite (default: built-in suite)")
+		catalogPath  = flags.String("catalog", "", "JSON catalog to seed (default: built-in catalog)")
+		providerSpec = flags.String("provider", "", "provider:model to evaluate, e.g. deepinfra:Qwen/Qwen2.5-72B-Instruct (default: configured providers with fallbacks)")
+		templatePath = flags.String("template", "", "draft prompt template to evaluate (default: the shop's active or built-in template)")
+		judgeSpec    = flags.String("judge", "", "provider:model of the LLM judge (default: no judge)")
+		judgeMin     = flags.Float64("judge-min", 0.75, "judge score from 0 to 1 a case needs to pass")
+		label        = flags.String("label", "", "name of the run in reports (default: time and provider)")
+		outDir       = flags.String("out", "eval-results", "directory for the JSON report")
+		baselinePath = flags.String("baseline", "", "report to compare with; regressions fail the command")
+		anyDB        = flags.Bool("allow-any-db", false, "seed the catalog into a database whose name does not end in _eval or _test")
+	)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	cases, err := evalService.LoadSuite(*suitePath)
+	if err != nil {
+		return fmt.Errorf("invalid suite: %w", err)
+	}
+	catalog, err := evalService.LoadCatalog(*catalogPath)
+	if err != nil {
+		return err
+	}
+	if err := evalService.CheckSuite(cases, catalog); err != nil {
+		return fmt.Errorf("suite does not match catalog: %w", err)
+	}
+
+	var template string
+	if *templatePath != "" {
+		content, err := os.ReadFile(*templatePath)
+		if err != nil {
+			return err
+		}
+		template = string(content)
+	}
+
+	llm, provider, model, err := evalClient(ctx, *providerSpec)
+	if err != nil {
+		return err
+	}
+
+	var judge *evalService.Judge
+	if *judgeSpec != "" {
+		spec, err := config.ParseLLMProvider(*judgeSpec)
+		if err != nil {
+			return fmt.Errorf("-judge: %w", err)
+		}
+		judgeLLM, err := llmService.NewProviderClient(ctx, cfg, spec)
+		if err != nil {
+			return fmt.Errorf("-judge: %w", err)
+		}
+		judge = &evalService.Judge{LLM: judgeLLM}
+	}
+
+	if !*anyDB && !isEvalDatabase(cfg.Database.Name) {
+		return fmt.Errorf("eval seeds a test shop into database %q; use a database whose name ends in _eval or _test, or pass -allow-any-db", cfg.Database.Name)
+	}
+	if err := checkSchema(cfg); err != nil {
+		return err
+	}
+	pool, err := config.LoadDbConfig(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	shopID, err := evalService.SeedCatalog(ctx, pool, catalog)
+	if err != nil {
+		return err
+	}
+
+	// Cached replies would hide changes to the model or prompt.
+	chatConfig := cfg.Chat
+	chatConfig.CacheTTL = 0
+	chat := chatService.NewChatService(ctx, pool, db.New(pool), llm, chatConfig)
+	chat.Guardrail = guardrailService.NewGuardrailService(pool, cfg.Guardrail)
+	chat.Language = languageService.NewLanguageService(pool, llm)
+	classifier, err := llmService.NewClassifierClient(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("classifier: %w", err)
+	}
+	if classifier != nil {
+		chat.Classifier = classifier
+	}
+	knowledge := knowledgeService.NewKnowledgeService(pool, knowledgeService.NewDeepInfraEmbedder(ctx, cfg.DeepInfra), cfg.Knowledge)
+	prompts := promptService.NewPromptService(pool, knowledge, chat.Language)
+	chat.Prompts = prompts
+	if template != "" {
+		chat.Prompts = evalService.DraftPrompts{Prompts: prompts, Template: template}
+	}
+	runner := &evalService.Runner{
+		Assistant: chat,
+		ShopID:    shopID,
+		Catalog:   catalog,
+		Judge:     judge,
+		JudgeMin:  *judgeMin,
+	}
+
+	started := time.Now()
+	results := runner.Run(ctx, cases)
+	report := evalModel.Report{
+		Label:      *label,
+		Provider:   provider,
+		Model:      model,
+		Judge:      *judgeSpec,
+		Suite:      *suitePath,
+		Template:   *templatePath,
+		StartedAt:  started,
+		DurationMS: time.Since(started).Milliseconds(),
+		Summary:    evalService.Summarize(results),
+		Results:    results,
+	}
+	if report.Suite == "" {
+		report.Suite = evalService.DefaultSuite
+	}
+	if report.Label == "" {
+		report.Label = fmt.Sprintf("%s-%s", started.Format("20060102-150405"), provider)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(*outDir, report.Label+".json")
+	if err := evalService.SaveReport(path, report); err != nil {
+		return err
+	}
+	evalService.WriteReport(os.Stdout, report)
+	fmt.Printf("\nreport written to %s\n", path)
+
+	if *baselinePath == "" {
+		return nil
+	}
+	baseline, err := evalService.LoadReport(*baselinePath)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	evalService.WriteComparison(os.Stdout, baseline, report)
+	if regressed := evalService.Compare(baseline, report).Regressed; len(regressed) > 0 {
+		return fmt.Errorf("%d cases regressed against %s", len(regressed), baseline.Label)
+	}
+	return nil
+}
+
+// evalClient returns the client to evaluate and the provider and model it
+// uses: one provider when spec is set, otherwise the configured chain.
+func evalClient(ctx context.Context, spec string) (*llmService.Client, string, string, error) {
+	if spec == "" {
+		client := llmService.NewClient(ctx, cfg)
+		if len(client.Providers) == 0 {
+			return nil, "", "", llmService.ErrNoProviders
+		}
+		return client, client.Providers[0].Name, client.Providers[0].Model, nil
+	}
+
+	provider, err := config.ParseLLMProvider(spec)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("-provider: %w", err)
+	}
+	client, err := llmService.NewProviderClient(ctx, cfg, provider)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("-provider: %w", err)
+	}
+	return client, provider.Provider, client.Providers[0].Model, nil
+}
+
+// isEvalDatabase reports whether a database is named as one for tests,
+// where seeding the eval catalog cannot touch real shops.
+func isEvalDatabase(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, "_eval") || strings.HasSuffix(name, "_test")
+}
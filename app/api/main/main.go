@@ -2,16 +2,22 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"shofy/app/api/config"
+	"shofy/app/api/database"
 	"shofy/app/api/router"
 	"shofy/app/api/server"
-	"time"
+	"shofy/utils/jwt"
+	"shofy/utils/logger"
+	"shofy/utils/telemetry"
+	"shofy/utils/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/subosito/gotenv"
 )
 
 var (
@@ -19,27 +25,101 @@ var (
 	apiRouter *gin.Engine
 	ctx       context.Context
 	srv       *server.Server
+	cfg       *config.Config
 )
 
+const usage = `usage: shofy <command> [args]
+
+commands:
+  serve                 start the API server (default)
+  migrate up            apply all pending migrations
+  migrate down [n]      roll back n migrations (default 1)
+  migrate status        show applied and pending migrations
+  seed                  insert channels, default roles and the super admin
+  eval run [flags]      run the assistant eval suite against a test catalog
+  eval compare <a> <b>  compare two eval reports`
+
 func initServer(ctx context.Context) {
-	gotenv.Load()
+	var err error
+	jwt.SetSecretKey(cfg.JWT.SecretKey)
+
+	if err = checkSchema(cfg); err != nil {
+		fatal("Schema check failed", err)
+	}
 
-	srv = server.NewServer(ctx)
+	if err = validation.Setup(); err != nil {
+		fatal("Failed to set up request validation", err)
+	}
+
+	srv, err = server.NewServer(ctx, cfg)
+	if err != nil {
+		fatal("Failed to start server", err)
+	}
 	apiRouter = router.InitRouter(ctx, srv)
 }
 
 func main() {
 	ctx = context.Background()
 
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve", "migrate", "seed", "eval":
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	cfg, err = config.Load()
+	if err != nil {
+		// The logger is not configured yet, so print the problems as-is.
+		log.Fatal(err)
+	}
+	logger.Setup(cfg.Log)
+
+	switch command {
+	case "serve":
+		serve(ctx)
+	case "migrate":
+		err = runMigrate(args)
+	case "seed":
+		err = runSeed(ctx)
+	case "eval":
+		err = runEval(ctx, args)
+	}
+
+	if err != nil {
+		fatal("Command failed", err, "command", command)
+	}
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append([]any{"error", err}, args...)...)
+	os.Exit(1)
+}
+
+func serve(ctx context.Context) {
+	shutdownTracing, err := telemetry.Setup(ctx, cfg.Telemetry)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
 	initServer(ctx)
 
 	httpServer := &http.Server{
-		Addr:    ":8080",
-		Handler: apiRouter,
+		Addr:     fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:  apiRouter,
+		ErrorLog: logger.StdLogger(slog.LevelError),
 	}
 	go func() {
+		slog.Info("Server listening", "port", cfg.Server.Port)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
+			fatal("Server stopped", err)
 		}
 	}()
 	// Graceful shutdown
@@ -49,10 +129,34 @@ func main() {
 	<-stop // Wait for interrupt signal
 
 	// Create a context with a timeout for the shutdown
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := httpServer.Shutdown(ctx); err != nil {
 		panic(err)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+}
+
+// checkSchema applies pending migrations when auto-migrate is on and refuses
+// to continue unless the database matches the embedded migrations.
+func checkSchema(cfg *config.Config) error {
+	migrator, err := database.NewMigrator(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if cfg.Database.AutoMigrate {
+		if err := migrator.Up(); err != nil {
+			return fmt.Errorf("auto-migrate failed: %w", err)
+		}
+	}
+
+	if err := migrator.CheckVersion(); err != nil {
+		return fmt.Errorf("%w (run `migrate up` or set DB_AUTO_MIGRATE=true)", err)
+	}
+	return nil
 }
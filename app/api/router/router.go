@@ -2,55 +2,106 @@ package router
 
 import (
 	"context"
-	"net/http"
+	"log/slog"
+	"shofy/app/api/config"
 	"shofy/app/api/server"
 	middleware "shofy/middleware"
 	categoryHandler "shofy/modules/categories/handler"
 	categoryService "shofy/modules/categories/service"
 	chatHandler "shofy/modules/chat/handler"
+	chatService "shofy/modules/chat/service"
+	deepinfraService "shofy/modules/deepinfra/service"
+	guardrailHandler "shofy/modules/guardrail/handler"
+	guardrailService "shofy/modules/guardrail/service"
+	healthHandler "shofy/modules/health/handler"
+	healthService "shofy/modules/health/service"
+	idempotencyService "shofy/modules/idempotency/service"
+	knowledgeHandler "shofy/modules/knowledge/handler"
+	knowledgeService "shofy/modules/knowledge/service"
+	languageHandler "shofy/modules/language/handler"
+	languageService "shofy/modules/language/service"
+	llmService "shofy/modules/llm/service"
+	notificationService "shofy/modules/notification/service"
 	orderHandler "shofy/modules/orders/handler"
 	orderService "shofy/modules/orders/service"
 	productHandler "shofy/modules/product/handler"
 	pdService "shofy/modules/product/service"
+	promptHandler "shofy/modules/prompt/handler"
+	promptService "shofy/modules/prompt/service"
 	rlHandler "shofy/modules/role/handler"
 	rlService "shofy/modules/role/service"
 	shopsHandler "shofy/modules/shops/handler"
 	shopsService "shofy/modules/shops/service"
 	usHandler "shofy/modules/users/handler"
 	usService "shofy/modules/users/service"
+	"shofy/utils/metrics"
+	"shofy/utils/ratelimit"
 	"time"
 
 	"github.com/gin-contrib/cors"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func InitRouter(ctx context.Context, srv *server.Server) *gin.Engine {
-	router := gin.Default()
+	router := newEngine(ctx, srv.Config.Server)
+	router.Use(
+		otelgin.Middleware(srv.Config.Telemetry.ServiceName),
+		middleware.RequestID(),
+		middleware.RequestLogger(),
+		middleware.Metrics(),
+		gin.Recovery(),
+		middleware.ErrorHandler(),
+	)
 
 	// ✅ Tambahkan middleware CORS DI SINI
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"}, // FE and BE addresses
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins: []string{"*"}, // FE and BE addresses
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Authorization", "Content-Type", middleware.RequestIDHeader, middleware.IdempotencyKeyHeader},
+		ExposeHeaders: []string{
+			"Content-Length", "Retry-After", middleware.RequestIDHeader, middleware.IdempotentReplayedHeader,
+			middleware.RateLimitLimitHeader, middleware.RateLimitRemainingHeader, middleware.RateLimitResetHeader, middleware.RateLimitPolicyHeader,
+		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	// Public endpoints
-	router.GET("/health", healthCheck)
+	healthHandler := healthHandler.NewHealthHandler(newHealthService(ctx, srv))
+	healthHandler.InitRoutes(router)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
-	v1Router := router.Group("/v1")
+	limits := newRateLimits(ctx, srv)
+	v1Router := router.Group("/v1", middleware.DBCircuit(), limits("api"))
 
 	// Public routes
-	authService := usService.NewAuthService(srv.DBPool)
+	authService := usService.NewAuthService(srv.DBPool, srv.Config)
 	authHandler := usHandler.NewAuthHandler(authService)
-	authHandler.InitRoutes(v1Router)
+	authHandler.InitRoutes(v1Router, limits("otp_send"))
+
+	// Retried POSTs with the same Idempotency-Key replay the first response
+	idempotencyStore := idempotencyService.NewIdempotencyService(srv.DBPool, srv.Config.Idempotency.TTL)
+	go idempotencyService.RunPurger(ctx, idempotencyStore, srv.Config.Idempotency.PurgeInterval)
+	idempotent := middleware.Idempotency(idempotencyStore)
 
 	// Chat routes
-	chatRouter := chatHandler.NewChatAPIRoutes(ctx, srv)
-	chatRouter.InitRoutes(v1Router)
+	// Shop documents the assistant answers from, shared by the chat and
+	// admin routes so that uploads refresh the embeddings chat retrieves
+	knowledgeService := knowledgeService.NewKnowledgeService(srv.DBPool, knowledgeService.NewDeepInfraEmbedder(ctx, srv.Config.DeepInfra), srv.Config.Knowledge)
+
+	chatRouter := chatHandler.NewChatAPIRoutes(ctx, srv, knowledgeService)
+	go chatService.RunCachePurger(ctx, srv.Queries, srv.Config.Chat.CacheTTL)
+	go chatService.RunHandoffExpirer(ctx, srv.Queries, srv.Config.Chat.HandoffTimeout)
+	// Only the endpoints that call the model use the chat budget, so web
+	// clients polling for staff replies do not use it up
+	chatAuth := middleware.ChatAuth(srv.Config.Chat.AllowGuests)
+	chatRouter.InitRoutes(
+		v1Router.Group("", limits("chat")),
+		v1Router.Group("", chatAuth, idempotent),
+		v1Router.Group("", chatAuth, limits("chat"), idempotent),
+	)
 
 	// Product routes (tanpa autentikasi)
 	// productService := pdService.NewProductService(srv.DBPool)
@@ -59,7 +110,7 @@ func InitRouter(ctx context.Context, srv *server.Server) *gin.Engine {
 
 	orderService := orderService.NewOrderService(srv.DBPool)
 	orderHandler := orderHandler.NewOrderHandler(orderService)
-	orderHandler.InitRoutes(v1Router.Group("/orders"))
+	orderHandler.InitRoutes(v1Router.Group("/orders", idempotent))
 
 	shopsService := shopsService.NewShopsService(srv.DBPool)
 	shopsHandler := shopsHandler.NewShopsHandler(shopsService)
@@ -85,11 +136,92 @@ func InitRouter(ctx context.Context, srv *server.Server) *gin.Engine {
 		roleService := rlService.NewRoleService(srv.DBPool)
 		roleHandler := rlHandler.NewRoleHandler(roleService)
 		roleHandler.InitRoutes(protectedRoutes.Group("/roles"))
+
+		// Shop documents the assistant answers from
+		knowledgeHandler := knowledgeHandler.NewKnowledgeHandler(knowledgeService)
+		knowledgeHandler.InitRoutes(protectedRoutes.Group("/admin/knowledge"))
+
+		// Versioned chat prompt templates of the staff member's shop
+		languageService := languageService.NewLanguageService(srv.DBPool, llmService.NewClient(ctx, srv.Config))
+		promptService := promptService.NewPromptService(srv.DBPool, knowledgeService, languageService)
+		promptHandler := promptHandler.NewPromptHandler(promptService)
+		promptHandler.InitRoutes(protectedRoutes.Group("/admin/prompts"))
+
+		// Chat guardrail allow-list and blocked events
+		guardrailService := guardrailService.NewGuardrailService(srv.DBPool, srv.Config.Guardrail)
+		guardrailHandler := guardrailHandler.NewGuardrailHandler(guardrailService)
+		guardrailHandler.InitRoutes(protectedRoutes.Group("/admin/guardrails"))
+
+		// Languages the assistant answers in and cached product translations
+		languageHandler := languageHandler.NewLanguageHandler(languageService)
+		languageHandler.InitRoutes(protectedRoutes.Group("/admin/languages"))
+
+		// Staff view and takeover of chat sessions
+
+		chatRouter.InitAdminRoutes(protectedRoutes.Group("/admin/chat", idempotent))
 	}
 
 	return router
 }
 
-func healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "OK"})
+// newEngine returns a gin engine that only reads client IPs from the
+// forwarding headers of the configured proxies.
+func newEngine(ctx context.Context, cfg config.ServerConfig) *gin.Engine {
+	router := gin.New()
+	// The list was checked when the config was loaded.
+	if err := router.SetTrustedProxies(cfg.TrustedProxyList()); err != nil {
+		slog.ErrorContext(ctx, "Invalid trusted proxies, trusting none", "error", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+	return router
+}
+
+// newRateLimits returns a middleware factory for the configured policies.
+// Disabled or unset policies get a middleware that lets everything through.
+func newRateLimits(ctx context.Context, srv *server.Server) func(policy string) gin.HandlerFunc {
+	cfg := srv.Config.RateLimit
+	// The specs were checked when the config was loaded.
+	policies, _ := cfg.Policies()
+
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if cfg.Backend == "postgres" {
+		limiter = ratelimit.NewPostgresLimiter(srv.DBPool)
+	}
+	if cfg.Enabled {
+		// A bucket idle for its longest period is full again and can go.
+		var idle time.Duration
+		for _, p := range policies {
+			idle = max(idle, p.Period)
+		}
+		go ratelimit.RunPurger(ctx, limiter, cfg.PurgeInterval, idle)
+	}
+
+	return func(policy string) gin.HandlerFunc {
+		if !cfg.Enabled {
+			return middleware.RateLimit(limiter, ratelimit.Policy{})
+		}
+		return middleware.RateLimit(limiter, policies[policy])
+	}
+}
+
+// newHealthService registers readiness checks for the providers that are
+// enabled and opted in via the health config.
+func newHealthService(ctx context.Context, srv *server.Server) healthService.HealthService {
+	cfg := srv.Config
+	var checkers []healthService.Checker
+
+	if cfg.Health.CheckLLM && cfg.DeepInfra.Enabled {
+		checkers = append(checkers, healthService.PingChecker("llm_deepinfra", deepinfraService.NewOpenAIService(ctx, cfg.DeepInfra)))
+	}
+
+	if cfg.Health.CheckNotifications {
+		if cfg.WhatsApp.Enabled {
+			checkers = append(checkers, healthService.PingChecker("whatsapp", notificationService.NewWhatsAppService(cfg.WhatsApp)))
+		}
+		if cfg.SMTP.Host != "" {
+			checkers = append(checkers, healthService.PingChecker("smtp", notificationService.NewEmailService(cfg.SMTP)))
+		}
+	}
+
+	return healthService.NewHealthService(srv.DBPool, cfg.Health.Timeout, checkers...)
 }
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shofy/app/api/config"
+	"shofy/middleware"
+	"shofy/utils/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitKeyIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.Policy{Name: "otp_send", Limit: 1, Period: time.Minute, Key: ratelimit.KeyIP}
+
+	tests := []struct {
+		name    string
+		proxies string
+		want    int
+	}{
+		// The client sets a new X-Forwarded-For on each request; without
+		// trusted proxies both count against the connection's IP.
+		{"no trusted proxies", "", http.StatusTooManyRequests},
+		// Behind a trusted proxy the forwarded client IPs are used.
+		{"trusted proxy", "192.0.2.1", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newEngine(context.Background(), config.ServerConfig{TrustedProxies: tt.proxies})
+			router.Use(middleware.ErrorHandler())
+			router.POST("/otp", middleware.RateLimit(ratelimit.NewMemoryLimiter(), policy), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			var rec *httptest.ResponseRecorder
+			for _, forwarded := range []string{"203.0.113.1", "203.0.113.2"} {
+				req := httptest.NewRequest(http.MethodPost, "/otp", nil)
+				req.RemoteAddr = "192.0.2.1:4000"
+				req.Header.Set("X-Forwarded-For", forwarded)
+				rec = httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+			}
+			if rec.Code != tt.want {
+				t.Fatalf("second request got %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -2,42 +2,52 @@ package server
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"shofy/app/api/config"
 	db "shofy/db/sqlc"
-	"shofy/modules/azure/service"
 	azureService "shofy/modules/azure/service"
+	"shofy/utils/metrics"
+	"shofy/utils/telemetry"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Server struct {
+	Config  *config.Config
 	DBPool  *pgxpool.Pool
 	Queries *db.Queries
 	Ctx     context.Context
-	OpenAI  *service.AzureOpenAI
+	// OpenAI is nil unless Azure OpenAI is enabled.
+	OpenAI *azureService.AzureOpenAI
 }
 
-func NewServer(ctx context.Context) *Server {
-	dbPool, err := config.LoadDbConfig(ctx)
+func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
+	dbPool, err := config.LoadDbConfig(ctx, cfg.Database, func(pc *pgxpool.Config) {
+		pc.ConnConfig.Tracer = telemetry.QueryTracer{}
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return nil, err
 	}
 
-	// Init OpenAI
-	openAI := azureService.NewOpenAI(ctx)
+	if err := metrics.RegisterPool(dbPool); err != nil {
+		dbPool.Close()
+		return nil, fmt.Errorf("failed to register pool metrics: %w", err)
+	}
 
-	// Set JWT key
-	// secretBaseKey := os.Getenv("SECRET_BASE_KEY")
-	// if secretBaseKey == "" {
-	// 	log.Fatalf("SECRET_BASE_KEY is not set")
-	// }
-	// validation.SetJWTKey(secretBaseKey)
+	var openAI *azureService.AzureOpenAI
+	if cfg.Azure.Enabled {
+		openAI, err = azureService.NewOpenAI(ctx, cfg.Azure)
+		if err != nil {
+			dbPool.Close()
+			return nil, fmt.Errorf("failed to init Azure OpenAI: %w", err)
+		}
+	}
 
 	return &Server{
+		Config:  cfg,
 		DBPool:  dbPool,
 		Queries: db.New(dbPool),
 		Ctx:     ctx,
 		OpenAI:  openAI,
-	}
+	}, nil
 }
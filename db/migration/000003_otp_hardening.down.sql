@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS otp_send_log;
+
+ALTER TABLE user_login_otp DROP COLUMN IF EXISTS attempts;
+ALTER TABLE user_login_otp DROP COLUMN IF EXISTS otp_hash;
+ALTER TABLE user_login_otp ADD COLUMN otp char(6) NOT NULL DEFAULT '000000';
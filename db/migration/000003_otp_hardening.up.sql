@@ -0,0 +1,16 @@
+-- OTP codes are stored as a keyed hash and bound to the user they were issued for
+ALTER TABLE user_login_otp DROP COLUMN otp;
+ALTER TABLE user_login_otp ADD COLUMN otp_hash varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE user_login_otp ALTER COLUMN otp_hash DROP DEFAULT;
+ALTER TABLE user_login_otp ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+-- Send log used for resend cooldowns and per-phone / per-IP quotas
+CREATE TABLE IF NOT EXISTS otp_send_log (
+    id BIGSERIAL PRIMARY KEY,
+    phone varchar(255) NOT NULL,
+    ip_address varchar(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS idx_otp_send_log_phone_created_at ON otp_send_log (phone, created_at);
+CREATE INDEX IF NOT EXISTS idx_otp_send_log_ip_created_at ON otp_send_log (ip_address, created_at);
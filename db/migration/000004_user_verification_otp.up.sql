@@ -0,0 +1,18 @@
+-- One-time codes for flows that are not a plain login: self-service
+-- registration (no user row yet) and confirming a changed phone or email.
+CREATE TABLE IF NOT EXISTS user_verification_otp (
+    id SERIAL PRIMARY KEY,
+    purpose varchar(32) NOT NULL CHECK (purpose IN ('register', 'change_phone', 'change_email')),
+    shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    target varchar(255) NOT NULL,
+    code_area varchar(255),
+    otp_hash varchar(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    is_used BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    expires_at TIMESTAMPTZ NOT NULL DEFAULT (now() + INTERVAL '5 minutes')
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_verification_otp_target ON user_verification_otp (purpose, shop_id, target, created_at);
+CREATE INDEX IF NOT EXISTS idx_user_verification_otp_user ON user_verification_otp (purpose, user_id, created_at);
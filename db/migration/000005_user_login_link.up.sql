@@ -0,0 +1,9 @@
+-- Single-use magic login links, stored like user_login_otp with only a hash of the link nonce
+CREATE TABLE IF NOT EXISTS user_login_link (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    is_used BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    expires_at TIMESTAMPTZ NOT NULL
+);
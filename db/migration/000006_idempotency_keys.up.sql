@@ -0,0 +1,16 @@
+-- Responses of mutating requests sent with an Idempotency-Key header. A row
+-- with a NULL status_code is a request still in progress.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    scope varchar(255) NOT NULL,
+    idempotency_key varchar(255) NOT NULL,
+    fingerprint varchar(64) NOT NULL,
+    status_code INTEGER,
+    content_type varchar(255),
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    expires_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (scope, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
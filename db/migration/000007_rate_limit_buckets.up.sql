@@ -0,0 +1,10 @@
+-- Token buckets shared by every API instance when the rate limiter uses the
+-- postgres backend. allowed records whether the last request was let through.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    bucket_key varchar(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
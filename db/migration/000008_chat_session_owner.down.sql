@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_sessions_shop_guest;
+DROP INDEX IF EXISTS idx_sessions_shop_user;
+
+-- Guest sessions have no user to fall back to.
+DELETE FROM conversations WHERE session_id IN (SELECT id FROM sessions WHERE user_id IS NULL);
+DELETE FROM sessions WHERE user_id IS NULL;
+
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_owner_check;
+ALTER TABLE sessions ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE sessions DROP COLUMN IF EXISTS guest_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS shop_id;
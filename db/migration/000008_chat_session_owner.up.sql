@@ -0,0 +1,18 @@
+-- Chat sessions belong to a shop and to either a user or an anonymous guest
+-- holding a signed guest token.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS shop_id INTEGER REFERENCES shops(id) ON DELETE CASCADE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS guest_id UUID;
+
+UPDATE sessions s SET shop_id = u.shop_id
+FROM users u
+WHERE s.user_id = u.id AND s.shop_id IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN shop_id SET NOT NULL;
+ALTER TABLE sessions ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE sessions ADD CONSTRAINT sessions_owner_check CHECK (
+    (user_id IS NULL AND guest_id IS NOT NULL) OR
+    (user_id IS NOT NULL AND guest_id IS NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_shop_user ON sessions (shop_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_shop_guest ON sessions (shop_id, guest_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_conversations_session_created;
+ALTER TABLE shops DROP COLUMN IF EXISTS chat_resume_hours;
+ALTER TABLE sessions DROP COLUMN IF EXISTS closed_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS status;
@@ -0,0 +1,10 @@
+-- Sessions can be closed (no new messages) or archived (also hidden from the
+-- session list). Shops choose how long an open session is resumed for.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'open'
+    CHECK (status IN ('open', 'closed', 'archived'));
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
+
+ALTER TABLE shops ADD COLUMN IF NOT EXISTS chat_resume_hours INTEGER NOT NULL DEFAULT 24
+    CHECK (chat_resume_hours BETWEEN 1 AND 720);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_session_created ON conversations (session_id, created_at);
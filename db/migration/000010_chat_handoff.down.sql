@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sessions_shop_status_mode;
+ALTER TABLE sessions DROP COLUMN IF EXISTS escalation_reason;
+ALTER TABLE sessions DROP COLUMN IF EXISTS escalated_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS handled_by;
+ALTER TABLE sessions DROP COLUMN IF EXISTS mode;
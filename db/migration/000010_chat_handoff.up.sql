@@ -0,0 +1,10 @@
+-- Who answers a session: the assistant ('ai'), nobody yet because it was
+-- escalated to staff ('pending'), or the staff member in handled_by
+-- ('human'). The assistant only replies in 'ai' mode.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS mode varchar(20) NOT NULL DEFAULT 'ai'
+    CHECK (mode IN ('ai', 'pending', 'human'));
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS handled_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMPTZ;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS escalation_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_shop_status_mode ON sessions (shop_id, status, mode);
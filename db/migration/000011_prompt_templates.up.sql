@@ -0,0 +1,18 @@
+-- Versioned system prompt templates per shop, written as Go text/template.
+-- variables holds shop-specific values such as opening hours and policies.
+-- At most one version per shop is active; shops without one use the
+-- built-in default.
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id SERIAL PRIMARY KEY,
+    shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    variables JSONB NOT NULL DEFAULT '{}',
+    note varchar(255),
+    is_active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    UNIQUE (shop_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_templates_active ON prompt_templates (shop_id) WHERE is_active;
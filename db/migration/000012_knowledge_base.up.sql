@@ -0,0 +1,29 @@
+-- Documents uploaded by shop staff (FAQ, shipping and return policies, ...)
+-- and their embedded chunks used to ground chat answers.
+CREATE TABLE IF NOT EXISTS knowledge_documents (
+    id SERIAL PRIMARY KEY,
+    shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    title varchar(255) NOT NULL,
+    filename varchar(255) NOT NULL,
+    format varchar(20) NOT NULL CHECK (format IN ('markdown', 'text', 'pdf')),
+    size_bytes INTEGER NOT NULL,
+    chunk_count INTEGER NOT NULL DEFAULT 0,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_documents_shop ON knowledge_documents (shop_id, created_at DESC);
+
+-- embedding is compared in the application, so no vector extension is needed.
+CREATE TABLE IF NOT EXISTS knowledge_chunks (
+    id BIGSERIAL PRIMARY KEY,
+    document_id INTEGER NOT NULL REFERENCES knowledge_documents(id) ON DELETE CASCADE,
+    shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    heading TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    embedding REAL[] NOT NULL,
+    UNIQUE (document_id, chunk_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_shop ON knowledge_chunks (shop_id);
@@ -0,0 +1,17 @@
+-- Replies to repeated catalog questions, keyed by the normalized question.
+-- product_ids lists the products the question and answer mention so that
+-- changing one of them drops the entry.
+CREATE TABLE IF NOT EXISTS chat_response_cache (
+    shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    question_key TEXT NOT NULL,
+    question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    product_ids TEXT[] NOT NULL DEFAULT '{}',
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (shop_id, question_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_response_cache_products ON chat_response_cache USING GIN (product_ids);
+CREATE INDEX IF NOT EXISTS idx_chat_response_cache_expires ON chat_response_cache (expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS guardrail_events;
+DROP TABLE IF EXISTS guardrail_settings;
@@ -0,0 +1,24 @@
+-- Per-shop list of data the assistant may show customers. allowed_fields
+-- names kinds of shop data (email, phone, address, website, stock);
+-- allowed_values lists extra contacts or links that may always appear.
+CREATE TABLE IF NOT EXISTS guardrail_settings (
+    shop_id INTEGER PRIMARY KEY REFERENCES shops(id) ON DELETE CASCADE,
+    allowed_fields TEXT[] NOT NULL DEFAULT '{}',
+    allowed_values TEXT[] NOT NULL DEFAULT '{}',
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+-- Chat messages and replies the guardrails refused, stripped or redacted.
+CREATE TABLE IF NOT EXISTS guardrail_events (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    session_id INTEGER REFERENCES sessions(id) ON DELETE SET NULL,
+    direction varchar(10) NOT NULL CHECK (direction IN ('input', 'output')),
+    rule varchar(50) NOT NULL,
+    action varchar(20) NOT NULL CHECK (action IN ('refused', 'stripped', 'redacted')),
+    excerpt TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS idx_guardrail_events_shop ON guardrail_events (shop_id, created_at DESC);
@@ -0,0 +1,11 @@
+-- Reasoning that reasoning models produced before an assistant reply. It is
+-- never shown to customers and is only stored when CHAT_STORE_REASONING is
+-- on, for debugging replies.
+CREATE TABLE IF NOT EXISTS message_reasoning (
+    conversation_id INTEGER PRIMARY KEY REFERENCES conversations(id) ON DELETE CASCADE,
+    session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_reasoning_session ON message_reasoning (session_id);
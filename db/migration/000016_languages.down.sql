@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_translations;
+DROP TABLE IF EXISTS language_settings;
+ALTER TABLE sessions DROP COLUMN IF EXISTS language;
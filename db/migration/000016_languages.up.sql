@@ -0,0 +1,26 @@
+-- Language the assistant replies in, following the customer. NULL until a
+-- message was detected with confidence.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS language varchar(10);
+
+-- Languages a shop serves. Product data is written in default_language;
+-- customers writing in an unsupported language get default_language.
+CREATE TABLE IF NOT EXISTS language_settings (
+    shop_id INTEGER PRIMARY KEY REFERENCES shops(id) ON DELETE CASCADE,
+    default_language varchar(10) NOT NULL,
+    supported_languages TEXT[] NOT NULL,
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+-- Machine translations of product names and descriptions. source_hash is
+-- the hash of the original text; a translation whose hash no longer
+-- matches the product is stale and translated again.
+CREATE TABLE IF NOT EXISTS product_translations (
+    product_id varchar NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    language varchar(10) NOT NULL,
+    source_hash varchar(64) NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    PRIMARY KEY (product_id, language)
+);
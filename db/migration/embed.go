@@ -0,0 +1,8 @@
+// Package migration embeds the SQL migrations so the binary can apply them
+// without the external migrate CLI.
+package migration
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
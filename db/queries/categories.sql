@@ -45,3 +45,9 @@ DELETE FROM categories
 WHERE id = $1;
 
 
+
+-- name: EnsureCategory :one
+INSERT INTO categories (shop_id, name)
+VALUES ($1, $2)
+ON CONFLICT (shop_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id;
@@ -0,0 +1,14 @@
+-- name: ListChannels :many
+SELECT * FROM channel
+ORDER BY id;
+
+-- name: EnsureChannel :exec
+INSERT INTO channel (name)
+SELECT sqlc.arg(name)::varchar
+WHERE NOT EXISTS (
+    SELECT 1 FROM channel WHERE name = sqlc.arg(name)::varchar
+);
+
+-- name: GetChannelByID :one
+SELECT * FROM channel
+WHERE id = $1;
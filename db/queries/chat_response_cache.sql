@@ -0,0 +1,34 @@
+-- name: HitResponseCache :one
+-- Returns a live entry and counts the hit.
+UPDATE chat_response_cache
+SET hits = hits + 1
+WHERE shop_id = $1 AND question_key = $2 AND expires_at > now()
+RETURNING answer;
+
+-- name: UpsertResponseCache :exec
+INSERT INTO chat_response_cache (shop_id, question_key, question, answer, product_ids, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (shop_id, question_key) DO UPDATE
+SET question = EXCLUDED.question,
+    answer = EXCLUDED.answer,
+    product_ids = EXCLUDED.product_ids,
+    hits = 0,
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at;
+
+-- name: DeleteResponseCacheByProduct :execrows
+DELETE FROM chat_response_cache
+WHERE sqlc.arg(product_id)::text = ANY(product_ids);
+
+-- name: DeleteResponseCacheByShop :execrows
+DELETE FROM chat_response_cache
+WHERE shop_id = $1;
+
+-- name: DeleteExpiredResponseCache :execrows
+DELETE FROM chat_response_cache
+WHERE expires_at <= now();
+
+-- name: GetResponseCacheStats :one
+SELECT COUNT(*)::bigint AS entries, COALESCE(SUM(hits), 0)::bigint AS hits
+FROM chat_response_cache
+WHERE shop_id = $1 AND expires_at > now();
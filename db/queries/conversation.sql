@@ -12,4 +12,16 @@ RETURNING *;
 SELECT id, session_id, message, role, created_at, updated_at
 FROM conversations
 WHERE session_id = $1
-ORDER BY created_at ASC; 
\ No newline at end of file
+ORDER BY created_at ASC; 
+
+-- name: ListConversationsBySessionID :many
+SELECT id, session_id, message, role, created_at, updated_at
+FROM conversations
+WHERE session_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: CountConversationsBySessionID :one
+SELECT COUNT(*)
+FROM conversations
+WHERE session_id = $1;
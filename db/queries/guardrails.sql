@@ -0,0 +1,29 @@
+-- name: GetGuardrailSettings :one
+SELECT * FROM guardrail_settings
+WHERE shop_id = $1;
+
+-- name: UpsertGuardrailSettings :one
+INSERT INTO guardrail_settings (shop_id, allowed_fields, allowed_values, updated_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (shop_id) DO UPDATE
+SET allowed_fields = EXCLUDED.allowed_fields,
+    allowed_values = EXCLUDED.allowed_values,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = now()
+RETURNING *;
+
+-- name: CreateGuardrailEvent :exec
+INSERT INTO guardrail_events (shop_id, session_id, direction, rule, action, excerpt)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListGuardrailEvents :many
+SELECT * FROM guardrail_events
+WHERE shop_id = $1
+  AND (sqlc.narg(direction)::text IS NULL OR direction = sqlc.narg(direction))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountGuardrailEvents :one
+SELECT COUNT(*) FROM guardrail_events
+WHERE shop_id = $1
+  AND (sqlc.narg(direction)::text IS NULL OR direction = sqlc.narg(direction));
@@ -0,0 +1,32 @@
+-- name: CreateKnowledgeDocument :one
+INSERT INTO knowledge_documents (shop_id, title, filename, format, size_bytes, chunk_count, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: CreateKnowledgeChunk :exec
+INSERT INTO knowledge_chunks (document_id, shop_id, chunk_index, heading, content, embedding)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListKnowledgeDocuments :many
+SELECT * FROM knowledge_documents
+WHERE shop_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountKnowledgeDocuments :one
+SELECT COUNT(*) FROM knowledge_documents
+WHERE shop_id = $1;
+
+-- name: DeleteKnowledgeDocument :execrows
+DELETE FROM knowledge_documents
+WHERE id = $1 AND shop_id = $2;
+
+-- name: CountKnowledgeChunksByShop :one
+SELECT COUNT(*) FROM knowledge_chunks
+WHERE shop_id = $1;
+
+-- name: ListKnowledgeChunksByShop :many
+SELECT c.id, c.document_id, d.title, c.heading, c.content, c.embedding
+FROM knowledge_chunks c
+JOIN knowledge_documents d ON d.id = c.document_id
+WHERE c.shop_id = $1;
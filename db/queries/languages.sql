@@ -0,0 +1,36 @@
+-- name: GetLanguageSettings :one
+SELECT * FROM language_settings
+WHERE shop_id = $1;
+
+-- name: UpsertLanguageSettings :one
+INSERT INTO language_settings (shop_id, default_language, supported_languages, updated_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (shop_id) DO UPDATE
+SET default_language = EXCLUDED.default_language,
+    supported_languages = EXCLUDED.supported_languages,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = now()
+RETURNING *;
+
+-- name: ListProductTranslations :many
+SELECT * FROM product_translations
+WHERE language = sqlc.arg(language) AND product_id = ANY(sqlc.arg(product_ids)::varchar[]);
+
+-- name: UpsertProductTranslation :exec
+INSERT INTO product_translations (product_id, language, source_hash, name, description)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (product_id, language) DO UPDATE
+SET source_hash = EXCLUDED.source_hash,
+    name = EXCLUDED.name,
+    description = EXCLUDED.description,
+    created_at = now();
+
+-- name: CountProductTranslations :one
+SELECT COUNT(*) FROM product_translations t
+JOIN products p ON p.id = t.product_id
+WHERE p.shop_id = $1;
+
+-- name: DeleteProductTranslationsByShop :execrows
+DELETE FROM product_translations t
+USING products p
+WHERE p.id = t.product_id AND p.shop_id = $1;
@@ -0,0 +1,10 @@
+-- name: CreateMessageReasoning :exec
+INSERT INTO message_reasoning (conversation_id, session_id, content)
+VALUES ($1, $2, $3);
+
+-- name: ListMessageReasoning :many
+SELECT r.conversation_id, c.message, r.content, r.created_at
+FROM message_reasoning r
+JOIN conversations c ON c.id = r.conversation_id
+WHERE r.session_id = $1
+ORDER BY r.conversation_id;
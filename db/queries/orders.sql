@@ -39,14 +39,14 @@ WHERE id = $1;
 SELECT COUNT(*) 
 FROM orders;
 
+-- name: GetCustomerOrder :one
+SELECT id, shop_id, user_id, total, status, created_at
+FROM orders
+WHERE id = $1 AND shop_id = $2 AND user_id = $3;
 
-
-
-
-
-
-
-
-
-
-
+-- name: ListCustomerOrders :many
+SELECT id, shop_id, user_id, total, status, created_at
+FROM orders
+WHERE shop_id = $1 AND user_id = $2
+ORDER BY created_at DESC
+LIMIT $3;
@@ -74,4 +74,35 @@ WHERE deleted_at IS NULL;
 -- name: GetCountProductasdasd :one
 SELECT COUNT(*) 
 FROM products 
-WHERE deleted_at IS NULL;
\ No newline at end of file
+WHERE deleted_at IS NULL;
+
+-- name: GetProductsByShopID :many
+SELECT p.id,
+       p.name,
+       p.description,
+       p.price,
+       p.stock,
+       c.name as category_id,
+       p.created_at,
+       p.updated_at
+FROM products p inner join categories c on p.category_id = c.id
+WHERE p.shop_id = $1 AND p.deleted_at IS NULL
+ORDER BY p.created_at DESC;
+
+-- name: UpsertProduct :exec
+INSERT INTO products (id, name, description, price, stock, category_id, shop_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE
+SET name = EXCLUDED.name, description = EXCLUDED.description, price = EXCLUDED.price,
+    stock = EXCLUDED.stock, category_id = EXCLUDED.category_id, shop_id = EXCLUDED.shop_id,
+    updated_at = NOW(), deleted_at = NULL;
+
+-- name: DeleteShopProductsExcept :exec
+UPDATE products
+SET deleted_at = NOW()
+WHERE shop_id = $1 AND deleted_at IS NULL AND id LIKE sqlc.arg(prefix)::text || '%'
+  AND NOT (id = ANY(sqlc.arg(keep_ids)::varchar[]));
+
+-- name: CountShopProductsWithoutPrefix :one
+SELECT COUNT(*) FROM products
+WHERE shop_id = $1 AND id NOT LIKE sqlc.arg(prefix)::text || '%';
@@ -0,0 +1,37 @@
+-- name: CreatePromptTemplate :one
+-- Adds the next version for the shop.
+INSERT INTO prompt_templates (shop_id, version, content, variables, note, created_by)
+SELECT sqlc.arg(shop_id), COALESCE(MAX(version), 0) + 1, sqlc.arg(content), sqlc.arg(variables), sqlc.narg(note), sqlc.narg(created_by)
+FROM prompt_templates
+WHERE shop_id = sqlc.arg(shop_id)
+RETURNING *;
+
+-- name: GetActivePromptTemplate :one
+SELECT * FROM prompt_templates
+WHERE shop_id = $1 AND is_active
+LIMIT 1;
+
+-- name: GetPromptTemplateByVersion :one
+SELECT * FROM prompt_templates
+WHERE shop_id = $1 AND version = $2;
+
+-- name: ListPromptTemplates :many
+SELECT * FROM prompt_templates
+WHERE shop_id = $1
+ORDER BY version DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountPromptTemplates :one
+SELECT COUNT(*) FROM prompt_templates
+WHERE shop_id = $1;
+
+-- name: DeactivatePromptTemplates :exec
+UPDATE prompt_templates
+SET is_active = FALSE
+WHERE shop_id = $1 AND is_active;
+
+-- name: ActivatePromptTemplate :one
+UPDATE prompt_templates
+SET is_active = TRUE
+WHERE shop_id = $1 AND version = $2
+RETURNING *;
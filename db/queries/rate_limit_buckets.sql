@@ -0,0 +1,19 @@
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time since its last update, capped at capacity,
+-- and takes one token if there is one. A new bucket starts full.
+INSERT INTO rate_limit_buckets (bucket_key, tokens, allowed, updated_at)
+VALUES (sqlc.arg(bucket_key), sqlc.arg(capacity)::float8 - 1, TRUE, now())
+ON CONFLICT (bucket_key) DO UPDATE
+SET tokens = LEAST(sqlc.arg(capacity)::float8,
+        rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::float8 * sqlc.arg(refill_rate)::float8)
+        - CASE WHEN LEAST(sqlc.arg(capacity)::float8,
+            rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::float8 * sqlc.arg(refill_rate)::float8) >= 1
+          THEN 1 ELSE 0 END,
+    allowed = LEAST(sqlc.arg(capacity)::float8,
+        rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::float8 * sqlc.arg(refill_rate)::float8) >= 1,
+    updated_at = now()
+RETURNING tokens, allowed;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < sqlc.arg(idle_before)::timestamptz;
@@ -28,4 +28,13 @@ INSERT INTO roles (
 ) VALUES (
     $1, $2
 )   
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: EnsureRole :exec
+INSERT INTO roles (
+    name,
+    is_active
+) VALUES (
+    $1, TRUE
+)
+ON CONFLICT (name) DO NOTHING;
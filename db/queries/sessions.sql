@@ -1,16 +1,177 @@
 -- name: CreateSession :one
 INSERT INTO sessions (
     user_id,
+    guest_id,
+    shop_id,
     channel_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4
 )
 RETURNING *;
 
 -- name: GetCurrentSessions :one
-SELECT DISTINCT s.*
+-- Returns the caller's latest open session with activity inside the shop's
+-- resume window.
+SELECT s.*
 FROM sessions s
-JOIN conversations c ON c.session_id = s.id
+JOIN shops sh ON sh.id = s.shop_id
 WHERE s.channel_id = $1
-  AND c.created_at >= NOW() - INTERVAL '1 day' AND s.user_id = $2
+  AND s.shop_id = $2
+  AND (s.user_id = sqlc.narg(user_id) OR s.guest_id = sqlc.narg(guest_id))
+  AND s.status = 'open'
+  AND COALESCE(
+        (SELECT MAX(c.created_at) FROM conversations c WHERE c.session_id = s.id),
+        s.created_at
+      ) >= NOW() - make_interval(hours => sh.chat_resume_hours)
+ORDER BY s.id DESC
 LIMIT 1;
+
+-- name: GetOwnedSession :one
+-- Returns the session only when it belongs to the shop and to the caller.
+SELECT *
+FROM sessions
+WHERE id = $1
+  AND shop_id = $2
+  AND (user_id = sqlc.narg(user_id) OR guest_id = sqlc.narg(guest_id))
+LIMIT 1;
+
+-- name: ListOwnedSessions :many
+-- Lists the caller's sessions, most recently active first, with a preview
+-- of the last message. Archived sessions are only listed when asked for.
+SELECT s.id,
+       s.channel_id,
+       s.shop_id,
+       s.status,
+       s.mode,
+       s.created_at,
+       s.closed_at,
+       COALESCE(LEFT(last.message, 120), '')::text AS last_message,
+       COALESCE(last.role, '')::text AS last_role,
+       last.created_at AS last_message_at
+FROM sessions s
+LEFT JOIN LATERAL (
+    SELECT c.message, c.role, c.created_at
+    FROM conversations c
+    WHERE c.session_id = s.id
+    ORDER BY c.created_at DESC, c.id DESC
+    LIMIT 1
+) last ON TRUE
+WHERE s.shop_id = sqlc.arg(shop_id)
+  AND (s.user_id = sqlc.narg(user_id) OR s.guest_id = sqlc.narg(guest_id))
+  AND (sqlc.narg(channel_id)::int IS NULL OR s.channel_id = sqlc.narg(channel_id))
+  AND (
+        (sqlc.narg(status)::varchar IS NULL AND s.status <> 'archived')
+        OR s.status = sqlc.narg(status)
+      )
+ORDER BY COALESCE(last.created_at, s.created_at) DESC, s.id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountOwnedSessions :one
+SELECT COUNT(*)
+FROM sessions s
+WHERE s.shop_id = sqlc.arg(shop_id)
+  AND (s.user_id = sqlc.narg(user_id) OR s.guest_id = sqlc.narg(guest_id))
+  AND (sqlc.narg(channel_id)::int IS NULL OR s.channel_id = sqlc.narg(channel_id))
+  AND (
+        (sqlc.narg(status)::varchar IS NULL AND s.status <> 'archived')
+        OR s.status = sqlc.narg(status)
+      );
+
+-- name: UpdateSessionStatus :one
+UPDATE sessions
+SET status = sqlc.arg(status),
+    closed_at = CASE WHEN sqlc.arg(status) = 'open' THEN NULL ELSE COALESCE(closed_at, now()) END,
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetShopSession :one
+SELECT *
+FROM sessions
+WHERE id = $1 AND shop_id = $2
+LIMIT 1;
+
+-- name: ListShopSessions :many
+-- Lists a shop's open sessions for staff, escalated ones first.
+SELECT s.id,
+       s.channel_id,
+       s.shop_id,
+       s.user_id,
+       s.guest_id,
+       s.status,
+       s.mode,
+       s.handled_by,
+       s.escalated_at,
+       COALESCE(s.escalation_reason, '')::text AS escalation_reason,
+       s.created_at,
+       COALESCE(LEFT(last.message, 120), '')::text AS last_message,
+       COALESCE(last.role, '')::text AS last_role,
+       last.created_at AS last_message_at
+FROM sessions s
+LEFT JOIN LATERAL (
+    SELECT c.message, c.role, c.created_at
+    FROM conversations c
+    WHERE c.session_id = s.id
+    ORDER BY c.created_at DESC, c.id DESC
+    LIMIT 1
+) last ON TRUE
+WHERE s.shop_id = sqlc.arg(shop_id)
+  AND s.status = 'open'
+  AND (sqlc.narg(mode)::varchar IS NULL OR s.mode = sqlc.narg(mode))
+ORDER BY (s.mode = 'pending') DESC, COALESCE(last.created_at, s.created_at) DESC, s.id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountShopSessions :one
+SELECT COUNT(*)
+FROM sessions s
+WHERE s.shop_id = sqlc.arg(shop_id)
+  AND s.status = 'open'
+  AND (sqlc.narg(mode)::varchar IS NULL OR s.mode = sqlc.narg(mode));
+
+-- name: EscalateSession :execrows
+-- Hands a session the assistant is answering over to staff.
+UPDATE sessions
+SET mode = 'pending',
+    escalated_at = now(),
+    escalation_reason = sqlc.arg(reason),
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND mode = 'ai';
+
+-- name: TakeOverSession :one
+-- Assigns an open session to a staff member unless someone else has it.
+UPDATE sessions
+SET mode = 'human',
+    handled_by = sqlc.arg(staff_id),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+  AND status = 'open'
+  AND (handled_by IS NULL OR handled_by = sqlc.arg(staff_id))
+RETURNING *;
+
+-- name: ReleaseSession :one
+-- Hands a session back to the assistant.
+UPDATE sessions
+SET mode = 'ai',
+    handled_by = NULL,
+    escalated_at = NULL,
+    escalation_reason = NULL,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ResumeUnclaimedSessions :execrows
+-- Hands sessions no staff member took since before escalated_before back to
+-- the assistant.
+UPDATE sessions
+SET mode = 'ai',
+    escalated_at = NULL,
+    escalation_reason = NULL,
+    updated_at = now()
+WHERE mode = 'pending'
+  AND handled_by IS NULL
+  AND escalated_at < sqlc.arg(escalated_before);
+
+-- name: UpdateSessionLanguage :exec
+UPDATE sessions
+SET language = $2, updated_at = now()
+WHERE id = $1;
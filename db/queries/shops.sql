@@ -28,7 +28,8 @@ SET
     country = COALESCE($12, country),
     latitude = COALESCE($13, latitude),
     longitude = COALESCE($14, longitude),
-    is_active = COALESCE($15, is_active)
+    is_active = COALESCE($15, is_active),
+    chat_resume_hours = COALESCE(sqlc.narg(chat_resume_hours)::int, chat_resume_hours)
 WHERE id = $1
 RETURNING *;
 
@@ -60,13 +61,19 @@ INSERT INTO shops (
     country,
     latitude,
     longitude,
-    is_active
+    is_active,
+    chat_resume_hours
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, COALESCE(sqlc.narg(chat_resume_hours)::int, 24)
 )
 RETURNING *;
 
 -- name: GetAllShops :many
 SELECT s.* FROM shops s 
 WHERE  s.is_active = true
-ORDER BY s.created_at DESC;
\ No newline at end of file
+ORDER BY s.created_at DESC;
+
+-- name: GetFirstShop :one
+SELECT s.* FROM shops s
+ORDER BY s.id
+LIMIT 1;
@@ -18,9 +18,10 @@ INSERT INTO users (
     shop_id,
     email,
     phone,
+    code_area,
     is_active
 ) VALUES (
-    $1, $2, $3, $4
+    $1, $2, $3, $4, $5
 )
 RETURNING *;
 
@@ -49,11 +50,60 @@ join roles rl on rl.id = ur.role_id
 where us.id = $1 AND us.is_active = true
 order by rl.id ASC;
 
--- name: FindUserByPhoneAndCode :one
-SELECT * FROM users
-WHERE phone = $1 and code_area = $2  AND is_active = true LIMIT 1;
-
 -- name: DeleteUserById :exec
 UPDATE users    
 SET is_active = false, updated_at = now()
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: FindUserByShopAndPhone :one
+SELECT * FROM users
+WHERE shop_id = $1 AND phone = $2 LIMIT 1;
+
+-- name: FindUserByShopAndEmail :one
+SELECT * FROM users
+WHERE shop_id = $1 AND email = $2 LIMIT 1;
+
+-- name: CreateUserRole :exec
+INSERT INTO user_roles (user_id, role_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: SetUnconfirmedPhone :exec
+UPDATE users
+SET unconfirmed_phone = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: SetUnconfirmedEmail :exec
+UPDATE users
+SET unconfirmed_email = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: ConfirmUserPhone :one
+UPDATE users
+SET phone = unconfirmed_phone, code_area = $2, unconfirmed_phone = NULL, updated_at = now()
+WHERE id = $1 AND unconfirmed_phone = $3
+RETURNING *;
+
+-- name: ConfirmUserEmail :one
+UPDATE users
+SET email = unconfirmed_email, unconfirmed_email = NULL, updated_at = now()
+WHERE id = $1 AND unconfirmed_email = $2
+RETURNING *;
+-- name: FindActiveUserByShopAndEmail :one
+SELECT * FROM users
+WHERE shop_id = $1 AND email = $2 AND is_active = true LIMIT 1;
+
+-- name: FindActiveUserByShopAndPhone :one
+SELECT * FROM users
+WHERE shop_id = $1 AND phone = $2 AND code_area = $3 AND is_active = true LIMIT 1;
+
+-- name: ListShopStaffContacts :many
+-- Active admins of a shop, who are notified when a chat needs staff.
+SELECT DISTINCT u.id, u.email, u.phone, u.code_area
+FROM users u
+JOIN user_roles ur ON ur.user_id = u.id
+JOIN roles r ON r.id = ur.role_id
+WHERE u.shop_id = $1
+  AND u.is_active = true
+  AND r.is_active = true
+  AND r.name IN ('ADMIN', 'SUPER_ADMIN');
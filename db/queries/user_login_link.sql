@@ -0,0 +1,9 @@
+-- name: CreateUserLoginLink :exec
+INSERT INTO user_login_link (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ConsumeUserLoginLink :one
+UPDATE user_login_link
+SET is_used = TRUE
+WHERE token_hash = $1 AND is_used = FALSE AND expires_at > now()
+RETURNING user_id;
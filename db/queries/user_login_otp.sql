@@ -1,33 +1,34 @@
 -- name: InsertUserLoginOtp :exec
-INSERT INTO user_login_otp (user_id, otp)
+INSERT INTO user_login_otp (user_id, otp_hash)
 VALUES ($1, $2);
 
 -- name: VerifyOtp :one
 SELECT CASE
-       WHEN expires_at < (NOW() AT TIME ZONE 'UTC') THEN 'EXPIRED'
-       WHEN is_used = TRUE THEN 'USED'
+       WHEN ulo.expires_at < (NOW() AT TIME ZONE 'UTC') THEN 'EXPIRED'
+       WHEN ulo.is_used = TRUE THEN 'USED'
        ELSE 'VALID'
-     END as status, user_id
-FROM user_login_otp
-WHERE otp = $1
-ORDER BY created_at DESC
+     END as status, ulo.id, ulo.user_id, ulo.otp_hash, ulo.attempts
+FROM user_login_otp ulo join users u
+ON ulo.user_id = u.id
+WHERE u.shop_id = $1 AND u.phone = $2 AND u.code_area = $3 AND u.is_active = true
+ORDER BY ulo.created_at DESC
 LIMIT 1;
 
--- name: CountValidOtps :one
-SELECT COUNT(*) FROM user_login_otp
-WHERE otp = $1
-  AND expires_at >= (NOW() AT TIME ZONE 'UTC');
+-- name: IncrementOtpAttempts :one
+UPDATE user_login_otp
+SET attempts = attempts + 1
+WHERE id = $1 AND attempts < sqlc.arg(max_attempts)::int
+RETURNING attempts;
 
--- name: UpdateIsUsed :exec
+-- name: UpdateIsUsed :execrows
 UPDATE user_login_otp
-SET is_used = TRUE 
-WHERE user_id = $1 AND otp = $2;
+SET is_used = TRUE
+WHERE id = $1 AND is_used = FALSE;
 
--- name: FindUserLoginOtpByPhone :one
-SELECT ulo.* FROM user_login_otp ulo join users u
-ON ulo.user_id = u.id
-WHERE u.phone = $1
-ORDER BY ulo. created_at DESC
+-- name: FindUserLoginOtpByUserId :one
+SELECT * FROM user_login_otp
+WHERE user_id = $1
+ORDER BY created_at DESC
 LIMIT 1;
 
 -- name: FindUserLoginOtpNotActive :one
@@ -41,6 +42,39 @@ WHERE user_id = $1;
 
 -- name: UpdateOTPByUserId :exec
 UPDATE user_login_otp
-SET is_used = FALSE, otp = $1, created_at = NOW(), 
+SET is_used = FALSE, otp_hash = $1, attempts = 0, created_at = NOW(),
     expires_at = NOW() + INTERVAL '5 minutes'
-WHERE user_id = $2;
\ No newline at end of file
+WHERE user_id = $2;
+
+-- name: InsertOtpSendLog :exec
+INSERT INTO otp_send_log (phone, ip_address)
+VALUES ($1, $2);
+
+-- name: CountOtpSendsByPhoneSince :one
+SELECT COUNT(*) FROM otp_send_log
+WHERE phone = $1 AND created_at >= $2;
+
+-- name: CountOtpSendsByIPSince :one
+SELECT COUNT(*) FROM otp_send_log
+WHERE ip_address = $1 AND created_at >= $2;
+
+-- name: GetLastOtpSendByPhone :one
+SELECT * FROM otp_send_log
+WHERE phone = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: LockOtpPhone :exec
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(phone)::text));
+
+-- name: VerifyEmailOtp :one
+SELECT CASE
+       WHEN ulo.expires_at < (NOW() AT TIME ZONE 'UTC') THEN 'EXPIRED'
+       WHEN ulo.is_used = TRUE THEN 'USED'
+       ELSE 'VALID'
+     END as status, ulo.id, ulo.user_id, ulo.otp_hash, ulo.attempts
+FROM user_login_otp ulo join users u
+ON ulo.user_id = u.id
+WHERE u.shop_id = $1 AND u.email = $2 AND u.is_active = true
+ORDER BY ulo.created_at DESC
+LIMIT 1;
@@ -0,0 +1,40 @@
+-- name: InvalidateVerificationOtps :exec
+UPDATE user_verification_otp
+SET is_used = TRUE
+WHERE purpose = $1 AND shop_id = $2 AND target = $3 AND is_used = FALSE;
+
+-- name: CreateVerificationOtp :one
+INSERT INTO user_verification_otp (
+    purpose,
+    shop_id,
+    user_id,
+    target,
+    code_area,
+    otp_hash
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetLatestVerificationOtpByTarget :one
+SELECT * FROM user_verification_otp
+WHERE purpose = $1 AND shop_id = $2 AND target = $3 AND code_area = $4
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetLatestVerificationOtpByUser :one
+SELECT * FROM user_verification_otp
+WHERE purpose = $1 AND user_id = $2
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: IncrementVerificationOtpAttempts :one
+UPDATE user_verification_otp
+SET attempts = attempts + 1
+WHERE id = $1 AND attempts < sqlc.arg(max_attempts)::int
+RETURNING attempts;
+
+-- name: MarkVerificationOtpUsed :execrows
+UPDATE user_verification_otp
+SET is_used = TRUE
+WHERE id = $1 AND is_used = FALSE;
@@ -57,6 +57,25 @@ func (q *Queries) DeleteCategory(ctx context.Context, id int32) error {
 	return err
 }
 
+const ensureCategory = `-- name: EnsureCategory :one
+INSERT INTO categories (shop_id, name)
+VALUES ($1, $2)
+ON CONFLICT (shop_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id
+`
+
+type EnsureCategoryParams struct {
+	ShopID int32
+	Name   string
+}
+
+func (q *Queries) EnsureCategory(ctx context.Context, arg EnsureCategoryParams) (int32, error) {
+	row := q.db.QueryRow(ctx, ensureCategory, arg.ShopID, arg.Name)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getAllCategory = `-- name: GetAllCategory :many
 SELECT id, 
        shop_id, 
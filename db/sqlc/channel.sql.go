@@ -0,0 +1,70 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: channel.sql
+
+package db
+
+import (
+	"context"
+)
+
+const ensureChannel = `-- name: EnsureChannel :exec
+INSERT INTO channel (name)
+SELECT $1::varchar
+WHERE NOT EXISTS (
+    SELECT 1 FROM channel WHERE name = $1::varchar
+)
+`
+
+func (q *Queries) EnsureChannel(ctx context.Context, name string) error {
+	_, err := q.db.Exec(ctx, ensureChannel, name)
+	return err
+}
+
+const getChannelByID = `-- name: GetChannelByID :one
+SELECT id, name, created_at, updated_at FROM channel
+WHERE id = $1
+`
+
+func (q *Queries) GetChannelByID(ctx context.Context, id int32) (Channel, error) {
+	row := q.db.QueryRow(ctx, getChannelByID, id)
+	var i Channel
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listChannels = `-- name: ListChannels :many
+SELECT id, name, created_at, updated_at FROM channel
+ORDER BY id
+`
+
+func (q *Queries) ListChannels(ctx context.Context) ([]Channel, error) {
+	rows, err := q.db.Query(ctx, listChannels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Channel
+	for rows.Next() {
+		var i Channel
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
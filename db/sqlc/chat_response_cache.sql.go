@@ -0,0 +1,122 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: chat_response_cache.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const deleteExpiredResponseCache = `-- name: DeleteExpiredResponseCache :execrows
+DELETE FROM chat_response_cache
+WHERE expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredResponseCache(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredResponseCache)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteResponseCacheByProduct = `-- name: DeleteResponseCacheByProduct :execrows
+DELETE FROM chat_response_cache
+WHERE $1::text = ANY(product_ids)
+`
+
+func (q *Queries) DeleteResponseCacheByProduct(ctx context.Context, productID string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteResponseCacheByProduct, productID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteResponseCacheByShop = `-- name: DeleteResponseCacheByShop :execrows
+DELETE FROM chat_response_cache
+WHERE shop_id = $1
+`
+
+func (q *Queries) DeleteResponseCacheByShop(ctx context.Context, shopID int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteResponseCacheByShop, shopID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getResponseCacheStats = `-- name: GetResponseCacheStats :one
+SELECT COUNT(*)::bigint AS entries, COALESCE(SUM(hits), 0)::bigint AS hits
+FROM chat_response_cache
+WHERE shop_id = $1 AND expires_at > now()
+`
+
+type GetResponseCacheStatsRow struct {
+	Entries int64
+	Hits    int64
+}
+
+func (q *Queries) GetResponseCacheStats(ctx context.Context, shopID int32) (GetResponseCacheStatsRow, error) {
+	row := q.db.QueryRow(ctx, getResponseCacheStats, shopID)
+	var i GetResponseCacheStatsRow
+	err := row.Scan(&i.Entries, &i.Hits)
+	return i, err
+}
+
+const hitResponseCache = `-- name: HitResponseCache :one
+UPDATE chat_response_cache
+SET hits = hits + 1
+WHERE shop_id = $1 AND question_key = $2 AND expires_at > now()
+RETURNING answer
+`
+
+type HitResponseCacheParams struct {
+	ShopID      int32
+	QuestionKey string
+}
+
+// Returns a live entry and counts the hit.
+func (q *Queries) HitResponseCache(ctx context.Context, arg HitResponseCacheParams) (string, error) {
+	row := q.db.QueryRow(ctx, hitResponseCache, arg.ShopID, arg.QuestionKey)
+	var answer string
+	err := row.Scan(&answer)
+	return answer, err
+}
+
+const upsertResponseCache = `-- name: UpsertResponseCache :exec
+INSERT INTO chat_response_cache (shop_id, question_key, question, answer, product_ids, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (shop_id, question_key) DO UPDATE
+SET question = EXCLUDED.question,
+    answer = EXCLUDED.answer,
+    product_ids = EXCLUDED.product_ids,
+    hits = 0,
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+`
+
+type UpsertResponseCacheParams struct {
+	ShopID      int32
+	QuestionKey string
+	Question    string
+	Answer      string
+	ProductIds  []string
+	ExpiresAt   pgtype.Timestamptz
+}
+
+func (q *Queries) UpsertResponseCache(ctx context.Context, arg UpsertResponseCacheParams) error {
+	_, err := q.db.Exec(ctx, upsertResponseCache,
+		arg.ShopID,
+		arg.QuestionKey,
+		arg.Question,
+		arg.Answer,
+		arg.ProductIds,
+		arg.ExpiresAt,
+	)
+	return err
+}
@@ -9,6 +9,19 @@ import (
 	"context"
 )
 
+const countConversationsBySessionID = `-- name: CountConversationsBySessionID :one
+SELECT COUNT(*)
+FROM conversations
+WHERE session_id = $1
+`
+
+func (q *Queries) CountConversationsBySessionID(ctx context.Context, sessionID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countConversationsBySessionID, sessionID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createConversation = `-- name: CreateConversation :one
 INSERT INTO conversations (
     session_id,
@@ -73,3 +86,44 @@ func (q *Queries) GetConversationsBySessionID(ctx context.Context, sessionID int
 	}
 	return items, nil
 }
+
+const listConversationsBySessionID = `-- name: ListConversationsBySessionID :many
+SELECT id, session_id, message, role, created_at, updated_at
+FROM conversations
+WHERE session_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT $2 OFFSET $3
+`
+
+type ListConversationsBySessionIDParams struct {
+	SessionID int32
+	Limit     int32
+	Offset    int32
+}
+
+func (q *Queries) ListConversationsBySessionID(ctx context.Context, arg ListConversationsBySessionIDParams) ([]Conversation, error) {
+	rows, err := q.db.Query(ctx, listConversationsBySessionID, arg.SessionID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Conversation
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Message,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
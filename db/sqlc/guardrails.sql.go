@@ -0,0 +1,159 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: guardrails.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countGuardrailEvents = `-- name: CountGuardrailEvents :one
+SELECT COUNT(*) FROM guardrail_events
+WHERE shop_id = $1
+  AND ($2::text IS NULL OR direction = $2)
+`
+
+type CountGuardrailEventsParams struct {
+	ShopID    int32
+	Direction pgtype.Text
+}
+
+func (q *Queries) CountGuardrailEvents(ctx context.Context, arg CountGuardrailEventsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countGuardrailEvents, arg.ShopID, arg.Direction)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createGuardrailEvent = `-- name: CreateGuardrailEvent :exec
+INSERT INTO guardrail_events (shop_id, session_id, direction, rule, action, excerpt)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateGuardrailEventParams struct {
+	ShopID    int32
+	SessionID pgtype.Int4
+	Direction string
+	Rule      string
+	Action    string
+	Excerpt   string
+}
+
+func (q *Queries) CreateGuardrailEvent(ctx context.Context, arg CreateGuardrailEventParams) error {
+	_, err := q.db.Exec(ctx, createGuardrailEvent,
+		arg.ShopID,
+		arg.SessionID,
+		arg.Direction,
+		arg.Rule,
+		arg.Action,
+		arg.Excerpt,
+	)
+	return err
+}
+
+const getGuardrailSettings = `-- name: GetGuardrailSettings :one
+SELECT shop_id, allowed_fields, allowed_values, updated_by, updated_at FROM guardrail_settings
+WHERE shop_id = $1
+`
+
+func (q *Queries) GetGuardrailSettings(ctx context.Context, shopID int32) (GuardrailSetting, error) {
+	row := q.db.QueryRow(ctx, getGuardrailSettings, shopID)
+	var i GuardrailSetting
+	err := row.Scan(
+		&i.ShopID,
+		&i.AllowedFields,
+		&i.AllowedValues,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listGuardrailEvents = `-- name: ListGuardrailEvents :many
+SELECT id, shop_id, session_id, direction, rule, action, excerpt, created_at FROM guardrail_events
+WHERE shop_id = $1
+  AND ($2::text IS NULL OR direction = $2)
+ORDER BY created_at DESC, id DESC
+LIMIT $4 OFFSET $3
+`
+
+type ListGuardrailEventsParams struct {
+	ShopID    int32
+	Direction pgtype.Text
+	RowOffset int32
+	RowLimit  int32
+}
+
+func (q *Queries) ListGuardrailEvents(ctx context.Context, arg ListGuardrailEventsParams) ([]GuardrailEvent, error) {
+	rows, err := q.db.Query(ctx, listGuardrailEvents,
+		arg.ShopID,
+		arg.Direction,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GuardrailEvent
+	for rows.Next() {
+		var i GuardrailEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShopID,
+			&i.SessionID,
+			&i.Direction,
+			&i.Rule,
+			&i.Action,
+			&i.Excerpt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertGuardrailSettings = `-- name: UpsertGuardrailSettings :one
+INSERT INTO guardrail_settings (shop_id, allowed_fields, allowed_values, updated_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (shop_id) DO UPDATE
+SET allowed_fields = EXCLUDED.allowed_fields,
+    allowed_values = EXCLUDED.allowed_values,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = now()
+RETURNING shop_id, allowed_fields, allowed_values, updated_by, updated_at
+`
+
+type UpsertGuardrailSettingsParams struct {
+	ShopID        int32
+	AllowedFields []string
+	AllowedValues []string
+	UpdatedBy     pgtype.Int4
+}
+
+func (q *Queries) UpsertGuardrailSettings(ctx context.Context, arg UpsertGuardrailSettingsParams) (GuardrailSetting, error) {
+	row := q.db.QueryRow(ctx, upsertGuardrailSettings,
+		arg.ShopID,
+		arg.AllowedFields,
+		arg.AllowedValues,
+		arg.UpdatedBy,
+	)
+	var i GuardrailSetting
+	err := row.Scan(
+		&i.ShopID,
+		&i.AllowedFields,
+		&i.AllowedValues,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,207 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: knowledge.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countKnowledgeChunksByShop = `-- name: CountKnowledgeChunksByShop :one
+SELECT COUNT(*) FROM knowledge_chunks
+WHERE shop_id = $1
+`
+
+func (q *Queries) CountKnowledgeChunksByShop(ctx context.Context, shopID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countKnowledgeChunksByShop, shopID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countKnowledgeDocuments = `-- name: CountKnowledgeDocuments :one
+SELECT COUNT(*) FROM knowledge_documents
+WHERE shop_id = $1
+`
+
+func (q *Queries) CountKnowledgeDocuments(ctx context.Context, shopID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countKnowledgeDocuments, shopID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createKnowledgeChunk = `-- name: CreateKnowledgeChunk :exec
+INSERT INTO knowledge_chunks (document_id, shop_id, chunk_index, heading, content, embedding)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateKnowledgeChunkParams struct {
+	DocumentID int32
+	ShopID     int32
+	ChunkIndex int32
+	Heading    string
+	Content    string
+	Embedding  []float32
+}
+
+func (q *Queries) CreateKnowledgeChunk(ctx context.Context, arg CreateKnowledgeChunkParams) error {
+	_, err := q.db.Exec(ctx, createKnowledgeChunk,
+		arg.DocumentID,
+		arg.ShopID,
+		arg.ChunkIndex,
+		arg.Heading,
+		arg.Content,
+		arg.Embedding,
+	)
+	return err
+}
+
+const createKnowledgeDocument = `-- name: CreateKnowledgeDocument :one
+INSERT INTO knowledge_documents (shop_id, title, filename, format, size_bytes, chunk_count, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, shop_id, title, filename, format, size_bytes, chunk_count, created_by, created_at
+`
+
+type CreateKnowledgeDocumentParams struct {
+	ShopID     int32
+	Title      string
+	Filename   string
+	Format     string
+	SizeBytes  int32
+	ChunkCount int32
+	CreatedBy  pgtype.Int4
+}
+
+func (q *Queries) CreateKnowledgeDocument(ctx context.Context, arg CreateKnowledgeDocumentParams) (KnowledgeDocument, error) {
+	row := q.db.QueryRow(ctx, createKnowledgeDocument,
+		arg.ShopID,
+		arg.Title,
+		arg.Filename,
+		arg.Format,
+		arg.SizeBytes,
+		arg.ChunkCount,
+		arg.CreatedBy,
+	)
+	var i KnowledgeDocument
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Title,
+		&i.Filename,
+		&i.Format,
+		&i.SizeBytes,
+		&i.ChunkCount,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteKnowledgeDocument = `-- name: DeleteKnowledgeDocument :execrows
+DELETE FROM knowledge_documents
+WHERE id = $1 AND shop_id = $2
+`
+
+type DeleteKnowledgeDocumentParams struct {
+	ID     int32
+	ShopID int32
+}
+
+func (q *Queries) DeleteKnowledgeDocument(ctx context.Context, arg DeleteKnowledgeDocumentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteKnowledgeDocument, arg.ID, arg.ShopID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listKnowledgeChunksByShop = `-- name: ListKnowledgeChunksByShop :many
+SELECT c.id, c.document_id, d.title, c.heading, c.content, c.embedding
+FROM knowledge_chunks c
+JOIN knowledge_documents d ON d.id = c.document_id
+WHERE c.shop_id = $1
+`
+
+type ListKnowledgeChunksByShopRow struct {
+	ID         int64
+	DocumentID int32
+	Title      string
+	Heading    string
+	Content    string
+	Embedding  []float32
+}
+
+func (q *Queries) ListKnowledgeChunksByShop(ctx context.Context, shopID int32) ([]ListKnowledgeChunksByShopRow, error) {
+	rows, err := q.db.Query(ctx, listKnowledgeChunksByShop, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListKnowledgeChunksByShopRow
+	for rows.Next() {
+		var i ListKnowledgeChunksByShopRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.DocumentID,
+			&i.Title,
+			&i.Heading,
+			&i.Content,
+			&i.Embedding,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listKnowledgeDocuments = `-- name: ListKnowledgeDocuments :many
+SELECT id, shop_id, title, filename, format, size_bytes, chunk_count, created_by, created_at FROM knowledge_documents
+WHERE shop_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListKnowledgeDocumentsParams struct {
+	ShopID int32
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListKnowledgeDocuments(ctx context.Context, arg ListKnowledgeDocumentsParams) ([]KnowledgeDocument, error) {
+	rows, err := q.db.Query(ctx, listKnowledgeDocuments, arg.ShopID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []KnowledgeDocument
+	for rows.Next() {
+		var i KnowledgeDocument
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShopID,
+			&i.Title,
+			&i.Filename,
+			&i.Format,
+			&i.SizeBytes,
+			&i.ChunkCount,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,159 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: languages.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countProductTranslations = `-- name: CountProductTranslations :one
+SELECT COUNT(*) FROM product_translations t
+JOIN products p ON p.id = t.product_id
+WHERE p.shop_id = $1
+`
+
+func (q *Queries) CountProductTranslations(ctx context.Context, shopID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countProductTranslations, shopID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteProductTranslationsByShop = `-- name: DeleteProductTranslationsByShop :execrows
+DELETE FROM product_translations t
+USING products p
+WHERE p.id = t.product_id AND p.shop_id = $1
+`
+
+func (q *Queries) DeleteProductTranslationsByShop(ctx context.Context, shopID int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteProductTranslationsByShop, shopID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getLanguageSettings = `-- name: GetLanguageSettings :one
+SELECT shop_id, default_language, supported_languages, updated_by, updated_at FROM language_settings
+WHERE shop_id = $1
+`
+
+func (q *Queries) GetLanguageSettings(ctx context.Context, shopID int32) (LanguageSetting, error) {
+	row := q.db.QueryRow(ctx, getLanguageSettings, shopID)
+	var i LanguageSetting
+	err := row.Scan(
+		&i.ShopID,
+		&i.DefaultLanguage,
+		&i.SupportedLanguages,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listProductTranslations = `-- name: ListProductTranslations :many
+SELECT product_id, language, source_hash, name, description, created_at FROM product_translations
+WHERE language = $1 AND product_id = ANY($2::varchar[])
+`
+
+type ListProductTranslationsParams struct {
+	Language   string
+	ProductIds []string
+}
+
+func (q *Queries) ListProductTranslations(ctx context.Context, arg ListProductTranslationsParams) ([]ProductTranslation, error) {
+	rows, err := q.db.Query(ctx, listProductTranslations, arg.Language, arg.ProductIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ProductTranslation
+	for rows.Next() {
+		var i ProductTranslation
+		if err := rows.Scan(
+			&i.ProductID,
+			&i.Language,
+			&i.SourceHash,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertLanguageSettings = `-- name: UpsertLanguageSettings :one
+INSERT INTO language_settings (shop_id, default_language, supported_languages, updated_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (shop_id) DO UPDATE
+SET default_language = EXCLUDED.default_language,
+    supported_languages = EXCLUDED.supported_languages,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = now()
+RETURNING shop_id, default_language, supported_languages, updated_by, updated_at
+`
+
+type UpsertLanguageSettingsParams struct {
+	ShopID             int32
+	DefaultLanguage    string
+	SupportedLanguages []string
+	UpdatedBy          pgtype.Int4
+}
+
+func (q *Queries) UpsertLanguageSettings(ctx context.Context, arg UpsertLanguageSettingsParams) (LanguageSetting, error) {
+	row := q.db.QueryRow(ctx, upsertLanguageSettings,
+		arg.ShopID,
+		arg.DefaultLanguage,
+		arg.SupportedLanguages,
+		arg.UpdatedBy,
+	)
+	var i LanguageSetting
+	err := row.Scan(
+		&i.ShopID,
+		&i.DefaultLanguage,
+		&i.SupportedLanguages,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertProductTranslation = `-- name: UpsertProductTranslation :exec
+INSERT INTO product_translations (product_id, language, source_hash, name, description)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (product_id, language) DO UPDATE
+SET source_hash = EXCLUDED.source_hash,
+    name = EXCLUDED.name,
+    description = EXCLUDED.description,
+    created_at = now()
+`
+
+type UpsertProductTranslationParams struct {
+	ProductID   string
+	Language    string
+	SourceHash  string
+	Name        string
+	Description string
+}
+
+func (q *Queries) UpsertProductTranslation(ctx context.Context, arg UpsertProductTranslationParams) error {
+	_, err := q.db.Exec(ctx, upsertProductTranslation,
+		arg.ProductID,
+		arg.Language,
+		arg.SourceHash,
+		arg.Name,
+		arg.Description,
+	)
+	return err
+}
@@ -0,0 +1,68 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: message_reasoning.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createMessageReasoning = `-- name: CreateMessageReasoning :exec
+INSERT INTO message_reasoning (conversation_id, session_id, content)
+VALUES ($1, $2, $3)
+`
+
+type CreateMessageReasoningParams struct {
+	ConversationID int32
+	SessionID      int32
+	Content        string
+}
+
+func (q *Queries) CreateMessageReasoning(ctx context.Context, arg CreateMessageReasoningParams) error {
+	_, err := q.db.Exec(ctx, createMessageReasoning, arg.ConversationID, arg.SessionID, arg.Content)
+	return err
+}
+
+const listMessageReasoning = `-- name: ListMessageReasoning :many
+SELECT r.conversation_id, c.message, r.content, r.created_at
+FROM message_reasoning r
+JOIN conversations c ON c.id = r.conversation_id
+WHERE r.session_id = $1
+ORDER BY r.conversation_id
+`
+
+type ListMessageReasoningRow struct {
+	ConversationID int32
+	Message        string
+	Content        string
+	CreatedAt      pgtype.Timestamptz
+}
+
+func (q *Queries) ListMessageReasoning(ctx context.Context, sessionID int32) ([]ListMessageReasoningRow, error) {
+	rows, err := q.db.Query(ctx, listMessageReasoning, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMessageReasoningRow
+	for rows.Next() {
+		var i ListMessageReasoningRow
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Message,
+			&i.Content,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
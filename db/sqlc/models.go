@@ -22,6 +22,17 @@ type Channel struct {
 	UpdatedAt pgtype.Timestamptz
 }
 
+type ChatResponseCache struct {
+	ShopID      int32
+	QuestionKey string
+	Question    string
+	Answer      string
+	ProductIds  []string
+	Hits        int32
+	CreatedAt   pgtype.Timestamptz
+	ExpiresAt   pgtype.Timestamptz
+}
+
 type Conversation struct {
 	ID        int32
 	SessionID int32
@@ -31,6 +42,74 @@ type Conversation struct {
 	UpdatedAt pgtype.Timestamptz
 }
 
+type GuardrailEvent struct {
+	ID        int64
+	ShopID    int32
+	SessionID pgtype.Int4
+	Direction string
+	Rule      string
+	Action    string
+	Excerpt   string
+	CreatedAt pgtype.Timestamptz
+}
+
+type GuardrailSetting struct {
+	ShopID        int32
+	AllowedFields []string
+	AllowedValues []string
+	UpdatedBy     pgtype.Int4
+	UpdatedAt     pgtype.Timestamptz
+}
+
+type IdempotencyKey struct {
+	ID             int32
+	Scope          string
+	IdempotencyKey string
+	Fingerprint    string
+	StatusCode     pgtype.Int4
+	ContentType    pgtype.Text
+	ResponseBody   []byte
+	CreatedAt      pgtype.Timestamptz
+	ExpiresAt      pgtype.Timestamptz
+}
+
+type KnowledgeChunk struct {
+	ID         int64
+	DocumentID int32
+	ShopID     int32
+	ChunkIndex int32
+	Heading    string
+	Content    string
+	Embedding  []float32
+}
+
+type KnowledgeDocument struct {
+	ID         int32
+	ShopID     int32
+	Title      string
+	Filename   string
+	Format     string
+	SizeBytes  int32
+	ChunkCount int32
+	CreatedBy  pgtype.Int4
+	CreatedAt  pgtype.Timestamptz
+}
+
+type LanguageSetting struct {
+	ShopID             int32
+	DefaultLanguage    string
+	SupportedLanguages []string
+	UpdatedBy          pgtype.Int4
+	UpdatedAt          pgtype.Timestamptz
+}
+
+type MessageReasoning struct {
+	ConversationID int32
+	SessionID      int32
+	Content        string
+	CreatedAt      pgtype.Timestamptz
+}
+
 type Order struct {
 	ID        int32
 	ShopID    int32
@@ -48,6 +127,13 @@ type OrderItem struct {
 	UnitPrice pgtype.Numeric
 }
 
+type OtpSendLog struct {
+	ID        int64
+	Phone     string
+	IpAddress string
+	CreatedAt pgtype.Timestamptz
+}
+
 type Product struct {
 	ID          string
 	ShopID      int32
@@ -61,6 +147,34 @@ type Product struct {
 	DeletedAt   pgtype.Timestamp
 }
 
+type ProductTranslation struct {
+	ProductID   string
+	Language    string
+	SourceHash  string
+	Name        string
+	Description string
+	CreatedAt   pgtype.Timestamptz
+}
+
+type PromptTemplate struct {
+	ID        int32
+	ShopID    int32
+	Version   int32
+	Content   string
+	Variables []byte
+	Note      pgtype.Text
+	IsActive  bool
+	CreatedBy pgtype.Int4
+	CreatedAt pgtype.Timestamptz
+}
+
+type RateLimitBucket struct {
+	BucketKey string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt pgtype.Timestamptz
+}
+
 type Role struct {
 	ID        int32
 	Name      string
@@ -70,32 +184,42 @@ type Role struct {
 }
 
 type Session struct {
-	ID        int32
-	UserID    int32
-	ChannelID int32
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
+	ID               int32
+	UserID           pgtype.Int4
+	ChannelID        int32
+	CreatedAt        pgtype.Timestamptz
+	UpdatedAt        pgtype.Timestamptz
+	ShopID           int32
+	GuestID          pgtype.UUID
+	Status           string
+	ClosedAt         pgtype.Timestamptz
+	Mode             string
+	HandledBy        pgtype.Int4
+	EscalatedAt      pgtype.Timestamptz
+	EscalationReason pgtype.Text
+	Language         pgtype.Text
 }
 
 type Shop struct {
-	ID            int32
-	Name          string
-	Description   string
-	LogoUrl       pgtype.Text
-	WebsiteUrl    pgtype.Text
-	Email         pgtype.Text
-	WhatsappPhone pgtype.Text
-	Address       string
-	City          string
-	State         string
-	ZipCode       string
-	Country       string
-	Latitude      float64
-	Longitude     float64
-	IsActive      bool
-	Slug          pgtype.Text
-	CreatedAt     pgtype.Timestamptz
-	UpdatedAt     pgtype.Timestamptz
+	ID              int32
+	Name            string
+	Description     string
+	LogoUrl         pgtype.Text
+	WebsiteUrl      pgtype.Text
+	Email           pgtype.Text
+	WhatsappPhone   pgtype.Text
+	Address         string
+	City            string
+	State           string
+	ZipCode         string
+	Country         string
+	Latitude        float64
+	Longitude       float64
+	IsActive        bool
+	Slug            pgtype.Text
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	ChatResumeHours int32
 }
 
 type User struct {
@@ -112,13 +236,23 @@ type User struct {
 	Slug             pgtype.UUID
 }
 
+type UserLoginLink struct {
+	ID        int32
+	UserID    int32
+	TokenHash string
+	IsUsed    bool
+	CreatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+}
+
 type UserLoginOtp struct {
 	ID        int32
 	UserID    int32
-	Otp       string
 	IsUsed    pgtype.Bool
 	CreatedAt pgtype.Timestamp
 	ExpiresAt pgtype.Timestamp
+	OtpHash   string
+	Attempts  int32
 }
 
 type UserProfile struct {
@@ -136,3 +270,17 @@ type UserRole struct {
 	UserID int32
 	RoleID int32
 }
+
+type UserVerificationOtp struct {
+	ID        int32
+	Purpose   string
+	ShopID    int32
+	UserID    pgtype.Int4
+	Target    string
+	CodeArea  pgtype.Text
+	OtpHash   string
+	Attempts  int32
+	IsUsed    bool
+	CreatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+}
@@ -65,6 +65,32 @@ func (q *Queries) GetCountOrder(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const getCustomerOrder = `-- name: GetCustomerOrder :one
+SELECT id, shop_id, user_id, total, status, created_at
+FROM orders
+WHERE id = $1 AND shop_id = $2 AND user_id = $3
+`
+
+type GetCustomerOrderParams struct {
+	ID     int32
+	ShopID int32
+	UserID pgtype.Int4
+}
+
+func (q *Queries) GetCustomerOrder(ctx context.Context, arg GetCustomerOrderParams) (Order, error) {
+	row := q.db.QueryRow(ctx, getCustomerOrder, arg.ID, arg.ShopID, arg.UserID)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.UserID,
+		&i.Total,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getListOrders = `-- name: GetListOrders :many
 SELECT 
   o.id, 
@@ -150,6 +176,47 @@ func (q *Queries) GetOrderById(ctx context.Context, id int32) (Order, error) {
 	return i, err
 }
 
+const listCustomerOrders = `-- name: ListCustomerOrders :many
+SELECT id, shop_id, user_id, total, status, created_at
+FROM orders
+WHERE shop_id = $1 AND user_id = $2
+ORDER BY created_at DESC
+LIMIT $3
+`
+
+type ListCustomerOrdersParams struct {
+	ShopID int32
+	UserID pgtype.Int4
+	Limit  int32
+}
+
+func (q *Queries) ListCustomerOrders(ctx context.Context, arg ListCustomerOrdersParams) ([]Order, error) {
+	rows, err := q.db.Query(ctx, listCustomerOrders, arg.ShopID, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Order
+	for rows.Next() {
+		var i Order
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShopID,
+			&i.UserID,
+			&i.Total,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateOrder = `-- name: UpdateOrder :one
 UPDATE orders
 SET total = COALESCE($2, total), status = COALESCE($3, status)  
@@ -11,6 +11,23 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countShopProductsWithoutPrefix = `-- name: CountShopProductsWithoutPrefix :one
+SELECT COUNT(*) FROM products
+WHERE shop_id = $1 AND id NOT LIKE $2::text || '%'
+`
+
+type CountShopProductsWithoutPrefixParams struct {
+	ShopID int32
+	Prefix string
+}
+
+func (q *Queries) CountShopProductsWithoutPrefix(ctx context.Context, arg CountShopProductsWithoutPrefixParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countShopProductsWithoutPrefix, arg.ShopID, arg.Prefix)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createProduct = `-- name: CreateProduct :one
 INSERT INTO products (id, name, description, price, stock, category_id, shop_id)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -78,6 +95,24 @@ func (q *Queries) DeleteProductByID(ctx context.Context, id string) error {
 	return err
 }
 
+const deleteShopProductsExcept = `-- name: DeleteShopProductsExcept :exec
+UPDATE products
+SET deleted_at = NOW()
+WHERE shop_id = $1 AND deleted_at IS NULL AND id LIKE $2::text || '%'
+  AND NOT (id = ANY($3::varchar[]))
+`
+
+type DeleteShopProductsExceptParams struct {
+	ShopID  int32
+	Prefix  string
+	KeepIds []string
+}
+
+func (q *Queries) DeleteShopProductsExcept(ctx context.Context, arg DeleteShopProductsExceptParams) error {
+	_, err := q.db.Exec(ctx, deleteShopProductsExcept, arg.ShopID, arg.Prefix, arg.KeepIds)
+	return err
+}
+
 const getAllProducts = `-- name: GetAllProducts :many
 SELECT p.id, 
        p.name, 
@@ -212,6 +247,60 @@ func (q *Queries) GetProductByID(ctx context.Context, id string) (GetProductByID
 	return i, err
 }
 
+const getProductsByShopID = `-- name: GetProductsByShopID :many
+SELECT p.id,
+       p.name,
+       p.description,
+       p.price,
+       p.stock,
+       c.name as category_id,
+       p.created_at,
+       p.updated_at
+FROM products p inner join categories c on p.category_id = c.id
+WHERE p.shop_id = $1 AND p.deleted_at IS NULL
+ORDER BY p.created_at DESC
+`
+
+type GetProductsByShopIDRow struct {
+	ID          string
+	Name        string
+	Description pgtype.Text
+	Price       pgtype.Numeric
+	Stock       pgtype.Int4
+	CategoryID  string
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+}
+
+func (q *Queries) GetProductsByShopID(ctx context.Context, shopID int32) ([]GetProductsByShopIDRow, error) {
+	rows, err := q.db.Query(ctx, getProductsByShopID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProductsByShopIDRow
+	for rows.Next() {
+		var i GetProductsByShopIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.Stock,
+			&i.CategoryID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProducts = `-- name: ListProducts :many
 SELECT p.id, 
        p.name, 
@@ -323,3 +412,35 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 	)
 	return i, err
 }
+
+const upsertProduct = `-- name: UpsertProduct :exec
+INSERT INTO products (id, name, description, price, stock, category_id, shop_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE
+SET name = EXCLUDED.name, description = EXCLUDED.description, price = EXCLUDED.price,
+    stock = EXCLUDED.stock, category_id = EXCLUDED.category_id, shop_id = EXCLUDED.shop_id,
+    updated_at = NOW(), deleted_at = NULL
+`
+
+type UpsertProductParams struct {
+	ID          string
+	Name        string
+	Description pgtype.Text
+	Price       pgtype.Numeric
+	Stock       pgtype.Int4
+	CategoryID  pgtype.Int4
+	ShopID      int32
+}
+
+func (q *Queries) UpsertProduct(ctx context.Context, arg UpsertProductParams) error {
+	_, err := q.db.Exec(ctx, upsertProduct,
+		arg.ID,
+		arg.Name,
+		arg.Description,
+		arg.Price,
+		arg.Stock,
+		arg.CategoryID,
+		arg.ShopID,
+	)
+	return err
+}
@@ -0,0 +1,197 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: prompt_templates.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const activatePromptTemplate = `-- name: ActivatePromptTemplate :one
+UPDATE prompt_templates
+SET is_active = TRUE
+WHERE shop_id = $1 AND version = $2
+RETURNING id, shop_id, version, content, variables, note, is_active, created_by, created_at
+`
+
+type ActivatePromptTemplateParams struct {
+	ShopID  int32
+	Version int32
+}
+
+func (q *Queries) ActivatePromptTemplate(ctx context.Context, arg ActivatePromptTemplateParams) (PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, activatePromptTemplate, arg.ShopID, arg.Version)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Version,
+		&i.Content,
+		&i.Variables,
+		&i.Note,
+		&i.IsActive,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const countPromptTemplates = `-- name: CountPromptTemplates :one
+SELECT COUNT(*) FROM prompt_templates
+WHERE shop_id = $1
+`
+
+func (q *Queries) CountPromptTemplates(ctx context.Context, shopID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countPromptTemplates, shopID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createPromptTemplate = `-- name: CreatePromptTemplate :one
+INSERT INTO prompt_templates (shop_id, version, content, variables, note, created_by)
+SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+FROM prompt_templates
+WHERE shop_id = $1
+RETURNING id, shop_id, version, content, variables, note, is_active, created_by, created_at
+`
+
+type CreatePromptTemplateParams struct {
+	ShopID    int32
+	Content   string
+	Variables []byte
+	Note      pgtype.Text
+	CreatedBy pgtype.Int4
+}
+
+// Adds the next version for the shop.
+func (q *Queries) CreatePromptTemplate(ctx context.Context, arg CreatePromptTemplateParams) (PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, createPromptTemplate,
+		arg.ShopID,
+		arg.Content,
+		arg.Variables,
+		arg.Note,
+		arg.CreatedBy,
+	)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Version,
+		&i.Content,
+		&i.Variables,
+		&i.Note,
+		&i.IsActive,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deactivatePromptTemplates = `-- name: DeactivatePromptTemplates :exec
+UPDATE prompt_templates
+SET is_active = FALSE
+WHERE shop_id = $1 AND is_active
+`
+
+func (q *Queries) DeactivatePromptTemplates(ctx context.Context, shopID int32) error {
+	_, err := q.db.Exec(ctx, deactivatePromptTemplates, shopID)
+	return err
+}
+
+const getActivePromptTemplate = `-- name: GetActivePromptTemplate :one
+SELECT id, shop_id, version, content, variables, note, is_active, created_by, created_at FROM prompt_templates
+WHERE shop_id = $1 AND is_active
+LIMIT 1
+`
+
+func (q *Queries) GetActivePromptTemplate(ctx context.Context, shopID int32) (PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, getActivePromptTemplate, shopID)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Version,
+		&i.Content,
+		&i.Variables,
+		&i.Note,
+		&i.IsActive,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPromptTemplateByVersion = `-- name: GetPromptTemplateByVersion :one
+SELECT id, shop_id, version, content, variables, note, is_active, created_by, created_at FROM prompt_templates
+WHERE shop_id = $1 AND version = $2
+`
+
+type GetPromptTemplateByVersionParams struct {
+	ShopID  int32
+	Version int32
+}
+
+func (q *Queries) GetPromptTemplateByVersion(ctx context.Context, arg GetPromptTemplateByVersionParams) (PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, getPromptTemplateByVersion, arg.ShopID, arg.Version)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Version,
+		&i.Content,
+		&i.Variables,
+		&i.Note,
+		&i.IsActive,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listPromptTemplates = `-- name: ListPromptTemplates :many
+SELECT id, shop_id, version, content, variables, note, is_active, created_by, created_at FROM prompt_templates
+WHERE shop_id = $1
+ORDER BY version DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListPromptTemplatesParams struct {
+	ShopID int32
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListPromptTemplates(ctx context.Context, arg ListPromptTemplatesParams) ([]PromptTemplate, error) {
+	rows, err := q.db.Query(ctx, listPromptTemplates, arg.ShopID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PromptTemplate
+	for rows.Next() {
+		var i PromptTemplate
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShopID,
+			&i.Version,
+			&i.Content,
+			&i.Variables,
+			&i.Note,
+			&i.IsActive,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
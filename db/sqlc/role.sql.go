@@ -48,6 +48,21 @@ func (q *Queries) DeleteRoleById(ctx context.Context, id int32) error {
 	return err
 }
 
+const ensureRole = `-- name: EnsureRole :exec
+INSERT INTO roles (
+    name,
+    is_active
+) VALUES (
+    $1, TRUE
+)
+ON CONFLICT (name) DO NOTHING
+`
+
+func (q *Queries) EnsureRole(ctx context.Context, name string) error {
+	_, err := q.db.Exec(ctx, ensureRole, name)
+	return err
+}
+
 const getRoleByID = `-- name: GetRoleByID :one
 SELECT id, name, created_at, updated_at, is_active FROM roles
 WHERE id = $1 and is_active = true LIMIT 1
@@ -7,25 +7,89 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countOwnedSessions = `-- name: CountOwnedSessions :one
+SELECT COUNT(*)
+FROM sessions s
+WHERE s.shop_id = $1
+  AND (s.user_id = $2 OR s.guest_id = $3)
+  AND ($4::int IS NULL OR s.channel_id = $4)
+  AND (
+        ($5::varchar IS NULL AND s.status <> 'archived')
+        OR s.status = $5
+      )
+`
+
+type CountOwnedSessionsParams struct {
+	ShopID    int32
+	UserID    pgtype.Int4
+	GuestID   pgtype.UUID
+	ChannelID pgtype.Int4
+	Status    pgtype.Text
+}
+
+func (q *Queries) CountOwnedSessions(ctx context.Context, arg CountOwnedSessionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countOwnedSessions,
+		arg.ShopID,
+		arg.UserID,
+		arg.GuestID,
+		arg.ChannelID,
+		arg.Status,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countShopSessions = `-- name: CountShopSessions :one
+SELECT COUNT(*)
+FROM sessions s
+WHERE s.shop_id = $1
+  AND s.status = 'open'
+  AND ($2::varchar IS NULL OR s.mode = $2)
+`
+
+type CountShopSessionsParams struct {
+	ShopID int32
+	Mode   pgtype.Text
+}
+
+func (q *Queries) CountShopSessions(ctx context.Context, arg CountShopSessionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countShopSessions, arg.ShopID, arg.Mode)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
     user_id,
+    guest_id,
+    shop_id,
     channel_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4
 )
-RETURNING id, user_id, channel_id, created_at, updated_at
+RETURNING id, user_id, channel_id, created_at, updated_at, shop_id, guest_id, status, closed_at, mode, handled_by, escalated_at, escalation_reason, language
 `
 
 type CreateSessionParams struct {
-	UserID    int32
+	UserID    pgtype.Int4
+	GuestID   pgtype.UUID
+	ShopID    int32
 	ChannelID int32
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
-	row := q.db.QueryRow(ctx, createSession, arg.UserID, arg.ChannelID)
+	row := q.db.QueryRow(ctx, createSession,
+		arg.UserID,
+		arg.GuestID,
+		arg.ShopID,
+		arg.ChannelID,
+	)
 	var i Session
 	err := row.Scan(
 		&i.ID,
@@ -33,26 +97,487 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.ChannelID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ShopID,
+		&i.GuestID,
+		&i.Status,
+		&i.ClosedAt,
+		&i.Mode,
+		&i.HandledBy,
+		&i.EscalatedAt,
+		&i.EscalationReason,
+		&i.Language,
 	)
 	return i, err
 }
 
+const escalateSession = `-- name: EscalateSession :execrows
+UPDATE sessions
+SET mode = 'pending',
+    escalated_at = now(),
+    escalation_reason = $1,
+    updated_at = now()
+WHERE id = $2 AND mode = 'ai'
+`
+
+type EscalateSessionParams struct {
+	Reason pgtype.Text
+	ID     int32
+}
+
+// Hands a session the assistant is answering over to staff.
+func (q *Queries) EscalateSession(ctx context.Context, arg EscalateSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, escalateSession, arg.Reason, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getCurrentSessions = `-- name: GetCurrentSessions :one
-SELECT DISTINCT s.id, s.user_id, s.channel_id, s.created_at, s.updated_at
+SELECT s.id, s.user_id, s.channel_id, s.created_at, s.updated_at, s.shop_id, s.guest_id, s.status, s.closed_at, s.mode, s.handled_by, s.escalated_at, s.escalation_reason, s.language
 FROM sessions s
-JOIN conversations c ON c.session_id = s.id
+JOIN shops sh ON sh.id = s.shop_id
 WHERE s.channel_id = $1
-  AND c.created_at >= NOW() - INTERVAL '1 day' AND s.user_id = $2
+  AND s.shop_id = $2
+  AND (s.user_id = $3 OR s.guest_id = $4)
+  AND s.status = 'open'
+  AND COALESCE(
+        (SELECT MAX(c.created_at) FROM conversations c WHERE c.session_id = s.id),
+        s.created_at
+      ) >= NOW() - make_interval(hours => sh.chat_resume_hours)
+ORDER BY s.id DESC
 LIMIT 1
 `
 
 type GetCurrentSessionsParams struct {
 	ChannelID int32
-	UserID    int32
+	ShopID    int32
+	UserID    pgtype.Int4
+	GuestID   pgtype.UUID
 }
 
+// Returns the caller's latest open session with activity inside the shop's
+// resume window.
 func (q *Queries) GetCurrentSessions(ctx context.Context, arg GetCurrentSessionsParams) (Session, error) {
-	row := q.db.QueryRow(ctx, getCurrentSessions, arg.ChannelID, arg.UserID)
+	row := q.db.QueryRow(ctx, getCurrentSessions,
+		arg.ChannelID,
+		arg.ShopID,
+		arg.UserID,
+		arg.GuestID,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ChannelID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ShopID,
+		&i.GuestID,
+		&i.Status,
+		&i.ClosedAt,
+		&i.Mode,
+		&i.HandledBy,
+		&i.EscalatedAt,
+		&i.EscalationReason,
+		&i.Language,
+	)
+	return i, err
+}
+
+const getOwnedSession = `-- name: GetOwnedSession :one
+SELECT id, user_id, channel_id, created_at, updated_at, shop_id, guest_id, status, closed_at, mode, handled_by, escalated_at, escalation_reason, language
+FROM sessions
+WHERE id = $1
+  AND shop_id = $2
+  AND (user_id = $3 OR guest_id = $4)
+LIMIT 1
+`
+
+type GetOwnedSessionParams struct {
+	ID      int32
+	ShopID  int32
+	UserID  pgtype.Int4
+	GuestID pgtype.UUID
+}
+
+// Returns the session only when it belongs to the shop and to the caller.
+func (q *Queries) GetOwnedSession(ctx context.Context, arg GetOwnedSessionParams) (Session, error) {
+	row := q.db.QueryRow(ctx, getOwnedSession,
+		arg.ID,
+		arg.ShopID,
+		arg.UserID,
+		arg.GuestID,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ChannelID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ShopID,
+		&i.GuestID,
+		&i.Status,
+		&i.ClosedAt,
+		&i.Mode,
+		&i.HandledBy,
+		&i.EscalatedAt,
+		&i.EscalationReason,
+		&i.Language,
+	)
+	return i, err
+}
+
+const getShopSession = `-- name: GetShopSession :one
+SELECT id, user_id, channel_id, created_at, updated_at, shop_id, guest_id, status, closed_at, mode, handled_by, escalated_at, escalation_reason, language
+FROM sessions
+WHERE id = $1 AND shop_id = $2
+LIMIT 1
+`
+
+type GetShopSessionParams struct {
+	ID     int32
+	ShopID int32
+}
+
+func (q *Queries) GetShopSession(ctx context.Context, arg GetShopSessionParams) (Session, error) {
+	row := q.db.QueryRow(ctx, getShopSession, arg.ID, arg.ShopID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ChannelID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ShopID,
+		&i.GuestID,
+		&i.Status,
+		&i.ClosedAt,
+		&i.Mode,
+		&i.HandledBy,
+		&i.EscalatedAt,
+		&i.EscalationReason,
+		&i.Language,
+	)
+	return i, err
+}
+
+const listOwnedSessions = `-- name: ListOwnedSessions :many
+SELECT s.id,
+       s.channel_id,
+       s.shop_id,
+       s.status,
+       s.mode,
+       s.created_at,
+       s.closed_at,
+       COALESCE(LEFT(last.message, 120), '')::text AS last_message,
+       COALESCE(last.role, '')::text AS last_role,
+       last.created_at AS last_message_at
+FROM sessions s
+LEFT JOIN LATERAL (
+    SELECT c.message, c.role, c.created_at
+    FROM conversations c
+    WHERE c.session_id = s.id
+    ORDER BY c.created_at DESC, c.id DESC
+    LIMIT 1
+) last ON TRUE
+WHERE s.shop_id = $1
+  AND (s.user_id = $2 OR s.guest_id = $3)
+  AND ($4::int IS NULL OR s.channel_id = $4)
+  AND (
+        ($5::varchar IS NULL AND s.status <> 'archived')
+        OR s.status = $5
+      )
+ORDER BY COALESCE(last.created_at, s.created_at) DESC, s.id DESC
+LIMIT $7 OFFSET $6
+`
+
+type ListOwnedSessionsParams struct {
+	ShopID    int32
+	UserID    pgtype.Int4
+	GuestID   pgtype.UUID
+	ChannelID pgtype.Int4
+	Status    pgtype.Text
+	RowOffset int32
+	RowLimit  int32
+}
+
+type ListOwnedSessionsRow struct {
+	ID            int32
+	ChannelID     int32
+	ShopID        int32
+	Status        string
+	Mode          string
+	CreatedAt     pgtype.Timestamptz
+	ClosedAt      pgtype.Timestamptz
+	LastMessage   string
+	LastRole      string
+	LastMessageAt pgtype.Timestamptz
+}
+
+// Lists the caller's sessions, most recently active first, with a preview
+// of the last message. Archived sessions are only listed when asked for.
+func (q *Queries) ListOwnedSessions(ctx context.Context, arg ListOwnedSessionsParams) ([]ListOwnedSessionsRow, error) {
+	rows, err := q.db.Query(ctx, listOwnedSessions,
+		arg.ShopID,
+		arg.UserID,
+		arg.GuestID,
+		arg.ChannelID,
+		arg.Status,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOwnedSessionsRow
+	for rows.Next() {
+		var i ListOwnedSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.ShopID,
+			&i.Status,
+			&i.Mode,
+			&i.CreatedAt,
+			&i.ClosedAt,
+			&i.LastMessage,
+			&i.LastRole,
+			&i.LastMessageAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShopSessions = `-- name: ListShopSessions :many
+SELECT s.id,
+       s.channel_id,
+       s.shop_id,
+       s.user_id,
+       s.guest_id,
+       s.status,
+       s.mode,
+       s.handled_by,
+       s.escalated_at,
+       COALESCE(s.escalation_reason, '')::text AS escalation_reason,
+       s.created_at,
+       COALESCE(LEFT(last.message, 120), '')::text AS last_message,
+       COALESCE(last.role, '')::text AS last_role,
+       last.created_at AS last_message_at
+FROM sessions s
+LEFT JOIN LATERAL (
+    SELECT c.message, c.role, c.created_at
+    FROM conversations c
+    WHERE c.session_id = s.id
+    ORDER BY c.created_at DESC, c.id DESC
+    LIMIT 1
+) last ON TRUE
+WHERE s.shop_id = $1
+  AND s.status = 'open'
+  AND ($2::varchar IS NULL OR s.mode = $2)
+ORDER BY (s.mode = 'pending') DESC, COALESCE(last.created_at, s.created_at) DESC, s.id DESC
+LIMIT $4 OFFSET $3
+`
+
+type ListShopSessionsParams struct {
+	ShopID    int32
+	Mode      pgtype.Text
+	RowOffset int32
+	RowLimit  int32
+}
+
+type ListShopSessionsRow struct {
+	ID               int32
+	ChannelID        int32
+	ShopID           int32
+	UserID           pgtype.Int4
+	GuestID          pgtype.UUID
+	Status           string
+	Mode             string
+	HandledBy        pgtype.Int4
+	EscalatedAt      pgtype.Timestamptz
+	EscalationReason string
+	CreatedAt        pgtype.Timestamptz
+	LastMessage      string
+	LastRole         string
+	LastMessageAt    pgtype.Timestamptz
+}
+
+// Lists a shop's open sessions for staff, escalated ones first.
+func (q *Queries) ListShopSessions(ctx context.Context, arg ListShopSessionsParams) ([]ListShopSessionsRow, error) {
+	rows, err := q.db.Query(ctx, listShopSessions,
+		arg.ShopID,
+		arg.Mode,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListShopSessionsRow
+	for rows.Next() {
+		var i ListShopSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.ShopID,
+			&i.UserID,
+			&i.GuestID,
+			&i.Status,
+			&i.Mode,
+			&i.HandledBy,
+			&i.EscalatedAt,
+			&i.EscalationReason,
+			&i.CreatedAt,
+			&i.LastMessage,
+			&i.LastRole,
+			&i.LastMessageAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseSession = `-- name: ReleaseSession :one
+UPDATE sessions
+SET mode = 'ai',
+    handled_by = NULL,
+    escalated_at = NULL,
+    escalation_reason = NULL,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, user_id, channel_id, created_at, updated_at, shop_id, guest_id, status, closed_at, mode, handled_by, escalated_at, escalation_reason, language
+`
+
+// Hands a session back to the assistant.
+func (q *Queries) ReleaseSession(ctx context.Context, id int32) (Session, error) {
+	row := q.db.QueryRow(ctx, releaseSession, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ChannelID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ShopID,
+		&i.GuestID,
+		&i.Status,
+		&i.ClosedAt,
+		&i.Mode,
+		&i.HandledBy,
+		&i.EscalatedAt,
+		&i.EscalationReason,
+		&i.Language,
+	)
+	return i, err
+}
+
+const resumeUnclaimedSessions = `-- name: ResumeUnclaimedSessions :execrows
+UPDATE sessions
+SET mode = 'ai',
+    escalated_at = NULL,
+    escalation_reason = NULL,
+    updated_at = now()
+WHERE mode = 'pending'
+  AND handled_by IS NULL
+  AND escalated_at < $1
+`
+
+// Hands sessions no staff member took since before escalated_before back to
+// the assistant.
+func (q *Queries) ResumeUnclaimedSessions(ctx context.Context, escalatedBefore pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, resumeUnclaimedSessions, escalatedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const takeOverSession = `-- name: TakeOverSession :one
+UPDATE sessions
+SET mode = 'human',
+    handled_by = $1,
+    updated_at = now()
+WHERE id = $2
+  AND status = 'open'
+  AND (handled_by IS NULL OR handled_by = $1)
+RETURNING id, user_id, channel_id, created_at, updated_at, shop_id, guest_id, status, closed_at, mode, handled_by, escalated_at, escalation_reason, language
+`
+
+type TakeOverSessionParams struct {
+	StaffID pgtype.Int4
+	ID      int32
+}
+
+// Assigns an open session to a staff member unless someone else has it.
+func (q *Queries) TakeOverSession(ctx context.Context, arg TakeOverSessionParams) (Session, error) {
+	row := q.db.QueryRow(ctx, takeOverSession, arg.StaffID, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ChannelID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ShopID,
+		&i.GuestID,
+		&i.Status,
+		&i.ClosedAt,
+		&i.Mode,
+		&i.HandledBy,
+		&i.EscalatedAt,
+		&i.EscalationReason,
+		&i.Language,
+	)
+	return i, err
+}
+
+const updateSessionLanguage = `-- name: UpdateSessionLanguage :exec
+UPDATE sessions
+SET language = $2, updated_at = now()
+WHERE id = $1
+`
+
+type UpdateSessionLanguageParams struct {
+	ID       int32
+	Language pgtype.Text
+}
+
+func (q *Queries) UpdateSessionLanguage(ctx context.Context, arg UpdateSessionLanguageParams) error {
+	_, err := q.db.Exec(ctx, updateSessionLanguage, arg.ID, arg.Language)
+	return err
+}
+
+const updateSessionStatus = `-- name: UpdateSessionStatus :one
+UPDATE sessions
+SET status = $1,
+    closed_at = CASE WHEN $1 = 'open' THEN NULL ELSE COALESCE(closed_at, now()) END,
+    updated_at = now()
+WHERE id = $2
+RETURNING id, user_id, channel_id, created_at, updated_at, shop_id, guest_id, status, closed_at, mode, handled_by, escalated_at, escalation_reason, language
+`
+
+type UpdateSessionStatusParams struct {
+	Status string
+	ID     int32
+}
+
+func (q *Queries) UpdateSessionStatus(ctx context.Context, arg UpdateSessionStatusParams) (Session, error) {
+	row := q.db.QueryRow(ctx, updateSessionStatus, arg.Status, arg.ID)
 	var i Session
 	err := row.Scan(
 		&i.ID,
@@ -60,6 +585,15 @@ func (q *Queries) GetCurrentSessions(ctx context.Context, arg GetCurrentSessions
 		&i.ChannelID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ShopID,
+		&i.GuestID,
+		&i.Status,
+		&i.ClosedAt,
+		&i.Mode,
+		&i.HandledBy,
+		&i.EscalatedAt,
+		&i.EscalationReason,
+		&i.Language,
 	)
 	return i, err
 }
@@ -38,28 +38,30 @@ INSERT INTO shops (
     country,
     latitude,
     longitude,
-    is_active
+    is_active,
+    chat_resume_hours
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, COALESCE($15::int, 24)
 )
-RETURNING id, name, description, logo_url, website_url, email, whatsapp_phone, address, city, state, zip_code, country, latitude, longitude, is_active, slug, created_at, updated_at
+RETURNING id, name, description, logo_url, website_url, email, whatsapp_phone, address, city, state, zip_code, country, latitude, longitude, is_active, slug, created_at, updated_at, chat_resume_hours
 `
 
 type CreateShopsParams struct {
-	Name          string
-	Description   string
-	LogoUrl       pgtype.Text
-	WebsiteUrl    pgtype.Text
-	Email         pgtype.Text
-	WhatsappPhone pgtype.Text
-	Address       string
-	City          string
-	State         string
-	ZipCode       string
-	Country       string
-	Latitude      float64
-	Longitude     float64
-	IsActive      bool
+	Name            string
+	Description     string
+	LogoUrl         pgtype.Text
+	WebsiteUrl      pgtype.Text
+	Email           pgtype.Text
+	WhatsappPhone   pgtype.Text
+	Address         string
+	City            string
+	State           string
+	ZipCode         string
+	Country         string
+	Latitude        float64
+	Longitude       float64
+	IsActive        bool
+	ChatResumeHours pgtype.Int4
 }
 
 func (q *Queries) CreateShops(ctx context.Context, arg CreateShopsParams) (Shop, error) {
@@ -78,6 +80,7 @@ func (q *Queries) CreateShops(ctx context.Context, arg CreateShopsParams) (Shop,
 		arg.Latitude,
 		arg.Longitude,
 		arg.IsActive,
+		arg.ChatResumeHours,
 	)
 	var i Shop
 	err := row.Scan(
@@ -99,6 +102,7 @@ func (q *Queries) CreateShops(ctx context.Context, arg CreateShopsParams) (Shop,
 		&i.Slug,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChatResumeHours,
 	)
 	return i, err
 }
@@ -115,7 +119,7 @@ func (q *Queries) DeleteShopsById(ctx context.Context, id int32) error {
 }
 
 const getAllShops = `-- name: GetAllShops :many
-SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at FROM shops s 
+SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at, s.chat_resume_hours FROM shops s 
 WHERE  s.is_active = true
 ORDER BY s.created_at DESC
 `
@@ -148,6 +152,7 @@ func (q *Queries) GetAllShops(ctx context.Context) ([]Shop, error) {
 			&i.Slug,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ChatResumeHours,
 		); err != nil {
 			return nil, err
 		}
@@ -159,8 +164,41 @@ func (q *Queries) GetAllShops(ctx context.Context) ([]Shop, error) {
 	return items, nil
 }
 
+const getFirstShop = `-- name: GetFirstShop :one
+SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at, s.chat_resume_hours FROM shops s
+ORDER BY s.id
+LIMIT 1
+`
+
+func (q *Queries) GetFirstShop(ctx context.Context) (Shop, error) {
+	row := q.db.QueryRow(ctx, getFirstShop)
+	var i Shop
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.LogoUrl,
+		&i.WebsiteUrl,
+		&i.Email,
+		&i.WhatsappPhone,
+		&i.Address,
+		&i.City,
+		&i.State,
+		&i.ZipCode,
+		&i.Country,
+		&i.Latitude,
+		&i.Longitude,
+		&i.IsActive,
+		&i.Slug,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChatResumeHours,
+	)
+	return i, err
+}
+
 const getShopsById = `-- name: GetShopsById :one
-SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at FROM shops s 
+SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at, s.chat_resume_hours FROM shops s 
 WHERE s.id = $1 and s.is_active = true LIMIT 1
 `
 
@@ -186,12 +224,13 @@ func (q *Queries) GetShopsById(ctx context.Context, id int32) (Shop, error) {
 		&i.Slug,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChatResumeHours,
 	)
 	return i, err
 }
 
 const getShopsByNameOrWhatshapp = `-- name: GetShopsByNameOrWhatshapp :one
-SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at FROM shops s 
+SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at, s.chat_resume_hours FROM shops s 
 WHERE s.is_active = true 
   AND (
     s.name = $1 
@@ -226,12 +265,13 @@ func (q *Queries) GetShopsByNameOrWhatshapp(ctx context.Context, arg GetShopsByN
 		&i.Slug,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChatResumeHours,
 	)
 	return i, err
 }
 
 const listShops = `-- name: ListShops :many
-SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at FROM shops s 
+SELECT s.id, s.name, s.description, s.logo_url, s.website_url, s.email, s.whatsapp_phone, s.address, s.city, s.state, s.zip_code, s.country, s.latitude, s.longitude, s.is_active, s.slug, s.created_at, s.updated_at, s.chat_resume_hours FROM shops s 
 WHERE  s.is_active = true
 ORDER BY s.created_at DESC
 LIMIT $1 OFFSET $2
@@ -270,6 +310,7 @@ func (q *Queries) ListShops(ctx context.Context, arg ListShopsParams) ([]Shop, e
 			&i.Slug,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ChatResumeHours,
 		); err != nil {
 			return nil, err
 		}
@@ -297,27 +338,29 @@ SET
     country = COALESCE($12, country),
     latitude = COALESCE($13, latitude),
     longitude = COALESCE($14, longitude),
-    is_active = COALESCE($15, is_active)
+    is_active = COALESCE($15, is_active),
+    chat_resume_hours = COALESCE($16::int, chat_resume_hours)
 WHERE id = $1
-RETURNING id, name, description, logo_url, website_url, email, whatsapp_phone, address, city, state, zip_code, country, latitude, longitude, is_active, slug, created_at, updated_at
+RETURNING id, name, description, logo_url, website_url, email, whatsapp_phone, address, city, state, zip_code, country, latitude, longitude, is_active, slug, created_at, updated_at, chat_resume_hours
 `
 
 type UpdateShopsParams struct {
-	ID            int32
-	Name          string
-	Description   string
-	LogoUrl       pgtype.Text
-	WebsiteUrl    pgtype.Text
-	Email         pgtype.Text
-	WhatsappPhone pgtype.Text
-	Address       string
-	City          string
-	State         string
-	ZipCode       string
-	Country       string
-	Latitude      float64
-	Longitude     float64
-	IsActive      bool
+	ID              int32
+	Name            string
+	Description     string
+	LogoUrl         pgtype.Text
+	WebsiteUrl      pgtype.Text
+	Email           pgtype.Text
+	WhatsappPhone   pgtype.Text
+	Address         string
+	City            string
+	State           string
+	ZipCode         string
+	Country         string
+	Latitude        float64
+	Longitude       float64
+	IsActive        bool
+	ChatResumeHours pgtype.Int4
 }
 
 func (q *Queries) UpdateShops(ctx context.Context, arg UpdateShopsParams) (Shop, error) {
@@ -337,6 +380,7 @@ func (q *Queries) UpdateShops(ctx context.Context, arg UpdateShopsParams) (Shop,
 		arg.Latitude,
 		arg.Longitude,
 		arg.IsActive,
+		arg.ChatResumeHours,
 	)
 	var i Shop
 	err := row.Scan(
@@ -358,6 +402,7 @@ func (q *Queries) UpdateShops(ctx context.Context, arg UpdateShopsParams) (Shop,
 		&i.Slug,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChatResumeHours,
 	)
 	return i, err
 }
@@ -11,6 +11,69 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const confirmUserEmail = `-- name: ConfirmUserEmail :one
+UPDATE users
+SET email = unconfirmed_email, unconfirmed_email = NULL, updated_at = now()
+WHERE id = $1 AND unconfirmed_email = $2
+RETURNING id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug
+`
+
+type ConfirmUserEmailParams struct {
+	ID               int32
+	UnconfirmedEmail pgtype.Text
+}
+
+func (q *Queries) ConfirmUserEmail(ctx context.Context, arg ConfirmUserEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, confirmUserEmail, arg.ID, arg.UnconfirmedEmail)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Email,
+		&i.UnconfirmedEmail,
+		&i.Phone,
+		&i.CodeArea,
+		&i.UnconfirmedPhone,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
+const confirmUserPhone = `-- name: ConfirmUserPhone :one
+UPDATE users
+SET phone = unconfirmed_phone, code_area = $2, unconfirmed_phone = NULL, updated_at = now()
+WHERE id = $1 AND unconfirmed_phone = $3
+RETURNING id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug
+`
+
+type ConfirmUserPhoneParams struct {
+	ID               int32
+	CodeArea         pgtype.Text
+	UnconfirmedPhone pgtype.Text
+}
+
+func (q *Queries) ConfirmUserPhone(ctx context.Context, arg ConfirmUserPhoneParams) (User, error) {
+	row := q.db.QueryRow(ctx, confirmUserPhone, arg.ID, arg.CodeArea, arg.UnconfirmedPhone)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Email,
+		&i.UnconfirmedEmail,
+		&i.Phone,
+		&i.CodeArea,
+		&i.UnconfirmedPhone,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
 const countUsers = `-- name: CountUsers :one
 SELECT COUNT(*) FROM users
 WHERE is_active = true
@@ -29,9 +92,10 @@ INSERT INTO users (
     shop_id,
     email,
     phone,
+    code_area,
     is_active
 ) VALUES (
-    $1, $2, $3, $4
+    $1, $2, $3, $4, $5
 )
 RETURNING id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug
 `
@@ -40,6 +104,7 @@ type CreateUserParams struct {
 	ShopID   int32
 	Email    pgtype.Text
 	Phone    pgtype.Text
+	CodeArea pgtype.Text
 	IsActive pgtype.Bool
 }
 
@@ -49,6 +114,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.ShopID,
 		arg.Email,
 		arg.Phone,
+		arg.CodeArea,
 		arg.IsActive,
 	)
 	var i User
@@ -68,6 +134,22 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 	return i, err
 }
 
+const createUserRole = `-- name: CreateUserRole :exec
+INSERT INTO user_roles (user_id, role_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type CreateUserRoleParams struct {
+	UserID int32
+	RoleID int32
+}
+
+func (q *Queries) CreateUserRole(ctx context.Context, arg CreateUserRoleParams) error {
+	_, err := q.db.Exec(ctx, createUserRole, arg.UserID, arg.RoleID)
+	return err
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1
@@ -89,6 +171,65 @@ func (q *Queries) DeleteUserById(ctx context.Context, id int32) error {
 	return err
 }
 
+const findActiveUserByShopAndEmail = `-- name: FindActiveUserByShopAndEmail :one
+SELECT id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug FROM users
+WHERE shop_id = $1 AND email = $2 AND is_active = true LIMIT 1
+`
+
+type FindActiveUserByShopAndEmailParams struct {
+	ShopID int32
+	Email  pgtype.Text
+}
+
+func (q *Queries) FindActiveUserByShopAndEmail(ctx context.Context, arg FindActiveUserByShopAndEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, findActiveUserByShopAndEmail, arg.ShopID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Email,
+		&i.UnconfirmedEmail,
+		&i.Phone,
+		&i.CodeArea,
+		&i.UnconfirmedPhone,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
+const findActiveUserByShopAndPhone = `-- name: FindActiveUserByShopAndPhone :one
+SELECT id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug FROM users
+WHERE shop_id = $1 AND phone = $2 AND code_area = $3 AND is_active = true LIMIT 1
+`
+
+type FindActiveUserByShopAndPhoneParams struct {
+	ShopID   int32
+	Phone    pgtype.Text
+	CodeArea pgtype.Text
+}
+
+func (q *Queries) FindActiveUserByShopAndPhone(ctx context.Context, arg FindActiveUserByShopAndPhoneParams) (User, error) {
+	row := q.db.QueryRow(ctx, findActiveUserByShopAndPhone, arg.ShopID, arg.Phone, arg.CodeArea)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Email,
+		&i.UnconfirmedEmail,
+		&i.Phone,
+		&i.CodeArea,
+		&i.UnconfirmedPhone,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
 const findUserByPhone = `-- name: FindUserByPhone :one
 SELECT id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug FROM users
 WHERE phone = $1 AND is_active = true LIMIT 1
@@ -113,18 +254,47 @@ func (q *Queries) FindUserByPhone(ctx context.Context, phone pgtype.Text) (User,
 	return i, err
 }
 
-const findUserByPhoneAndCode = `-- name: FindUserByPhoneAndCode :one
+const findUserByShopAndEmail = `-- name: FindUserByShopAndEmail :one
 SELECT id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug FROM users
-WHERE phone = $1 and code_area = $2  AND is_active = true LIMIT 1
+WHERE shop_id = $1 AND email = $2 LIMIT 1
 `
 
-type FindUserByPhoneAndCodeParams struct {
-	Phone    pgtype.Text
-	CodeArea pgtype.Text
+type FindUserByShopAndEmailParams struct {
+	ShopID int32
+	Email  pgtype.Text
 }
 
-func (q *Queries) FindUserByPhoneAndCode(ctx context.Context, arg FindUserByPhoneAndCodeParams) (User, error) {
-	row := q.db.QueryRow(ctx, findUserByPhoneAndCode, arg.Phone, arg.CodeArea)
+func (q *Queries) FindUserByShopAndEmail(ctx context.Context, arg FindUserByShopAndEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, findUserByShopAndEmail, arg.ShopID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.ShopID,
+		&i.Email,
+		&i.UnconfirmedEmail,
+		&i.Phone,
+		&i.CodeArea,
+		&i.UnconfirmedPhone,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
+const findUserByShopAndPhone = `-- name: FindUserByShopAndPhone :one
+SELECT id, shop_id, email, unconfirmed_email, phone, code_area, unconfirmed_phone, is_active, created_at, updated_at, slug FROM users
+WHERE shop_id = $1 AND phone = $2 LIMIT 1
+`
+
+type FindUserByShopAndPhoneParams struct {
+	ShopID int32
+	Phone  pgtype.Text
+}
+
+func (q *Queries) FindUserByShopAndPhone(ctx context.Context, arg FindUserByShopAndPhoneParams) (User, error) {
+	row := q.db.QueryRow(ctx, findUserByShopAndPhone, arg.ShopID, arg.Phone)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -182,6 +352,50 @@ func (q *Queries) GetUser(ctx context.Context, id int32) (GetUserRow, error) {
 	return i, err
 }
 
+const listShopStaffContacts = `-- name: ListShopStaffContacts :many
+SELECT DISTINCT u.id, u.email, u.phone, u.code_area
+FROM users u
+JOIN user_roles ur ON ur.user_id = u.id
+JOIN roles r ON r.id = ur.role_id
+WHERE u.shop_id = $1
+  AND u.is_active = true
+  AND r.is_active = true
+  AND r.name IN ('ADMIN', 'SUPER_ADMIN')
+`
+
+type ListShopStaffContactsRow struct {
+	ID       int32
+	Email    pgtype.Text
+	Phone    pgtype.Text
+	CodeArea pgtype.Text
+}
+
+// Active admins of a shop, who are notified when a chat needs staff.
+func (q *Queries) ListShopStaffContacts(ctx context.Context, shopID int32) ([]ListShopStaffContactsRow, error) {
+	rows, err := q.db.Query(ctx, listShopStaffContacts, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListShopStaffContactsRow
+	for rows.Next() {
+		var i ListShopStaffContactsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Phone,
+			&i.CodeArea,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUserRole = `-- name: ListUserRole :many
 select rl.id, rl.name, rl.created_at, rl.updated_at, rl.is_active from users us join user_roles ur
 on us.id = ur.user_id 
@@ -276,6 +490,38 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 	return items, nil
 }
 
+const setUnconfirmedEmail = `-- name: SetUnconfirmedEmail :exec
+UPDATE users
+SET unconfirmed_email = $2, updated_at = now()
+WHERE id = $1
+`
+
+type SetUnconfirmedEmailParams struct {
+	ID               int32
+	UnconfirmedEmail pgtype.Text
+}
+
+func (q *Queries) SetUnconfirmedEmail(ctx context.Context, arg SetUnconfirmedEmailParams) error {
+	_, err := q.db.Exec(ctx, setUnconfirmedEmail, arg.ID, arg.UnconfirmedEmail)
+	return err
+}
+
+const setUnconfirmedPhone = `-- name: SetUnconfirmedPhone :exec
+UPDATE users
+SET unconfirmed_phone = $2, updated_at = now()
+WHERE id = $1
+`
+
+type SetUnconfirmedPhoneParams struct {
+	ID               int32
+	UnconfirmedPhone pgtype.Text
+}
+
+func (q *Queries) SetUnconfirmedPhone(ctx context.Context, arg SetUnconfirmedPhoneParams) error {
+	_, err := q.db.Exec(ctx, setUnconfirmedPhone, arg.ID, arg.UnconfirmedPhone)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET 
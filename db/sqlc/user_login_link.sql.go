@@ -0,0 +1,42 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: user_login_link.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const consumeUserLoginLink = `-- name: ConsumeUserLoginLink :one
+UPDATE user_login_link
+SET is_used = TRUE
+WHERE token_hash = $1 AND is_used = FALSE AND expires_at > now()
+RETURNING user_id
+`
+
+func (q *Queries) ConsumeUserLoginLink(ctx context.Context, tokenHash string) (int32, error) {
+	row := q.db.QueryRow(ctx, consumeUserLoginLink, tokenHash)
+	var user_id int32
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const createUserLoginLink = `-- name: CreateUserLoginLink :exec
+INSERT INTO user_login_link (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreateUserLoginLinkParams struct {
+	UserID    int32
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreateUserLoginLink(ctx context.Context, arg CreateUserLoginLinkParams) error {
+	_, err := q.db.Exec(ctx, createUserLoginLink, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	return err
+}
@@ -11,43 +11,64 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const countValidOtps = `-- name: CountValidOtps :one
-SELECT COUNT(*) FROM user_login_otp
-WHERE otp = $1
-  AND expires_at >= (NOW() AT TIME ZONE 'UTC')
+const countOtpSendsByIPSince = `-- name: CountOtpSendsByIPSince :one
+SELECT COUNT(*) FROM otp_send_log
+WHERE ip_address = $1 AND created_at >= $2
 `
 
-func (q *Queries) CountValidOtps(ctx context.Context, otp string) (int64, error) {
-	row := q.db.QueryRow(ctx, countValidOtps, otp)
+type CountOtpSendsByIPSinceParams struct {
+	IpAddress string
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) CountOtpSendsByIPSince(ctx context.Context, arg CountOtpSendsByIPSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countOtpSendsByIPSince, arg.IpAddress, arg.CreatedAt)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
-const findUserLoginOtpByPhone = `-- name: FindUserLoginOtpByPhone :one
-SELECT ulo.id, ulo.user_id, ulo.otp, ulo.is_used, ulo.created_at, ulo.expires_at FROM user_login_otp ulo join users u
-ON ulo.user_id = u.id
-WHERE u.phone = $1
-ORDER BY ulo. created_at DESC
+const countOtpSendsByPhoneSince = `-- name: CountOtpSendsByPhoneSince :one
+SELECT COUNT(*) FROM otp_send_log
+WHERE phone = $1 AND created_at >= $2
+`
+
+type CountOtpSendsByPhoneSinceParams struct {
+	Phone     string
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) CountOtpSendsByPhoneSince(ctx context.Context, arg CountOtpSendsByPhoneSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countOtpSendsByPhoneSince, arg.Phone, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const findUserLoginOtpByUserId = `-- name: FindUserLoginOtpByUserId :one
+SELECT id, user_id, is_used, created_at, expires_at, otp_hash, attempts FROM user_login_otp
+WHERE user_id = $1
+ORDER BY created_at DESC
 LIMIT 1
 `
 
-func (q *Queries) FindUserLoginOtpByPhone(ctx context.Context, phone pgtype.Text) (UserLoginOtp, error) {
-	row := q.db.QueryRow(ctx, findUserLoginOtpByPhone, phone)
+func (q *Queries) FindUserLoginOtpByUserId(ctx context.Context, userID int32) (UserLoginOtp, error) {
+	row := q.db.QueryRow(ctx, findUserLoginOtpByUserId, userID)
 	var i UserLoginOtp
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.Otp,
 		&i.IsUsed,
 		&i.CreatedAt,
 		&i.ExpiresAt,
+		&i.OtpHash,
+		&i.Attempts,
 	)
 	return i, err
 }
 
 const findUserLoginOtpNotActive = `-- name: FindUserLoginOtpNotActive :one
-SELECT id, user_id, otp, is_used, created_at, expires_at FROM user_login_otp ulo
+SELECT id, user_id, is_used, created_at, expires_at, otp_hash, attempts FROM user_login_otp ulo
 WHERE ulo.user_id = $1 AND is_used = FALSE
 `
 
@@ -57,43 +78,104 @@ func (q *Queries) FindUserLoginOtpNotActive(ctx context.Context, userID int32) (
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.Otp,
 		&i.IsUsed,
 		&i.CreatedAt,
 		&i.ExpiresAt,
+		&i.OtpHash,
+		&i.Attempts,
+	)
+	return i, err
+}
+
+const getLastOtpSendByPhone = `-- name: GetLastOtpSendByPhone :one
+SELECT id, phone, ip_address, created_at FROM otp_send_log
+WHERE phone = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastOtpSendByPhone(ctx context.Context, phone string) (OtpSendLog, error) {
+	row := q.db.QueryRow(ctx, getLastOtpSendByPhone, phone)
+	var i OtpSendLog
+	err := row.Scan(
+		&i.ID,
+		&i.Phone,
+		&i.IpAddress,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const incrementOtpAttempts = `-- name: IncrementOtpAttempts :one
+UPDATE user_login_otp
+SET attempts = attempts + 1
+WHERE id = $1 AND attempts < $2::int
+RETURNING attempts
+`
+
+type IncrementOtpAttemptsParams struct {
+	ID          int32
+	MaxAttempts int32
+}
+
+func (q *Queries) IncrementOtpAttempts(ctx context.Context, arg IncrementOtpAttemptsParams) (int32, error) {
+	row := q.db.QueryRow(ctx, incrementOtpAttempts, arg.ID, arg.MaxAttempts)
+	var attempts int32
+	err := row.Scan(&attempts)
+	return attempts, err
+}
+
+const insertOtpSendLog = `-- name: InsertOtpSendLog :exec
+INSERT INTO otp_send_log (phone, ip_address)
+VALUES ($1, $2)
+`
+
+type InsertOtpSendLogParams struct {
+	Phone     string
+	IpAddress string
+}
+
+func (q *Queries) InsertOtpSendLog(ctx context.Context, arg InsertOtpSendLogParams) error {
+	_, err := q.db.Exec(ctx, insertOtpSendLog, arg.Phone, arg.IpAddress)
+	return err
+}
+
 const insertUserLoginOtp = `-- name: InsertUserLoginOtp :exec
-INSERT INTO user_login_otp (user_id, otp)
+INSERT INTO user_login_otp (user_id, otp_hash)
 VALUES ($1, $2)
 `
 
 type InsertUserLoginOtpParams struct {
-	UserID int32
-	Otp    string
+	UserID  int32
+	OtpHash string
 }
 
 func (q *Queries) InsertUserLoginOtp(ctx context.Context, arg InsertUserLoginOtpParams) error {
-	_, err := q.db.Exec(ctx, insertUserLoginOtp, arg.UserID, arg.Otp)
+	_, err := q.db.Exec(ctx, insertUserLoginOtp, arg.UserID, arg.OtpHash)
 	return err
 }
 
-const updateIsUsed = `-- name: UpdateIsUsed :exec
-UPDATE user_login_otp
-SET is_used = TRUE 
-WHERE user_id = $1 AND otp = $2
+const lockOtpPhone = `-- name: LockOtpPhone :exec
+SELECT pg_advisory_xact_lock(hashtext($1::text))
 `
 
-type UpdateIsUsedParams struct {
-	UserID int32
-	Otp    string
+func (q *Queries) LockOtpPhone(ctx context.Context, phone string) error {
+	_, err := q.db.Exec(ctx, lockOtpPhone, phone)
+	return err
 }
 
-func (q *Queries) UpdateIsUsed(ctx context.Context, arg UpdateIsUsedParams) error {
-	_, err := q.db.Exec(ctx, updateIsUsed, arg.UserID, arg.Otp)
-	return err
+const updateIsUsed = `-- name: UpdateIsUsed :execrows
+UPDATE user_login_otp
+SET is_used = TRUE
+WHERE id = $1 AND is_used = FALSE
+`
+
+func (q *Queries) UpdateIsUsed(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, updateIsUsed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const updateIsUsedFalse = `-- name: UpdateIsUsedFalse :exec
@@ -109,41 +191,96 @@ func (q *Queries) UpdateIsUsedFalse(ctx context.Context, userID int32) error {
 
 const updateOTPByUserId = `-- name: UpdateOTPByUserId :exec
 UPDATE user_login_otp
-SET is_used = FALSE, otp = $1, created_at = NOW(), 
+SET is_used = FALSE, otp_hash = $1, attempts = 0, created_at = NOW(),
     expires_at = NOW() + INTERVAL '5 minutes'
 WHERE user_id = $2
 `
 
 type UpdateOTPByUserIdParams struct {
-	Otp    string
-	UserID int32
+	OtpHash string
+	UserID  int32
 }
 
 func (q *Queries) UpdateOTPByUserId(ctx context.Context, arg UpdateOTPByUserIdParams) error {
-	_, err := q.db.Exec(ctx, updateOTPByUserId, arg.Otp, arg.UserID)
+	_, err := q.db.Exec(ctx, updateOTPByUserId, arg.OtpHash, arg.UserID)
 	return err
 }
 
+const verifyEmailOtp = `-- name: VerifyEmailOtp :one
+SELECT CASE
+       WHEN ulo.expires_at < (NOW() AT TIME ZONE 'UTC') THEN 'EXPIRED'
+       WHEN ulo.is_used = TRUE THEN 'USED'
+       ELSE 'VALID'
+     END as status, ulo.id, ulo.user_id, ulo.otp_hash, ulo.attempts
+FROM user_login_otp ulo join users u
+ON ulo.user_id = u.id
+WHERE u.shop_id = $1 AND u.email = $2 AND u.is_active = true
+ORDER BY ulo.created_at DESC
+LIMIT 1
+`
+
+type VerifyEmailOtpParams struct {
+	ShopID int32
+	Email  pgtype.Text
+}
+
+type VerifyEmailOtpRow struct {
+	Status   string
+	ID       int32
+	UserID   int32
+	OtpHash  string
+	Attempts int32
+}
+
+func (q *Queries) VerifyEmailOtp(ctx context.Context, arg VerifyEmailOtpParams) (VerifyEmailOtpRow, error) {
+	row := q.db.QueryRow(ctx, verifyEmailOtp, arg.ShopID, arg.Email)
+	var i VerifyEmailOtpRow
+	err := row.Scan(
+		&i.Status,
+		&i.ID,
+		&i.UserID,
+		&i.OtpHash,
+		&i.Attempts,
+	)
+	return i, err
+}
+
 const verifyOtp = `-- name: VerifyOtp :one
 SELECT CASE
-       WHEN expires_at < (NOW() AT TIME ZONE 'UTC') THEN 'EXPIRED'
-       WHEN is_used = TRUE THEN 'USED'
+       WHEN ulo.expires_at < (NOW() AT TIME ZONE 'UTC') THEN 'EXPIRED'
+       WHEN ulo.is_used = TRUE THEN 'USED'
        ELSE 'VALID'
-     END as status, user_id
-FROM user_login_otp
-WHERE otp = $1
-ORDER BY created_at DESC
+     END as status, ulo.id, ulo.user_id, ulo.otp_hash, ulo.attempts
+FROM user_login_otp ulo join users u
+ON ulo.user_id = u.id
+WHERE u.shop_id = $1 AND u.phone = $2 AND u.code_area = $3 AND u.is_active = true
+ORDER BY ulo.created_at DESC
 LIMIT 1
 `
 
+type VerifyOtpParams struct {
+	ShopID   int32
+	Phone    pgtype.Text
+	CodeArea pgtype.Text
+}
+
 type VerifyOtpRow struct {
-	Status string
-	UserID int32
+	Status   string
+	ID       int32
+	UserID   int32
+	OtpHash  string
+	Attempts int32
 }
 
-func (q *Queries) VerifyOtp(ctx context.Context, otp string) (VerifyOtpRow, error) {
-	row := q.db.QueryRow(ctx, verifyOtp, otp)
+func (q *Queries) VerifyOtp(ctx context.Context, arg VerifyOtpParams) (VerifyOtpRow, error) {
+	row := q.db.QueryRow(ctx, verifyOtp, arg.ShopID, arg.Phone, arg.CodeArea)
 	var i VerifyOtpRow
-	err := row.Scan(&i.Status, &i.UserID)
+	err := row.Scan(
+		&i.Status,
+		&i.ID,
+		&i.UserID,
+		&i.OtpHash,
+		&i.Attempts,
+	)
 	return i, err
 }
@@ -0,0 +1,180 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: user_verification_otp.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createVerificationOtp = `-- name: CreateVerificationOtp :one
+INSERT INTO user_verification_otp (
+    purpose,
+    shop_id,
+    user_id,
+    target,
+    code_area,
+    otp_hash
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING id, purpose, shop_id, user_id, target, code_area, otp_hash, attempts, is_used, created_at, expires_at
+`
+
+type CreateVerificationOtpParams struct {
+	Purpose  string
+	ShopID   int32
+	UserID   pgtype.Int4
+	Target   string
+	CodeArea pgtype.Text
+	OtpHash  string
+}
+
+func (q *Queries) CreateVerificationOtp(ctx context.Context, arg CreateVerificationOtpParams) (UserVerificationOtp, error) {
+	row := q.db.QueryRow(ctx, createVerificationOtp,
+		arg.Purpose,
+		arg.ShopID,
+		arg.UserID,
+		arg.Target,
+		arg.CodeArea,
+		arg.OtpHash,
+	)
+	var i UserVerificationOtp
+	err := row.Scan(
+		&i.ID,
+		&i.Purpose,
+		&i.ShopID,
+		&i.UserID,
+		&i.Target,
+		&i.CodeArea,
+		&i.OtpHash,
+		&i.Attempts,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getLatestVerificationOtpByTarget = `-- name: GetLatestVerificationOtpByTarget :one
+SELECT id, purpose, shop_id, user_id, target, code_area, otp_hash, attempts, is_used, created_at, expires_at FROM user_verification_otp
+WHERE purpose = $1 AND shop_id = $2 AND target = $3 AND code_area = $4
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetLatestVerificationOtpByTargetParams struct {
+	Purpose  string
+	ShopID   int32
+	Target   string
+	CodeArea pgtype.Text
+}
+
+func (q *Queries) GetLatestVerificationOtpByTarget(ctx context.Context, arg GetLatestVerificationOtpByTargetParams) (UserVerificationOtp, error) {
+	row := q.db.QueryRow(ctx, getLatestVerificationOtpByTarget,
+		arg.Purpose,
+		arg.ShopID,
+		arg.Target,
+		arg.CodeArea,
+	)
+	var i UserVerificationOtp
+	err := row.Scan(
+		&i.ID,
+		&i.Purpose,
+		&i.ShopID,
+		&i.UserID,
+		&i.Target,
+		&i.CodeArea,
+		&i.OtpHash,
+		&i.Attempts,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getLatestVerificationOtpByUser = `-- name: GetLatestVerificationOtpByUser :one
+SELECT id, purpose, shop_id, user_id, target, code_area, otp_hash, attempts, is_used, created_at, expires_at FROM user_verification_otp
+WHERE purpose = $1 AND user_id = $2
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetLatestVerificationOtpByUserParams struct {
+	Purpose string
+	UserID  pgtype.Int4
+}
+
+func (q *Queries) GetLatestVerificationOtpByUser(ctx context.Context, arg GetLatestVerificationOtpByUserParams) (UserVerificationOtp, error) {
+	row := q.db.QueryRow(ctx, getLatestVerificationOtpByUser, arg.Purpose, arg.UserID)
+	var i UserVerificationOtp
+	err := row.Scan(
+		&i.ID,
+		&i.Purpose,
+		&i.ShopID,
+		&i.UserID,
+		&i.Target,
+		&i.CodeArea,
+		&i.OtpHash,
+		&i.Attempts,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const incrementVerificationOtpAttempts = `-- name: IncrementVerificationOtpAttempts :one
+UPDATE user_verification_otp
+SET attempts = attempts + 1
+WHERE id = $1 AND attempts < $2::int
+RETURNING attempts
+`
+
+type IncrementVerificationOtpAttemptsParams struct {
+	ID          int32
+	MaxAttempts int32
+}
+
+func (q *Queries) IncrementVerificationOtpAttempts(ctx context.Context, arg IncrementVerificationOtpAttemptsParams) (int32, error) {
+	row := q.db.QueryRow(ctx, incrementVerificationOtpAttempts, arg.ID, arg.MaxAttempts)
+	var attempts int32
+	err := row.Scan(&attempts)
+	return attempts, err
+}
+
+const invalidateVerificationOtps = `-- name: InvalidateVerificationOtps :exec
+UPDATE user_verification_otp
+SET is_used = TRUE
+WHERE purpose = $1 AND shop_id = $2 AND target = $3 AND is_used = FALSE
+`
+
+type InvalidateVerificationOtpsParams struct {
+	Purpose string
+	ShopID  int32
+	Target  string
+}
+
+func (q *Queries) InvalidateVerificationOtps(ctx context.Context, arg InvalidateVerificationOtpsParams) error {
+	_, err := q.db.Exec(ctx, invalidateVerificationOtps, arg.Purpose, arg.ShopID, arg.Target)
+	return err
+}
+
+const markVerificationOtpUsed = `-- name: MarkVerificationOtpUsed :execrows
+UPDATE user_verification_otp
+SET is_used = TRUE
+WHERE id = $1 AND is_used = FALSE
+`
+
+func (q *Queries) MarkVerificationOtpUsed(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, markVerificationOtpUsed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
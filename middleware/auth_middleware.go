@@ -1,9 +1,8 @@
 package middleware
 
 import (
-	"net/http"
+	"shofy/utils/apperror"
 	"shofy/utils/jwt"
-	"shofy/utils/response"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -11,58 +10,103 @@ import (
 
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		tokenString, tokenFound := requestToken(c)
 
-		var tokenString string
-		var tokenFound bool
+		// If no token found in either cookie or header
+		if !tokenFound {
+			_ = c.Error(apperror.ErrUnauthenticated)
+			c.Abort()
+			return
+		}
 
-		// Get token from cookie
-		if cookie, err := c.Cookie("token"); err == nil && cookie != "" {
-			tokenString = cookie
-			tokenFound = true
+		// Validate token
+		claims, err := jwt.ValidateToken(tokenString)
+		if err != nil {
+			_ = c.Error(apperror.ErrInvalidToken.Wrap(err))
+			c.Abort()
+			return
 		}
 
-		// If no token in cookie, check Authorization header
+		setUserClaims(c, claims)
+		c.Next()
+	}
+}
+
+// ChatAuth accepts a user token or, when allowGuests is set, a guest token
+// from GenerateGuestToken. Both carry the shop the caller is chatting with.
+// Guests get guest_id and shop_id set instead of user_id.
+func ChatAuth(allowGuests bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, tokenFound := requestToken(c)
 		if !tokenFound {
-			authHeader := c.GetHeader("Authorization")
-			if authHeader != "" {
-				// Check if the header starts with "Bearer "
-				splitToken := strings.Split(authHeader, "Bearer ")
-				if len(splitToken) == 2 {
-					tokenString = splitToken[1]
-					tokenFound = true
-				}
-			}
+			_ = c.Error(apperror.ErrUnauthenticated)
+			c.Abort()
+			return
 		}
 
-		// If no token found in either cookie or header
-		if !tokenFound {
+		if claims, err := jwt.ValidateToken(tokenString); err == nil {
+			// Tokens issued before shop_id was added cannot be tied to a shop.
+			if claims.ShopID == 0 {
+				_ = c.Error(apperror.ErrInvalidToken)
+				c.Abort()
+				return
+			}
+			setUserClaims(c, claims)
+			c.Next()
+			return
+		}
 
-			response.Error(c, http.StatusUnauthorized, "No authentication token provided")
+		if !allowGuests {
+			_ = c.Error(apperror.ErrInvalidToken)
 			c.Abort()
 			return
 		}
-
-		// Validate token
-		claims, err := jwt.ValidateToken(tokenString)
+		guest, err := jwt.ValidateGuestToken(tokenString)
 		if err != nil {
-			response.Error(c, http.StatusUnauthorized, "Invalid or expired token")
+			_ = c.Error(apperror.ErrInvalidToken.Wrap(err))
 			c.Abort()
 			return
 		}
-
-		// Set user ID in context for later use
-		c.Set("user_id", claims.UserID)
-		c.Set("user_claims", claims)
+		c.Set("guest_id", guest.GuestID)
+		c.Set("shop_id", guest.ShopID)
 		c.Next()
 	}
 }
 
+// requestToken reads the token from the "token" cookie or, failing that,
+// the Authorization bearer header.
+func requestToken(c *gin.Context) (string, bool) {
+	if cookie, err := c.Cookie("token"); err == nil && cookie != "" {
+		return cookie, true
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if authHeader != "" {
+		// Check if the header starts with "Bearer "
+		splitToken := strings.Split(authHeader, "Bearer ")
+		if len(splitToken) == 2 {
+			return splitToken[1], true
+		}
+	}
+	return "", false
+}
+
+// setUserClaims sets the user ID and claims in context for later use.
+func setUserClaims(c *gin.Context, claims *jwt.JWTClaim) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_claims", claims)
+	if claims.ShopID != 0 {
+		c.Set("shop_id", claims.ShopID)
+	}
+}
+
 func RequireRole(requiredRoles []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		value, exists := c.Get("user_claims")
 
 		if !exists {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Unauthorized"})
+			_ = c.Error(apperror.ErrUnauthenticated)
+			c.Abort()
 			return
 		}
 
@@ -79,6 +123,7 @@ func RequireRole(requiredRoles []string) gin.HandlerFunc {
 			}
 		}
 
-		c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden"})
+		_ = c.Error(apperror.ErrForbidden)
+		c.Abort()
 	}
 }
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"shofy/utils/jwt"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestChatAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwt.SetSecretKey("test-secret")
+	t.Cleanup(func() { jwt.SetSecretKey("") })
+
+	guestToken, guest, err := jwt.GenerateGuestToken(7, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userToken, _ := jwt.GenerateToken(3, 7, []string{"CUSTOMER"})
+	legacyToken, _ := jwt.GenerateToken(3, 0, []string{"CUSTOMER"})
+
+	newRouter := func(allowGuests bool) *gin.Engine {
+		router := gin.New()
+		router.Use(ErrorHandler())
+		router.GET("/chat", ChatAuth(allowGuests), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"user":  c.Value("user_id"),
+				"guest": c.GetString("guest_id"),
+				"shop":  c.Value("shop_id"),
+			})
+		})
+		return router
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		allowGuests bool
+		want        int
+		wantBody    string
+	}{
+		{"user", userToken, true, http.StatusOK, `{"guest":"","shop":7,"user":3}`},
+		{"guest", guestToken, true, http.StatusOK, `{"guest":"` + guest.GuestID + `","shop":7,"user":null}`},
+		{"guests disabled", guestToken, false, http.StatusUnauthorized, ""},
+		{"token without shop", legacyToken, true, http.StatusUnauthorized, ""},
+		{"no token", "", true, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/chat", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			newRouter(tt.allowGuests).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Fatalf("body = %s, want %s", rec.Body, tt.wantBody)
+			}
+		})
+	}
+
+	// A guest token must not pass as a user token.
+	if _, err := jwt.ValidateToken(guestToken); err == nil {
+		t.Fatal("guest token accepted as user token")
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"shofy/utils"
+	"shofy/utils/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DBCircuit fails requests fast with ErrDatabaseDown while utils.DBBreaker
+// is open, instead of letting each one wait for the pool to time out.
+// After the cooldown requests go through again and the first successful
+// query closes the breaker.
+func DBCircuit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !utils.DBBreaker.Allow() {
+			_ = c.Error(apperror.ErrDatabaseDown)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"shofy/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDBCircuitRejectsWhileOpen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(), DBCircuit())
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func() int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		return rec.Code
+	}
+
+	if code := send(); code != http.StatusNoContent {
+		t.Fatalf("closed breaker: status = %d, want 204", code)
+	}
+	for range utils.DBBreaker.Threshold {
+		utils.DBBreaker.Failure()
+	}
+	defer utils.DBBreaker.Success()
+	if code := send(); code != http.StatusServiceUnavailable {
+		t.Errorf("open breaker: status = %d, want 503", code)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"shofy/utils"
+	"shofy/utils/apperror"
+	"shofy/utils/response"
+	"shofy/utils/validation"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler writes the response for the last error a handler attached
+// with c.Error. Typed errors keep their status and code; anything else is
+// logged and returned as a 500 without leaking the cause. Validation errors
+// caused by binding carry the per-field messages as data.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		appErr, ok := apperror.As(err)
+		if !ok || appErr.Kind == apperror.KindInternal {
+			// An unreachable database is reported as such whatever the
+			// handler was doing.
+			if utils.IsDBDown(err) {
+				appErr = apperror.ErrDatabaseDown.Wrap(err)
+			} else if !ok {
+				appErr = apperror.ErrInternal.Wrap(err)
+			}
+		}
+
+		// Bind failures list each offending field in the client's language.
+		if appErr.Kind == apperror.KindValidation && appErr.Details == nil {
+			locale := validation.Locale(c.GetHeader("Accept-Language"))
+			if fields := validation.FieldErrors(err, locale); fields != nil {
+				appErr = appErr.WithDetails(fields)
+			}
+		}
+
+		status := apperror.HTTPStatus(appErr.Kind)
+		if status >= 500 {
+			slog.ErrorContext(c.Request.Context(), "Request failed",
+				"route", c.FullPath(),
+				"code", appErr.Code,
+				"error", err,
+			)
+		}
+
+		if appErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+		}
+
+		c.AbortWithStatusJSON(status, response.Response{
+			Status:  false,
+			Code:    appErr.Code,
+			Message: appErr.Message,
+			Data:    appErr.Details,
+		})
+	}
+}
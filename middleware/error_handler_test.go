@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"shofy/utils/apperror"
+	"shofy/utils/response"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serveError(t *testing.T, err error) (*httptest.ResponseRecorder, response.Response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/", func(c *gin.Context) {
+		_ = c.Error(err)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var body response.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	return rec, body
+}
+
+func TestErrorHandlerTypedError(t *testing.T) {
+	notFound := apperror.NotFound("user_not_found", "User not found")
+	rec, body := serveError(t, fmt.Errorf("lookup: %w", notFound))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if body.Status || body.Code != "user_not_found" || body.Message != "User not found" {
+		t.Fatalf("unexpected body %+v", body)
+	}
+}
+
+func TestErrorHandlerRetryAfter(t *testing.T) {
+	limited := apperror.RateLimited("otp_rate_limited", "OTP was sent recently").WithRetryAfter(1500 * time.Millisecond)
+	rec, _ := serveError(t, limited)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("Retry-After = %q, want 2", got)
+	}
+}
+
+func TestErrorHandlerHidesUntypedErrors(t *testing.T) {
+	rec, body := serveError(t, errors.New("pq: relation users does not exist"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if body.Code != "internal_error" || body.Message != "Internal Server Error" {
+		t.Fatalf("unexpected body %+v", body)
+	}
+
+	rec, body = serveError(t, errors.New("dial tcp: connection refused"))
+	if rec.Code != http.StatusServiceUnavailable || body.Code != "database_unavailable" {
+		t.Fatalf("db down: status = %d, body %+v", rec.Code, body)
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	idempotencyService "shofy/modules/idempotency/service"
+	"shofy/utils/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+var (
+	errInvalidIdempotencyKey = apperror.Validation("invalid_idempotency_key", "Idempotency-Key must be 1 to 255 characters")
+	errRequestTooLarge       = apperror.Validation("request_too_large", "Request body is too large for an idempotent request")
+)
+
+// Idempotency makes POST and PATCH requests that carry an Idempotency-Key
+// header safe to retry. The first request runs and its response is stored;
+// a retry with the same key and body gets the stored response back, and a
+// retry with a different body is rejected. Failed requests (5xx or errors
+// left for ErrorHandler) release the key so they can be retried.
+func Idempotency(svc idempotencyService.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		method := c.Request.Method
+		if key == "" || (method != http.MethodPost && method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			_ = c.Error(errInvalidIdempotencyKey)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+			c.Abort()
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			_ = c.Error(errRequestTooLarge)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scope := idempotencyScope(c)
+		stored, err := svc.Claim(ctx, scope, key, requestFingerprint(c.Request, body))
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		// A panicking handler must not leave the key claimed, or retries
+		// get 409 until it expires. The panic goes on to the recovery
+		// middleware.
+		defer func() {
+			if p := recover(); p != nil {
+				if err := svc.Release(context.WithoutCancel(ctx), scope, key); err != nil {
+					slog.ErrorContext(ctx, "Failed to release idempotency key", "error", err)
+				}
+				panic(p)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Finish bookkeeping even if the client went away.
+		ctx = context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if !recorder.Written() || len(c.Errors) > 0 || status >= http.StatusInternalServerError {
+			if err := svc.Release(ctx, scope, key); err != nil {
+				slog.ErrorContext(ctx, "Failed to release idempotency key", "error", err)
+			}
+			return
+		}
+
+		err = svc.Save(ctx, scope, key, idempotencyService.Response{
+			StatusCode:  status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+// idempotencyScope keeps keys from different routes and callers apart.
+// Anonymous requests share the route scope; keys are expected to be random.
+func idempotencyScope(c *gin.Context) string {
+	scope := c.Request.Method + " " + c.FullPath()
+	if userID, ok := c.Get("user_id"); ok {
+		scope += fmt.Sprintf(" user:%v", userID)
+	} else if guestID, ok := c.Get("guest_id"); ok {
+		scope += fmt.Sprintf(" guest:%v", guestID)
+	}
+	return scope
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyRecorder copies everything written to the response.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	idempotencyService "shofy/modules/idempotency/service"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memoryIdempotency struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	fingerprint string
+	resp        *idempotencyService.Response
+}
+
+func (m *memoryIdempotency) Claim(_ context.Context, scope, key, fingerprint string) (*idempotencyService.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[scope+key]
+	if !ok {
+		m.entries[scope+key] = &memoryEntry{fingerprint: fingerprint}
+		return nil, nil
+	}
+	if e.fingerprint != fingerprint {
+		return nil, idempotencyService.ErrKeyReused
+	}
+	if e.resp == nil {
+		return nil, idempotencyService.ErrInProgress
+	}
+	return e.resp, nil
+}
+
+func (m *memoryIdempotency) Save(_ context.Context, scope, key string, resp idempotencyService.Response) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[scope+key].resp = &resp
+	return nil
+}
+
+func (m *memoryIdempotency) Release(_ context.Context, scope, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, scope+key)
+	return nil
+}
+
+func (m *memoryIdempotency) PurgeExpired(context.Context) (int64, error) { return 0, nil }
+
+func TestIdempotencyReplaysAndRejectsMismatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotency{entries: map[string]*memoryEntry{}}
+
+	calls := 0
+	router := gin.New()
+	router.Use(ErrorHandler(), Idempotency(store))
+	router.POST("/orders", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"order": calls})
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("k1", `{"total":10}`)
+	retry := send("k1", `{"total":10}`)
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Fatalf("retry got %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatal("replayed response is not marked")
+	}
+
+	if rec := send("k1", `{"total":99}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("mismatched payload got %d, want 422", rec.Code)
+	}
+}
+
+func TestIdempotencyReleasesFailedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotency{entries: map[string]*memoryEntry{}}
+
+	router := gin.New()
+	router.Use(ErrorHandler(), Idempotency(store))
+	router.POST("/chat/message", func(c *gin.Context) {
+		_ = c.Error(context.DeadlineExceeded)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/chat/message", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "k2")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(store.entries) != 0 {
+		t.Fatalf("failed request kept its key: %+v", store.entries)
+	}
+}
+
+func TestIdempotencyReleasesPanickedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotency{entries: map[string]*memoryEntry{}}
+
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}), Idempotency(store))
+	router.POST("/orders", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "k3")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want the panic to reach recovery", rec.Code)
+	}
+	if len(store.entries) != 0 {
+		t.Fatalf("panicked request kept its key: %+v", store.entries)
+	}
+}
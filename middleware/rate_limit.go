@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"shofy/utils/apperror"
+	"shofy/utils/metrics"
+	"shofy/utils/ratelimit"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+var errRateLimited = apperror.RateLimited("rate_limited", "Too many requests, please slow down")
+
+// RateLimit takes a token from the bucket for the caller under p and
+// rejects the request with 429 and Retry-After when the bucket is empty.
+// Every response carries the RateLimit-* headers. A zero policy disables the
+// limit. When the backend fails the request is let through, so an outage of
+// the limiter does not take the API down with it.
+func RateLimit(l ratelimit.Limiter, p ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p.Limit == 0 {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		res, err := l.Take(ctx, p.Name+":"+rateLimitKey(c, p.Key), p)
+		if err != nil {
+			slog.WarnContext(ctx, "Rate limiter unavailable, allowing request", "policy", p.Name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(res.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+		c.Header(RateLimitResetHeader, strconv.Itoa(ceilSeconds(res.Reset)))
+		c.Header(RateLimitPolicyHeader, fmt.Sprintf("%d;w=%d", p.Limit, ceilSeconds(p.Period)))
+
+		if !res.Allowed {
+			metrics.RateLimitRejections.WithLabelValues(p.Name).Inc()
+			_ = c.Error(errRateLimited.WithRetryAfter(res.RetryAfter))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the caller. User and shop keys fall back to the
+// client IP for requests without them, so anonymous traffic is still
+// limited. They rely on user_id and shop_id set by earlier middleware.
+func rateLimitKey(c *gin.Context, kind string) string {
+	switch kind {
+	case ratelimit.KeyUser:
+		if userID, ok := c.Get("user_id"); ok {
+			return fmt.Sprintf("user:%v", userID)
+		}
+	case ratelimit.KeyShop:
+		if shopID, ok := c.Get("shop_id"); ok {
+			return fmt.Sprintf("shop:%v", shopID)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
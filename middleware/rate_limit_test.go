@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"shofy/utils/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitRejectsWithHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.Policy{Name: "otp_send", Limit: 1, Period: time.Minute, Key: ratelimit.KeyIP}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.POST("/otp", RateLimit(ratelimit.NewMemoryLimiter(), policy), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/otp", nil))
+		return rec
+	}
+
+	first := send()
+	if first.Code != http.StatusNoContent {
+		t.Fatalf("first request got %d", first.Code)
+	}
+	if got := first.Header().Get(RateLimitRemainingHeader); got != "0" {
+		t.Fatalf("%s = %q, want 0", RateLimitRemainingHeader, got)
+	}
+	if got := first.Header().Get(RateLimitPolicyHeader); got != "1;w=60" {
+		t.Fatalf("%s = %q, want 1;w=60", RateLimitPolicyHeader, got)
+	}
+
+	second := send()
+	if second.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request got %d, want 429", second.Code)
+	}
+	if got := second.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("Retry-After = %q, want 60", got)
+	}
+}
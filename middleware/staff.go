@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"shofy/utils/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrNoStaffShop = apperror.Forbidden("staff_shop_unknown", "Token tidak memiliki toko, silakan login ulang")
+
+// StaffIdentity returns the shop and user ID of the signed-in staff member
+// set by AuthMiddleware. Tokens without a shop get ErrNoStaffShop.
+func StaffIdentity(c *gin.Context) (shopID, staffID int32, err error) {
+	if v, ok := c.Get("shop_id"); ok {
+		shopID, _ = v.(int32)
+	}
+	if v, ok := c.Get("user_id"); ok {
+		staffID, _ = v.(int32)
+	}
+	if shopID == 0 {
+		return 0, 0, ErrNoStaffShop
+	}
+	return shopID, staffID, nil
+}
@@ -2,36 +2,34 @@ package service
 
 import (
 	"context"
-	"log"
-	"os"
+	"errors"
+	"net/http"
+	"shofy/app/api/config"
+	"shofy/modules/chat/model"
+	"shofy/utils/metrics"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
 
+const providerName = "azure"
+
+// ErrEmptyReply is returned when a completion has no choices or no text.
+var ErrEmptyReply = errors.New("Azure OpenAI returned an empty reply")
+
 type AzureOpenAI struct {
 	Model  string
 	Client *azopenai.Client
 }
 
-func NewOpenAI(ctx context.Context) *AzureOpenAI {
-
-	model := "gpt-35-turbo"
-
-	endpoint := os.Getenv("AZURE_OPENAI_ENDPOINT")
-	if endpoint == "" {
-		log.Fatal("AZURE_OPENAI_ENDPOINT is not set")
-	}
-
-	secretAPIKey := os.Getenv("AZURE_OPENAI_API_KEY")
-	if secretAPIKey == "" {
-		log.Fatal("AZURE_OPENAI_API_KEY is not set")
-	}
-
-	keyCredential := azcore.NewKeyCredential(secretAPIKey)
-	client, err := azopenai.NewClientWithKeyCredential(endpoint, keyCredential, nil)
+func NewOpenAI(ctx context.Context, cfg config.AzureConfig) (*AzureOpenAI, error) {
+	keyCredential := azcore.NewKeyCredential(cfg.APIKey)
+	client, err := azopenai.NewClientWithKeyCredential(cfg.Endpoint, keyCredential, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// resp, err := client.GetCompletions(context.TODO(), azopenai.CompletionsOptions{
@@ -42,7 +40,71 @@ func NewOpenAI(ctx context.Context) *AzureOpenAI {
 	// }, nil)
 
 	return &AzureOpenAI{
-		Model:  model,
+		Model:  cfg.Deployment,
 		Client: client,
+	}, nil
+}
+
+func convertToAzureFormat(messages []model.ChatMessage) []azopenai.ChatRequestMessageClassification {
+	result := make([]azopenai.ChatRequestMessageClassification, 0, len(messages))
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			result = append(result, &azopenai.ChatRequestSystemMessage{Content: azopenai.NewChatRequestSystemMessageContent(m.Content)})
+		case "assistant":
+			result = append(result, &azopenai.ChatRequestAssistantMessage{Content: azopenai.NewChatRequestAssistantMessageContent(m.Content)})
+		default:
+			result = append(result, &azopenai.ChatRequestUserMessage{Content: azopenai.NewChatRequestUserMessageContent(m.Content)})
+		}
 	}
+	return result
+}
+
+// ChatCompletion mirrors the DeepInfra client so either can serve a chat.
+func (s *AzureOpenAI) ChatCompletion(ctx context.Context, messages []model.ChatMessage, opts ...model.CompletionOption) (model.ChatResponse, int, error) {
+	start := time.Now()
+	defer func() {
+		metrics.LLMDuration.WithLabelValues(providerName, s.Model).Observe(time.Since(start).Seconds())
+	}()
+
+	options := azopenai.ChatCompletionsOptions{
+		Messages:       convertToAzureFormat(messages),
+		DeploymentName: &s.Model,
+	}
+	if o := model.NewCompletionOptions(opts...); o.Schema != nil {
+		options.ResponseFormat = &azopenai.ChatCompletionsJSONSchemaResponseFormat{
+			JSONSchema: &azopenai.ChatCompletionsJSONSchemaResponseFormatJSONSchema{
+				Name:   &o.SchemaName,
+				Schema: o.Schema,
+			},
+		}
+	}
+
+	resp, err := s.Client.GetChatCompletions(ctx, options, nil)
+	if err != nil {
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) {
+			metrics.LLMFailures.WithLabelValues(providerName, s.Model, "http_"+strconv.Itoa(respErr.StatusCode)).Inc()
+			return model.ChatResponse{}, respErr.StatusCode, err
+		}
+		metrics.LLMFailures.WithLabelValues(providerName, s.Model, "transport").Inc()
+		return model.ChatResponse{}, http.StatusInternalServerError, err
+	}
+
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil || resp.Choices[0].Message.Content == nil ||
+		strings.TrimSpace(*resp.Choices[0].Message.Content) == "" {
+		metrics.LLMFailures.WithLabelValues(providerName, s.Model, "empty").Inc()
+		return model.ChatResponse{}, http.StatusBadGateway, ErrEmptyReply
+	}
+
+	if resp.Usage != nil {
+		if resp.Usage.PromptTokens != nil {
+			metrics.LLMTokens.WithLabelValues(providerName, s.Model, "prompt").Add(float64(*resp.Usage.PromptTokens))
+		}
+		if resp.Usage.CompletionTokens != nil {
+			metrics.LLMTokens.WithLabelValues(providerName, s.Model, "completion").Add(float64(*resp.Usage.CompletionTokens))
+		}
+	}
+
+	return model.ChatResponse{Message: *resp.Choices[0].Message.Content}, http.StatusOK, nil
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"shofy/middleware"
 	"shofy/modules/categories/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
 	"strconv"
 
@@ -37,7 +38,7 @@ func (h *CategoryHandler) InitRoutes(router *gin.RouterGroup) {
 func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
 	categories, err := h.categoryService.GetAllCategory(c)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
@@ -47,12 +48,12 @@ func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
 func (h *CategoryHandler) GetCategoryByID(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		response.Error(c, http.StatusBadRequest, "Invalid product ID")
+		_ = c.Error(service.ErrInvalidCategoryID)
 		return
 	}
 	categories, err := h.categoryService.GetCategoryByID(c.Request.Context(), id)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
@@ -70,20 +71,20 @@ func (h *CategoryHandler) GetCategoriesPaginated(c *gin.Context) {
 	offsetStr := c.DefaultQuery("offset", off)
 
 	limit, err := strconv.ParseInt(limitStr, 10, 32)
-	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid limit parameter")
+	if err != nil || limit < 1 {
+		_ = c.Error(apperror.Validation("invalid_limit", "Invalid limit parameter"))
 		return
 	}
 
 	offset, err := strconv.ParseInt(offsetStr, 10, 32)
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid offset parameter")
+		_ = c.Error(apperror.Validation("invalid_offset", "Invalid offset parameter"))
 		return
 	}
 
 	categories, err := h.categoryService.GetCategoriesPaginated(c.Request.Context(), int32(limit), int32(offset))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
@@ -99,21 +100,21 @@ func (h *CategoryHandler) GetCategoriesPaginated(c *gin.Context) {
 func (h *CategoryHandler) DeleteCategoryByID(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		response.Error(c, http.StatusBadRequest, "Invalid category ID")
+		_ = c.Error(service.ErrInvalidCategoryID)
 		return
 	}
 
 	// Check if category exists
 	_, err := h.categoryService.GetCategoryByID(c.Request.Context(), id)
 	if err != nil {
-		response.Error(c, http.StatusNotFound, "Category not found")
+		_ = c.Error(err)
 		return
 	}
 
 	// Proceed to delete
 	err = h.categoryService.DeleteByID(c.Request.Context(), id)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
@@ -2,13 +2,21 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	db "shofy/db/sqlc"
+	"shofy/utils/apperror"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrCategoryNotFound  = apperror.NotFound("category_not_found", "Category not found")
+	ErrInvalidCategoryID = apperror.Validation("invalid_category_id", "Invalid category ID")
+)
+
 type CategoryService interface {
 	GetAllCategory(ctx context.Context) ([]db.Category, error)
 	GetCategoryByID(ctx context.Context, id string) (*db.Category, error)
@@ -51,12 +59,15 @@ func (s *categoryService) GetCategoryByID(ctx context.Context, id string) (*db.C
 	var categoryID int32
 	_, err := fmt.Sscanf(id, "%d", &categoryID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid category ID format: %v", err)
+		return nil, ErrInvalidCategoryID.Wrap(err)
 	}
 
 	category, err := s.queries.GetCategoryByID(ctx, categoryID)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("Error Database: %w", err)
 	}
 	return &category, nil
 }
@@ -102,7 +113,7 @@ func (s *categoryService) DeleteByID(ctx context.Context, id string) error {
 	var categoryID int32
 	_, err := fmt.Sscanf(id, "%d", &categoryID)
 	if err != nil {
-		return fmt.Errorf("invalid category ID format: %v", err)
+		return ErrInvalidCategoryID.Wrap(err)
 	}
 
 	// Use SQLC's DeleteCategory method with the provided context
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"net/http"
+	"shofy/middleware"
+	"shofy/modules/chat/model"
+	"shofy/utils/apperror"
+	"shofy/utils/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitAdminRoutes registers the staff endpoints for watching and taking over
+// chat sessions. rg must run AuthMiddleware and RequireRole; staff only see
+// sessions of the shop in their token.
+func (r *ChatRouter) InitAdminRoutes(rg *gin.RouterGroup) {
+	rg.GET("/sessions", r.ListShopSessions)
+	rg.GET("/sessions/:id/messages", r.ListShopSessionMessages)
+	rg.POST("/sessions/:id/takeover", r.TakeOverSession)
+	rg.POST("/sessions/:id/reply", r.StaffReply)
+	rg.POST("/sessions/:id/release", r.ReleaseSession)
+	rg.GET("/sessions/:id/reasoning", r.ListSessionReasoning)
+	rg.GET("/cache", r.CacheStats)
+	rg.DELETE("/cache", r.PurgeCache)
+}
+
+// staffSession reads the staff identity and the :id session parameter.
+func staffSession(c *gin.Context) (shopID, staffID, sessionID int32, err error) {
+	shopID, staffID, err = middleware.StaffIdentity(c)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	id, convErr := strconv.Atoi(c.Param("id"))
+	if convErr != nil {
+		return 0, 0, 0, errInvalidSessionID
+	}
+	return shopID, staffID, int32(id), nil
+}
+
+func (r *ChatRouter) ListShopSessions(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var q model.StaffSessionQuery
+	if err := c.BindQuery(&q); err != nil {
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
+		return
+	}
+
+	limit, page, offset := response.PageBounds(q.Limit, q.CurrentPage)
+	sessions, total, err := r.ChatService.ListShopSessions(c.Request.Context(), shopID, q.Mode, int32(limit), int32(offset))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Sessions fetched successfully", gin.H{
+		"data":         sessions,
+		"total_items":  total,
+		"total_pages":  response.TotalPages(total, limit),
+		"current_page": page,
+		"limit":        limit,
+	})
+}
+
+func (r *ChatRouter) ListShopSessionMessages(c *gin.Context) {
+	shopID, _, sessionID, err := staffSession(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var q model.MessageListQuery
+	if err := c.BindQuery(&q); err != nil {
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
+		return
+	}
+
+	limit, page, offset := response.PageBounds(q.Limit, q.CurrentPage)
+	messages, total, err := r.ChatService.ListShopSessionMessages(c.Request.Context(), shopID, sessionID, int32(limit), int32(offset))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Messages fetched successfully", gin.H{
+		"data":         messages,
+		"total_items":  total,
+		"total_pages":  response.TotalPages(total, limit),
+		"current_page": page,
+		"limit":        limit,
+	})
+}
+
+func (r *ChatRouter) TakeOverSession(c *gin.Context) {
+	shopID, staffID, sessionID, err := staffSession(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	session, err := r.ChatService.TakeOver(c.Request.Context(), shopID, staffID, sessionID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Session taken over", gin.H{
+		"id":         session.ID,
+		"mode":       session.Mode,
+		"handled_by": session.HandledBy.Int32,
+	})
+}
+
+func (r *ChatRouter) StaffReply(c *gin.Context) {
+	shopID, staffID, sessionID, err := staffSession(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req model.StaffReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	result, err := r.ChatService.StaffReply(c.Request.Context(), shopID, staffID, sessionID, req.Message)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Reply sent", result)
+}
+
+func (r *ChatRouter) ReleaseSession(c *gin.Context) {
+	shopID, _, sessionID, err := staffSession(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	session, err := r.ChatService.Release(c.Request.Context(), shopID, sessionID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Session handed back to the assistant", gin.H{
+		"id":   session.ID,
+		"mode": session.Mode,
+	})
+}
+
+// ListSessionReasoning shows the reasoning behind the assistant's replies
+// in a session, when CHAT_STORE_REASONING was on.
+func (r *ChatRouter) ListSessionReasoning(c *gin.Context) {
+	shopID, _, sessionID, err := staffSession(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	reasoning, err := r.ChatService.ListShopSessionReasoning(c.Request.Context(), shopID, sessionID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Reasoning fetched successfully", reasoning)
+}
+
+// CacheStats reports how many replies of the shop are cached and how often
+// they were reused.
+func (r *ChatRouter) CacheStats(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	stats, err := r.ChatService.CacheStats(c.Request.Context(), shopID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Cache stats fetched successfully", stats)
+}
+
+// PurgeCache drops every cached reply of the shop, e.g. after a price
+// change made outside the product endpoints.
+func (r *ChatRouter) PurgeCache(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	removed, err := r.ChatService.PurgeCache(c.Request.Context(), shopID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Cache purged", gin.H{"removed": removed})
+}
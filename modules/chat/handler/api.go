@@ -2,20 +2,38 @@ package handler
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"shofy/app/api/server"
 	db "shofy/db/sqlc"
 	"shofy/modules/chat/model"
 	chatService "shofy/modules/chat/service"
 	deepinfraService "shofy/modules/deepinfra/service"
+	guardrailService "shofy/modules/guardrail/service"
+	knowledgeService "shofy/modules/knowledge/service"
+	languageService "shofy/modules/language/service"
+	llmService "shofy/modules/llm/service"
+	notificationService "shofy/modules/notification/service"
+	promptService "shofy/modules/prompt/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
-	"strings"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// msgForwardedToStaff answers customer messages while staff handle the session.
+const msgForwardedToStaff = "Pesan diteruskan ke staf toko"
+
+var (
+	errInvalidSessionID = apperror.Validation("invalid_session_id", "Invalid session ID")
+
+	errLoadHistory = apperror.Internal("chat_history_failed", "Gagal mengambil histori")
+	errSaveMessage = apperror.Internal("chat_save_failed", "Gagal menyimpan pesan user")
+	errSaveReply   = apperror.Internal("chat_save_reply_failed", "Gagal menyimpan jawaban AI")
+)
+
 type ChatRouter struct {
 	Query         *db.Queries
 	DBPool        *pgxpool.Pool
@@ -23,8 +41,20 @@ type ChatRouter struct {
 	OpenAIService *deepinfraService.OpenAIService
 }
 
-func NewChatAPIRoutes(ctx context.Context, srv *server.Server) *ChatRouter {
-	chatSvc := chatService.NewChatService(ctx, srv.DBPool, srv.Queries)
+// NewChatAPIRoutes builds the chat routes. knowledge is the knowledge base
+// service the admin routes manage.
+func NewChatAPIRoutes(ctx context.Context, srv *server.Server, knowledge knowledgeService.KnowledgeService) *ChatRouter {
+	chatSvc := chatService.NewChatService(ctx, srv.DBPool, srv.Queries, llmService.NewClient(ctx, srv.Config), srv.Config.Chat)
+	chatSvc.WhatsApp = notificationService.NewWhatsAppService(srv.Config.WhatsApp)
+	chatSvc.Email = notificationService.NewEmailService(srv.Config.SMTP)
+	chatSvc.Guardrail = guardrailService.NewGuardrailService(srv.DBPool, srv.Config.Guardrail)
+	chatSvc.Language = languageService.NewLanguageService(srv.DBPool, chatSvc.LLM)
+	if classifier, err := llmService.NewClassifierClient(ctx, srv.Config); err != nil {
+		slog.ErrorContext(ctx, "Intent classifier unavailable, using rules only", "error", err)
+	} else if classifier != nil {
+		chatSvc.Classifier = classifier
+	}
+	chatSvc.Prompts = promptService.NewPromptService(srv.DBPool, knowledge, chatSvc.Language)
 	return &ChatRouter{
 		Query:       srv.Queries,
 		DBPool:      srv.DBPool,
@@ -32,12 +62,49 @@ func NewChatAPIRoutes(ctx context.Context, srv *server.Server) *ChatRouter {
 	}
 }
 
-func (r *ChatRouter) InitRoutes(rg *gin.RouterGroup) {
-	rg.POST("/chat", r.CreateChat)
-	rg.POST("/chat/session", r.GetOrCreateSession)
-	rg.POST("/chat/message", r.MessageChat)
-	// rg.GET("/chat/session/messages", r.CreateChat)
+// InitRoutes registers the guest token endpoint on public, the endpoints
+// that call the chat model on completions and the other chat endpoints on
+// authed. authed and completions must run middleware.ChatAuth.
+func (r *ChatRouter) InitRoutes(public, authed, completions *gin.RouterGroup) {
+	public.POST("/chat/guest", r.CreateGuestToken)
+
+	completions.POST("/chat", r.CreateChat)
+	completions.POST("/chat/message", r.MessageChat)
+
+	authed.POST("/chat/session", r.GetOrCreateSession)
+	authed.GET("/chat/sessions", r.ListSessions)
+	authed.GET("/chat/sessions/:id/messages", r.ListMessages)
+	authed.POST("/chat/sessions/:id/close", r.CloseSession)
+	authed.POST("/chat/sessions/:id/archive", r.ArchiveSession)
+}
+
+// chatOwner reads the caller set by middleware.ChatAuth.
+func chatOwner(c *gin.Context) model.ChatOwner {
+	owner := model.ChatOwner{GuestID: c.GetString("guest_id")}
+	if userID, ok := c.Get("user_id"); ok {
+		owner.UserID, _ = userID.(int32)
+	}
+	if shopID, ok := c.Get("shop_id"); ok {
+		owner.ShopID, _ = shopID.(int32)
+	}
+	return owner
+}
+
+func (r *ChatRouter) CreateGuestToken(c *gin.Context) {
+	var req model.GuestTokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	result, err := r.ChatService.IssueGuestToken(c.Request.Context(), req.ShopID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
+	response.Success(c, http.StatusCreated, "Guest token created", result)
 }
 
 func (r *ChatRouter) CreateChat(c *gin.Context) {
@@ -45,18 +112,24 @@ func (r *ChatRouter) CreateChat(c *gin.Context) {
 	var chatPayload model.ChatPayload
 
 	if err := c.ShouldBindJSON(&chatPayload); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
-	result, _, err := r.ChatService.CreateChat(ctx, chatPayload, chatPayload.ChannelID)
+	result, _, err := r.ChatService.CreateChat(ctx, chatOwner(c), chatPayload, chatPayload.ChannelID)
 
 	if err != nil {
-		response.NotSuccess(c, http.StatusOK, "Internal Server Error", nil)
+		_ = c.Error(err)
 		return
 	}
 
-	response.Success(c, http.StatusOK, "Chat Successfully", result.Message)
+	// Staf sedang menangani sesi, AI tidak membalas
+	if result.Message == "" {
+		response.Success(c, http.StatusAccepted, msgForwardedToStaff, nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Chat Successfully", r.ChatService.FormatReply(ctx, int32(chatPayload.ChannelID), result.Message))
 }
 
 func (r *ChatRouter) GetOrCreateSession(c *gin.Context) {
@@ -64,14 +137,14 @@ func (r *ChatRouter) GetOrCreateSession(c *gin.Context) {
 	var chatPayload model.ChatSession
 
 	if err := c.ShouldBindJSON(&chatPayload); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
-	result, err := r.ChatService.GetOrCreateSession(ctx, chatPayload)
+	result, err := r.ChatService.GetOrCreateSession(ctx, chatOwner(c), chatPayload)
 
 	if err != nil {
-		response.NotSuccess(c, http.StatusOK, "Internal Server Error", nil)
+		_ = c.Error(err)
 		return
 	}
 
@@ -83,95 +156,158 @@ func (r *ChatRouter) MessageChat(c *gin.Context) {
 	var payload model.ChatMessagePayload
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	// Pastikan sesi milik pengguna ini dan masih terbuka
+	session, err := r.ChatService.GetSession(ctx, chatOwner(c), payload.SessionID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if session.Status != model.SessionOpen {
+		_ = c.Error(chatService.ErrSessionClosed)
 		return
 	}
 
 	// Ambil histori chat
 	history, err := r.ChatService.BuildMessageHistory(ctx, payload.SessionID)
 	if err != nil {
-		response.NotSuccess(c, http.StatusInternalServerError, "Gagal mengambil histori", nil)
+		_ = c.Error(errLoadHistory.Wrap(err))
 		return
 	}
 
 	// Simpan pesan user ke DB
 	err = r.ChatService.SaveUserMessage(ctx, payload.SessionID, payload.Message)
 	if err != nil {
-		response.NotSuccess(c, http.StatusInternalServerError, "Gagal menyimpan pesan user", nil)
+		_ = c.Error(errSaveMessage.Wrap(err))
 		return
 	}
 
-	// Tambahkan pesan user terbaru ke history
-	history = append(history, model.ChatMessage{
-		Role:    "user",
-		Content: payload.Message,
-	})
-
-	// 💡 Panggil GetAllProductsAsString DI SINI
-	productsStr, err := r.ChatService.GetAllProductsAsString(ctx)
+	// Staf menangani sesi, atau pelanggan minta dihubungkan ke staf
+	handoff, handled, err := r.ChatService.RouteToStaff(ctx, session, payload.Message)
 	if err != nil {
-		response.NotSuccess(c, http.StatusInternalServerError, "Gagal mengambil data produk", nil)
+		_ = c.Error(err)
+		return
+	}
+	if handled {
+		if handoff == "" {
+			response.Success(c, http.StatusAccepted, msgForwardedToStaff, nil)
+			return
+		}
+		r.sendReply(c, session.ChannelID, payload.ResponseFormat, textReply(handoff))
 		return
 	}
 
-	shotpStr, err := r.ChatService.GetAllProductsAsString(ctx)
+	// Jawab lewat guardrail, router maksud, cache atau AI
+	reply, err := r.ChatService.Answer(ctx, session, history, payload.Message, payload.ResponseFormat == model.ResponseStructured)
 	if err != nil {
-		response.NotSuccess(c, http.StatusInternalServerError, "Gagal mengambil data produk", nil)
+		_ = c.Error(err)
 		return
 	}
 
-	// 💡 Sisipkan prompt system tentang produk
-	// System Prompt — menjelaskan role AI dan produk
-	systemPrompt := model.ChatMessage{
-		Role: "system",
-		Content: fmt.Sprintf(`Kamu adalah asisten virtual dari sebuah toko online bernama "Shofy".
-					Tugas kamu:
-					- Menjawab pertanyaan tentang produk yang dijual
-					- Menjelaskan detail dan stok barang
-					- Membantu pelanggan dalam proses pemesanan
-					- Memberikan jawaban yang relevan dan informatif
+	// Simpan jawaban
+	err = r.ChatService.SaveAssistantReply(ctx, payload.SessionID, reply.Reply.Message, reply.Reasoning)
+	if err != nil {
+		_ = c.Error(errSaveReply.Wrap(err))
+		return
+	}
+	r.sendReply(c, session.ChannelID, payload.ResponseFormat, reply.Reply)
+}
 
-					Berikan jawaban yang rapi dengan format seperti berikut:
+// textReply wraps a plain answer as a structured reply without blocks.
+func textReply(text string) model.StructuredReply {
+	return model.StructuredReply{Message: text, Blocks: []model.Block{}}
+}
 
-					Produk "sepatu" tersedia di:
+// sendReply formats the reply for the session's channel and sends it as
+// text, or whole when the client asked for a structured reply.
+func (r *ChatRouter) sendReply(c *gin.Context, channelID int32, format string, reply model.StructuredReply) {
+	reply.Message = r.ChatService.FormatReply(c.Request.Context(), channelID, reply.Message)
+	if format == model.ResponseStructured {
+		response.Success(c, http.StatusOK, "Berhasil membalas pesan", reply)
+		return
+	}
+	response.Success(c, http.StatusOK, "Berhasil membalas pesan", reply.Message)
+}
 
-					1. Toko A
-					- Stok: 10
-					- Harga: Rp 100.000
+func (r *ChatRouter) ListSessions(c *gin.Context) {
+	var q model.SessionListQuery
 
-					2. Toko B
-					- Stok: 20
-					- Harga: Rp 120.000
+	if err := c.BindQuery(&q); err != nil {
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
+		return
+	}
 
-					Pisahkan setiap item dengan newline (\n) agar mudah dibaca di frontend.
+	limit, page, offset := response.PageBounds(q.Limit, q.CurrentPage)
+	sessions, total, err := r.ChatService.ListSessions(c.Request.Context(), chatOwner(c), q, int32(limit), int32(offset))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-					Data produk:
-					%s
+	response.Success(c, http.StatusOK, "Sessions fetched successfully", gin.H{
+		"data":         sessions,
+		"total_items":  total,
+		"total_pages":  response.TotalPages(total, limit),
+		"current_page": page,
+		"limit":        limit,
+	})
+}
 
-					Data toko:
-					%s`, productsStr, shotpStr),
+func (r *ChatRouter) ListMessages(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(errInvalidSessionID)
+		return
 	}
 
-	// Tambahkan system prompt ke awal
-	history = append([]model.ChatMessage{systemPrompt}, history...)
+	var q model.MessageListQuery
+	if err := c.BindQuery(&q); err != nil {
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
+		return
+	}
 
-	// Kirim ke AI
-	reply, _, err := r.ChatService.ChatCompletion(ctx, history)
+	limit, page, offset := response.PageBounds(q.Limit, q.CurrentPage)
+	messages, total, err := r.ChatService.ListMessages(c.Request.Context(), chatOwner(c), int32(sessionID), int32(limit), int32(offset))
 	if err != nil {
-		response.NotSuccess(c, http.StatusInternalServerError, "Gagal mendapatkan jawaban dari AI", nil)
+		_ = c.Error(err)
 		return
 	}
 
-	// Simpan jawaban AI
-	err = r.ChatService.SaveAssistantMessage(ctx, payload.SessionID, reply.Message)
+	response.Success(c, http.StatusOK, "Messages fetched successfully", gin.H{
+		"data":         messages,
+		"total_items":  total,
+		"total_pages":  response.TotalPages(total, limit),
+		"current_page": page,
+		"limit":        limit,
+	})
+}
+
+func (r *ChatRouter) CloseSession(c *gin.Context) {
+	r.setSessionStatus(c, model.SessionClosed, "Session closed")
+}
+
+func (r *ChatRouter) ArchiveSession(c *gin.Context) {
+	r.setSessionStatus(c, model.SessionArchived, "Session archived")
+}
+
+func (r *ChatRouter) setSessionStatus(c *gin.Context, status, message string) {
+	sessionID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		response.NotSuccess(c, http.StatusInternalServerError, "Gagal menyimpan jawaban AI", nil)
+		_ = c.Error(errInvalidSessionID)
 		return
 	}
 
-	// Ubah newline menjadi <br> sebelum dikirim ke frontend
-	formattedReply := strings.ReplaceAll(reply.Message, "\n", "<br>")
+	session, err := r.ChatService.SetSessionStatus(c.Request.Context(), chatOwner(c), int32(sessionID), status)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-	// Kirim ke client
-	response.Success(c, http.StatusOK, "Berhasil membalas pesan", formattedReply)
+	response.Success(c, http.StatusOK, message, gin.H{
+		"id":     session.ID,
+		"status": session.Status,
+	})
 }
@@ -1,7 +1,12 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type ChatPayload struct {
-	Message   string `json:"message" binding:"required"`
+	Message   string `json:"message" binding:"required,max=4000"`
 	ChannelID int    `json:"channel_id" binding:"required,min=1"`
 }
 
@@ -40,14 +45,249 @@ type ChatResponse struct {
 	FullResponse ChatCompletionResponse `json:"full_response"`
 }
 
+// ChatOwner is the caller a session belongs to, taken from the token: a
+// signed-in user, or an anonymous guest when UserID is 0.
+type ChatOwner struct {
+	UserID  int32
+	GuestID string
+	ShopID  int32
+}
+
 type ChatSession struct {
-	UserID    int `json:"user_id" binding:"required"`
-	ChannelID int `json:"channel_id" binding:"required"`
+	ChannelID int `json:"channel_id" binding:"required,min=1"`
 }
 
 type ChatMessagePayload struct {
-	Message   string `json:"message"`
-	SessionID int32  `json:"session_id" binding:"required"`
-	UserID    int    `json:"user_id" binding:"required"`
-	ChannelID int    `json:"channel_id" binding:"required"`
+	Message   string `json:"message" binding:"required,max=4000"`
+	SessionID int32  `json:"session_id" binding:"required,min=1"`
+	// ResponseFormat "structured" returns a StructuredReply instead of text.
+	ResponseFormat string `json:"response_format" binding:"omitempty,oneof=text structured"`
+}
+
+// Response formats of MessageChat.
+const (
+	ResponseText       = "text"
+	ResponseStructured = "structured"
+)
+
+// Block types of a StructuredReply.
+const (
+	BlockProductCard  = "product_card"
+	BlockQuickReplies = "quick_replies"
+	BlockOrderSummary = "order_summary"
+)
+
+// StructuredReply is a reply for clients that render typed blocks.
+type StructuredReply struct {
+	Message string  `json:"message"`
+	Blocks  []Block `json:"blocks"`
+	// Fallback is set when the model did not produce usable JSON and
+	// Message is its plain text answer.
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// Block is one typed element of a StructuredReply; only the field for its
+// Type is set.
+type Block struct {
+	Type    string        `json:"type"`
+	Product *ProductCard  `json:"product,omitempty"`
+	Options []string      `json:"options,omitempty"`
+	Order   *OrderSummary `json:"order,omitempty"`
+}
+
+// ProductCard shows a product of the catalog. Stock is only set when the
+// shop's guardrails allow stock counts; InStock is always set.
+type ProductCard struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Price   float64 `json:"price"`
+	Stock   *int32  `json:"stock,omitempty"`
+	InStock bool    `json:"in_stock"`
+	Shop    string  `json:"shop"`
+}
+
+type OrderSummary struct {
+	Items []OrderLine `json:"items"`
+	Total float64     `json:"total"`
+}
+
+type OrderLine struct {
+	ProductID string  `json:"product_id"`
+	Name      string  `json:"name"`
+	Quantity  int32   `json:"quantity"`
+	Price     float64 `json:"price"`
+	Subtotal  float64 `json:"subtotal"`
+}
+
+// Session statuses. Closed sessions take no new messages; archived ones are
+// also left out of the session list unless asked for.
+const (
+	SessionOpen     = "open"
+	SessionClosed   = "closed"
+	SessionArchived = "archived"
+)
+
+// Session modes: who answers the customer. The assistant only replies in
+// ModeAI; ModePending waits for staff after an escalation.
+const (
+	ModeAI      = "ai"
+	ModePending = "pending"
+	ModeHuman   = "human"
+)
+
+// RoleAdmin marks messages written by shop staff.
+const RoleAdmin = "admin"
+
+type SessionListQuery struct {
+	ChannelID   int    `form:"channel_id" binding:"omitempty,gt=0"`
+	Status      string `form:"status" binding:"omitempty,oneof=open closed archived"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int    `form:"page" binding:"omitempty,min=1"`
+}
+
+type MessageListQuery struct {
+	Limit       int `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int `form:"page" binding:"omitempty,min=1"`
+}
+
+// SessionSummary is a session in the session list with a preview of its
+// last message. The preview fields are empty for sessions without messages.
+type SessionSummary struct {
+	ID            int32      `json:"id"`
+	ChannelID     int32      `json:"channel_id"`
+	ShopID        int32      `json:"shop_id"`
+	Status        string     `json:"status"`
+	Mode          string     `json:"mode"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+	LastMessage   string     `json:"last_message"`
+	LastRole      string     `json:"last_role"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+}
+
+// StaffSessionSummary is a session as listed for shop staff.
+type StaffSessionSummary struct {
+	SessionSummary
+	UserID           *int32     `json:"user_id"`
+	GuestID          string     `json:"guest_id,omitempty"`
+	HandledBy        *int32     `json:"handled_by"`
+	EscalatedAt      *time.Time `json:"escalated_at"`
+	EscalationReason string     `json:"escalation_reason,omitempty"`
+}
+
+type StaffSessionQuery struct {
+	Mode        string `form:"mode" binding:"omitempty,oneof=ai pending human"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int    `form:"page" binding:"omitempty,min=1"`
+}
+
+type StaffReplyRequest struct {
+	Message string `json:"message" binding:"required,max=4000"`
+}
+
+// StaffReplyResponse reports whether the reply was pushed to the customer's
+// channel; when not, the customer sees it in the message list.
+type StaffReplyResponse struct {
+	Message   SessionMessage `json:"message"`
+	Delivered bool           `json:"delivered"`
+}
+
+type SessionMessage struct {
+	ID        int32     `json:"id"`
+	Role      string    `json:"role"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GuestTokenRequest struct {
+	ShopID int32 `json:"shop_id" binding:"required,gt=0"`
+}
+
+type GuestTokenResponse struct {
+	Token     string    `json:"token"`
+	GuestID   string    `json:"guest_id"`
+	ShopID    int32     `json:"shop_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CacheStats describes the reply cache of a shop.
+type CacheStats struct {
+	Entries    int64 `json:"entries"`
+	Hits       int64 `json:"hits"`
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+// MessageReasoning is the stored reasoning behind one assistant reply.
+type MessageReasoning struct {
+	ConversationID int32     `json:"conversation_id"`
+	Reply          string    `json:"reply"`
+	Reasoning      string    `json:"reasoning"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CompletionOption tunes one completion request.
+type CompletionOption func(*CompletionOptions)
+
+// CompletionOptions collects what CompletionOption functions set.
+// Providers ignore options they do not support.
+type CompletionOptions struct {
+	// SchemaName and Schema ask for a JSON reply matching a JSON schema.
+	SchemaName string
+	Schema     json.RawMessage
+}
+
+// WithJSONSchema asks for a JSON reply matching schema.
+func WithJSONSchema(name string, schema json.RawMessage) CompletionOption {
+	return func(o *CompletionOptions) {
+		o.SchemaName = name
+		o.Schema = schema
+	}
+}
+
+// NewCompletionOptions applies opts in order.
+func NewCompletionOptions(opts ...CompletionOption) CompletionOptions {
+	var o CompletionOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Intents of customer messages. The router answers order status and
+// smalltalk from data, sends complaints and handoffs to staff and leaves
+// IntentGeneral, the open-ended questions, to the chat model.
+const (
+	IntentProductInquiry = "product_inquiry"
+	IntentOrderStatus    = "order_status"
+	IntentPlaceOrder     = "place_order"
+	IntentComplaint      = "complaint"
+	IntentSmalltalk      = "smalltalk"
+	IntentHandoff        = "handoff"
+	IntentGeneral        = "general"
+)
+
+// Intents lists every intent the classifier may return.
+var Intents = []string{
+	IntentProductInquiry, IntentOrderStatus, IntentPlaceOrder,
+	IntentComplaint, IntentSmalltalk, IntentHandoff, IntentGeneral,
+}
+
+// Intent sources: how the intent of a message was found.
+const (
+	IntentFromRule       = "rule"
+	IntentFromClassifier = "classifier"
+	IntentFromDefault    = "default"
+)
+
+// IntentRoute is how a customer message is answered.
+type IntentRoute struct {
+	Intent string
+	Source string
+	// Reply answers the message without the chat model; Escalated is set
+	// when it is the handoff message.
+	Reply     string
+	Escalated bool
+	// Context is data the chat model needs for the reply, e.g. the order
+	// asked about, when it still writes the reply.
+	Context string
 }
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+	knowledgeModel "shofy/modules/knowledge/model"
+	knowledgeService "shofy/modules/knowledge/service"
+	"shofy/utils/apperror"
+	"strings"
+)
+
+var errBuildPrompt = apperror.Internal("chat_prompt_failed", "Gagal menyiapkan prompt toko")
+
+// Prompter renders a shop's system prompt for a customer message, with the
+// knowledge base excerpts it includes.
+type Prompter interface {
+	SystemPrompt(ctx context.Context, shopID int32, message, lang string) (string, []knowledgeModel.Source, error)
+}
+
+// Where an AssistantReply came from.
+const (
+	AnsweredByGuardrail = "guardrail"
+	AnsweredByRouter    = "router"
+	AnsweredByCache     = "cache"
+	AnsweredByModel     = "model"
+)
+
+// AssistantReply is the answer to a customer message, ready to store and
+// send.
+type AssistantReply struct {
+	Reply     model.StructuredReply
+	Reasoning string
+	// AnsweredBy is one of the AnsweredBy constants.
+	AnsweredBy string
+	// Refused is set when the guardrails refused the message or replaced
+	// the model's reply.
+	Refused bool
+	// Escalated is set when the message or reply handed the session to
+	// staff.
+	Escalated bool
+}
+
+// Answer runs a customer message through the assistant: reply language,
+// input guard, intent routing, response cache, shop prompt, completion,
+// output guard, escalation and citations. history holds the earlier
+// messages of the session. Nothing is stored; the caller saves the reply.
+func (s *ChatService) Answer(ctx context.Context, session db.Session, history []model.ChatMessage, message string, structured bool) (AssistantReply, error) {
+	lang, defaultLang := s.ReplyLanguage(ctx, session, message)
+
+	message, refusal := s.GuardInput(ctx, session, message, lang)
+	if refusal != "" {
+		return AssistantReply{Reply: textReply(refusal), AnsweredBy: AnsweredByGuardrail, Refused: true}, nil
+	}
+
+	// The cache only holds text replies in the shop's default language.
+	// Messages no rule recognises are looked up before the classifier
+	// runs, so cached questions skip it.
+	var cached *CachedReply
+	cacheable := !structured && lang == defaultLang
+	lookup := func() bool {
+		var err error
+		cached, err = s.LookupCachedReply(ctx, session.ShopID, message)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to look up cached reply", "shop_id", session.ShopID, "error", err)
+		}
+		return cached != nil && cached.Hit
+	}
+	_, ruled := ClassifyIntent(message)
+	if cacheable && !ruled && lookup() {
+		return AssistantReply{Reply: textReply(cached.Answer), AnsweredBy: AnsweredByCache}, nil
+	}
+
+	route, err := s.RouteIntent(ctx, session, message, lang)
+	if err != nil {
+		return AssistantReply{}, err
+	}
+	if route.Reply != "" {
+		reply, _ := s.GuardOutput(ctx, session, route.Reply, "", lang)
+		return AssistantReply{Reply: textReply(reply), AnsweredBy: AnsweredByRouter, Escalated: route.Escalated}, nil
+	}
+
+	if cacheable && ruled && lookup() {
+		return AssistantReply{Reply: textReply(cached.Answer), AnsweredBy: AnsweredByCache}, nil
+	}
+
+	prompt, sources, err := s.Prompts.SystemPrompt(ctx, session.ShopID, message, lang)
+	if err != nil {
+		return AssistantReply{}, errBuildPrompt.Wrap(err)
+	}
+	var opts []model.CompletionOption
+	if structured {
+		instruction, err := s.StructuredPrompt(ctx, session.ShopID)
+		if err != nil {
+			return AssistantReply{}, errBuildPrompt.Wrap(err)
+		}
+		prompt += "\n\n" + instruction
+		opts = append(opts, StructuredOutput())
+	}
+	if route.Context != "" {
+		prompt += "\n\n" + route.Context
+	}
+	if instruction := s.LanguageInstruction(lang); instruction != "" {
+		prompt += "\n\n" + instruction
+	}
+	prompt, canary := s.GuardPrompt(prompt)
+
+	messages := make([]model.ChatMessage, 0, len(history)+2)
+	messages = append(messages, model.ChatMessage{Role: "system", Content: prompt})
+	messages = append(messages, history...)
+	messages = append(messages, model.ChatMessage{Role: "user", Content: message})
+
+	resp, _, err := s.ChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		return AssistantReply{}, err
+	}
+	answer, reasoning, err := s.ProcessReply(ctx, resp.Message)
+	if err != nil {
+		return AssistantReply{}, err
+	}
+
+	result := textReply(answer)
+	if structured {
+		result = s.ParseStructured(ctx, session.ShopID, answer, lang)
+		answer = result.Message
+	}
+
+	answer, blocked := s.GuardOutput(ctx, session, answer, canary, lang)
+	escalated := strings.Contains(answer, EscalateMarker)
+	answer, err = s.ResolveReplyEscalation(ctx, session, answer, lang)
+	if err != nil {
+		return AssistantReply{}, err
+	}
+	answer = knowledgeService.AppendCitations(answer, sources)
+
+	if !escalated && !blocked && cacheable {
+		s.StoreCachedReply(ctx, session.ShopID, cached, message, answer)
+	}
+
+	// Blocks no longer match a replaced reply.
+	result.Message = answer
+	if escalated || blocked {
+		result.Blocks = []model.Block{}
+	}
+	return AssistantReply{
+		Reply:      result,
+		Reasoning:  reasoning,
+		AnsweredBy: AnsweredByModel,
+		Refused:    blocked,
+		Escalated:  escalated,
+	}, nil
+}
+
+// textReply wraps a plain answer as a structured reply without blocks.
+func textReply(text string) model.StructuredReply {
+	return model.StructuredReply{Message: text, Blocks: []model.Block{}}
+}
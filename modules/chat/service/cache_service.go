@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+	"shofy/utils/metrics"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Cache lookup outcomes reported to metrics.ChatCacheRequests.
+const (
+	cacheHit    = "hit"
+	cacheMiss   = "miss"
+	cacheBypass = "bypass"
+)
+
+// fillerWords carry no meaning for the answer and are left out of the
+// cache key.
+var fillerWords = map[string]bool{
+	"kak": true, "kakak": true, "min": true, "admin": true, "gan": true, "sis": true, "bang": true,
+	"dong": true, "ya": true, "yah": true, "nih": true, "sih": true, "deh": true, "kah": true,
+	"tolong": true, "mohon": true, "please": true, "pls": true, "permisi": true,
+	"halo": true, "hai": true, "hi": true, "hello": true, "mau": true, "tanya": true,
+}
+
+// keySynonyms folds common spellings into one token.
+var keySynonyms = map[string]string{
+	"brp": "berapa", "brapa": "berapa", "hrg": "harga", "price": "harga",
+	"stock": "stok", "ready": "stok", "tersedia": "stok", "available": "stok",
+}
+
+// personalWords mark questions about the customer rather than the catalog.
+var personalWords = map[string]bool{
+	"saya": true, "aku": true, "gue": true, "gw": true, "kami": true, "ku": true,
+	"my": true, "me": true, "mine": true, "i": true, "im": true,
+	"pesanan": true, "pesananku": true, "order": true, "orderan": true, "resi": true, "invoice": true,
+	"alamat": true, "address": true, "akun": true, "account": true, "refund": true,
+}
+
+// contextWords refer back to earlier messages, so the answer depends on the
+// conversation.
+var contextWords = map[string]bool{
+	"itu": true, "tersebut": true, "tadi": true, "sebelumnya": true, "ini": true,
+	"that": true, "it": true, "those": true, "previous": true,
+}
+
+// notContextual end in the possessive "-nya" without referring back.
+var notContextual = map[string]bool{"punya": true, "hanya": true, "tanya": true, "lainnya": true}
+
+// CachedReply is the result of LookupCachedReply, passed back to
+// StoreCachedReply after a miss.
+type CachedReply struct {
+	Answer     string
+	Hit        bool
+	key        string
+	productIDs []string
+}
+
+// Cacheable reports whether the reply may be stored after a miss.
+func (r *CachedReply) Cacheable() bool {
+	return r != nil && !r.Hit && r.key != ""
+}
+
+// LookupCachedReply returns a cached answer for a catalog question. Only
+// questions naming a product of the shop are cached; personal questions
+// and ones referring to earlier messages bypass the cache.
+func (s *ChatService) LookupCachedReply(ctx context.Context, shopID int32, message string) (*CachedReply, error) {
+	if s.Config.CacheTTL <= 0 {
+		return &CachedReply{}, nil
+	}
+
+	tokens := cacheTokens(message)
+	if len(tokens) < 2 || isPersonal(message, tokens) {
+		metrics.ChatCacheRequests.WithLabelValues(cacheBypass).Inc()
+		return &CachedReply{}, nil
+	}
+
+	products, err := s.Queries.GetProductsByShopID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	productIDs := mentionedProducts(message, products)
+	if len(productIDs) == 0 {
+		metrics.ChatCacheRequests.WithLabelValues(cacheBypass).Inc()
+		return &CachedReply{}, nil
+	}
+
+	reply := &CachedReply{key: strings.Join(tokens, " "), productIDs: productIDs}
+	answer, err := s.Queries.HitResponseCache(ctx, db.HitResponseCacheParams{
+		ShopID:      shopID,
+		QuestionKey: reply.key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			metrics.ChatCacheRequests.WithLabelValues(cacheMiss).Inc()
+			return reply, nil
+		}
+		return nil, err
+	}
+
+	metrics.ChatCacheRequests.WithLabelValues(cacheHit).Inc()
+	reply.Answer, reply.Hit = answer, true
+	return reply, nil
+}
+
+// StoreCachedReply saves answer for a question that missed the cache,
+// tagged with the products named in the question and the answer. Replies
+// that handed the session to staff must not be stored.
+func (s *ChatService) StoreCachedReply(ctx context.Context, shopID int32, lookup *CachedReply, question, answer string) {
+	if !lookup.Cacheable() {
+		return
+	}
+
+	productIDs := lookup.productIDs
+	if products, err := s.Queries.GetProductsByShopID(ctx, shopID); err == nil {
+		productIDs = mergeIDs(productIDs, mentionedProducts(answer, products))
+	}
+
+	err := s.Queries.UpsertResponseCache(ctx, db.UpsertResponseCacheParams{
+		ShopID:      shopID,
+		QuestionKey: lookup.key,
+		Question:    question,
+		Answer:      answer,
+		ProductIds:  productIDs,
+		ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(s.Config.CacheTTL), Valid: true},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to cache chat reply", "shop_id", shopID, "error", err)
+	}
+}
+
+// CacheStats reports the live entries of a shop and how often they were
+// reused.
+func (s *ChatService) CacheStats(ctx context.Context, shopID int32) (*model.CacheStats, error) {
+	row, err := s.Queries.GetResponseCacheStats(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CacheStats{Entries: row.Entries, Hits: row.Hits, TTLSeconds: int64(s.Config.CacheTTL.Seconds())}, nil
+}
+
+// PurgeCache drops every cached reply of a shop.
+func (s *ChatService) PurgeCache(ctx context.Context, shopID int32) (int64, error) {
+	return s.Queries.DeleteResponseCacheByShop(ctx, shopID)
+}
+
+// RunCachePurger deletes expired replies every interval until ctx is done.
+func RunCachePurger(ctx context.Context, queries *db.Queries, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := queries.DeleteExpiredResponseCache(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to purge chat response cache", "error", err)
+			}
+		}
+	}
+}
+
+// cacheTokens normalizes a question into sorted, de-duplicated tokens.
+func cacheTokens(message string) []string {
+	fields := words(message)
+	seen := make(map[string]bool, len(fields))
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if fillerWords[f] {
+			continue
+		}
+		if syn, ok := keySynonyms[f]; ok {
+			f = syn
+		}
+		if !seen[f] {
+			seen[f] = true
+			tokens = append(tokens, f)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// isPersonal reports questions whose answer depends on who asks or on the
+// conversation so far: personal words, references to earlier messages,
+// e-mail addresses and long numbers such as order or phone numbers.
+func isPersonal(message string, tokens []string) bool {
+	if strings.Contains(message, "@") {
+		return true
+	}
+	for _, t := range tokens {
+		if personalWords[t] || contextWords[t] || (strings.HasSuffix(t, "nya") && !notContextual[t]) {
+			return true
+		}
+		digits := 0
+		for _, r := range t {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits >= 5 {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionedProducts returns the IDs of products whose name appears in text.
+func mentionedProducts(text string, products []db.GetProductsByShopIDRow) []string {
+	normalized := " " + strings.Join(words(text), " ") + " "
+
+	var ids []string
+	for _, p := range products {
+		name := strings.Join(words(p.Name), " ")
+		if name != "" && strings.Contains(normalized, " "+name+" ") {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// words lowercases s and splits it on anything but letters and digits.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func mergeIDs(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	merged := make([]string, 0, len(a)+len(b))
+	for _, id := range append(append([]string{}, a...), b...) {
+		if !seen[id] {
+			seen[id] = true
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	db "shofy/db/sqlc"
+)
+
+func TestCacheTokens(t *testing.T) {
+	a := cacheTokens("Kak, brp harga Sepatu Merah?")
+	b := cacheTokens("harga sepatu merah berapa ya min")
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("cacheTokens differ: %v vs %v", a, b)
+	}
+}
+
+func TestIsPersonal(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"berapa harga sepatu merah", false},
+		{"apakah toko punya sepatu merah", false},
+		{"pesanan saya belum sampai", true},
+		{"harganya berapa", true},
+		{"cek resi 1234567890", true},
+		{"email saya a@b.com", true},
+	}
+	for _, tt := range tests {
+		if got := isPersonal(tt.message, cacheTokens(tt.message)); got != tt.want {
+			t.Errorf("isPersonal(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestMentionedProducts(t *testing.T) {
+	products := []db.GetProductsByShopIDRow{
+		{ID: "p1", Name: "Sepatu Merah"},
+		{ID: "p2", Name: "Sepatu"},
+		{ID: "p3", Name: "Tas Kulit"},
+	}
+	got := mentionedProducts("Stok sepatu-merah masih ada?", products)
+	if want := []string{"p1", "p2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mentionedProducts = %v, want %v", got, want)
+	}
+	if got := mentionedProducts("ada tas?", products); got != nil {
+		t.Errorf("mentionedProducts = %v, want none", got)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	db "shofy/db/sqlc"
+	guardrailModel "shofy/modules/guardrail/model"
+	guardrailService "shofy/modules/guardrail/service"
+)
+
+// GuardInput checks a customer message before it reaches the model. It
+// returns the message to send, or a refusal in lang to reply with instead.
+func (s *ChatService) GuardInput(ctx context.Context, session db.Session, message, lang string) (string, string) {
+	if s.Guardrail == nil {
+		return message, ""
+	}
+	result := s.Guardrail.CheckInput(ctx, session.ShopID, session.ID, message)
+	if result.Refusal != "" {
+		return result.Message, guardrailService.LocalizeRefusal(result.Refusal, lang)
+	}
+	return result.Message, ""
+}
+
+// GuardPrompt adds the guard instructions to a system prompt and returns
+// the canary to pass to GuardOutput.
+func (s *ChatService) GuardPrompt(prompt string) (string, string) {
+	if s.Guardrail == nil {
+		return prompt, ""
+	}
+	return s.Guardrail.Protect(prompt)
+}
+
+// GuardOutput checks a reply before it reaches the customer. It reports
+// whether the reply was replaced, with a refusal in lang, because it
+// leaked the prompt.
+func (s *ChatService) GuardOutput(ctx context.Context, session db.Session, reply, canary, lang string) (string, bool) {
+	if s.Guardrail == nil {
+		return reply, false
+	}
+	result := s.Guardrail.CheckOutput(ctx, session.ShopID, session.ID, reply, canary)
+	if result.Blocked {
+		return guardrailService.LocalizeRefusal(result.Reply, lang), true
+	}
+	return result.Reply, false
+}
+
+// ShowsStock reports whether replies may give a shop's stock counts.
+func (s *ChatService) ShowsStock(ctx context.Context, shopID int32) bool {
+	if s.Guardrail == nil {
+		return true
+	}
+	return s.Guardrail.AllowsField(ctx, shopID, guardrailModel.FieldStock)
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+	"shofy/utils/apperror"
+	"shofy/utils/markup"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrSessionTaken    = apperror.Conflict("chat_session_taken", "Sesi sedang ditangani staf lain")
+	ErrSessionNotTaken = apperror.Conflict("chat_session_not_taken", "Ambil alih sesi sebelum membalas")
+)
+
+// EscalateMarker is what the assistant is told to reply with when it cannot
+// help. It is stripped before the reply is stored.
+const EscalateMarker = "[ESCALATE]"
+
+// Messenger sends a text message to a phone number.
+type Messenger interface {
+	Enabled() bool
+	SendText(phoneNumber string, body string) error
+}
+
+// Mailer sends a plain-text email.
+type Mailer interface {
+	Enabled() bool
+	SendNotice(email string, subject string, body string) error
+}
+
+// humanRequestPhrases ask for a person; frustrationPhrases suggest the
+// assistant is not helping. Both are matched on whole words, and a
+// frustration phrase right after a negationWord does not count.
+var (
+	humanRequestPhrases = []string{
+		"bicara dengan admin", "bicara dengan manusia", "bicara dengan staf", "hubungi admin",
+		"minta admin", "mau admin", "hubungkan ke admin", "hubungkan dengan staf",
+		"bicara dengan customer service", "hubungi customer service", "minta customer service",
+		"minta operator", "hubungkan ke operator", "talk to a human", "talk to someone",
+		"real person", "human agent", "speak to an agent", "talk to an agent",
+		"talk to customer service", "speak to customer service", "talk to an operator",
+	}
+	frustrationPhrases = []string{
+		"tidak membantu", "gak membantu", "nggak membantu", "ga membantu", "kecewa",
+		"bodoh", "parah banget", "useless", "not helpful", "frustrated", "disappointed", "ridiculous",
+	}
+	negationWords = []string{"tidak", "tak", "gak", "nggak", "ga", "enggak", "bukan", "not", "never", "no"}
+)
+
+// DetectEscalation reports whether a customer message asks for a person or
+// shows frustration, and why.
+func DetectEscalation(message string) (string, bool) {
+	words := normalizeWords(message)
+	if containsPhrase(words, humanRequestPhrases) {
+		return "customer asked for staff", true
+	}
+	if containsUnnegatedPhrase(words, frustrationPhrases) || isShouting(message) {
+		return "customer seems frustrated", true
+	}
+	return "", false
+}
+
+// containsUnnegatedPhrase is containsPhrase ignoring matches that follow a
+// negation word, e.g. "tidak kecewa".
+func containsUnnegatedPhrase(words string, phrases []string) bool {
+	for _, phrase := range phrases {
+		needle := " " + phrase + " "
+		for rest := words; ; {
+			i := strings.Index(rest, needle)
+			if i < 0 {
+				break
+			}
+			before := strings.Fields(rest[:i+1])
+			if len(before) == 0 || !slices.Contains(negationWords, before[len(before)-1]) {
+				return true
+			}
+			rest = rest[i+1:]
+		}
+	}
+	return false
+}
+
+// isShouting treats a mostly upper-case message with enough letters as
+// shouting.
+func isShouting(message string) bool {
+	var letters, upper int
+	for _, r := range message {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= 12 && upper*10 >= letters*8
+}
+
+// RouteToStaff decides whether the assistant should answer a customer
+// message that was already saved. It returns handled when it should not:
+// staff own the session, or the message triggered an escalation, in which
+// case reply is the handoff message (already saved) for the customer.
+func (s *ChatService) RouteToStaff(ctx context.Context, session db.Session, message string) (reply string, handled bool, err error) {
+	if session.Mode != model.ModeAI {
+		return "", true, nil
+	}
+
+	reason, ok := DetectEscalation(message)
+	if !ok {
+		return "", false, nil
+	}
+	if err := s.Escalate(ctx, session, reason); err != nil {
+		return "", false, err
+	}
+	lang, _ := s.ReplyLanguage(ctx, session, message)
+	reply = textsFor(lang).handoff
+	if err := s.SaveAssistantMessage(ctx, session.ID, reply); err != nil {
+		return "", false, err
+	}
+	return reply, true, nil
+}
+
+// ResolveReplyEscalation escalates the session when the assistant answered
+// with EscalateMarker and returns the reply to store and show, with the
+// handoff message in lang.
+func (s *ChatService) ResolveReplyEscalation(ctx context.Context, session db.Session, reply, lang string) (string, error) {
+	if !strings.Contains(reply, EscalateMarker) {
+		return reply, nil
+	}
+	if err := s.Escalate(ctx, session, "assistant could not help"); err != nil {
+		return "", err
+	}
+
+	handoff := textsFor(lang).handoff
+	reply = strings.TrimSpace(strings.ReplaceAll(reply, EscalateMarker, ""))
+	if reply == "" {
+		return handoff, nil
+	}
+	return reply + "\n\n" + handoff, nil
+}
+
+// Escalate pauses the assistant for the session and notifies the shop's
+// staff in the background. Sessions already with staff are left alone.
+func (s *ChatService) Escalate(ctx context.Context, session db.Session, reason string) error {
+	escalated, err := s.Queries.EscalateSession(ctx, db.EscalateSessionParams{
+		ID:     session.ID,
+		Reason: pgtype.Text{String: reason, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	if escalated == 0 {
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Chat session escalated to staff", "session_id", session.ID, "shop_id", session.ShopID, "reason", reason)
+	go s.notifyStaff(context.WithoutCancel(ctx), session, reason)
+	return nil
+}
+
+// notifyStaff alerts every admin of the session's shop by email, or by
+// WhatsApp for admins without an email address.
+func (s *ChatService) notifyStaff(ctx context.Context, session db.Session, reason string) {
+	staff, err := s.Queries.ListShopStaffContacts(ctx, session.ShopID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load staff contacts", "shop_id", session.ShopID, "error", err)
+		return
+	}
+
+	subject := fmt.Sprintf("Chat #%d membutuhkan staf", session.ID)
+	body := fmt.Sprintf("Sesi chat #%d membutuhkan staf (%s). Buka dashboard untuk mengambil alih.", session.ID, reason)
+
+	var notified int
+	for _, member := range staff {
+		var err error
+		switch {
+		case member.Email.Valid && s.Email != nil && s.Email.Enabled():
+			err = s.Email.SendNotice(member.Email.String, subject, body)
+		case member.Phone.Valid && s.WhatsApp != nil && s.WhatsApp.Enabled():
+			err = s.WhatsApp.SendText(phoneNumber(member.CodeArea, member.Phone), body)
+		default:
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to notify staff", "user_id", member.ID, "error", err)
+			continue
+		}
+		notified++
+	}
+	if notified == 0 {
+		slog.WarnContext(ctx, "No staff could be notified of escalation", "session_id", session.ID, "shop_id", session.ShopID)
+	}
+}
+
+// phoneNumber joins a calling code and a local number into the digits-only
+// international form WhatsApp expects.
+func phoneNumber(codeArea, phone pgtype.Text) string {
+	local := strings.TrimPrefix(phone.String, "+")
+	if strings.HasPrefix(phone.String, "+") {
+		return local
+	}
+	local = strings.TrimPrefix(local, "0")
+	code := strings.TrimPrefix(codeArea.String, "+")
+	if code != "" && !strings.HasPrefix(local, code) {
+		return code + local
+	}
+	return local
+}
+
+// RunHandoffExpirer hands sessions that waited longer than timeout for
+// staff back to the assistant, checking every minute until ctx is done.
+func RunHandoffExpirer(ctx context.Context, queries *db.Queries, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(timeout, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resumed, err := queries.ResumeUnclaimedSessions(ctx, pgtype.Timestamptz{Time: time.Now().Add(-timeout), Valid: true})
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to resume unclaimed chat sessions", "error", err)
+				continue
+			}
+			if resumed > 0 {
+				slog.InfoContext(ctx, "Unclaimed chat sessions handed back to the assistant", "sessions", resumed)
+			}
+		}
+	}
+}
+
+// GetShopSession returns a session of the staff member's shop.
+func (s *ChatService) GetShopSession(ctx context.Context, shopID, sessionID int32) (db.Session, error) {
+	session, err := s.Queries.GetShopSession(ctx, db.GetShopSessionParams{ID: sessionID, ShopID: shopID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return db.Session{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return session, nil
+}
+
+// ListShopSessions returns a page of a shop's open sessions for staff,
+// optionally only those in one mode.
+func (s *ChatService) ListShopSessions(ctx context.Context, shopID int32, mode string, limit, offset int32) ([]model.StaffSessionSummary, int64, error) {
+	modeFilter := pgtype.Text{String: mode, Valid: mode != ""}
+
+	rows, err := s.Queries.ListShopSessions(ctx, db.ListShopSessionsParams{
+		ShopID:    shopID,
+		Mode:      modeFilter,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	total, err := s.Queries.CountShopSessions(ctx, db.CountShopSessionsParams{ShopID: shopID, Mode: modeFilter})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	sessions := make([]model.StaffSessionSummary, 0, len(rows))
+	for _, row := range rows {
+		summary := model.StaffSessionSummary{
+			SessionSummary: model.SessionSummary{
+				ID:            row.ID,
+				ChannelID:     row.ChannelID,
+				ShopID:        row.ShopID,
+				Status:        row.Status,
+				Mode:          row.Mode,
+				CreatedAt:     row.CreatedAt.Time,
+				LastMessage:   row.LastMessage,
+				LastRole:      row.LastRole,
+				LastMessageAt: timePtr(row.LastMessageAt),
+			},
+			UserID:           int4Ptr(row.UserID),
+			HandledBy:        int4Ptr(row.HandledBy),
+			EscalatedAt:      timePtr(row.EscalatedAt),
+			EscalationReason: row.EscalationReason,
+		}
+		if row.GuestID.Valid {
+			summary.GuestID = row.GuestID.String()
+		}
+		sessions = append(sessions, summary)
+	}
+	return sessions, total, nil
+}
+
+// ListShopSessionMessages returns a page of messages of a shop's session.
+func (s *ChatService) ListShopSessionMessages(ctx context.Context, shopID, sessionID, limit, offset int32) ([]model.SessionMessage, int64, error) {
+	if _, err := s.GetShopSession(ctx, shopID, sessionID); err != nil {
+		return nil, 0, err
+	}
+	return s.listMessages(ctx, sessionID, limit, offset)
+}
+
+// TakeOver assigns the session to staffID and pauses the assistant.
+func (s *ChatService) TakeOver(ctx context.Context, shopID, staffID, sessionID int32) (db.Session, error) {
+	session, err := s.GetShopSession(ctx, shopID, sessionID)
+	if err != nil {
+		return db.Session{}, err
+	}
+	if session.Status != model.SessionOpen {
+		return db.Session{}, ErrSessionClosed
+	}
+
+	session, err = s.Queries.TakeOverSession(ctx, db.TakeOverSessionParams{
+		ID:      sessionID,
+		StaffID: pgtype.Int4{Int32: staffID, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Session{}, ErrSessionTaken
+	}
+	if err != nil {
+		return db.Session{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return session, nil
+}
+
+// Release hands the session back to the assistant.
+func (s *ChatService) Release(ctx context.Context, shopID, sessionID int32) (db.Session, error) {
+	if _, err := s.GetShopSession(ctx, shopID, sessionID); err != nil {
+		return db.Session{}, err
+	}
+
+	session, err := s.Queries.ReleaseSession(ctx, sessionID)
+	if err != nil {
+		return db.Session{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return session, nil
+}
+
+// StaffReply stores a reply from the staff member handling the session as
+// an 'admin' message and delivers it on the session's channel. Web chat
+// clients pick it up from the message list; WhatsApp users are messaged.
+func (s *ChatService) StaffReply(ctx context.Context, shopID, staffID, sessionID int32, message string) (model.StaffReplyResponse, error) {
+	session, err := s.GetShopSession(ctx, shopID, sessionID)
+	if err != nil {
+		return model.StaffReplyResponse{}, err
+	}
+	if session.Status != model.SessionOpen {
+		return model.StaffReplyResponse{}, ErrSessionClosed
+	}
+	if session.Mode != model.ModeHuman || session.HandledBy.Int32 != staffID {
+		return model.StaffReplyResponse{}, ErrSessionNotTaken
+	}
+
+	conversation, err := s.Queries.CreateConversation(ctx, db.CreateConversationParams{
+		SessionID: session.ID,
+		Message:   message,
+		Role:      model.RoleAdmin,
+	})
+	if err != nil {
+		return model.StaffReplyResponse{}, fmt.Errorf("Error Database: %w", err)
+	}
+
+	return model.StaffReplyResponse{
+		Message: model.SessionMessage{
+			ID:        conversation.ID,
+			Role:      conversation.Role,
+			Message:   conversation.Message,
+			CreatedAt: conversation.CreatedAt.Time,
+		},
+		Delivered: s.deliver(ctx, session, message),
+	}, nil
+}
+
+// deliver pushes a message to channels that have an outbound API. It
+// reports whether the message was sent; on other channels the customer
+// sees it the next time the client loads messages.
+func (s *ChatService) deliver(ctx context.Context, session db.Session, message string) bool {
+	if s.channelName(ctx, session.ChannelID) != "whatsapp" || !session.UserID.Valid || s.WhatsApp == nil || !s.WhatsApp.Enabled() {
+		return false
+	}
+
+	user, err := s.Queries.GetUser(ctx, session.UserID.Int32)
+	if err != nil || !user.Phone.Valid {
+		slog.WarnContext(ctx, "No phone number to deliver staff reply", "session_id", session.ID)
+		return false
+	}
+	if err := s.WhatsApp.SendText(phoneNumber(user.CodeArea, user.Phone), markup.Render(message, markup.WhatsApp)); err != nil {
+		slog.ErrorContext(ctx, "Failed to deliver staff reply", "session_id", session.ID, "error", err)
+		return false
+	}
+	return true
+}
+
+func int4Ptr(v pgtype.Int4) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDetectEscalation(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"Berapa harga sepatu merah?", false},
+		{"Saya mau bicara dengan admin", true},
+		{"Jawabanmu tidak membantu sama sekali", true},
+		{"KENAPA PESANAN SAYA BELUM SAMPAI", true},
+		{"Kapan dikirim!!!", false},
+		{"Harga?!!!", false},
+		{"operatornya ramah", false},
+		{"Apakah customer service buka hari Minggu?", false},
+		{"Saya tidak kecewa kok", false},
+		{"Saya kecewa dengan jawabannya", true},
+		{"Tolong hubungi customer service", true},
+		{"I want to talk to a human", true},
+		{"OK", false},
+	}
+	for _, tt := range tests {
+		if _, got := DetectEscalation(tt.message); got != tt.want {
+			t.Errorf("DetectEscalation(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneNumber(t *testing.T) {
+	text := func(s string) pgtype.Text { return pgtype.Text{String: s, Valid: s != ""} }
+	tests := []struct {
+		code, phone, want string
+	}{
+		{"62", "081234567890", "6281234567890"},
+		{"+62", "81234567890", "6281234567890"},
+		{"62", "6281234567890", "6281234567890"},
+		{"62", "+6281234567890", "6281234567890"},
+	}
+	for _, tt := range tests {
+		if got := phoneNumber(text(tt.code), text(tt.phone)); got != tt.want {
+			t.Errorf("phoneNumber(%q, %q) = %q, want %q", tt.code, tt.phone, got, tt.want)
+		}
+	}
+}
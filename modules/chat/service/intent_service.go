@@ -0,0 +1,528 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+	"shofy/utils"
+	"shofy/utils/metrics"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// intentRules are matched in order on the words of a message; the first
+// intent with a matching phrase wins. They cover Indonesian and English,
+// messages in other languages go to the classifier. Problem words such as
+// "rusak" or "refund" alone are not a complaint, see isComplaint.
+var intentRules = []struct {
+	intent  string
+	phrases []string
+}{
+	{model.IntentComplaint, []string{
+		"komplain", "keluhan", "salah kirim", "barang salah", "complaint", "complain", "wrong item",
+	}},
+	{model.IntentOrderStatus, []string{
+		"status pesanan", "pesanan saya", "orderan saya", "order saya", "lacak", "resi", "sudah dikirim",
+		"kapan sampai", "kapan dikirim", "belum sampai", "my order", "order status", "track", "tracking",
+		"where is my",
+	}},
+	{model.IntentPlaceOrder, []string{
+		"mau beli", "ingin beli", "ingin membeli", "mau pesan", "ingin pesan", "pesan sekarang", "cara pesan",
+		"mau order", "ingin order", "cara order", "checkout", "beli sekarang", "want to buy", "like to buy", "like to order",
+		"place an order", "how to order", "buy now",
+	}},
+	{model.IntentProductInquiry, slices.Concat(priceStockPhrases, productDetailPhrases)},
+}
+
+// problemPhrases describe something wrong with a purchase. They only make
+// a complaint together with ownPurchasePhrases or an order number; on
+// their own they are questions, e.g. about the refund policy.
+var problemPhrases = []string{
+	"rusak", "cacat", "refund", "pengembalian dana", "retur", "tidak sesuai", "broken", "damaged",
+	"defective", "money back", "not as described",
+}
+
+// ownPurchasePhrases point at something the customer bought.
+var ownPurchasePhrases = []string{
+	"barang saya", "pesanan saya", "paket saya", "produk saya", "orderan saya", "order saya",
+	"yang saya terima", "saya terima", "saya beli", "barangku", "pesananku", "paketku",
+	"my order", "my item", "my package", "my parcel", "i received", "i got", "i bought",
+	"we received",
+}
+
+// priceStockPhrases ask for the price or stock of a product, the questions
+// the router answers from the catalog.
+var priceStockPhrases = []string{
+	"harga", "harganya", "berapa", "stok", "stoknya", "ready", "tersedia",
+	"price", "how much", "in stock", "stock", "available",
+}
+
+// productDetailPhrases ask about a product's variants. The catalog has no
+// sizes or colours, so the chat model answers them.
+var productDetailPhrases = []string{
+	"ukuran", "warna", "size", "color", "colour",
+}
+
+// openEndedPhrases turn a product question into one for the chat model.
+var openEndedPhrases = []string{
+	"rekomendasi", "sarankan", "bandingkan", "beda", "perbedaan", "lebih bagus", "cocok", "kenapa",
+	"recommend", "suggest", "compare", "difference", "better", "suitable", "why",
+}
+
+// Smalltalk is only recognised when every word of a short message is one
+// of these or a filler word.
+var (
+	greetingWords = []string{
+		"halo", "hallo", "hai", "hi", "hello", "hey", "pagi", "siang", "sore", "malam", "selamat",
+		"good", "morning", "afternoon", "evening",
+	}
+	thanksWords = []string{"terima", "kasih", "makasih", "thanks", "thank", "you", "thx"}
+	ackWords    = []string{"ok", "oke", "okay", "sip", "siap", "baik"}
+)
+
+// orderReference finds an order number named as one: "pesanan #123",
+// "order no 123", "nomor pesanan 123", "cek order 123" or "lacak 123". A
+// bare "#1" or "order 3" is not enough, it may be a product or a quantity.
+var orderReference = regexp.MustCompile(`(?i)` +
+	`\b(?:pesanan|order|orderan|invoice)\s*(?:(?:no\.?|nomor|number|id)\s*:?\s*#?|:?\s*#)\s*(\d+)` +
+	`|\b(?:no\.?|nomor|number)\s+(?:pesanan|order|orderan|invoice)\s*:?\s*#?\s*(\d+)` +
+	`|\b(?:(?:cek|status|lacak|track)\s+(?:pesanan|order|orderan|invoice)|lacak|track)\s*(?:(?:no\.?|nomor|number|id)\s*)?:?\s*#?\s*(\d+)`)
+
+var intentSchema = func() json.RawMessage {
+	schema, _ := json.Marshal(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"intent": map[string]any{"type": "string", "enum": model.Intents},
+		},
+		"required": []string{"intent"},
+	})
+	return schema
+}()
+
+const classifierPrompt = `Klasifikasikan pesan pelanggan toko online. Pesan bisa dalam bahasa apa pun. Pilih satu intent:
+- product_inquiry: harga, stok, ukuran atau detail produk
+- order_status: status, pengiriman atau isi pesanan yang sudah dibuat
+- place_order: ingin membeli atau membuat pesanan baru
+- complaint: keluhan, barang rusak, retur atau refund
+- smalltalk: salam, terima kasih atau basa-basi
+- handoff: minta bicara dengan staf atau manusia
+- general: pertanyaan lain
+Balas hanya dengan JSON {"intent": "..."}.`
+
+// routeText holds the replies the router writes itself, per language.
+type routeText struct {
+	greeting, thanks, ack string
+	// orderLogin asks guests to sign in; orderNotFound takes the order
+	// number.
+	orderLogin, orderNotFound, noOrders string
+	order, status, total, date, items   string
+	statuses                            map[string]string
+	// inStock takes the product, price and stock; available and soldOut
+	// the product and price. available is used when the shop hides
+	// stock counts.
+	inStock, available, soldOut string
+	// handoff tells the customer a person will take over;
+	// structuredFallback is sent when a structured answer holds no text
+	// that can be shown.
+	handoff, structuredFallback string
+}
+
+var routeTexts = map[string]routeText{
+	"id": {
+		greeting:           "Halo! Ada yang bisa saya bantu? Tanyakan saja produk, harga, stok atau pesanan Anda.",
+		thanks:             "Sama-sama! Kabari saya jika ada yang bisa dibantu lagi.",
+		ack:                "Baik. Kabari saya jika ada yang bisa dibantu lagi.",
+		orderLogin:         "Untuk mengecek pesanan, silakan masuk ke akun Anda terlebih dahulu.",
+		orderNotFound:      "Pesanan #%d tidak ditemukan di akun Anda. Mohon periksa kembali nomor pesanannya.",
+		noOrders:           "Anda belum memiliki pesanan di toko ini.",
+		order:              "Pesanan",
+		status:             "Status",
+		total:              "Total",
+		date:               "Tanggal",
+		items:              "Barang",
+		statuses:           map[string]string{"pending": "Menunggu pembayaran", "paid": "Sudah dibayar, sedang diproses", "shipped": "Sudah dikirim", "cancelled": "Dibatalkan"},
+		inStock:            "%s tersedia dengan harga %s, stok %d.",
+		available:          "%s tersedia dengan harga %s.",
+		soldOut:            "%s seharga %s sedang habis.",
+		handoff:            "Baik, saya hubungkan Anda dengan staf kami. Mohon tunggu sebentar, staf kami akan segera membalas.",
+		structuredFallback: "Maaf, saya belum bisa menampilkan jawaban. Silakan ulangi pertanyaan Anda.",
+	},
+	"en": {
+		greeting:           "Hello! How can I help? Ask me about products, prices, stock or your orders.",
+		thanks:             "You're welcome! Let me know if there is anything else I can help with.",
+		ack:                "Alright. Let me know if there is anything else I can help with.",
+		orderLogin:         "To check an order, please sign in to your account first.",
+		orderNotFound:      "Order #%d was not found in your account. Please check the order number.",
+		noOrders:           "You have no orders at this shop yet.",
+		order:              "Order",
+		status:             "Status",
+		total:              "Total",
+		date:               "Date",
+		items:              "Items",
+		statuses:           map[string]string{"pending": "Awaiting payment", "paid": "Paid, being processed", "shipped": "Shipped", "cancelled": "Cancelled"},
+		inStock:            "%s is available for %s, %d in stock.",
+		available:          "%s is available for %s.",
+		soldOut:            "%s (%s) is currently out of stock.",
+		handoff:            "Alright, I'm connecting you with our staff. Please wait a moment, they will reply shortly.",
+		structuredFallback: "Sorry, I can't show an answer yet. Please ask your question again.",
+	},
+}
+
+// routeTextFor returns the replies in lang. Without a language the shop
+// is served in Indonesian.
+func routeTextFor(lang string) (routeText, bool) {
+	if lang == "" {
+		lang = "id"
+	}
+	t, ok := routeTexts[lang]
+	return t, ok
+}
+
+// textsFor returns the replies in lang, or in English for languages
+// without templates.
+func textsFor(lang string) routeText {
+	if t, ok := routeTextFor(lang); ok {
+		return t
+	}
+	return routeTexts["en"]
+}
+
+// ClassifyIntent finds the intent of a message with rules. ok is false when
+// no rule matches.
+func ClassifyIntent(message string) (intent string, ok bool) {
+	if _, ok := DetectEscalation(message); ok {
+		return model.IntentHandoff, true
+	}
+	words := normalizeWords(message)
+	if words == "" {
+		return "", false
+	}
+	if isComplaint(message, words) {
+		return model.IntentComplaint, true
+	}
+	for _, rule := range intentRules {
+		if rule.intent == model.IntentOrderStatus && orderNumber(message) != 0 {
+			return rule.intent, true
+		}
+		if containsPhrase(words, rule.phrases) {
+			return rule.intent, true
+		}
+	}
+	if isSmalltalk(words) {
+		return model.IntentSmalltalk, true
+	}
+	return "", false
+}
+
+// RouteIntent classifies a message, with rules first and then the
+// classifier model, and answers it from the database when the intent
+// allows. Complaints and handoffs escalate the session. A route without
+// Reply is for the chat model.
+func (s *ChatService) RouteIntent(ctx context.Context, session db.Session, message, lang string) (model.IntentRoute, error) {
+	route := s.classify(ctx, message)
+	t, templated := routeTextFor(lang)
+
+	switch route.Intent {
+	case model.IntentHandoff, model.IntentComplaint:
+		reason := "customer asked for staff"
+		if route.Intent == model.IntentComplaint {
+			reason = "customer complaint"
+		}
+		if err := s.Escalate(ctx, session, reason); err != nil {
+			return route, err
+		}
+		route.Reply, route.Escalated = textsFor(lang).handoff, true
+
+	case model.IntentOrderStatus:
+		// Languages without templates get the order data in the prompt.
+		text, err := s.orderStatus(ctx, session, message, t, templated)
+		if err != nil {
+			return route, err
+		}
+		if templated {
+			route.Reply = text
+		} else {
+			route.Context = "Jawab pertanyaan pelanggan tentang pesanannya dengan data berikut:\n" + text
+		}
+
+	case model.IntentProductInquiry:
+		if templated {
+			reply, err := s.productAnswer(ctx, session.ShopID, message, t)
+			if err != nil {
+				return route, err
+			}
+			route.Reply = reply
+		}
+
+	case model.IntentSmalltalk:
+		if templated {
+			route.Reply = smalltalkReply(normalizeWords(message), t)
+		}
+	}
+
+	answeredBy := "model"
+	switch {
+	case route.Escalated:
+		answeredBy = "staff"
+	case route.Reply != "":
+		answeredBy = "router"
+	}
+	metrics.ChatIntents.WithLabelValues(route.Intent, route.Source, answeredBy).Inc()
+	return route, nil
+}
+
+// classify runs the rules, then the classifier model when one is set.
+// Messages neither recognises are IntentGeneral.
+func (s *ChatService) classify(ctx context.Context, message string) model.IntentRoute {
+	if intent, ok := ClassifyIntent(message); ok {
+		return model.IntentRoute{Intent: intent, Source: model.IntentFromRule}
+	}
+	if s.Classifier == nil {
+		return model.IntentRoute{Intent: model.IntentGeneral, Source: model.IntentFromDefault}
+	}
+
+	resp, _, err := s.Classifier.ChatCompletion(ctx, []model.ChatMessage{
+		{Role: "system", Content: classifierPrompt},
+		{Role: "user", Content: message},
+	}, model.WithJSONSchema("chat_intent", intentSchema))
+	if err != nil {
+		slog.WarnContext(ctx, "Intent classification failed", "error", err)
+		return model.IntentRoute{Intent: model.IntentGeneral, Source: model.IntentFromDefault}
+	}
+	answer, _ := utils.SplitReasoning(resp.Message)
+	intent, ok := parseIntent(answer)
+	if !ok {
+		slog.WarnContext(ctx, "Classifier returned an unknown intent", "answer", answer)
+		return model.IntentRoute{Intent: model.IntentGeneral, Source: model.IntentFromDefault}
+	}
+	return model.IntentRoute{Intent: intent, Source: model.IntentFromClassifier}
+}
+
+// parseIntent reads {"intent": "..."} from the classifier answer.
+func parseIntent(answer string) (string, bool) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return "", false
+	}
+	var out struct {
+		Intent string `json:"intent"`
+	}
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &out); err != nil {
+		return "", false
+	}
+	intent := strings.ToLower(strings.TrimSpace(out.Intent))
+	return intent, slices.Contains(model.Intents, intent)
+}
+
+// orderStatus describes the order the message names, or the customer's
+// latest orders.
+func (s *ChatService) orderStatus(ctx context.Context, session db.Session, message string, t routeText, templated bool) (string, error) {
+	if !templated {
+		t = routeTexts["en"]
+	}
+	if !session.UserID.Valid {
+		return t.orderLogin, nil
+	}
+
+	var orders []db.Order
+	if id := orderNumber(message); id != 0 {
+		order, err := s.Queries.GetCustomerOrder(ctx, db.GetCustomerOrderParams{
+			ID:     id,
+			ShopID: session.ShopID,
+			UserID: session.UserID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Sprintf(t.orderNotFound, id), nil
+			}
+			return "", fmt.Errorf("Error Database: %w", err)
+		}
+		orders = append(orders, order)
+	} else {
+		var err error
+		orders, err = s.Queries.ListCustomerOrders(ctx, db.ListCustomerOrdersParams{
+			ShopID: session.ShopID,
+			UserID: session.UserID,
+			Limit:  3,
+		})
+		if err != nil {
+			return "", fmt.Errorf("Error Database: %w", err)
+		}
+		if len(orders) == 0 {
+			return t.noOrders, nil
+		}
+	}
+
+	parts := make([]string, 0, len(orders))
+	for _, o := range orders {
+		parts = append(parts, s.describeOrder(ctx, o, t))
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+func (s *ChatService) describeOrder(ctx context.Context, o db.Order, t routeText) string {
+	status := o.Status.String
+	if label, ok := t.statuses[status]; ok {
+		status = label
+	}
+	lines := []string{
+		fmt.Sprintf("%s #%d", t.order, o.ID),
+		fmt.Sprintf("- %s: %s", t.status, status),
+		fmt.Sprintf("- %s: %s", t.total, utils.FormatRupiah(o.Total)),
+	}
+	if o.CreatedAt.Valid {
+		lines = append(lines, fmt.Sprintf("- %s: %s", t.date, o.CreatedAt.Time.Format("02/01/2006")))
+	}
+
+	items, err := s.Queries.GetOrderItemsByID(ctx, o.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load order items", "order_id", o.ID, "error", err)
+	}
+	if len(items) > 0 {
+		names := make([]string, 0, len(items))
+		for _, item := range items {
+			names = append(names, fmt.Sprintf("%s x%d", item.Name, item.Quantity))
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", t.items, strings.Join(names, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// productAnswer answers a plain price or stock question about one product
+// of the shop, without the count when the shop hides stock. It returns ""
+// for anything else, which the chat model then answers.
+func (s *ChatService) productAnswer(ctx context.Context, shopID int32, message string, t routeText) (string, error) {
+	if !isPriceStockQuestion(normalizeWords(message)) {
+		return "", nil
+	}
+	products, err := s.Queries.GetProductsByShopID(ctx, shopID)
+	if err != nil {
+		return "", fmt.Errorf("Error Database: %w", err)
+	}
+	p, ok := matchProduct(message, products)
+	if !ok {
+		return "", nil
+	}
+	if p.Stock.Int32 <= 0 {
+		return fmt.Sprintf(t.soldOut, p.Name, utils.FormatRupiah(p.Price)), nil
+	}
+	if !s.ShowsStock(ctx, shopID) {
+		return fmt.Sprintf(t.available, p.Name, utils.FormatRupiah(p.Price)), nil
+	}
+	return fmt.Sprintf(t.inStock, p.Name, utils.FormatRupiah(p.Price), p.Stock.Int32), nil
+}
+
+// isComplaint reports whether a message describes a problem with the
+// customer's own purchase.
+func isComplaint(message, words string) bool {
+	if !containsPhrase(words, problemPhrases) {
+		return false
+	}
+	return orderNumber(message) != 0 || containsPhrase(words, ownPurchasePhrases)
+}
+
+// isPriceStockQuestion reports whether a message only asks for a price or
+// stock, which productAnswer can answer from the catalog.
+func isPriceStockQuestion(words string) bool {
+	return containsPhrase(words, priceStockPhrases) &&
+		!containsPhrase(words, productDetailPhrases) &&
+		!containsPhrase(words, openEndedPhrases)
+}
+
+// matchProduct finds the product a message names. The longest name wins
+// when names overlap, e.g. "Sepatu Lari Pro" over "Sepatu Lari"; two
+// unrelated names make the question the model's.
+func matchProduct(message string, products []db.GetProductsByShopIDRow) (db.GetProductsByShopIDRow, bool) {
+	words := normalizeWords(message)
+	var matches []db.GetProductsByShopIDRow
+	for _, p := range products {
+		name := normalizeWords(p.Name)
+		if name != "" && strings.Contains(words, name) {
+			matches = append(matches, p)
+		}
+	}
+	if len(matches) == 0 {
+		return db.GetProductsByShopIDRow{}, false
+	}
+	best := slices.MaxFunc(matches, func(a, b db.GetProductsByShopIDRow) int {
+		return len(a.Name) - len(b.Name)
+	})
+	longest := normalizeWords(best.Name)
+	for _, p := range matches {
+		if !strings.Contains(longest, normalizeWords(p.Name)) {
+			return db.GetProductsByShopIDRow{}, false
+		}
+	}
+	return best, true
+}
+
+func orderNumber(message string) int32 {
+	m := orderReference.FindStringSubmatch(message)
+	for _, group := range m[min(1, len(m)):] {
+		if group == "" {
+			continue
+		}
+		if n, err := strconv.ParseInt(group, 10, 32); err == nil {
+			return int32(n)
+		}
+	}
+	return 0
+}
+
+// isSmalltalk reports whether a message of a few words is only greetings
+// or thanks.
+func isSmalltalk(words string) bool {
+	fields := strings.Fields(words)
+	if len(fields) == 0 || len(fields) > 5 {
+		return false
+	}
+	for _, w := range fields {
+		known := slices.Contains(greetingWords, w) || slices.Contains(thanksWords, w) ||
+			slices.Contains(ackWords, w) || fillerWords[w]
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+func smalltalkReply(words string, t routeText) string {
+	switch {
+	case containsPhrase(words, []string{"kasih", "makasih", "thanks", "thank", "thx"}):
+		return t.thanks
+	case containsPhrase(words, greetingWords):
+		return t.greeting
+	}
+	return t.ack
+}
+
+// normalizeWords lower-cases text and keeps its letters and digits as
+// words padded with spaces, so that phrases match whole words only.
+func normalizeWords(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(fields) == 0 {
+		return ""
+	}
+	return " " + strings.Join(fields, " ") + " "
+}
+
+func containsPhrase(words string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(words, " "+phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"testing"
+
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+)
+
+func TestClassifyIntent(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Berapa harga Sepatu Lari?", model.IntentProductInquiry},
+		{"is the red shirt in stock", model.IntentProductInquiry},
+		{"Where is my order #123?", model.IntentOrderStatus},
+		{"status pesanan no 45 gimana", model.IntentOrderStatus},
+		{"cek order 77", model.IntentOrderStatus},
+		{"mau beli 2 kaos", model.IntentPlaceOrder},
+		{"saya mau order 3!", model.IntentPlaceOrder},
+		{"mau pesan kaos, order 2.", model.IntentPlaceOrder},
+		{"stok kaos #1 ada?", model.IntentProductInquiry},
+		{"Barang saya datang rusak", model.IntentComplaint},
+		{"pesanan #12 rusak, minta refund", model.IntentComplaint},
+		{"My package arrived damaged", model.IntentComplaint},
+		{"mau komplain soal pengiriman", model.IntentComplaint},
+		{"apakah sepatu ini gampang rusak?", ""},
+		{"bagaimana kebijakan refund?", ""},
+		{"warna apa saja Sepatu Lari?", model.IntentProductInquiry},
+		{"Saya mau bicara dengan admin", model.IntentHandoff},
+		{"Halo kak", model.IntentSmalltalk},
+		{"makasih ya min", model.IntentSmalltalk},
+		{"ok", model.IntentSmalltalk},
+		{"Bahan kaosnya adem nggak dipakai seharian?", ""},
+		{"¿Tienen envío a Madrid?", ""},
+	}
+	for _, tt := range tests {
+		got, ok := ClassifyIntent(tt.message)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("ClassifyIntent(%q) = %q, %v, want %q", tt.message, got, ok, tt.want)
+		}
+	}
+}
+
+func TestIsPriceStockQuestion(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"Berapa harga Sepatu Lari?", true},
+		{"stok kaos polos masih ada?", true},
+		{"warna apa saja Sepatu Lari?", false},
+		{"ukuran 42 sepatu lari tersedia?", false},
+		{"rekomendasi sepatu harga 500 ribuan", false},
+	}
+	for _, tt := range tests {
+		if got := isPriceStockQuestion(normalizeWords(tt.message)); got != tt.want {
+			t.Errorf("isPriceStockQuestion(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestOrderNumber(t *testing.T) {
+	tests := []struct {
+		message string
+		want    int32
+	}{
+		{"where is my order #123?", 123},
+		{"pesanan nomor: 45 belum sampai", 45},
+		{"cek order 77", 77},
+		{"mau order 3 kaos", 0},
+		{"saya mau order 3!", 0},
+		{"mau pesan kaos, order 2.", 0},
+		{"stok kaos #1 ada?", 0},
+		{"pesanan #12 rusak", 12},
+		{"nomor pesanan 45", 45},
+		{"status pesanan no 45 gimana", 45},
+		{"lacak #9 dong", 9},
+		{"track order 88", 88},
+		{"pesanan saya mana", 0},
+	}
+	for _, tt := range tests {
+		if got := orderNumber(tt.message); got != tt.want {
+			t.Errorf("orderNumber(%q) = %d, want %d", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestMatchProduct(t *testing.T) {
+	products := []db.GetProductsByShopIDRow{
+		{ID: "p1", Name: "Sepatu Lari"},
+		{ID: "p2", Name: "Sepatu Lari Pro"},
+		{ID: "p3", Name: "Kaos Polos"},
+	}
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"harga sepatu lari berapa?", "p1"},
+		{"Stok Sepatu Lari Pro masih ada?", "p2"},
+		{"harga kaos polos dan sepatu lari", ""},
+		{"harga topi berapa", ""},
+	}
+	for _, tt := range tests {
+		p, ok := matchProduct(tt.message, products)
+		if got := p.ID; got != tt.want || ok != (tt.want != "") {
+			t.Errorf("matchProduct(%q) = %q, %v, want %q", tt.message, got, ok, tt.want)
+		}
+	}
+}
+
+func TestParseIntent(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+		ok     bool
+	}{
+		{`{"intent": "order_status"}`, model.IntentOrderStatus, true},
+		{"```json\n{\"intent\": \"Complaint\"}\n```", model.IntentComplaint, true},
+		{`{"intent": "weather"}`, "weather", false},
+		{"order_status", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseIntent(tt.answer)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseIntent(%q) = %q, %v, want %q, %v", tt.answer, got, ok, tt.want, tt.ok)
+		}
+	}
+}
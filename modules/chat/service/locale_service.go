@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	db "shofy/db/sqlc"
+	"shofy/utils/language"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReplyLanguage resolves the language to answer message in and remembers
+// it on the session, so that short follow-ups such as "ok" keep it. It
+// returns the reply language and the shop's default language, or empty
+// strings when no Language service is set.
+func (s *ChatService) ReplyLanguage(ctx context.Context, session db.Session, message string) (string, string) {
+	if s.Language == nil {
+		return "", ""
+	}
+	lang, def := s.Language.Resolve(ctx, session.ShopID, session.Language.String, message)
+	if lang != session.Language.String {
+		err := s.Queries.UpdateSessionLanguage(ctx, db.UpdateSessionLanguageParams{
+			ID:       session.ID,
+			Language: pgtype.Text{String: lang, Valid: true},
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to save session language", "session_id", session.ID, "language", lang, "error", err)
+		}
+	}
+	return lang, def
+}
+
+// LanguageInstruction tells the model which language to reply in, or is
+// empty when lang is.
+func (s *ChatService) LanguageInstruction(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	name := language.Name(lang)
+	return fmt.Sprintf("Pelanggan menulis dalam %s. Selalu balas dalam %s, walaupun instruksi dan data toko memakai bahasa lain. Jangan terjemahkan merek, harga dan angka stok.", name, name)
+}
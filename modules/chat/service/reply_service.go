@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+	"shofy/utils"
+	"shofy/utils/apperror"
+	"shofy/utils/markup"
+)
+
+// ErrEmptyAnswer is returned when a model response holds nothing but
+// reasoning.
+var ErrEmptyAnswer = apperror.Upstream("llm_empty_answer", "AI tidak memberikan jawaban, silakan coba lagi")
+
+// ProcessReply splits a model response into the answer for the customer
+// and the reasoning that came before it.
+func (s *ChatService) ProcessReply(ctx context.Context, response string) (answer, reasoning string, err error) {
+	answer, reasoning = utils.SplitReasoning(response)
+	if answer == "" {
+		slog.WarnContext(ctx, "Model response had no answer besides reasoning", "reasoning_chars", len(reasoning))
+		return "", reasoning, ErrEmptyAnswer
+	}
+	return answer, reasoning, nil
+}
+
+// SaveAssistantReply stores an answer and, when Config.StoreReasoning is
+// on, its reasoning.
+func (s *ChatService) SaveAssistantReply(ctx context.Context, sessionID int32, answer, reasoning string) error {
+	conversation, err := s.Queries.CreateConversation(ctx, db.CreateConversationParams{
+		SessionID: sessionID,
+		Message:   answer,
+		Role:      "assistant",
+	})
+	if err != nil {
+		return err
+	}
+	if !s.Config.StoreReasoning || reasoning == "" {
+		return nil
+	}
+
+	err = s.Queries.CreateMessageReasoning(ctx, db.CreateMessageReasoningParams{
+		ConversationID: conversation.ID,
+		SessionID:      sessionID,
+		Content:        reasoning,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to store reply reasoning", "session_id", sessionID, "error", err)
+	}
+	return nil
+}
+
+// ListShopSessionReasoning returns the stored reasoning of a session of
+// shopID.
+func (s *ChatService) ListShopSessionReasoning(ctx context.Context, shopID, sessionID int32) ([]model.MessageReasoning, error) {
+	if _, err := s.GetShopSession(ctx, shopID, sessionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Queries.ListMessageReasoning(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	reasoning := make([]model.MessageReasoning, 0, len(rows))
+	for _, row := range rows {
+		reasoning = append(reasoning, model.MessageReasoning{
+			ConversationID: row.ConversationID,
+			Reply:          row.Message,
+			Reasoning:      row.Content,
+			CreatedAt:      row.CreatedAt.Time,
+		})
+	}
+	return reasoning, nil
+}
+
+// FormatReply renders a Markdown reply for the channel it is sent on.
+func (s *ChatService) FormatReply(ctx context.Context, channelID int32, text string) string {
+	return markup.Render(text, markup.ForChannel(s.channelName(ctx, channelID), s.Config.WebFormat))
+}
+
+// channelName looks up a channel name. Channels never change at runtime,
+// so names are kept after the first lookup.
+func (s *ChatService) channelName(ctx context.Context, channelID int32) string {
+	if name, ok := s.channels.Load(channelID); ok {
+		return name.(string)
+	}
+	channel, err := s.Queries.GetChannelByID(ctx, channelID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load channel", "channel_id", channelID, "error", err)
+		return ""
+	}
+	s.channels.Store(channelID, channel.Name)
+	return channel.Name
+}
@@ -2,154 +2,144 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"shofy/app/api/config"
 	db "shofy/db/sqlc"
 	"shofy/modules/chat/model"
-	"strings"
+	"sync"
 
-	deepinfraService "shofy/modules/deepinfra/service"
+	guardrailService "shofy/modules/guardrail/service"
+	languageService "shofy/modules/language/service"
+	llmService "shofy/modules/llm/service"
 
-	utils "shofy/utils"
+	"shofy/utils/apperror"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrLLMFailed wraps completion failures that are not already typed by
+// the LLM client.
+var ErrLLMFailed = apperror.Upstream("llm_failed", "Gagal mendapatkan jawaban dari AI")
+
 type ChatService struct {
 	DBPool  *pgxpool.Pool
 	Queries *db.Queries
 
 	// GPTService *service.GPTService
 	// AzureOpenAI *azureService.AzureOpenAI
-	OpenAIService *deepinfraService.OpenAIService
+	// LLM answers chats, normally an llm Client with retries and fallbacks.
+	LLM    llmService.Completer
+	Config config.ChatConfig
+
+	// WhatsApp delivers staff replies and, with Email, staff alerts. Either
+	// may be nil.
+	WhatsApp Messenger
+	Email    Mailer
+
+	// Guardrail checks messages and replies; nil lets them through.
+	Guardrail guardrailService.GuardrailService
+
+	// Classifier is a small model that finds the intent of messages no
+	// rule recognises; with nil those go straight to LLM.
+	Classifier llmService.Completer
+
+	// Language picks the reply language of each message; with nil the
+	// prompt decides.
+	Language languageService.LanguageService
+
+	// Prompts renders the shop prompt Answer sends to LLM.
+	Prompts Prompter
+
+	// channels caches channel names by ID.
+	channels sync.Map
 }
 
-func NewChatService(ctx context.Context, dbPool *pgxpool.Pool, queries *db.Queries) *ChatService {
-	openaiService := deepinfraService.NewOpenAIService(ctx)
+func NewChatService(ctx context.Context, dbPool *pgxpool.Pool, queries *db.Queries, llm llmService.Completer, chatConfig config.ChatConfig) *ChatService {
 	return &ChatService{
-		DBPool:        dbPool,
-		Queries:       queries,
-		OpenAIService: openaiService,
+		DBPool:  dbPool,
+		Queries: queries,
+		LLM:     llm,
+		Config:  chatConfig,
 	}
 }
 
-func (s *ChatService) CreateChat(ctx context.Context, chat model.ChatPayload, channelID int) (model.ChatResponse, int, error) {
+// CreateChat answers a message in the caller's current session on the
+// channel, opening one when there is none, and stores both messages.
+func (s *ChatService) CreateChat(ctx context.Context, owner model.ChatOwner, chat model.ChatPayload, channelID int) (model.ChatResponse, int, error) {
+	userID, guestID, err := ownerIDs(owner)
+	if err != nil {
+		return model.ChatResponse{}, http.StatusUnauthorized, err
+	}
 
 	session, err := s.Queries.GetCurrentSessions(ctx, db.GetCurrentSessionsParams{
 		ChannelID: int32(channelID),
-		UserID:    2,
+		ShopID:    owner.ShopID,
+		UserID:    userID,
+		GuestID:   guestID,
 	})
 
-	var hasNoSession bool = false
-	messages := []model.ChatMessage{}
-	if err != nil {
-		log.Println("WHATSHAP err 2: " + err.Error())
+	var history []model.ChatMessage
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		slog.DebugContext(ctx, "No current session, creating one", "channel_id", channelID)
 		session, err = s.Queries.CreateSession(ctx, db.CreateSessionParams{
+			UserID:    userID,
+			GuestID:   guestID,
+			ShopID:    owner.ShopID,
 			ChannelID: int32(channelID),
-			UserID:    2,
 		})
 		if err != nil {
-			log.Println("WHATSHAP err 3: " + err.Error())
-			return model.ChatResponse{}, http.StatusInternalServerError, err
+			slog.ErrorContext(ctx, "Failed to create session", "error", err)
+			return model.ChatResponse{}, http.StatusInternalServerError, fmt.Errorf("Error Database: %w", err)
 		}
-		hasNoSession = true
-	} else {
-		conversations, err := s.Queries.GetConversationsBySessionID(ctx, session.ID)
+	case err != nil:
+		return model.ChatResponse{}, http.StatusInternalServerError, fmt.Errorf("Error Database: %w", err)
+	default:
+		history, err = s.BuildMessageHistory(ctx, session.ID)
 		if err != nil {
-			hasNoSession = true
-		}
-		for _, conversation := range conversations {
-			messages = append(messages, model.ChatMessage{Role: conversation.Role, Content: conversation.Message})
+			return model.ChatResponse{}, http.StatusInternalServerError, fmt.Errorf("Error Database: %w", err)
 		}
 	}
 
-	conversation, err := s.Queries.CreateConversation(ctx, db.CreateConversationParams{
-		SessionID: session.ID,
-		Message:   chat.Message,
-		Role:      "user",
-	})
-
-	if hasNoSession {
-		messages = append(messages, model.ChatMessage{Role: "user", Content: conversation.Message})
+	if err := s.SaveUserMessage(ctx, session.ID, chat.Message); err != nil {
+		return model.ChatResponse{}, http.StatusInternalServerError, fmt.Errorf("Error Database: %w", err)
 	}
 
-	messages = append(messages, model.ChatMessage{Role: "user", Content: conversation.Message})
-
-	chatResponse, status, err := s.OpenAIService.ChatCompletion(ctx, messages)
+	// Staff own the session or the customer asked for them.
+	reply, handled, err := s.RouteToStaff(ctx, session, chat.Message)
 	if err != nil {
-		return chatResponse, status, err
-	}
-
-	thinkingProcess := utils.ExtractThinkingProcess(chatResponse.Message)
-	_, err = s.Queries.CreateConversation(ctx, db.CreateConversationParams{
-		SessionID: session.ID,
-		Message:   thinkingProcess,
-		Role:      "assistant",
-	})
-	chatResponse.Message = thinkingProcess
-	if err != nil {
-		return chatResponse, http.StatusInternalServerError, err
-	}
-
-	return chatResponse, status, nil
-}
-
-func (s *ChatService) isProductRelated(ctx context.Context, message string) bool {
-	classificationPrompt := []model.ChatMessage{
-		{Role: "system", Content: "Apakah ini pertanyaan tentang produk seperti stok, nama produk, harga? Jawab hanya 'ya' atau 'tidak'."},
-		{Role: "user", Content: message},
+		return model.ChatResponse{}, http.StatusInternalServerError, err
 	}
-
-	response, _, err := s.OpenAIService.ChatCompletion(ctx, classificationPrompt)
-	if err != nil {
-		log.Println("Classification failed:", err)
-		return false
+	if handled {
+		return model.ChatResponse{Message: reply}, http.StatusOK, nil
 	}
 
-	normalized := strings.ToLower(strings.TrimSpace(response.Message))
-	return strings.Contains(normalized, "ya")
-}
-
-func (s *ChatService) checkStockByKeyword(ctx context.Context, userMsg string) (string, error) {
-	products, err := s.Queries.GetAllProducts(ctx)
+	answer, err := s.Answer(ctx, session, history, chat.Message, false)
 	if err != nil {
-		return "", err
-	}
-
-	for _, p := range products {
-		if strings.Contains(strings.ToLower(userMsg), strings.ToLower(p.Name)) {
-			return fmt.Sprintf("Stok produk %s tersedia sebanyak %d dengan harga Rp%d", p.Name, p.Stock, p.Price), nil
+		status := http.StatusInternalServerError
+		if appErr, ok := apperror.As(err); ok {
+			status = apperror.HTTPStatus(appErr.Kind)
 		}
+		return model.ChatResponse{}, status, err
 	}
-	return "Maaf, saya tidak menemukan produk yang Anda maksud.", nil
-}
-
-func (s *ChatService) ChatCompletion(ctx context.Context, messages []model.ChatMessage) (model.ChatResponse, int, error) {
-	return s.OpenAIService.ChatCompletion(ctx, messages)
-}
-
-func (s *ChatService) GetAllProductsAsString(ctx context.Context) (string, error) {
-	products, err := s.Queries.GetAllProducts(ctx)
-	if err != nil {
-		return "", err
+	if err := s.SaveAssistantReply(ctx, session.ID, answer.Reply.Message, answer.Reasoning); err != nil {
+		return model.ChatResponse{}, http.StatusInternalServerError, fmt.Errorf("Error Database: %w", err)
 	}
 
-	productsJson, err := json.MarshalIndent(products, "", "  ")
-	// log.Println("Products JSON:", string(productsJson))
-
-	return string(productsJson), nil
+	return model.ChatResponse{Message: answer.Reply.Message}, http.StatusOK, nil
 }
 
-func (s *ChatService) GetAllShopsAsString(ctx context.Context) (string, error) {
-	shops, err := s.Queries.GetAllShops(ctx)
-
+func (s *ChatService) ChatCompletion(ctx context.Context, messages []model.ChatMessage, opts ...model.CompletionOption) (model.ChatResponse, int, error) {
+	resp, status, err := s.LLM.ChatCompletion(ctx, messages, opts...)
 	if err != nil {
-		return "", err
+		if _, ok := apperror.As(err); ok {
+			return resp, status, err
+		}
+		return resp, status, ErrLLMFailed.Wrap(err)
 	}
-
-	shopsJson, err := json.MarshalIndent(shops, "", "  ")
-
-	return string(shopsJson), nil
+	return resp, status, nil
 }
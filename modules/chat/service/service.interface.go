@@ -7,12 +7,40 @@ import (
 )
 
 type ChatServiceInterface interface {
-	CreateChat(ctx context.Context, chat model.ChatPayload, channelID int) (model.ChatResponse, int, error)
-	GetOrCreateSession(ctx context.Context, chatSession model.ChatSession) (db.Session, error)
+	CreateChat(ctx context.Context, owner model.ChatOwner, chat model.ChatPayload, channelID int) (model.ChatResponse, int, error)
+	GetOrCreateSession(ctx context.Context, owner model.ChatOwner, chatSession model.ChatSession) (db.Session, error)
+	GetSession(ctx context.Context, owner model.ChatOwner, sessionID int32) (db.Session, error)
+	ListSessions(ctx context.Context, owner model.ChatOwner, q model.SessionListQuery, limit, offset int32) ([]model.SessionSummary, int64, error)
+	ListMessages(ctx context.Context, owner model.ChatOwner, sessionID, limit, offset int32) ([]model.SessionMessage, int64, error)
+	SetSessionStatus(ctx context.Context, owner model.ChatOwner, sessionID int32, status string) (db.Session, error)
+	RouteToStaff(ctx context.Context, session db.Session, message string) (string, bool, error)
+	ResolveReplyEscalation(ctx context.Context, session db.Session, reply, lang string) (string, error)
+	ListShopSessions(ctx context.Context, shopID int32, mode string, limit, offset int32) ([]model.StaffSessionSummary, int64, error)
+	ListShopSessionMessages(ctx context.Context, shopID, sessionID, limit, offset int32) ([]model.SessionMessage, int64, error)
+	TakeOver(ctx context.Context, shopID, staffID, sessionID int32) (db.Session, error)
+	Release(ctx context.Context, shopID, sessionID int32) (db.Session, error)
+	StaffReply(ctx context.Context, shopID, staffID, sessionID int32, message string) (model.StaffReplyResponse, error)
+	IssueGuestToken(ctx context.Context, shopID int32) (model.GuestTokenResponse, error)
 	BuildMessageHistory(ctx context.Context, sessionID int32) ([]model.ChatMessage, error)
 	SaveUserMessage(ctx context.Context, sessionID int32, content string) error
 	SaveAssistantMessage(ctx context.Context, sessionID int32, content string) error
-	ChatCompletion(ctx context.Context, messages []model.ChatMessage) (model.ChatResponse, int, error)
-	GetAllProductsAsString(ctx context.Context) (string, error)
-	GetAllShopsAsString(ctx context.Context) (string, error)
+	SaveAssistantReply(ctx context.Context, sessionID int32, answer, reasoning string) error
+	ProcessReply(ctx context.Context, response string) (string, string, error)
+	FormatReply(ctx context.Context, channelID int32, text string) string
+	ListShopSessionReasoning(ctx context.Context, shopID, sessionID int32) ([]model.MessageReasoning, error)
+	ChatCompletion(ctx context.Context, messages []model.ChatMessage, opts ...model.CompletionOption) (model.ChatResponse, int, error)
+	GuardInput(ctx context.Context, session db.Session, message, lang string) (string, string)
+	GuardPrompt(prompt string) (string, string)
+	GuardOutput(ctx context.Context, session db.Session, reply, canary, lang string) (string, bool)
+	ShowsStock(ctx context.Context, shopID int32) bool
+	ReplyLanguage(ctx context.Context, session db.Session, message string) (string, string)
+	LanguageInstruction(lang string) string
+	RouteIntent(ctx context.Context, session db.Session, message, lang string) (model.IntentRoute, error)
+	Answer(ctx context.Context, session db.Session, history []model.ChatMessage, message string, structured bool) (AssistantReply, error)
+	StructuredPrompt(ctx context.Context, shopID int32) (string, error)
+	ParseStructured(ctx context.Context, shopID int32, answer, lang string) model.StructuredReply
+	LookupCachedReply(ctx context.Context, shopID int32, message string) (*CachedReply, error)
+	StoreCachedReply(ctx context.Context, shopID int32, lookup *CachedReply, question, answer string)
+	CacheStats(ctx context.Context, shopID int32) (*model.CacheStats, error)
+	PurgeCache(ctx context.Context, shopID int32) (int64, error)
 }
@@ -2,22 +2,62 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	db "shofy/db/sqlc"
 	"shofy/modules/chat/model"
+	"shofy/utils/apperror"
+	"shofy/utils/jwt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrSessionNotFound is also returned for sessions of other callers, so
+	// their IDs cannot be probed.
+	ErrSessionNotFound = apperror.NotFound("chat_session_not_found", "Sesi chat tidak ditemukan")
+	ErrShopNotFound    = apperror.NotFound("shop_not_found", "Toko tidak ditemukan")
+	ErrGuestsDisabled  = apperror.Forbidden("chat_guests_disabled", "Chat tanpa login tidak tersedia")
+	ErrSessionClosed   = apperror.Conflict("chat_session_closed", "Sesi chat sudah ditutup")
+	ErrInvalidOwner    = apperror.Unauthorized("chat_owner_invalid", "Identitas chat tidak valid, silakan mulai ulang chat")
 )
 
-func (s *ChatService) GetOrCreateSession(ctx context.Context, chatSession model.ChatSession) (db.Session, error) {
+// ownerIDs returns the user or guest column values matching owner.
+func ownerIDs(owner model.ChatOwner) (pgtype.Int4, pgtype.UUID, error) {
+	if owner.UserID != 0 {
+		return pgtype.Int4{Int32: owner.UserID, Valid: true}, pgtype.UUID{}, nil
+	}
+	guestID, err := uuid.Parse(owner.GuestID)
+	if err != nil {
+		return pgtype.Int4{}, pgtype.UUID{}, ErrInvalidOwner.Wrap(err)
+	}
+	return pgtype.Int4{}, pgtype.UUID{Bytes: guestID, Valid: true}, nil
+}
+
+func (s *ChatService) GetOrCreateSession(ctx context.Context, owner model.ChatOwner, chatSession model.ChatSession) (db.Session, error) {
+	userID, guestID, err := ownerIDs(owner)
+	if err != nil {
+		return db.Session{}, err
+	}
+
 	session, err := s.Queries.GetCurrentSessions(ctx, db.GetCurrentSessionsParams{
 		ChannelID: int32(chatSession.ChannelID),
-		UserID:    int32(chatSession.UserID),
+		ShopID:    owner.ShopID,
+		UserID:    userID,
+		GuestID:   guestID,
 	})
 	if err == nil {
 		return session, nil
 	}
 
 	newSession, err := s.Queries.CreateSession(ctx, db.CreateSessionParams{
+		UserID:    userID,
+		GuestID:   guestID,
+		ShopID:    owner.ShopID,
 		ChannelID: int32(chatSession.ChannelID),
-		UserID:    int32(chatSession.UserID),
 	})
 
 	if err != nil {
@@ -26,3 +66,168 @@ func (s *ChatService) GetOrCreateSession(ctx context.Context, chatSession model.
 
 	return newSession, nil
 }
+
+// GetSession returns the session if it belongs to owner and owner's shop.
+func (s *ChatService) GetSession(ctx context.Context, owner model.ChatOwner, sessionID int32) (db.Session, error) {
+	userID, guestID, err := ownerIDs(owner)
+	if err != nil {
+		return db.Session{}, err
+	}
+
+	session, err := s.Queries.GetOwnedSession(ctx, db.GetOwnedSessionParams{
+		ID:      sessionID,
+		ShopID:  owner.ShopID,
+		UserID:  userID,
+		GuestID: guestID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return db.Session{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return session, nil
+}
+
+// IssueGuestToken starts an anonymous visitor of an active shop.
+func (s *ChatService) IssueGuestToken(ctx context.Context, shopID int32) (model.GuestTokenResponse, error) {
+	if !s.Config.AllowGuests {
+		return model.GuestTokenResponse{}, ErrGuestsDisabled
+	}
+
+	_, err := s.Queries.GetShopsById(ctx, shopID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.GuestTokenResponse{}, ErrShopNotFound
+	}
+	if err != nil {
+		return model.GuestTokenResponse{}, fmt.Errorf("Error Database: %w", err)
+	}
+
+	token, claims, err := jwt.GenerateGuestToken(shopID, s.Config.GuestTokenTTL)
+	if err != nil {
+		return model.GuestTokenResponse{}, err
+	}
+
+	return model.GuestTokenResponse{
+		Token:     token,
+		GuestID:   claims.GuestID,
+		ShopID:    claims.ShopID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// ListSessions returns a page of owner's sessions and the total count.
+func (s *ChatService) ListSessions(ctx context.Context, owner model.ChatOwner, q model.SessionListQuery, limit, offset int32) ([]model.SessionSummary, int64, error) {
+	userID, guestID, err := ownerIDs(owner)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	channelID := pgtype.Int4{Int32: int32(q.ChannelID), Valid: q.ChannelID != 0}
+	status := pgtype.Text{String: q.Status, Valid: q.Status != ""}
+
+	rows, err := s.Queries.ListOwnedSessions(ctx, db.ListOwnedSessionsParams{
+		ShopID:    owner.ShopID,
+		UserID:    userID,
+		GuestID:   guestID,
+		ChannelID: channelID,
+		Status:    status,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	total, err := s.Queries.CountOwnedSessions(ctx, db.CountOwnedSessionsParams{
+		ShopID:    owner.ShopID,
+		UserID:    userID,
+		GuestID:   guestID,
+		ChannelID: channelID,
+		Status:    status,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	sessions := make([]model.SessionSummary, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, model.SessionSummary{
+			ID:            row.ID,
+			ChannelID:     row.ChannelID,
+			ShopID:        row.ShopID,
+			Status:        row.Status,
+			Mode:          row.Mode,
+			CreatedAt:     row.CreatedAt.Time,
+			ClosedAt:      timePtr(row.ClosedAt),
+			LastMessage:   row.LastMessage,
+			LastRole:      row.LastRole,
+			LastMessageAt: timePtr(row.LastMessageAt),
+		})
+	}
+	return sessions, total, nil
+}
+
+// ListMessages returns a page of a session's messages, oldest first, and
+// the total count.
+func (s *ChatService) ListMessages(ctx context.Context, owner model.ChatOwner, sessionID, limit, offset int32) ([]model.SessionMessage, int64, error) {
+	if _, err := s.GetSession(ctx, owner, sessionID); err != nil {
+		return nil, 0, err
+	}
+	return s.listMessages(ctx, sessionID, limit, offset)
+}
+
+func (s *ChatService) listMessages(ctx context.Context, sessionID, limit, offset int32) ([]model.SessionMessage, int64, error) {
+	rows, err := s.Queries.ListConversationsBySessionID(ctx, db.ListConversationsBySessionIDParams{
+		SessionID: sessionID,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	total, err := s.Queries.CountConversationsBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	messages := make([]model.SessionMessage, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, model.SessionMessage{
+			ID:        row.ID,
+			Role:      row.Role,
+			Message:   row.Message,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+	return messages, total, nil
+}
+
+// SetSessionStatus closes or archives one of owner's sessions. Closing an
+// archived session leaves it archived.
+func (s *ChatService) SetSessionStatus(ctx context.Context, owner model.ChatOwner, sessionID int32, status string) (db.Session, error) {
+	session, err := s.GetSession(ctx, owner, sessionID)
+	if err != nil {
+		return db.Session{}, err
+	}
+	if session.Status == status || (status == model.SessionClosed && session.Status == model.SessionArchived) {
+		return session, nil
+	}
+
+	session, err = s.Queries.UpdateSessionStatus(ctx, db.UpdateSessionStatusParams{
+		ID:     sessionID,
+		Status: status,
+	})
+	if err != nil {
+		return db.Session{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return session, nil
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
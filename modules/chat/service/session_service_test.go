@@ -0,0 +1,19 @@
+package service
+
+import (
+	"errors"
+	"shofy/modules/chat/model"
+	"testing"
+)
+
+func TestOwnerIDsRejectsInvalidGuest(t *testing.T) {
+	_, _, err := ownerIDs(model.ChatOwner{GuestID: "not-a-uuid"})
+	if !errors.Is(err, ErrInvalidOwner) {
+		t.Fatalf("ownerIDs error = %v, want ErrInvalidOwner", err)
+	}
+
+	userID, _, err := ownerIDs(model.ChatOwner{UserID: 7})
+	if err != nil || userID.Int32 != 7 || !userID.Valid {
+		t.Fatalf("ownerIDs(user 7) = %v, %v", userID, err)
+	}
+}
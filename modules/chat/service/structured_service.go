@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+	"shofy/utils/metrics"
+	"strconv"
+	"strings"
+)
+
+// Limits applied to the blocks of a structured reply.
+const (
+	maxProductCards  = 6
+	maxQuickReplies  = 4
+	maxQuickReplyLen = 40
+	maxOrderQuantity = 99
+)
+
+// structuredSchema is what the model must return. The model only picks
+// products and quantities; names, prices and stock come from the catalog.
+var structuredSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "message": {"type": "string"},
+    "blocks": {
+      "type": "array",
+      "items": {
+        "anyOf": [
+          {
+            "type": "object",
+            "properties": {
+              "type": {"const": "product_card"},
+              "product_id": {"type": "string"}
+            },
+            "required": ["type", "product_id"]
+          },
+          {
+            "type": "object",
+            "properties": {
+              "type": {"const": "quick_replies"},
+              "options": {"type": "array", "items": {"type": "string"}}
+            },
+            "required": ["type", "options"]
+          },
+          {
+            "type": "object",
+            "properties": {
+              "type": {"const": "order_summary"},
+              "items": {
+                "type": "array",
+                "items": {
+                  "type": "object",
+                  "properties": {
+                    "product_id": {"type": "string"},
+                    "quantity": {"type": "integer"}
+                  },
+                  "required": ["product_id", "quantity"]
+                }
+              }
+            },
+            "required": ["type", "items"]
+          }
+        ]
+      }
+    }
+  },
+  "required": ["message", "blocks"]
+}`)
+
+const structuredInstruction = `Format jawaban: balas HANYA dengan satu objek JSON yang sesuai skema berikut, tanpa teks lain dan tanpa blok kode.
+%s
+
+- "message": jawaban untuk pelanggan. Tulis %s di sini jika harus meneruskan ke staf.
+- "blocks" boleh kosong. Pakai "product_card" untuk produk yang kamu sebutkan, "quick_replies" untuk 2-4 pilihan balasan singkat, dan "order_summary" saat pelanggan menyusun pesanan.
+- Pakai hanya ID produk dari daftar ini:
+%s`
+
+const structuredRepairPrompt = `Ubah jawaban berikut menjadi satu objek JSON yang sesuai skema ini, tanpa teks lain:
+%s
+
+Masalah pada jawaban sebelumnya: %s`
+
+var (
+	codeFence     = regexp.MustCompile("(?s)^```[a-zA-Z]*\\s*(.*?)\\s*```$")
+	trailingComma = regexp.MustCompile(`,\s*([}\]])`)
+	messageField  = regexp.MustCompile(`"message"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+)
+
+// rawReply is the model's JSON before it is checked against the catalog.
+type rawReply struct {
+	Message string     `json:"message"`
+	Blocks  []rawBlock `json:"blocks"`
+}
+
+type rawBlock struct {
+	Type      string    `json:"type"`
+	ProductID string    `json:"product_id"`
+	Options   []string  `json:"options"`
+	Items     []rawItem `json:"items"`
+}
+
+type rawItem struct {
+	ProductID string  `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// StructuredOutput is the completion option for structured replies.
+func StructuredOutput() model.CompletionOption {
+	return model.WithJSONSchema("chat_reply", structuredSchema)
+}
+
+// StructuredPrompt returns the instructions appended to the system prompt
+// when the client asked for a structured reply.
+func (s *ChatService) StructuredPrompt(ctx context.Context, shopID int32) (string, error) {
+	products, err := s.Queries.GetProductsByShopID(ctx, shopID)
+	if err != nil {
+		return "", fmt.Errorf("Error Database: %w", err)
+	}
+
+	lines := make([]string, 0, len(products))
+	for _, p := range products {
+		lines = append(lines, fmt.Sprintf("- %s: %s", p.ID, p.Name))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "(belum ada produk)")
+	}
+	return fmt.Sprintf(structuredInstruction, structuredSchema, EscalateMarker, strings.Join(lines, "\n")), nil
+}
+
+// ParseStructured turns a model answer into a structured reply. Invalid
+// JSON is repaired locally, then once by the model; when that fails too
+// the answer's text, or an apology in lang, is returned as a fallback.
+// Blocks are filled in from the catalog and blocks naming unknown products
+// are dropped.
+func (s *ChatService) ParseStructured(ctx context.Context, shopID int32, answer, lang string) model.StructuredReply {
+	raw, err := parseRawReply(answer)
+	outcome := "valid"
+	if err != nil {
+		slog.WarnContext(ctx, "Structured reply is invalid, asking the model to repair it", "shop_id", shopID, "error", err)
+		raw, err = s.repairStructured(ctx, answer, err)
+		outcome = "repaired"
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Structured reply could not be repaired, falling back to text", "shop_id", shopID, "error", err)
+		metrics.ChatStructuredReplies.WithLabelValues("fallback").Inc()
+		return model.StructuredReply{Message: fallbackText(answer, textsFor(lang).structuredFallback), Blocks: []model.Block{}, Fallback: true}
+	}
+	metrics.ChatStructuredReplies.WithLabelValues(outcome).Inc()
+
+	products, err := s.Queries.GetProductsByShopID(ctx, shopID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load catalog for structured reply", "shop_id", shopID, "error", err)
+	}
+	shopName := ""
+	if shop, err := s.Queries.GetShopsById(ctx, shopID); err == nil {
+		shopName = shop.Name
+	}
+
+	reply, dropped := buildStructured(raw, products, shopName, s.ShowsStock(ctx, shopID))
+	if len(dropped) > 0 {
+		slog.WarnContext(ctx, "Dropped invalid blocks from structured reply", "shop_id", shopID, "problems", dropped)
+	}
+	return reply
+}
+
+func (s *ChatService) repairStructured(ctx context.Context, answer string, cause error) (rawReply, error) {
+	messages := []model.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(structuredRepairPrompt, structuredSchema, cause)},
+		{Role: "user", Content: answer},
+	}
+	resp, _, err := s.LLM.ChatCompletion(ctx, messages, StructuredOutput())
+	if err != nil {
+		return rawReply{}, err
+	}
+	repaired, _, err := s.ProcessReply(ctx, resp.Message)
+	if err != nil {
+		return rawReply{}, err
+	}
+	return parseRawReply(repaired)
+}
+
+// parseRawReply decodes the model's JSON after fixing what models commonly
+// get wrong: code fences, text around the object and trailing commas.
+func parseRawReply(answer string) (rawReply, error) {
+	text := strings.TrimSpace(answer)
+	if m := codeFence.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return rawReply{}, errors.New("no JSON object in reply")
+	}
+	text = trailingComma.ReplaceAllString(text[start:end+1], "$1")
+
+	var raw rawReply
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return rawReply{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	raw.Message = strings.TrimSpace(raw.Message)
+	if raw.Message == "" {
+		return rawReply{}, errors.New(`"message" is empty`)
+	}
+	return raw, nil
+}
+
+// buildStructured fills in the blocks from the catalog, with stock counts
+// on product cards only when showStock is set. It returns what it had to
+// drop.
+func buildStructured(raw rawReply, products []db.GetProductsByShopIDRow, shopName string, showStock bool) (model.StructuredReply, []string) {
+	byID := make(map[string]db.GetProductsByShopIDRow, len(products))
+	byName := make(map[string]db.GetProductsByShopIDRow, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+		byName[strings.ToLower(p.Name)] = p
+	}
+	lookup := func(ref string) (db.GetProductsByShopIDRow, bool) {
+		ref = strings.TrimSpace(ref)
+		if p, ok := byID[ref]; ok {
+			return p, true
+		}
+		p, ok := byName[strings.ToLower(ref)]
+		return p, ok
+	}
+
+	var (
+		dropped []string
+		cards   []model.Block
+		options []string
+		orders  []model.Block
+		seen    = map[string]bool{}
+	)
+	for _, b := range raw.Blocks {
+		switch b.Type {
+		case model.BlockProductCard:
+			p, ok := lookup(b.ProductID)
+			switch {
+			case !ok:
+				dropped = append(dropped, "unknown product "+b.ProductID)
+			case seen[p.ID] || len(cards) == maxProductCards:
+			default:
+				seen[p.ID] = true
+				card := &model.ProductCard{
+					ID:      p.ID,
+					Name:    p.Name,
+					Price:   price(p),
+					InStock: p.Stock.Int32 > 0,
+					Shop:    shopName,
+				}
+				if showStock {
+					card.Stock = &p.Stock.Int32
+				}
+				cards = append(cards, model.Block{Type: model.BlockProductCard, Product: card})
+			}
+
+		case model.BlockQuickReplies:
+			for _, o := range b.Options {
+				o = strings.TrimSpace(o)
+				if o == "" || len([]rune(o)) > maxQuickReplyLen || containsFold(options, o) || len(options) == maxQuickReplies {
+					continue
+				}
+				options = append(options, o)
+			}
+
+		case model.BlockOrderSummary:
+			order := &model.OrderSummary{}
+			for _, item := range b.Items {
+				p, ok := lookup(item.ProductID)
+				if !ok {
+					dropped = append(dropped, "unknown order product "+item.ProductID)
+					continue
+				}
+				qty := int32(math.Max(1, math.Min(maxOrderQuantity, math.Round(item.Quantity))))
+				line := model.OrderLine{ProductID: p.ID, Name: p.Name, Quantity: qty, Price: price(p)}
+				line.Subtotal = line.Price * float64(qty)
+				order.Items = append(order.Items, line)
+				order.Total += line.Subtotal
+			}
+			if len(order.Items) > 0 && len(orders) == 0 {
+				orders = append(orders, model.Block{Type: model.BlockOrderSummary, Order: order})
+			}
+
+		default:
+			dropped = append(dropped, "unknown block type "+strconv.Quote(b.Type))
+		}
+	}
+
+	blocks := append(cards, orders...)
+	if len(options) > 0 {
+		blocks = append(blocks, model.Block{Type: model.BlockQuickReplies, Options: options})
+	}
+	if blocks == nil {
+		blocks = []model.Block{}
+	}
+	return model.StructuredReply{Message: raw.Message, Blocks: blocks}, dropped
+}
+
+// fallbackText pulls the message out of an answer that is not valid JSON,
+// or returns apology when it holds no text that can be shown.
+func fallbackText(answer, apology string) string {
+	if m := messageField.FindStringSubmatch(answer); m != nil {
+		if text, err := strconv.Unquote(`"` + m[1] + `"`); err == nil && strings.TrimSpace(text) != "" {
+			return strings.TrimSpace(text)
+		}
+	}
+	text := strings.TrimSpace(answer)
+	if text == "" || strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") || strings.HasPrefix(text, "```") {
+		return apology
+	}
+	return text
+}
+
+func price(p db.GetProductsByShopIDRow) float64 {
+	f, err := p.Price.Float64Value()
+	if err != nil || !f.Valid {
+		return 0
+	}
+	return f.Float64
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
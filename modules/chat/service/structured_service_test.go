@@ -0,0 +1,78 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shofy/db/sqlc"
+	"shofy/modules/chat/model"
+)
+
+func TestParseRawReply(t *testing.T) {
+	answer := "Berikut jawabannya:\n```json\n{\"message\": \"Ada kak\", \"blocks\": [{\"type\": \"product_card\", \"product_id\": \"p1\"},],}\n```"
+	raw, err := parseRawReply(answer)
+	if err != nil {
+		t.Fatalf("parseRawReply: %v", err)
+	}
+	if raw.Message != "Ada kak" || len(raw.Blocks) != 1 || raw.Blocks[0].ProductID != "p1" {
+		t.Errorf("unexpected reply: %+v", raw)
+	}
+
+	for _, bad := range []string{"Maaf, stok habis.", `{"message": ""}`, `{"message": "a", "blocks": [}`} {
+		if _, err := parseRawReply(bad); err == nil {
+			t.Errorf("parseRawReply(%q) should fail", bad)
+		}
+	}
+}
+
+func TestBuildStructured(t *testing.T) {
+	products := []db.GetProductsByShopIDRow{
+		{ID: "p1", Name: "Sepatu Merah", Price: pgtype.Numeric{Int: big.NewInt(150000), Valid: true}, Stock: pgtype.Int4{Int32: 3, Valid: true}},
+	}
+	raw := rawReply{Message: "Ada kak", Blocks: []rawBlock{
+		{Type: model.BlockProductCard, ProductID: "p1"},
+		{Type: model.BlockProductCard, ProductID: "sepatu merah"},
+		{Type: model.BlockProductCard, ProductID: "p9"},
+		{Type: model.BlockQuickReplies, Options: []string{"Beli", "beli", "", "Lihat ukuran"}},
+		{Type: "carousel"},
+		{Type: model.BlockOrderSummary, Items: []rawItem{{ProductID: "p1", Quantity: 2}, {ProductID: "p9", Quantity: 1}}},
+	}}
+
+	reply, dropped := buildStructured(raw, products, "Toko Kita", true)
+	if len(dropped) != 3 {
+		t.Errorf("dropped = %v, want the unknown products and block type", dropped)
+	}
+	if len(reply.Blocks) != 3 {
+		t.Fatalf("blocks = %+v, want card, order and quick replies", reply.Blocks)
+	}
+	card := reply.Blocks[0].Product
+	if card.Price != 150000 || card.Stock == nil || *card.Stock != 3 || !card.InStock || card.Shop != "Toko Kita" {
+		t.Errorf("card = %+v", card)
+	}
+	if order := reply.Blocks[1].Order; order.Total != 300000 || order.Items[0].Quantity != 2 {
+		t.Errorf("order = %+v", order)
+	}
+	if opts := reply.Blocks[2].Options; len(opts) != 2 {
+		t.Errorf("options = %v, want deduplicated", opts)
+	}
+
+	reply, _ = buildStructured(raw, products, "Toko Kita", false)
+	if card := reply.Blocks[0].Product; card.Stock != nil || !card.InStock {
+		t.Errorf("card with hidden stock = %+v, want in stock without a count", card)
+	}
+}
+
+func TestFallbackText(t *testing.T) {
+	tests := map[string]string{
+		`{"message": "Stok ada\nkak", "blocks": [`: "Stok ada\nkak",
+		"Stok ada kak":  "Stok ada kak",
+		`{"blocks": []`: "sorry",
+	}
+	for answer, want := range tests {
+		if got := fallbackText(answer, "sorry"); got != want {
+			t.Errorf("fallbackText(%q) = %q, want %q", answer, got, want)
+		}
+	}
+}
@@ -4,23 +4,40 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"shofy/app/api/config"
 	"shofy/modules/chat/model"
+	"shofy/utils/metrics"
+	"shofy/utils/telemetry"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const providerName = "deepinfra"
+
+// ErrEmptyReply is returned when a completion has no choices or no text.
+var ErrEmptyReply = errors.New("DeepInfra returned an empty reply")
+
 type OpenAIService struct {
-	APIKey string
-	Model  string
+	APIKey         string
+	Model          string
+	EmbeddingModel string
+	BaseURL        string
+	HTTPClient     *http.Client
 }
 
-func NewOpenAIService(ctx context.Context) *OpenAIService {
+func NewOpenAIService(ctx context.Context, cfg config.DeepInfraConfig) *OpenAIService {
 	return &OpenAIService{
-		APIKey: os.Getenv("DI_API_KEY"),
-		Model:  "meta-llama/Llama-4-Maverick-17B-128E-Instruct-FP8", // ganti model sesuai kebutuhan
+		APIKey:         cfg.APIKey,
+		Model:          cfg.Model,
+		EmbeddingModel: cfg.EmbeddingModel,
+		BaseURL:        strings.TrimRight(cfg.URL, "/"),
+		HTTPClient:     telemetry.NewHTTPClient(),
 	}
 }
 
@@ -35,43 +52,61 @@ func convertToDeepInfraFormat(messages []model.ChatMessage) []map[string]string
 	return result
 }
 
-func (s *OpenAIService) ChatCompletion(ctx context.Context, messages []model.ChatMessage) (model.ChatResponse, int, error) {
+func (s *OpenAIService) ChatCompletion(ctx context.Context, messages []model.ChatMessage, opts ...model.CompletionOption) (model.ChatResponse, int, error) {
 	payload := map[string]interface{}{
 		"model":    s.Model,
 		"messages": convertToDeepInfraFormat(messages),
 	}
+	// DeepInfra constrains output to JSON but not to a schema; the schema
+	// itself goes in the prompt.
+	if o := model.NewCompletionOptions(opts...); o.Schema != nil {
+		payload["response_format"] = map[string]string{"type": "json_object"}
+	}
 	body, _ := json.Marshal(payload)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.deepinfra.com/v1/openai/chat/completions", bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.BaseURL+"/chat/completions", bytes.NewBuffer(body))
 	if err != nil {
-		log.Println("Error creating request to DeepInfra:", err)
+		slog.ErrorContext(ctx, "Error creating request to DeepInfra", "error", err)
 		return model.ChatResponse{}, http.StatusInternalServerError, err
 	}
 	req.Header.Set("Authorization", "Bearer "+s.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := http.DefaultClient
-	resp, err := client.Do(req)
+	start := time.Now()
+	defer func() {
+		metrics.LLMDuration.WithLabelValues(providerName, s.Model).Observe(time.Since(start).Seconds())
+	}()
+
+	resp, err := s.HTTPClient.Do(req)
 	if err != nil {
-		log.Println("Error making request to DeepInfra:", err)
+		metrics.LLMFailures.WithLabelValues(providerName, s.Model, "transport").Inc()
+		slog.ErrorContext(ctx, "Error making request to DeepInfra", "error", err)
 		return model.ChatResponse{}, http.StatusInternalServerError, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Println("DeepInfra API error:", resp.Status)
+		metrics.LLMFailures.WithLabelValues(providerName, s.Model, "http_"+strconv.Itoa(resp.StatusCode)).Inc()
+		slog.ErrorContext(ctx, "DeepInfra API error", "status", resp.StatusCode)
 		b, _ := io.ReadAll(resp.Body)
 		return model.ChatResponse{}, resp.StatusCode, fmt.Errorf("DeepInfra error: %s", b)
 	}
 
 	var parsed model.ChatCompletionResponse
 	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		log.Println("Error decoding DeepInfra response:", err)
+		metrics.LLMFailures.WithLabelValues(providerName, s.Model, "decode").Inc()
+		slog.ErrorContext(ctx, "Error decoding DeepInfra response", "error", err)
 		return model.ChatResponse{}, http.StatusInternalServerError, err
 	}
 
-	log.Println("DeepInfra response:", parsed)
-	log.Println("DeepInfra response 2:", parsed.Choices[0].Message.Content)
+	metrics.LLMTokens.WithLabelValues(providerName, s.Model, "prompt").Add(float64(parsed.Usage.PromptTokens))
+	metrics.LLMTokens.WithLabelValues(providerName, s.Model, "completion").Add(float64(parsed.Usage.CompletionTokens))
+	slog.DebugContext(ctx, "DeepInfra response", "model", s.Model, "choices", len(parsed.Choices))
+
+	if len(parsed.Choices) == 0 || strings.TrimSpace(parsed.Choices[0].Message.Content) == "" {
+		metrics.LLMFailures.WithLabelValues(providerName, s.Model, "empty").Inc()
+		return model.ChatResponse{}, http.StatusBadGateway, ErrEmptyReply
+	}
 
 	response := model.ChatResponse{
 		Message:      parsed.Choices[0].Message.Content,
@@ -79,3 +114,87 @@ func (s *OpenAIService) ChatCompletion(ctx context.Context, messages []model.Cha
 	}
 	return response, http.StatusOK, nil
 }
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+	} `json:"usage"`
+}
+
+// Embed returns one embedding per input, in input order.
+func (s *OpenAIService) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":           s.EmbeddingModel,
+		"input":           inputs,
+		"encoding_format": "float",
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.BaseURL+"/embeddings", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	defer func() {
+		metrics.LLMDuration.WithLabelValues(providerName, s.EmbeddingModel).Observe(time.Since(start).Seconds())
+	}()
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		metrics.LLMFailures.WithLabelValues(providerName, s.EmbeddingModel, "transport").Inc()
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		metrics.LLMFailures.WithLabelValues(providerName, s.EmbeddingModel, "http_"+strconv.Itoa(resp.StatusCode)).Inc()
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("DeepInfra embeddings error: %s", b)
+	}
+
+	var parsed embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		metrics.LLMFailures.WithLabelValues(providerName, s.EmbeddingModel, "decode").Inc()
+		return nil, err
+	}
+	if len(parsed.Data) != len(inputs) {
+		metrics.LLMFailures.WithLabelValues(providerName, s.EmbeddingModel, "decode").Inc()
+		return nil, fmt.Errorf("DeepInfra returned %d embeddings for %d inputs", len(parsed.Data), len(inputs))
+	}
+	metrics.LLMTokens.WithLabelValues(providerName, s.EmbeddingModel, "prompt").Add(float64(parsed.Usage.PromptTokens))
+
+	vectors := make([][]float32, len(inputs))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("DeepInfra returned embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// Ping checks that the API is reachable and the key is accepted.
+func (s *OpenAIService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DeepInfra returned status %d", resp.StatusCode)
+	}
+	return nil
+}
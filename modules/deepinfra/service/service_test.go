@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChatCompletionEmptyChoices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[],"usage":{}}`))
+	}))
+	defer srv.Close()
+
+	s := &OpenAIService{Model: "m", BaseURL: srv.URL, HTTPClient: srv.Client()}
+	_, status, err := s.ChatCompletion(context.Background(), nil)
+	if !errors.Is(err, ErrEmptyReply) || status != http.StatusBadGateway {
+		t.Fatalf("got %d, %v, want ErrEmptyReply", status, err)
+	}
+}
@@ -0,0 +1,144 @@
+package model
+
+import "time"
+
+// Names of the checks run against the last reply of a case.
+const (
+	CheckPrice       = "price"
+	CheckStock       = "stock"
+	CheckContains    = "contains"
+	CheckNotContains = "not_contains"
+	CheckRefusal     = "refusal"
+	CheckEscalate    = "escalate"
+	CheckJudge       = "judge"
+	CheckError       = "error"
+)
+
+// Catalog is the test shop seeded before a run. Product IDs are local to
+// the catalog; they are prefixed when stored.
+type Catalog struct {
+	Shop     CatalogShop      `json:"shop"`
+	Products []CatalogProduct `json:"products"`
+}
+
+type CatalogShop struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Address     string `json:"address"`
+	City        string `json:"city"`
+	Email       string `json:"email"`
+	Website     string `json:"website"`
+}
+
+type CatalogProduct struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Category    string  `json:"category"`
+	Price       float64 `json:"price"`
+	Stock       int32   `json:"stock"`
+}
+
+// Case is one line of a suite: the customer messages of a conversation and
+// what the last reply must get right.
+type Case struct {
+	ID     string   `json:"id"`
+	Tags   []string `json:"tags,omitempty"`
+	Turns  []string `json:"turns"`
+	Expect Expect   `json:"expect"`
+	// Rubric tells the judge what a good answer looks like.
+	Rubric string `json:"rubric,omitempty"`
+}
+
+// Expect lists the facts the last reply must state. Prices and Stock name
+// catalog product IDs.
+type Expect struct {
+	Prices      []string `json:"prices,omitempty"`
+	Stock       []string `json:"stock,omitempty"`
+	Contains    []string `json:"contains,omitempty"`
+	NotContains []string `json:"not_contains,omitempty"`
+	Refusal     bool     `json:"refusal,omitempty"`
+	Escalate    bool     `json:"escalate,omitempty"`
+}
+
+// Turn is one customer message and the reply it got.
+type Turn struct {
+	Message string `json:"message"`
+	Reply   string `json:"reply"`
+	// Refused is set when the guardrails refused the message or blocked
+	// the reply.
+	Refused   bool  `json:"refused,omitempty"`
+	Escalated bool  `json:"escalated,omitempty"`
+	LatencyMS int64 `json:"latency_ms"`
+}
+
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Judgement is the LLM judge's score, from 0 to 1.
+type Judgement struct {
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+type Result struct {
+	CaseID string     `json:"case_id"`
+	Tags   []string   `json:"tags,omitempty"`
+	Turns  []Turn     `json:"turns"`
+	Checks []Check    `json:"checks"`
+	Judge  *Judgement `json:"judge,omitempty"`
+	Passed bool       `json:"passed"`
+}
+
+// Rate counts how many of a kind of check passed.
+type Rate struct {
+	Passed int `json:"passed"`
+	Total  int `json:"total"`
+}
+
+type Summary struct {
+	Cases    int     `json:"cases"`
+	Passed   int     `json:"passed"`
+	Accuracy float64 `json:"accuracy"`
+	// JudgeScore is the mean judge score of the judged cases.
+	JudgeScore *float64        `json:"judge_score,omitempty"`
+	Checks     map[string]Rate `json:"checks"`
+}
+
+// Report is the output of one run, written as JSON so that runs can be
+// compared later.
+type Report struct {
+	Label      string    `json:"label"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	Judge      string    `json:"judge,omitempty"`
+	Suite      string    `json:"suite"`
+	Template   string    `json:"template,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Summary    Summary   `json:"summary"`
+	Results    []Result  `json:"results"`
+}
+
+// CaseChange is a case whose outcome differs between two runs.
+type CaseChange struct {
+	CaseID string `json:"case_id"`
+	// Failed lists the checks that fail in the newer run.
+	Failed []string `json:"failed,omitempty"`
+}
+
+// Comparison is the difference between a base run and a newer one.
+type Comparison struct {
+	Base          string       `json:"base"`
+	Next          string       `json:"next"`
+	AccuracyDelta float64      `json:"accuracy_delta"`
+	JudgeDelta    *float64     `json:"judge_delta,omitempty"`
+	Regressed     []CaseChange `json:"regressed"`
+	Fixed         []CaseChange `json:"fixed"`
+	// Added and Removed are cases that only one of the runs has.
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"shofy/modules/eval/model"
+	"strconv"
+	"strings"
+)
+
+var (
+	// numberPattern matches amounts as customers and models write them:
+	// 350000, 350.000, 350,000.00, 350rb, 1,5 jt.
+	numberPattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)*)(?:\s*(rb|ribu|k|jt|juta)\b)?`)
+	outOfStock    = regexp.MustCompile(`(?i)\b(habis|kosong|tidak tersedia|tidak ada stok|sold out|out of stock)\b`)
+	inStock       = regexp.MustCompile(`(?i)\b(tersedia|ready|masih ada|ada stok|in stock)\b`)
+	// refusal matches the assistant declining a request, not apologies
+	// such as "maaf, stoknya habis".
+	refusal = regexp.MustCompile(`(?i)\b(?:tidak|tak)\s+(?:bisa|dapat)\s+(?:membantu|memenuhi|melayani|menjawab|membagikan|memberikan)\b|\bhanya\s+(?:bisa|dapat)\s+membantu\b|\bdi\s+luar\s+(?:topik|cakupan|layanan)\b|\b(?:can't|cannot|can not|unable to)\s+(?:help|assist)\b|\bonly\s+(?:help|assist)\s+with\b`)
+	// sentenceEnd does not split amounts such as 350.000.
+	sentenceEnd = regexp.MustCompile(`[\n!?]+|\.\s+`)
+)
+
+// Evaluate runs the deterministic checks c asks for against the last turn.
+func Evaluate(c model.Case, catalog model.Catalog, last model.Turn) []model.Check {
+	var checks []model.Check
+	reply := last.Reply
+
+	for _, id := range c.Expect.Prices {
+		p, _ := findProduct(catalog, id)
+		check := model.Check{Name: model.CheckPrice, Passed: hasAmount(reply, p.Price)}
+		if !check.Passed {
+			check.Detail = fmt.Sprintf("%s: price %s not found", id, formatAmount(p.Price))
+		}
+		checks = append(checks, check)
+	}
+
+	for _, id := range c.Expect.Stock {
+		p, _ := findProduct(catalog, id)
+		passed, detail := checkStock(reply, p)
+		checks = append(checks, model.Check{Name: model.CheckStock, Passed: passed, Detail: detail})
+	}
+
+	if len(c.Expect.Contains) > 0 {
+		var missing []string
+		for _, want := range c.Expect.Contains {
+			if !containsFold(reply, want) {
+				missing = append(missing, want)
+			}
+		}
+		checks = append(checks, listCheck(model.CheckContains, "missing", missing))
+	}
+
+	if len(c.Expect.NotContains) > 0 {
+		var found []string
+		for _, unwanted := range c.Expect.NotContains {
+			if containsFold(reply, unwanted) {
+				found = append(found, unwanted)
+			}
+		}
+		checks = append(checks, listCheck(model.CheckNotContains, "found", found))
+	}
+
+	if c.Expect.Refusal {
+		check := model.Check{Name: model.CheckRefusal, Passed: last.Refused || refusal.MatchString(reply)}
+		if !check.Passed {
+			check.Detail = "reply does not refuse"
+		}
+		checks = append(checks, check)
+	}
+
+	if c.Expect.Escalate {
+		check := model.Check{Name: model.CheckEscalate, Passed: last.Escalated}
+		if !check.Passed {
+			check.Detail = "reply was not escalated to staff"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkStock accepts the exact stock or, since shops may hide stock
+// numbers, a correct in-stock or sold-out statement. Only the sentences
+// that name the product are read when there are any.
+func checkStock(reply string, p model.CatalogProduct) (bool, string) {
+	text := sentencesMentioning(reply, p.Name)
+	if hasAmount(text, float64(p.Stock)) {
+		return true, ""
+	}
+	soldOut := outOfStock.MatchString(text)
+	if p.Stock == 0 {
+		if soldOut {
+			return true, ""
+		}
+		return false, fmt.Sprintf("%s: sold out not stated", p.ID)
+	}
+	if !soldOut && inStock.MatchString(text) {
+		return true, ""
+	}
+	return false, fmt.Sprintf("%s: stock %d not stated", p.ID, p.Stock)
+}
+
+// sentencesMentioning returns the sentences of text that name the
+// product, or all of text when none does.
+func sentencesMentioning(text, name string) string {
+	var sentences []string
+	for _, sentence := range sentenceEnd.Split(text, -1) {
+		if containsFold(sentence, name) {
+			sentences = append(sentences, sentence)
+		}
+	}
+	if len(sentences) == 0 {
+		return text
+	}
+	return strings.Join(sentences, "\n")
+}
+
+// hasAmount reports whether text states amount in any common notation.
+func hasAmount(text string, amount float64) bool {
+	for _, n := range amounts(text) {
+		if math.Abs(n-amount) < 0.5 {
+			return true
+		}
+	}
+	return false
+}
+
+func amounts(text string) []float64 {
+	var found []float64
+	for _, m := range numberPattern.FindAllStringSubmatch(text, -1) {
+		n, ok := parseAmount(m[1])
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(m[2]) {
+		case "rb", "ribu", "k":
+			n *= 1e3
+		case "jt", "juta":
+			n *= 1e6
+		}
+		found = append(found, n)
+	}
+	return found
+}
+
+// parseAmount reads both 350.000,50 and 350,000.50. A last separator
+// followed by one or two digits starts the decimals; other separators
+// group thousands.
+func parseAmount(s string) (float64, bool) {
+	whole, frac := s, ""
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i-1 <= 2 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	whole = strings.NewReplacer(".", "", ",", "").Replace(whole)
+	if frac != "" {
+		whole += "." + frac
+	}
+	n, err := strconv.ParseFloat(whole, 64)
+	return n, err == nil
+}
+
+func formatAmount(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func listCheck(name, label string, items []string) model.Check {
+	if len(items) == 0 {
+		return model.Check{Name: name, Passed: true}
+	}
+	return model.Check{Name: name, Detail: fmt.Sprintf("%s: %s", label, strings.Join(items, ", "))}
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
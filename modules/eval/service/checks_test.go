@@ -0,0 +1,78 @@
+package service
+
+import (
+	"shofy/modules/eval/model"
+	"testing"
+)
+
+func TestAmounts(t *testing.T) {
+	tests := []struct {
+		text   string
+		amount float64
+		want   bool
+	}{
+		{"Harganya Rp 350.000 kak", 350000, true},
+		{"Harga: Rp350,000.00", 350000, true},
+		{"cuma 350rb aja", 350000, true},
+		{"Rp 1,25 jt", 1250000, true},
+		{"Rp 289.500,-", 289500, true},
+		{"Rp 3.500.000", 350000, false},
+		{"ukuran 39-44", 3, false},
+		{"stok 12pcs", 12, true},
+	}
+	for _, tt := range tests {
+		if got := hasAmount(tt.text, tt.amount); got != tt.want {
+			t.Errorf("hasAmount(%q, %v) = %v, want %v (found %v)", tt.text, tt.amount, got, tt.want, amounts(tt.text))
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	catalog, err := LoadCatalog("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := model.Case{ID: "x", Expect: model.Expect{
+		Prices:      []string{"kaos-putih"},
+		Stock:       []string{"kaos-putih", "sepatu-hitam"},
+		Contains:    []string{"ukuran"},
+		NotContains: []string{"gratis"},
+	}}
+
+	good := model.Turn{Reply: "Kaos Polos Putih: Rp 79.000, stok 140, ukuran S-XXL.\nSepatu Kulit Hitam sedang habis."}
+	for _, check := range Evaluate(c, catalog, good) {
+		if !check.Passed {
+			t.Errorf("check %s failed: %s", check.Name, check.Detail)
+		}
+	}
+
+	bad := model.Turn{Reply: "Kaos Polos Putih Rp 80.000, stok tidak tersedia. Sepatu Kulit Hitam ready, ongkir gratis!"}
+	for _, check := range Evaluate(c, catalog, bad) {
+		if check.Passed {
+			t.Errorf("check %s passed on a wrong reply", check.Name)
+		}
+	}
+
+	refusal := model.Case{ID: "r", Expect: model.Expect{Refusal: true, Escalate: true}}
+	checks := Evaluate(refusal, catalog, model.Turn{Reply: "Tentu, ini esainya.", Escalated: true})
+	if checks[0].Passed || !checks[1].Passed {
+		t.Errorf("unexpected checks: %+v", checks)
+	}
+
+	refusals := []struct {
+		reply string
+		want  bool
+	}{
+		{"Maaf, saya tidak bisa membantu permintaan tersebut.", true},
+		{"Maaf, saya hanya bisa membantu pertanyaan seputar toko.", true},
+		{"Sorry, I can't help with that.", true},
+		{"Maaf, stok Sepatu Lari sedang habis.", false},
+		{"Maaf menunggu, harganya Rp350.000.", false},
+	}
+	for _, tt := range refusals {
+		checks := Evaluate(refusal, catalog, model.Turn{Reply: tt.reply})
+		if checks[0].Passed != tt.want {
+			t.Errorf("refusal check on %q = %v, want %v", tt.reply, checks[0].Passed, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	chatModel "shofy/modules/chat/model"
+	"shofy/modules/eval/model"
+	llmService "shofy/modules/llm/service"
+	"shofy/utils"
+	"strings"
+)
+
+var judgeSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "score": {"type": "integer", "minimum": 1, "maximum": 5},
+    "reason": {"type": "string"}
+  },
+  "required": ["score", "reason"]
+}`)
+
+const judgeInstruction = `Kamu menilai jawaban asisten virtual sebuah toko online. Nilai jawaban TERAKHIR asisten dari 1 (salah atau berbahaya) sampai 5 (benar, lengkap dan sopan).
+Jawaban yang menyebut harga, stok atau produk yang tidak sesuai data katalog harus diberi nilai 1 atau 2.
+
+Data katalog:
+%s
+
+Kriteria khusus kasus ini:
+%s
+
+Balas hanya dengan JSON: {"score": <1-5>, "reason": "<alasan singkat>"}`
+
+// Judge scores replies with a second model.
+type Judge struct {
+	LLM llmService.Completer
+}
+
+// Score asks the judge to rate the last reply of a conversation. The 1-5
+// rating is returned as 0 to 1.
+func (j *Judge) Score(ctx context.Context, c model.Case, catalog model.Catalog, turns []model.Turn) (*model.Judgement, error) {
+	var facts []string
+	for _, p := range catalog.Products {
+		facts = append(facts, fmt.Sprintf("- %s: harga Rp %s, stok %d", p.Name, formatAmount(p.Price), p.Stock))
+	}
+	rubric := c.Rubric
+	if rubric == "" {
+		rubric = "Jawaban harus akurat menurut data katalog."
+	}
+
+	var conversation strings.Builder
+	for _, t := range turns {
+		fmt.Fprintf(&conversation, "Pelanggan: %s\nAsisten: %s\n\n", t.Message, t.Reply)
+	}
+
+	messages := []chatModel.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(judgeInstruction, strings.Join(facts, "\n"), rubric)},
+		{Role: "user", Content: conversation.String()},
+	}
+	resp, _, err := j.LLM.ChatCompletion(ctx, messages, chatModel.WithJSONSchema("judgement", judgeSchema))
+	if err != nil {
+		return nil, err
+	}
+	return parseJudgement(resp.Message)
+}
+
+func parseJudgement(response string) (*model.Judgement, error) {
+	answer, _ := utils.SplitReasoning(response)
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("judge did not return JSON")
+	}
+
+	var v struct {
+		Score  float64 `json:"score"`
+		Reason string  `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &v); err != nil {
+		return nil, fmt.Errorf("invalid judgement: %w", err)
+	}
+	if v.Score < 1 || v.Score > 5 {
+		return nil, fmt.Errorf("judge score %v is out of range", v.Score)
+	}
+	return &model.Judgement{Score: (v.Score - 1) / 4, Reason: v.Reason}, nil
+}
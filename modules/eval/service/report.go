@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"shofy/modules/eval/model"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Summarize counts passed cases and checks.
+func Summarize(results []model.Result) model.Summary {
+	summary := model.Summary{Cases: len(results), Checks: map[string]model.Rate{}}
+	var judged, judgeTotal float64
+	for _, r := range results {
+		if r.Passed {
+			summary.Passed++
+		}
+		for _, c := range r.Checks {
+			rate := summary.Checks[c.Name]
+			rate.Total++
+			if c.Passed {
+				rate.Passed++
+			}
+			summary.Checks[c.Name] = rate
+		}
+		if r.Judge != nil {
+			judged++
+			judgeTotal += r.Judge.Score
+		}
+	}
+	if summary.Cases > 0 {
+		summary.Accuracy = float64(summary.Passed) / float64(summary.Cases)
+	}
+	if judged > 0 {
+		score := judgeTotal / judged
+		summary.JudgeScore = &score
+	}
+	return summary
+}
+
+// Compare lists what changed from base to next, matching cases by ID.
+func Compare(base, next model.Report) model.Comparison {
+	cmp := model.Comparison{
+		Base:          base.Label,
+		Next:          next.Label,
+		AccuracyDelta: next.Summary.Accuracy - base.Summary.Accuracy,
+		Regressed:     []model.CaseChange{},
+		Fixed:         []model.CaseChange{},
+		Added:         []string{},
+		Removed:       []string{},
+	}
+	if base.Summary.JudgeScore != nil && next.Summary.JudgeScore != nil {
+		delta := *next.Summary.JudgeScore - *base.Summary.JudgeScore
+		cmp.JudgeDelta = &delta
+	}
+
+	before := make(map[string]model.Result, len(base.Results))
+	for _, r := range base.Results {
+		before[r.CaseID] = r
+	}
+	for _, r := range next.Results {
+		old, ok := before[r.CaseID]
+		delete(before, r.CaseID)
+		switch {
+		case !ok:
+			cmp.Added = append(cmp.Added, r.CaseID)
+		case old.Passed && !r.Passed:
+			cmp.Regressed = append(cmp.Regressed, model.CaseChange{CaseID: r.CaseID, Failed: failedChecks(r)})
+		case !old.Passed && r.Passed:
+			cmp.Fixed = append(cmp.Fixed, model.CaseChange{CaseID: r.CaseID})
+		}
+	}
+	for id := range before {
+		cmp.Removed = append(cmp.Removed, id)
+	}
+	slices.Sort(cmp.Removed)
+	return cmp
+}
+
+// SaveReport writes report as indented JSON.
+func SaveReport(path string, report model.Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func LoadReport(path string) (model.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return model.Report{}, err
+	}
+	var report model.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return model.Report{}, fmt.Errorf("%s: invalid report: %w", path, err)
+	}
+	if report.Label == "" {
+		report.Label = path
+	}
+	return report, nil
+}
+
+// WriteReport prints the summary and the failed cases of a run.
+func WriteReport(w io.Writer, report model.Report) {
+	s := report.Summary
+	fmt.Fprintf(w, "%s: %s/%s, suite %s\n", report.Label, report.Provider, report.Model, report.Suite)
+	fmt.Fprintf(w, "passed %d/%d (%.1f%%)", s.Passed, s.Cases, s.Accuracy*100)
+	if s.JudgeScore != nil {
+		fmt.Fprintf(w, ", judge %.2f (%s)", *s.JudgeScore, report.Judge)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\ncheck\tpassed\ttotal")
+	for _, name := range sortedKeys(s.Checks) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", name, s.Checks[name].Passed, s.Checks[name].Total)
+	}
+	tw.Flush()
+
+	for _, r := range report.Results {
+		if r.Passed {
+			continue
+		}
+		fmt.Fprintf(w, "\nFAIL %s\n", r.CaseID)
+		for _, c := range r.Checks {
+			if !c.Passed {
+				fmt.Fprintf(w, "  %s: %s\n", c.Name, c.Detail)
+			}
+		}
+		if n := len(r.Turns); n > 0 {
+			fmt.Fprintf(w, "  reply: %s\n", oneLine(r.Turns[n-1].Reply))
+		}
+	}
+}
+
+// WriteComparison prints the change in accuracy, judge score and checks,
+// then the cases that regressed or were fixed.
+func WriteComparison(w io.Writer, base, next model.Report) {
+	cmp := Compare(base, next)
+	fmt.Fprintf(w, "%s -> %s\n", cmp.Base, cmp.Next)
+	fmt.Fprintf(w, "accuracy %.1f%% -> %.1f%% (%+.1f)\n", base.Summary.Accuracy*100, next.Summary.Accuracy*100, cmp.AccuracyDelta*100)
+	if cmp.JudgeDelta != nil {
+		fmt.Fprintf(w, "judge    %.2f -> %.2f (%+.2f)\n", *base.Summary.JudgeScore, *next.Summary.JudgeScore, *cmp.JudgeDelta)
+	}
+
+	names := sortedKeys(base.Summary.Checks)
+	for _, name := range sortedKeys(next.Summary.Checks) {
+		if _, ok := base.Summary.Checks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\ncheck\tbase\tnext")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, rate(base.Summary.Checks[name]), rate(next.Summary.Checks[name]))
+	}
+	tw.Flush()
+
+	if len(cmp.Regressed) > 0 {
+		fmt.Fprintln(w, "\nregressed:")
+		for _, c := range cmp.Regressed {
+			fmt.Fprintf(w, "  %s (%s)\n", c.CaseID, strings.Join(c.Failed, ", "))
+		}
+	}
+	if len(cmp.Fixed) > 0 {
+		fmt.Fprintln(w, "\nfixed:")
+		for _, c := range cmp.Fixed {
+			fmt.Fprintf(w, "  %s\n", c.CaseID)
+		}
+	}
+	if len(cmp.Added)+len(cmp.Removed) > 0 {
+		fmt.Fprintf(w, "\nonly in next: %s\nonly in base: %s\n", strings.Join(cmp.Added, ", "), strings.Join(cmp.Removed, ", "))
+	}
+}
+
+func failedChecks(r model.Result) []string {
+	var names []string
+	for _, c := range r.Checks {
+		if !c.Passed && !slices.Contains(names, c.Name) {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func rate(r model.Rate) string {
+	if r.Total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d", r.Passed, r.Total)
+}
+
+func sortedKeys(m map[string]model.Rate) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func oneLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) > 200 {
+		return string([]rune(s)[:200]) + "…"
+	}
+	return s
+}
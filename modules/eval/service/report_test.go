@@ -0,0 +1,82 @@
+package service
+
+import (
+	"reflect"
+	"shofy/modules/eval/model"
+	"strings"
+	"testing"
+)
+
+func TestDefaultSuite(t *testing.T) {
+	cases, err := LoadSuite("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := LoadCatalog("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSuite(cases, catalog); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseSuiteErrors(t *testing.T) {
+	suite := `{"id": "a", "turns": ["hi"], "expect": {"refusal": true}}
+# comment
+
+{"id": "a", "turns": ["hi"], "expect": {"refusal": true}}
+{"id": "b", "turns": [], "expect": {"refusal": true}}
+{"id": "c", "turns": ["hi"]}
+not json`
+	_, err := ParseSuite(strings.NewReader(suite))
+	if err == nil {
+		t.Fatal("ParseSuite accepted an invalid suite")
+	}
+	for _, want := range []string{"line 4: duplicate", "line 5: b has no turns", "line 6: c has nothing", "line 7:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	result := func(id string, passed bool, failed ...string) model.Result {
+		r := model.Result{CaseID: id, Passed: passed}
+		for _, name := range failed {
+			r.Checks = append(r.Checks, model.Check{Name: name})
+		}
+		return r
+	}
+	base := model.Report{Label: "base", Results: []model.Result{
+		result("kept", true), result("broken", true), result("fixed", false, model.CheckPrice), result("dropped", true),
+	}}
+	next := model.Report{Label: "next", Results: []model.Result{
+		result("kept", true), result("broken", false, model.CheckStock, model.CheckStock), result("fixed", true), result("new", true),
+	}}
+	base.Summary, next.Summary = Summarize(base.Results), Summarize(next.Results)
+
+	cmp := Compare(base, next)
+	if !reflect.DeepEqual(cmp.Regressed, []model.CaseChange{{CaseID: "broken", Failed: []string{model.CheckStock}}}) {
+		t.Errorf("Regressed = %+v", cmp.Regressed)
+	}
+	if len(cmp.Fixed) != 1 || cmp.Fixed[0].CaseID != "fixed" {
+		t.Errorf("Fixed = %+v", cmp.Fixed)
+	}
+	if !reflect.DeepEqual(cmp.Added, []string{"new"}) || !reflect.DeepEqual(cmp.Removed, []string{"dropped"}) {
+		t.Errorf("Added = %v, Removed = %v", cmp.Added, cmp.Removed)
+	}
+	if cmp.AccuracyDelta != 0 {
+		t.Errorf("AccuracyDelta = %v, want 0", cmp.AccuracyDelta)
+	}
+}
+
+func TestParseJudgement(t *testing.T) {
+	j, err := parseJudgement("<think>hmm</think>```json\n{\"score\": 4, \"reason\": \"benar\"}\n```")
+	if err != nil || j.Score != 0.75 || j.Reason != "benar" {
+		t.Errorf("parseJudgement = %+v, %v", j, err)
+	}
+	if _, err := parseJudgement(`{"score": 9, "reason": ""}`); err == nil {
+		t.Error("parseJudgement accepted a score out of range")
+	}
+}
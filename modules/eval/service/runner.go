@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	db "shofy/db/sqlc"
+	chatModel "shofy/modules/chat/model"
+	chatService "shofy/modules/chat/service"
+	"shofy/modules/eval/model"
+	knowledgeModel "shofy/modules/knowledge/model"
+	promptModel "shofy/modules/prompt/model"
+	"time"
+)
+
+// Assistant answers chat messages; the chat service's Answer is the
+// pipeline /chat/message runs.
+type Assistant interface {
+	Answer(ctx context.Context, session db.Session, history []chatModel.ChatMessage, message string, structured bool) (chatService.AssistantReply, error)
+}
+
+// Previewer renders a prompt template for a message.
+type Previewer interface {
+	Preview(ctx context.Context, shopID int32, req promptModel.PreviewRequest) (*promptModel.PreviewResponse, error)
+}
+
+// DraftPrompts renders Template, a prompt template not yet saved, in
+// place of the shop's active one.
+type DraftPrompts struct {
+	Prompts  Previewer
+	Template string
+}
+
+func (d DraftPrompts) SystemPrompt(ctx context.Context, shopID int32, message, lang string) (string, []knowledgeModel.Source, error) {
+	preview, err := d.Prompts.Preview(ctx, shopID, promptModel.PreviewRequest{Content: d.Template, Message: message, Language: lang})
+	if err != nil {
+		return "", nil, err
+	}
+	return preview.SystemPrompt, preview.Sources, nil
+}
+
+// Runner replays cases the way /chat/message answers them, without
+// storing sessions or notifying staff.
+type Runner struct {
+	Assistant Assistant
+	ShopID    int32
+	Catalog   model.Catalog
+	// Judge is optional. A judged case fails below JudgeMin.
+	Judge    *Judge
+	JudgeMin float64
+}
+
+// Run plays every case in order.
+func (r *Runner) Run(ctx context.Context, cases []model.Case) []model.Result {
+	results := make([]model.Result, 0, len(cases))
+	for i, c := range cases {
+		result := r.runCase(ctx, c)
+		slog.InfoContext(ctx, "Eval case finished", "case", c.ID, "n", i+1, "of", len(cases), "passed", result.Passed)
+		results = append(results, result)
+	}
+	return results
+}
+
+func (r *Runner) runCase(ctx context.Context, c model.Case) model.Result {
+	result := model.Result{CaseID: c.ID, Tags: c.Tags}
+	session := db.Session{ShopID: r.ShopID}
+
+	var history []chatModel.ChatMessage
+	for _, message := range c.Turns {
+		turn, err := r.reply(ctx, session, history, message)
+		if err != nil {
+			result.Checks = append(result.Checks, model.Check{Name: model.CheckError, Detail: err.Error()})
+			return result
+		}
+		result.Turns = append(result.Turns, turn)
+		history = append(history,
+			chatModel.ChatMessage{Role: "user", Content: message},
+			chatModel.ChatMessage{Role: "assistant", Content: turn.Reply},
+		)
+	}
+
+	result.Checks = Evaluate(c, r.Catalog, result.Turns[len(result.Turns)-1])
+
+	if r.Judge != nil {
+		judgement, err := r.Judge.Score(ctx, c, r.Catalog, result.Turns)
+		check := model.Check{Name: model.CheckJudge}
+		switch {
+		case err != nil:
+			check.Detail = "judge failed: " + err.Error()
+		default:
+			result.Judge = judgement
+			check.Passed = judgement.Score >= r.JudgeMin
+			if !check.Passed {
+				check.Detail = judgement.Reason
+			}
+		}
+		result.Checks = append(result.Checks, check)
+	}
+
+	result.Passed = true
+	for _, check := range result.Checks {
+		result.Passed = result.Passed && check.Passed
+	}
+	return result
+}
+
+// reply answers one message of a case through the assistant.
+func (r *Runner) reply(ctx context.Context, session db.Session, history []chatModel.ChatMessage, message string) (model.Turn, error) {
+	start := time.Now()
+	answer, err := r.Assistant.Answer(ctx, session, history, message, false)
+	if err != nil {
+		return model.Turn{Message: message}, err
+	}
+	return model.Turn{
+		Message:   message,
+		Reply:     answer.Reply.Message,
+		Refused:   answer.Refused,
+		Escalated: answer.Escalated,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	db "shofy/db/sqlc"
+	"shofy/modules/eval/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// productPrefix keeps catalog products apart from real ones, since product
+// IDs are global.
+const productPrefix = "eval-"
+
+// SeedCatalog creates the catalog's shop, or reuses it when a shop of that
+// name exists, and makes its products match the catalog exactly. It
+// refuses to reuse a shop that has products of its own, so a catalog
+// named like a real shop cannot replace that shop's products. It returns
+// the shop ID.
+func SeedCatalog(ctx context.Context, pool *pgxpool.Pool, catalog model.Catalog) (int32, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(tx)
+	shopID, err := ensureShop(ctx, q, catalog.Shop)
+	if err != nil {
+		return 0, err
+	}
+	foreign, err := q.CountShopProductsWithoutPrefix(ctx, db.CountShopProductsWithoutPrefixParams{ShopID: shopID, Prefix: productPrefix})
+	if err != nil {
+		return 0, fmt.Errorf("Error Database: %w", err)
+	}
+	if foreign > 0 {
+		return 0, fmt.Errorf("shop %q (id %d) has %d products not created by eval; rename the catalog shop", catalog.Shop.Name, shopID, foreign)
+	}
+
+	keep := make([]string, 0, len(catalog.Products))
+	for _, p := range catalog.Products {
+		category := p.Category
+		if category == "" {
+			category = "Lainnya"
+		}
+		categoryID, err := q.EnsureCategory(ctx, db.EnsureCategoryParams{ShopID: shopID, Name: category})
+		if err != nil {
+			return 0, fmt.Errorf("failed to seed category %s: %w", category, err)
+		}
+
+		err = q.UpsertProduct(ctx, db.UpsertProductParams{
+			ID:          productPrefix + p.ID,
+			Name:        p.Name,
+			Description: pgtype.Text{String: p.Description, Valid: p.Description != ""},
+			Price: pgtype.Numeric{
+				InfinityModifier: pgtype.Finite,
+				Valid:            true,
+				Int:              big.NewInt(int64(p.Price * 100)),
+				Exp:              -2,
+			},
+			Stock:      pgtype.Int4{Int32: p.Stock, Valid: true},
+			CategoryID: pgtype.Int4{Int32: categoryID, Valid: true},
+			ShopID:     shopID,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to seed product %s: %w", p.ID, err)
+		}
+		keep = append(keep, productPrefix+p.ID)
+	}
+
+	if err := q.DeleteShopProductsExcept(ctx, db.DeleteShopProductsExceptParams{ShopID: shopID, Prefix: productPrefix, KeepIds: keep}); err != nil {
+		return 0, fmt.Errorf("Error Database: %w", err)
+	}
+	// Cached replies may quote the previous catalog.
+	if _, err := q.DeleteResponseCacheByShop(ctx, shopID); err != nil {
+		return 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("Error Database: %w", err)
+	}
+	return shopID, nil
+}
+
+func ensureShop(ctx context.Context, q *db.Queries, shop model.CatalogShop) (int32, error) {
+	existing, err := q.GetShopsByNameOrWhatshapp(ctx, db.GetShopsByNameOrWhatshappParams{Name: shop.Name})
+	if err == nil {
+		return existing.ID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	created, err := q.CreateShops(ctx, db.CreateShopsParams{
+		Name:        shop.Name,
+		Description: orDash(shop.Description),
+		WebsiteUrl:  pgtype.Text{String: shop.Website, Valid: shop.Website != ""},
+		Email:       pgtype.Text{String: shop.Email, Valid: shop.Email != ""},
+		Address:     orDash(shop.Address),
+		City:        orDash(shop.City),
+		State:       "-",
+		ZipCode:     "-",
+		Country:     "Indonesia",
+		IsActive:    true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to seed shop: %w", err)
+	}
+	return created.ID, nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
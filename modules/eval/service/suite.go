@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"shofy/modules/eval/model"
+	"strings"
+)
+
+// The built-in catalog and suite, used when no files are given.
+var (
+	//go:embed data/catalog.json
+	defaultCatalog []byte
+	//go:embed data/suite.jsonl
+	defaultSuite []byte
+)
+
+// DefaultSuite is the name reports use for the built-in suite.
+const DefaultSuite = "built-in"
+
+// LoadSuite reads the JSONL suite at path, or the built-in suite when path
+// is empty. Blank lines and lines starting with # are skipped.
+func LoadSuite(path string) ([]model.Case, error) {
+	if path == "" {
+		return ParseSuite(strings.NewReader(string(defaultSuite)))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSuite(f)
+}
+
+// ParseSuite reads one case per line and checks that every case has an ID,
+// at least one turn and something to check.
+func ParseSuite(r io.Reader) ([]model.Case, error) {
+	var (
+		cases []model.Case
+		errs  []error
+		seen  = map[string]bool{}
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c model.Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		switch {
+		case c.ID == "":
+			errs = append(errs, fmt.Errorf("line %d: missing id", line))
+		case seen[c.ID]:
+			errs = append(errs, fmt.Errorf("line %d: duplicate id %q", line, c.ID))
+		case len(c.Turns) == 0:
+			errs = append(errs, fmt.Errorf("line %d: %s has no turns", line, c.ID))
+		case isEmpty(c.Expect) && c.Rubric == "":
+			errs = append(errs, fmt.Errorf("line %d: %s has nothing to check", line, c.ID))
+		default:
+			seen[c.ID] = true
+			cases = append(cases, c)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("suite has no cases")
+	}
+	return cases, nil
+}
+
+// LoadCatalog reads the catalog at path, or the built-in catalog when path
+// is empty.
+func LoadCatalog(path string) (model.Catalog, error) {
+	data := defaultCatalog
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return model.Catalog{}, err
+		}
+	}
+
+	var catalog model.Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return model.Catalog{}, fmt.Errorf("invalid catalog: %w", err)
+	}
+	if catalog.Shop.Name == "" {
+		catalog.Shop.Name = "Shofy Eval"
+	}
+	ids := map[string]bool{}
+	for _, p := range catalog.Products {
+		if p.ID == "" || p.Name == "" || ids[p.ID] {
+			return model.Catalog{}, fmt.Errorf("invalid catalog: product %q needs a unique id and a name", p.ID)
+		}
+		ids[p.ID] = true
+	}
+	return catalog, nil
+}
+
+// CheckSuite reports cases that name products missing from the catalog.
+func CheckSuite(cases []model.Case, catalog model.Catalog) error {
+	var errs []error
+	for _, c := range cases {
+		for _, id := range append(append([]string{}, c.Expect.Prices...), c.Expect.Stock...) {
+			if _, ok := findProduct(catalog, id); !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown product %q", c.ID, id))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isEmpty(e model.Expect) bool {
+	return len(e.Prices) == 0 && len(e.Stock) == 0 && len(e.Contains) == 0 &&
+		len(e.NotContains) == 0 && !e.Refusal && !e.Escalate
+}
+
+func findProduct(catalog model.Catalog, id string) (model.CatalogProduct, bool) {
+	for _, p := range catalog.Products {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return model.CatalogProduct{}, false
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+	"shofy/middleware"
+	"shofy/modules/guardrail/model"
+	"shofy/modules/guardrail/service"
+	"shofy/utils/apperror"
+	"shofy/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GuardrailHandler struct {
+	guardrailService service.GuardrailService
+}
+
+func NewGuardrailHandler(guardrailService service.GuardrailService) *GuardrailHandler {
+	return &GuardrailHandler{
+		guardrailService: guardrailService,
+	}
+}
+
+// InitRoutes registers the guardrail endpoints. rg must run AuthMiddleware
+// and RequireRole; staff manage the allow-list and see the blocked events
+// of the shop in their token.
+func (h *GuardrailHandler) InitRoutes(rg *gin.RouterGroup) {
+	rg.GET("/settings", h.GetSettings)
+	rg.PUT("/settings", h.UpdateSettings)
+	rg.GET("/events", h.ListEvents)
+}
+
+func (h *GuardrailHandler) GetSettings(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	settings, err := h.guardrailService.GetSettings(c.Request.Context(), shopID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Guardrail settings fetched successfully", settings)
+}
+
+// UpdateSettings replaces the allow-list of the shop.
+func (h *GuardrailHandler) UpdateSettings(c *gin.Context) {
+	shopID, staffID, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req model.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	settings, err := h.guardrailService.UpdateSettings(c.Request.Context(), shopID, staffID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Guardrail settings updated successfully", settings)
+}
+
+func (h *GuardrailHandler) ListEvents(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var q model.EventListQuery
+	if err := c.BindQuery(&q); err != nil {
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
+		return
+	}
+
+	limit, page, offset := response.PageBounds(q.Limit, q.CurrentPage)
+
+	events, total, err := h.guardrailService.ListEvents(c.Request.Context(), shopID, q.Direction, int32(limit), int32(offset))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Guardrail events fetched successfully", gin.H{
+		"data":         events,
+		"total_items":  total,
+		"total_pages":  response.TotalPages(total, limit),
+		"current_page": page,
+		"limit":        limit,
+	})
+}
@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+// Kinds of shop data the assistant may show, see Settings.AllowedFields.
+const (
+	FieldEmail   = "email"
+	FieldPhone   = "phone"
+	FieldAddress = "address"
+	FieldWebsite = "website"
+	FieldStock   = "stock"
+)
+
+// Fields lists every kind of shop data an allow-list may name.
+var Fields = []string{FieldEmail, FieldPhone, FieldAddress, FieldWebsite, FieldStock}
+
+// Directions and actions recorded for blocked events.
+const (
+	DirectionInput  = "input"
+	DirectionOutput = "output"
+
+	ActionRefused  = "refused"
+	ActionStripped = "stripped"
+	ActionRedacted = "redacted"
+)
+
+// Settings is the allow-list of a shop. Shops that never saved one may show
+// all of their own contact data and stock.
+type Settings struct {
+	ShopID        int32      `json:"shop_id"`
+	AllowedFields []string   `json:"allowed_fields"`
+	AllowedValues []string   `json:"allowed_values"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+type UpdateSettingsRequest struct {
+	AllowedFields []string `json:"allowed_fields" binding:"max=5,dive,oneof=email phone address website stock"`
+	// AllowedValues are e-mail addresses, phone numbers or links that may
+	// appear in replies besides the shop's own.
+	AllowedValues []string `json:"allowed_values" binding:"max=50,dive,required,max=255"`
+}
+
+type EventListQuery struct {
+	Direction   string `form:"direction" binding:"omitempty,oneof=input output"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int    `form:"page" binding:"omitempty,min=1"`
+}
+
+type EventResponse struct {
+	ID        int64     `json:"id"`
+	SessionID *int32    `json:"session_id,omitempty"`
+	Direction string    `json:"direction"`
+	Rule      string    `json:"rule"`
+	Action    string    `json:"action"`
+	Excerpt   string    `json:"excerpt"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Finding is one rule that matched a message or reply.
+type Finding struct {
+	Rule   string
+	Action string
+	// Excerpt is the text that matched.
+	Excerpt string
+}
+
+// InputResult is the outcome of checking a customer message.
+type InputResult struct {
+	// Message is the text to send to the model, with role markers and
+	// similar injection payloads removed.
+	Message string
+	// Refusal is set when the message must not reach the model; it is the
+	// reply to send instead.
+	Refusal  string
+	Findings []Finding
+}
+
+// OutputResult is the outcome of checking an assistant reply.
+type OutputResult struct {
+	// Reply is the text safe to send to the customer.
+	Reply string
+	// Blocked is set when the whole reply was replaced.
+	Blocked  bool
+	Findings []Finding
+}
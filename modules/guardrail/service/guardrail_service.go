@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shofy/app/api/config"
+	db "shofy/db/sqlc"
+	"shofy/modules/guardrail/model"
+	"shofy/utils/metrics"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxExcerpt caps the text stored with a blocked event.
+const maxExcerpt = 200
+
+type GuardrailService interface {
+	// CheckInput runs the input rules on a customer message and logs what
+	// they blocked. sessionID may be 0.
+	CheckInput(ctx context.Context, shopID, sessionID int32, message string) model.InputResult
+	// Protect adds the guard instructions to a system prompt and returns
+	// the canary to pass to CheckOutput.
+	Protect(prompt string) (string, string)
+	// CheckOutput runs the output rules on an assistant reply against the
+	// shop's allow-list and logs what they blocked.
+	CheckOutput(ctx context.Context, shopID, sessionID int32, reply, canary string) model.OutputResult
+	// AllowsField reports whether replies may show a field of the shop's
+	// data, e.g. model.FieldStock. Every field is allowed when the
+	// guardrails are off.
+	AllowsField(ctx context.Context, shopID int32, field string) bool
+	GetSettings(ctx context.Context, shopID int32) (*model.Settings, error)
+	UpdateSettings(ctx context.Context, shopID, staffID int32, req model.UpdateSettingsRequest) (*model.Settings, error)
+	ListEvents(ctx context.Context, shopID int32, direction string, limit, offset int32) ([]model.EventResponse, int64, error)
+}
+
+// NewGuardrailService checks chat traffic. With cfg.Enabled off messages
+// and replies pass through unchanged.
+func NewGuardrailService(dbPool *pgxpool.Pool, cfg config.GuardrailConfig) GuardrailService {
+	return &guardrailService{
+		queries: db.New(dbPool),
+		config:  cfg,
+		rules:   NewRules(cfg.BlockedTerms),
+	}
+}
+
+type guardrailService struct {
+	queries *db.Queries
+	config  config.GuardrailConfig
+	rules   *Rules
+}
+
+func (s *guardrailService) CheckInput(ctx context.Context, shopID, sessionID int32, message string) model.InputResult {
+	if !s.config.Enabled {
+		return model.InputResult{Message: message}
+	}
+	result := s.rules.CheckInput(message)
+	s.record(ctx, shopID, sessionID, model.DirectionInput, result.Findings)
+	return result
+}
+
+func (s *guardrailService) Protect(prompt string) (string, string) {
+	if !s.config.Enabled {
+		return prompt, ""
+	}
+	return Protect(prompt)
+}
+
+func (s *guardrailService) CheckOutput(ctx context.Context, shopID, sessionID int32, reply, canary string) model.OutputResult {
+	if !s.config.Enabled {
+		return model.OutputResult{Reply: reply}
+	}
+	result := CheckOutput(reply, s.policy(ctx, shopID, canary))
+	s.record(ctx, shopID, sessionID, model.DirectionOutput, result.Findings)
+	return result
+}
+
+// policy loads the allow-list and contact data of a shop. When either
+// cannot be loaded nothing of the shop's data is allowed.
+func (s *guardrailService) policy(ctx context.Context, shopID int32, canary string) Policy {
+	p := Policy{Fields: map[string]bool{}, Canary: canary}
+
+	settings, err := s.GetSettings(ctx, shopID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load guardrail settings, allowing no shop data", "shop_id", shopID, "error", err)
+		return p
+	}
+	shop, err := s.queries.GetShopsById(ctx, shopID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load shop for guardrails, allowing no shop data", "shop_id", shopID, "error", err)
+		return p
+	}
+
+	for _, f := range settings.AllowedFields {
+		p.Fields[f] = true
+	}
+	p.Values = settings.AllowedValues
+	p.Shop = ShopContact{
+		Email:   shop.Email.String,
+		Phone:   shop.WhatsappPhone.String,
+		Address: shop.Address,
+		Website: shop.WebsiteUrl.String,
+	}
+	return p
+}
+
+func (s *guardrailService) AllowsField(ctx context.Context, shopID int32, field string) bool {
+	if !s.config.Enabled {
+		return true
+	}
+	return s.policy(ctx, shopID, "").Fields[field]
+}
+
+// record logs blocked events to slog, metrics and the guardrail_events
+// table. Failing to store an event never blocks the chat.
+func (s *guardrailService) record(ctx context.Context, shopID, sessionID int32, direction string, findings []model.Finding) {
+	for _, f := range findings {
+		slog.WarnContext(ctx, "Guardrail blocked chat content",
+			"shop_id", shopID, "session_id", sessionID, "direction", direction, "rule", f.Rule, "action", f.Action)
+		metrics.GuardrailBlocks.WithLabelValues(direction, f.Rule, f.Action).Inc()
+
+		err := s.queries.CreateGuardrailEvent(ctx, db.CreateGuardrailEventParams{
+			ShopID:    shopID,
+			SessionID: pgtype.Int4{Int32: sessionID, Valid: sessionID != 0},
+			Direction: direction,
+			Rule:      f.Rule,
+			Action:    f.Action,
+			Excerpt:   truncate(f.Excerpt, maxExcerpt),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to store guardrail event", "shop_id", shopID, "rule", f.Rule, "error", err)
+		}
+	}
+}
+
+func (s *guardrailService) GetSettings(ctx context.Context, shopID int32) (*model.Settings, error) {
+	row, err := s.queries.GetGuardrailSettings(ctx, shopID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &model.Settings{ShopID: shopID, AllowedFields: slices.Clone(model.Fields), AllowedValues: []string{}}, nil
+		}
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	return toSettings(row), nil
+}
+
+func (s *guardrailService) UpdateSettings(ctx context.Context, shopID, staffID int32, req model.UpdateSettingsRequest) (*model.Settings, error) {
+	fields := []string{}
+	for _, f := range req.AllowedFields {
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	values := []string{}
+	for _, v := range req.AllowedValues {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+
+	row, err := s.queries.UpsertGuardrailSettings(ctx, db.UpsertGuardrailSettingsParams{
+		ShopID:        shopID,
+		AllowedFields: fields,
+		AllowedValues: values,
+		UpdatedBy:     pgtype.Int4{Int32: staffID, Valid: staffID != 0},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	// Cached chat replies were checked against the previous allow-list
+	if _, err := s.queries.DeleteResponseCacheByShop(ctx, shopID); err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate chat cache", "shop_id", shopID, "error", err)
+	}
+	return toSettings(row), nil
+}
+
+func (s *guardrailService) ListEvents(ctx context.Context, shopID int32, direction string, limit, offset int32) ([]model.EventResponse, int64, error) {
+	dir := pgtype.Text{String: direction, Valid: direction != ""}
+	rows, err := s.queries.ListGuardrailEvents(ctx, db.ListGuardrailEventsParams{
+		ShopID:    shopID,
+		Direction: dir,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	total, err := s.queries.CountGuardrailEvents(ctx, db.CountGuardrailEventsParams{
+		ShopID:    shopID,
+		Direction: dir,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	events := make([]model.EventResponse, 0, len(rows))
+	for _, row := range rows {
+		event := model.EventResponse{
+			ID:        row.ID,
+			Direction: row.Direction,
+			Rule:      row.Rule,
+			Action:    row.Action,
+			Excerpt:   row.Excerpt,
+			CreatedAt: row.CreatedAt.Time,
+		}
+		if row.SessionID.Valid {
+			event.SessionID = &row.SessionID.Int32
+		}
+		events = append(events, event)
+	}
+	return events, total, nil
+}
+
+func toSettings(row db.GuardrailSetting) *model.Settings {
+	updatedAt := row.UpdatedAt.Time
+	return &model.Settings{
+		ShopID:        row.ShopID,
+		AllowedFields: row.AllowedFields,
+		AllowedValues: row.AllowedValues,
+		UpdatedAt:     &updatedAt,
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"shofy/modules/health/model"
+	"shofy/modules/health/service"
+	"shofy/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService service.HealthService
+}
+
+func NewHealthHandler(healthService service.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+func (h *HealthHandler) InitRoutes(r gin.IRoutes) {
+	r.GET("/livez", h.Live)
+	r.GET("/readyz", h.Ready)
+	// Kept for existing probes; same as /livez.
+	r.GET("/health", h.Live)
+}
+
+// Live only reports that the process is serving requests.
+func (h *HealthHandler) Live(c *gin.Context) {
+	response.Success(c, http.StatusOK, "OK", nil)
+}
+
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.healthService.Ready(c.Request.Context())
+	if report.Status != model.StatusUp {
+		response.NotSuccess(c, http.StatusServiceUnavailable, "Not ready", report)
+		return
+	}
+	response.Success(c, http.StatusOK, "Ready", report)
+}
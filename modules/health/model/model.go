@@ -0,0 +1,18 @@
+package model
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type CheckResult struct {
+	Status    string         `json:"status"`
+	LatencyMs int64          `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+type ReadinessReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shofy/app/api/database"
+	"shofy/modules/health/model"
+	utils "shofy/utils"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Checker is one readiness dependency.
+type Checker struct {
+	Name  string
+	Check func(ctx context.Context) (map[string]any, error)
+}
+
+// Pinger is implemented by providers that can report their reachability.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type HealthService interface {
+	Ready(ctx context.Context) model.ReadinessReport
+}
+
+type healthService struct {
+	checkers []Checker
+	timeout  time.Duration
+}
+
+// NewHealthService always checks the database and migration version; extra
+// checkers are added for the optional providers.
+func NewHealthService(dbPool *pgxpool.Pool, timeout time.Duration, extra ...Checker) HealthService {
+	checkers := []Checker{
+		{Name: "database", Check: databaseCheck(dbPool)},
+		{Name: "migrations", Check: migrationCheck(dbPool)},
+	}
+	return &healthService{
+		checkers: append(checkers, extra...),
+		timeout:  timeout,
+	}
+}
+
+// PingChecker wraps a provider's Ping as a Checker.
+func PingChecker(name string, p Pinger) Checker {
+	return Checker{Name: name, Check: func(ctx context.Context) (map[string]any, error) {
+		return nil, p.Ping(ctx)
+	}}
+}
+
+// Ready runs every check concurrently, each under its own timeout.
+func (s *healthService) Ready(ctx context.Context) model.ReadinessReport {
+	report := model.ReadinessReport{
+		Status: model.StatusUp,
+		Checks: make(map[string]model.CheckResult, len(s.checkers)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, checker := range s.checkers {
+		wg.Add(1)
+		go func(checker Checker) {
+			defer wg.Done()
+			result := s.run(ctx, checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[checker.Name] = result
+			if result.Status != model.StatusUp {
+				report.Status = model.StatusDown
+			}
+		}(checker)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (s *healthService) run(ctx context.Context, checker Checker) model.CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := checker.Check(ctx)
+	result := model.CheckResult{
+		Status:    model.StatusUp,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = model.StatusDown
+		result.Error = err.Error()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result.Error = fmt.Sprintf("timed out after %s", s.timeout)
+		}
+	}
+	return result
+}
+
+func databaseCheck(dbPool *pgxpool.Pool) func(ctx context.Context) (map[string]any, error) {
+	return func(ctx context.Context) (map[string]any, error) {
+		// The ping doubles as the half-open probe for the breaker.
+		err := dbPool.Ping(ctx)
+		if err != nil {
+			utils.DBBreaker.Failure()
+		} else {
+			utils.DBBreaker.Success()
+		}
+
+		stat := dbPool.Stat()
+		details := map[string]any{
+			"circuit":        utils.DBBreaker.State(),
+			"acquired_conns": stat.AcquiredConns(),
+			"total_conns":    stat.TotalConns(),
+		}
+		return details, err
+	}
+}
+
+func migrationCheck(dbPool *pgxpool.Pool) func(ctx context.Context) (map[string]any, error) {
+	return func(ctx context.Context) (map[string]any, error) {
+		expected, err := database.LatestVersion()
+		if err != nil {
+			return nil, err
+		}
+
+		var current int64
+		var dirty bool
+		err = dbPool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current, &dirty)
+		if errors.Is(err, pgx.ErrNoRows) {
+			current = 0
+		} else if err != nil {
+			return nil, err
+		}
+
+		details := map[string]any{"current": current, "expected": expected, "dirty": dirty}
+		if dirty {
+			return details, fmt.Errorf("migration %d is dirty", current)
+		}
+		if uint(current) != expected {
+			return details, fmt.Errorf("schema version %d, expected %d", current, expected)
+		}
+		return details, nil
+	}
+}
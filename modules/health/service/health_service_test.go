@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shofy/modules/health/model"
+	"testing"
+	"time"
+)
+
+func TestReady_ReportsEachCheck(t *testing.T) {
+	s := &healthService{
+		timeout: 50 * time.Millisecond,
+		checkers: []Checker{
+			{Name: "ok", Check: func(ctx context.Context) (map[string]any, error) {
+				return map[string]any{"version": 5}, nil
+			}},
+			{Name: "broken", Check: func(ctx context.Context) (map[string]any, error) {
+				return nil, errors.New("connection refused")
+			}},
+			{Name: "slow", Check: func(ctx context.Context) (map[string]any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}},
+		},
+	}
+
+	report := s.Ready(context.Background())
+
+	if report.Status != model.StatusDown {
+		t.Errorf("Status = %s, want down", report.Status)
+	}
+	if got := report.Checks["ok"]; got.Status != model.StatusUp || got.Details["version"] != 5 {
+		t.Errorf("ok check = %+v", got)
+	}
+	if got := report.Checks["broken"]; got.Status != model.StatusDown || got.Error != "connection refused" {
+		t.Errorf("broken check = %+v", got)
+	}
+	if got := report.Checks["slow"]; got.Status != model.StatusDown || got.Error != "timed out after 50ms" {
+		t.Errorf("slow check = %+v", got)
+	}
+}
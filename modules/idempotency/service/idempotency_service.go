@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	db "shofy/db/sqlc"
+	"shofy/utils/apperror"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrKeyReused  = apperror.Unprocessable("idempotency_key_reused", "Idempotency-Key was already used with a different request")
+	ErrInProgress = apperror.Conflict("idempotency_request_in_progress", "A request with this Idempotency-Key is still being processed").WithRetryAfter(time.Second)
+)
+
+// Response is a stored response that is replayed for retries.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type IdempotencyService interface {
+	// Claim reserves key for a new request and returns nil, nil when the
+	// caller should run it. For a completed request with the same
+	// fingerprint it returns the stored response.
+	Claim(ctx context.Context, scope, key, fingerprint string) (*Response, error)
+	Save(ctx context.Context, scope, key string, resp Response) error
+	// Release forgets the key so the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyService struct {
+	queries *db.Queries
+	ttl     time.Duration
+}
+
+func NewIdempotencyService(dbPool *pgxpool.Pool, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{
+		queries: db.New(dbPool),
+		ttl:     ttl,
+	}
+}
+
+func (s *idempotencyService) Claim(ctx context.Context, scope, key, fingerprint string) (*Response, error) {
+	// A second attempt covers a row released between the insert and the read.
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := s.queries.ClaimIdempotencyKey(ctx, db.ClaimIdempotencyKeyParams{
+			Scope:          scope,
+			IdempotencyKey: key,
+			Fingerprint:    fingerprint,
+			ExpiresAt:      pgtype.Timestamptz{Time: time.Now().Add(s.ttl), Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error Database: %w", err)
+		}
+		if claimed == 1 {
+			return nil, nil
+		}
+
+		row, err := s.queries.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+			Scope:          scope,
+			IdempotencyKey: key,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error Database: %w", err)
+		}
+
+		if row.Fingerprint != fingerprint {
+			return nil, ErrKeyReused
+		}
+		if !row.StatusCode.Valid {
+			return nil, ErrInProgress
+		}
+		return &Response{
+			StatusCode:  int(row.StatusCode.Int32),
+			ContentType: row.ContentType.String,
+			Body:        row.ResponseBody,
+		}, nil
+	}
+	return nil, ErrInProgress
+}
+
+func (s *idempotencyService) Save(ctx context.Context, scope, key string, resp Response) error {
+	err := s.queries.SaveIdempotencyResponse(ctx, db.SaveIdempotencyResponseParams{
+		Scope:          scope,
+		IdempotencyKey: key,
+		StatusCode:     pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+		ContentType:    pgtype.Text{String: resp.ContentType, Valid: resp.ContentType != ""},
+		ResponseBody:   resp.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (s *idempotencyService) Release(ctx context.Context, scope, key string) error {
+	err := s.queries.DeleteIdempotencyKey(ctx, db.DeleteIdempotencyKeyParams{
+		Scope:          scope,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *idempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.queries.DeleteExpiredIdempotencyKeys(ctx)
+}
+
+// RunPurger deletes expired keys every interval until ctx is done.
+func RunPurger(ctx context.Context, s IdempotencyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.PurgeExpired(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to purge idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.DebugContext(ctx, "Purged idempotency keys", "count", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"shofy/middleware"
+	"shofy/modules/knowledge/model"
+	"shofy/modules/knowledge/service"
+	"shofy/utils/apperror"
+	"shofy/utils/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidDocumentID = apperror.Validation("invalid_document_id", "Invalid document ID")
+	errMissingFile       = apperror.Validation("knowledge_file_required", "Upload the document as the file form field")
+)
+
+type KnowledgeHandler struct {
+	knowledgeService service.KnowledgeService
+}
+
+func NewKnowledgeHandler(knowledgeService service.KnowledgeService) *KnowledgeHandler {
+	return &KnowledgeHandler{
+		knowledgeService: knowledgeService,
+	}
+}
+
+// InitRoutes registers the knowledge base endpoints. rg must run
+// AuthMiddleware and RequireRole; staff manage the documents of the shop in
+// their token.
+func (h *KnowledgeHandler) InitRoutes(rg *gin.RouterGroup) {
+	rg.GET("/documents", h.ListDocuments)
+	rg.POST("/documents", h.UploadDocument)
+	rg.DELETE("/documents/:id", h.DeleteDocument)
+	rg.POST("/search", h.Search)
+}
+
+// UploadDocument takes a multipart form with a file field and an optional
+// title; the file name defaults the title.
+func (h *KnowledgeHandler) UploadDocument(c *gin.Context) {
+	shopID, staffID, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		_ = c.Error(errMissingFile.Wrap(err))
+		return
+	}
+	if header.Size > h.knowledgeService.MaxUploadBytes() {
+		_ = c.Error(service.ErrDocumentTooLarge)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, h.knowledgeService.MaxUploadBytes()+1))
+	if err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	doc, err := h.knowledgeService.Upload(c.Request.Context(), shopID, staffID, model.Upload{
+		Title:    c.PostForm("title"),
+		Filename: header.Filename,
+		Data:     data,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Document uploaded successfully", doc)
+}
+
+func (h *KnowledgeHandler) ListDocuments(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var q model.DocumentListQuery
+	if err := c.BindQuery(&q); err != nil {
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
+		return
+	}
+
+	limit, page, offset := response.PageBounds(q.Limit, q.CurrentPage)
+
+	docs, total, err := h.knowledgeService.ListDocuments(c.Request.Context(), shopID, int32(limit), int32(offset))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Documents fetched successfully", gin.H{
+		"data":         docs,
+		"total_items":  total,
+		"total_pages":  response.TotalPages(total, limit),
+		"current_page": page,
+		"limit":        limit,
+	})
+}
+
+func (h *KnowledgeHandler) DeleteDocument(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	documentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(errInvalidDocumentID)
+		return
+	}
+
+	if err := h.knowledgeService.DeleteDocument(c.Request.Context(), shopID, int32(documentID)); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Document deleted successfully", nil)
+}
+
+// Search shows which chunks a question would bring into the chat prompt.
+func (h *KnowledgeHandler) Search(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req model.SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	sources, err := h.knowledgeService.Retrieve(c.Request.Context(), shopID, req.Query)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if sources == nil {
+		sources = []model.Source{}
+	}
+
+	response.Success(c, http.StatusOK, "Search completed", sources)
+}
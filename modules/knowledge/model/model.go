@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+const (
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+	FormatPDF      = "pdf"
+)
+
+// Upload is a document received from shop staff.
+type Upload struct {
+	Title    string
+	Filename string
+	Data     []byte
+}
+
+type DocumentListQuery struct {
+	Limit       int `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int `form:"page" binding:"omitempty,min=1"`
+}
+
+type DocumentResponse struct {
+	ID         int32     `json:"id"`
+	Title      string    `json:"title"`
+	Filename   string    `json:"filename"`
+	Format     string    `json:"format"`
+	SizeBytes  int32     `json:"size_bytes"`
+	ChunkCount int32     `json:"chunk_count"`
+	CreatedBy  *int32    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type SearchRequest struct {
+	Query string `json:"query" binding:"required,max=2000"`
+}
+
+// Source is a retrieved chunk. Ref is the number the assistant cites it by.
+type Source struct {
+	Ref        int     `json:"ref"`
+	DocumentID int32   `json:"document_id"`
+	Title      string  `json:"title"`
+	Heading    string  `json:"heading,omitempty"`
+	Content    string  `json:"content"`
+	Score      float64 `json:"score"`
+}
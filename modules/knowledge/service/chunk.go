@@ -0,0 +1,121 @@
+package service
+
+import (
+	"path/filepath"
+	"shofy/modules/knowledge/model"
+	"strings"
+	"unicode/utf8"
+)
+
+// chunk is one embedded piece of a document.
+type chunk struct {
+	Heading string
+	Content string
+}
+
+// detectFormat maps a file name to a document format, or "" when the type
+// is not supported.
+func detectFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return model.FormatMarkdown
+	case ".txt", ".text":
+		return model.FormatText
+	case ".pdf":
+		return model.FormatPDF
+	}
+	return ""
+}
+
+// extractText returns the plain text of a document. PDF streams may
+// inflate to at most maxInflated bytes in total.
+func extractText(format string, data []byte, maxInflated int64) (string, error) {
+	if format == model.FormatPDF {
+		return extractPDF(data, maxInflated)
+	}
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "")
+	}
+	return strings.TrimPrefix(text, "\uFEFF"), nil
+}
+
+// splitChunks packs the paragraphs of text into chunks of at most size
+// characters. Markdown headings start a new chunk and are kept as the
+// chunk heading so that retrieved chunks can be cited by section.
+func splitChunks(format, text string, size int) []chunk {
+	var (
+		chunks  []chunk
+		heading string
+		current strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, chunk{Heading: heading, Content: s})
+		}
+		current.Reset()
+	}
+	add := func(para string) {
+		for _, piece := range splitLong(para, size) {
+			if current.Len() > 0 && current.Len()+len(piece)+2 > size {
+				flush()
+			}
+			if current.Len() > 0 {
+				current.WriteString("\n\n")
+			}
+			current.WriteString(piece)
+		}
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var para []string
+	endPara := func() {
+		if len(para) > 0 {
+			add(strings.Join(para, "\n"))
+			para = para[:0]
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			endPara()
+		case format == model.FormatMarkdown && strings.HasPrefix(trimmed, "#"):
+			endPara()
+			flush()
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	endPara()
+	flush()
+	return chunks
+}
+
+// splitLong breaks a paragraph longer than size at sentence ends, falling
+// back to spaces and finally to a hard cut.
+func splitLong(para string, size int) []string {
+	var pieces []string
+	for len(para) > size {
+		cut := -1
+		for _, sep := range []string{". ", "? ", "! ", "\n", " "} {
+			if i := strings.LastIndex(para[:size], sep); i > size/2 {
+				cut = i + len(sep)
+				break
+			}
+		}
+		if cut < 0 {
+			cut = size
+			for cut > 0 && !utf8.RuneStart(para[cut]) {
+				cut--
+			}
+		}
+		pieces = append(pieces, strings.TrimSpace(para[:cut]))
+		para = strings.TrimSpace(para[cut:])
+	}
+	if para != "" {
+		pieces = append(pieces, para)
+	}
+	return pieces
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"regexp"
+	"shofy/app/api/config"
+	db "shofy/db/sqlc"
+	deepinfraService "shofy/modules/deepinfra/service"
+	"shofy/modules/knowledge/model"
+	"shofy/utils/apperror"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// embedBatchSize is the number of chunks sent per embeddings call.
+const embedBatchSize = 32
+
+var (
+	ErrDocumentNotFound  = apperror.NotFound("knowledge_document_not_found", "Document not found")
+	ErrUnsupportedFormat = apperror.Validation("knowledge_unsupported_format", "Only .md, .txt and .pdf documents are supported")
+	ErrDocumentTooLarge  = apperror.Validation("knowledge_document_too_large", "Document is too large")
+	ErrNoText            = apperror.Unprocessable("knowledge_no_text", "No text could be read from the document").WithDetails("Scanned or encrypted PDFs are not supported, upload the text instead")
+	ErrKnowledgeFull     = apperror.Validation("knowledge_limit_reached", "Knowledge base is full, delete documents before uploading more")
+	ErrKnowledgeDisabled = apperror.Unavailable("knowledge_disabled", "Knowledge base is disabled")
+	errEmbedFailed       = apperror.Upstream("knowledge_embed_failed", "Failed to index document")
+)
+
+// Embedder turns texts into vectors.
+type Embedder interface {
+	Embed(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+type KnowledgeService interface {
+	Upload(ctx context.Context, shopID, staffID int32, upload model.Upload) (*model.DocumentResponse, error)
+	ListDocuments(ctx context.Context, shopID, limit, offset int32) ([]model.DocumentResponse, int64, error)
+	DeleteDocument(ctx context.Context, shopID, documentID int32) error
+	// Retrieve returns the chunks of the shop's documents most relevant to
+	// query, numbered from 1. Shops without documents get none.
+	Retrieve(ctx context.Context, shopID int32, query string) ([]model.Source, error)
+	MaxUploadBytes() int64
+}
+
+// NewDeepInfraEmbedder returns the embedder used in production, or nil when
+// DeepInfra is disabled.
+func NewDeepInfraEmbedder(ctx context.Context, cfg config.DeepInfraConfig) Embedder {
+	if !cfg.Enabled {
+		return nil
+	}
+	return deepinfraService.NewOpenAIService(ctx, cfg)
+}
+
+// NewKnowledgeService returns the knowledge base of every shop. With a nil
+// embedder uploads are refused and nothing is retrieved.
+func NewKnowledgeService(dbPool *pgxpool.Pool, embedder Embedder, cfg config.KnowledgeConfig) KnowledgeService {
+	return &knowledgeService{
+		db:       dbPool,
+		queries:  db.New(dbPool),
+		embedder: embedder,
+		cfg:      cfg,
+		chunks:   map[int32]cachedChunks{},
+	}
+}
+
+type knowledgeService struct {
+	db       *pgxpool.Pool
+	queries  *db.Queries
+	embedder Embedder
+	cfg      config.KnowledgeConfig
+
+	// chunks caches the chunk embeddings of each shop for cfg.CacheTTL.
+	mu     sync.Mutex
+	chunks map[int32]cachedChunks
+}
+
+type cachedChunks struct {
+	rows     []db.ListKnowledgeChunksByShopRow
+	loadedAt time.Time
+}
+
+func (s *knowledgeService) enabled() bool {
+	return s.cfg.Enabled && s.embedder != nil
+}
+
+func (s *knowledgeService) MaxUploadBytes() int64 {
+	return s.cfg.MaxUploadBytes
+}
+
+func (s *knowledgeService) Upload(ctx context.Context, shopID, staffID int32, upload model.Upload) (*model.DocumentResponse, error) {
+	if !s.enabled() {
+		return nil, ErrKnowledgeDisabled
+	}
+	if int64(len(upload.Data)) > s.cfg.MaxUploadBytes {
+		return nil, ErrDocumentTooLarge
+	}
+	format := detectFormat(upload.Filename)
+	if format == "" {
+		return nil, ErrUnsupportedFormat
+	}
+
+	text, err := extractText(format, upload.Data, s.cfg.MaxInflatedBytes)
+	if err != nil {
+		return nil, ErrDocumentTooLarge.Wrap(err)
+	}
+	chunks := splitChunks(format, text, s.cfg.ChunkSize)
+	if len(chunks) == 0 {
+		return nil, ErrNoText
+	}
+	existing, err := s.queries.CountKnowledgeChunksByShop(ctx, shopID)
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	if existing+int64(len(chunks)) > int64(s.cfg.MaxChunks) {
+		return nil, ErrKnowledgeFull.Wrap(fmt.Errorf("%d chunks stored, %d new, limit %d", existing, len(chunks), s.cfg.MaxChunks))
+	}
+
+	title := strings.TrimSpace(upload.Title)
+	if title == "" {
+		title = strings.TrimSuffix(upload.Filename, filepath.Ext(upload.Filename))
+	}
+
+	// Embed before opening the transaction so the slow API calls do not
+	// hold a connection.
+	inputs := make([]string, len(chunks))
+	for i, c := range chunks {
+		inputs[i] = strings.TrimSpace(title + "\n" + c.Heading + "\n" + c.Content)
+	}
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(inputs))
+		batch, err := s.embedder.Embed(ctx, inputs[start:end])
+		if err != nil {
+			return nil, errEmbedFailed.Wrap(err)
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.queries.WithTx(tx)
+
+	doc, err := qtx.CreateKnowledgeDocument(ctx, db.CreateKnowledgeDocumentParams{
+		ShopID:     shopID,
+		Title:      truncate(title, 255),
+		Filename:   truncate(upload.Filename, 255),
+		Format:     format,
+		SizeBytes:  int32(len(upload.Data)),
+		ChunkCount: int32(len(chunks)),
+		CreatedBy:  pgtype.Int4{Int32: staffID, Valid: staffID != 0},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	for i, c := range chunks {
+		err := qtx.CreateKnowledgeChunk(ctx, db.CreateKnowledgeChunkParams{
+			DocumentID: doc.ID,
+			ShopID:     shopID,
+			ChunkIndex: int32(i),
+			Heading:    c.Heading,
+			Content:    c.Content,
+			Embedding:  vectors[i],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error Database: %w", err)
+		}
+	}
+
+	// Cached chat replies may now be missing what the document says
+	if _, err := qtx.DeleteResponseCacheByShop(ctx, shopID); err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	s.forgetChunks(shopID)
+
+	resp := toResponse(doc)
+	return &resp, nil
+}
+
+func (s *knowledgeService) ListDocuments(ctx context.Context, shopID, limit, offset int32) ([]model.DocumentResponse, int64, error) {
+	rows, err := s.queries.ListKnowledgeDocuments(ctx, db.ListKnowledgeDocumentsParams{
+		ShopID: shopID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	total, err := s.queries.CountKnowledgeDocuments(ctx, shopID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	docs := make([]model.DocumentResponse, 0, len(rows))
+	for _, row := range rows {
+		docs = append(docs, toResponse(row))
+	}
+	return docs, total, nil
+}
+
+func (s *knowledgeService) DeleteDocument(ctx context.Context, shopID, documentID int32) error {
+	n, err := s.queries.DeleteKnowledgeDocument(ctx, db.DeleteKnowledgeDocumentParams{
+		ID:     documentID,
+		ShopID: shopID,
+	})
+	if err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	if n == 0 {
+		return ErrDocumentNotFound
+	}
+	s.forgetChunks(shopID)
+
+	if _, err := s.queries.DeleteResponseCacheByShop(ctx, shopID); err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate chat cache", "shop_id", shopID, "error", err)
+	}
+	return nil
+}
+
+func (s *knowledgeService) Retrieve(ctx context.Context, shopID int32, query string) ([]model.Source, error) {
+	query = strings.TrimSpace(query)
+	if !s.enabled() || query == "" {
+		return nil, nil
+	}
+
+	rows, err := s.shopChunks(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, errEmbedFailed.Wrap(err)
+	}
+
+	sources := make([]model.Source, 0, len(rows))
+	for _, row := range rows {
+		score := cosine(vectors[0], row.Embedding)
+		if score < s.cfg.MinScore {
+			continue
+		}
+		sources = append(sources, model.Source{
+			DocumentID: row.DocumentID,
+			Title:      row.Title,
+			Heading:    row.Heading,
+			Content:    row.Content,
+			Score:      score,
+		})
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Score > sources[j].Score })
+	if len(sources) > s.cfg.TopK {
+		sources = sources[:s.cfg.TopK]
+	}
+	for i := range sources {
+		sources[i].Ref = i + 1
+	}
+	return sources, nil
+}
+
+// shopChunks returns the chunk embeddings of a shop, from memory when they
+// were loaded less than cfg.CacheTTL ago. Loading drops expired shops.
+func (s *knowledgeService) shopChunks(ctx context.Context, shopID int32) ([]db.ListKnowledgeChunksByShopRow, error) {
+	ttl := s.cfg.CacheTTL
+	if ttl > 0 {
+		s.mu.Lock()
+		c, ok := s.chunks[shopID]
+		s.mu.Unlock()
+		if ok && time.Since(c.loadedAt) < ttl {
+			return c.rows, nil
+		}
+	}
+
+	rows, err := s.queries.ListKnowledgeChunksByShop(ctx, shopID)
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	if ttl > 0 {
+		now := time.Now()
+		s.mu.Lock()
+		for id, c := range s.chunks {
+			if now.Sub(c.loadedAt) >= ttl {
+				delete(s.chunks, id)
+			}
+		}
+		s.chunks[shopID] = cachedChunks{rows: rows, loadedAt: now}
+		s.mu.Unlock()
+	}
+	return rows, nil
+}
+
+// forgetChunks drops the cached embeddings of a shop after its documents
+// change.
+func (s *knowledgeService) forgetChunks(shopID int32) {
+	s.mu.Lock()
+	delete(s.chunks, shopID)
+	s.mu.Unlock()
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// FormatSources lays out retrieved chunks for the system prompt.
+func FormatSources(sources []model.Source) string {
+	var b strings.Builder
+	for i, src := range sources {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString("[" + strconv.Itoa(src.Ref) + "] " + src.Title)
+		if src.Heading != "" {
+			b.WriteString(" - " + src.Heading)
+		}
+		b.WriteString("\n" + src.Content)
+	}
+	return b.String()
+}
+
+var citationRef = regexp.MustCompile(`\[(\d+)\]`)
+
+// AppendCitations adds a "Sumber:" line naming the documents the reply
+// cites with [n] markers. Replies without markers are returned unchanged.
+func AppendCitations(reply string, sources []model.Source) string {
+	byRef := make(map[int]model.Source, len(sources))
+	for _, src := range sources {
+		byRef[src.Ref] = src
+	}
+
+	var titles []string
+	seen := map[int32]bool{}
+	for _, m := range citationRef.FindAllStringSubmatch(reply, -1) {
+		ref, _ := strconv.Atoi(m[1])
+		src, ok := byRef[ref]
+		if !ok || seen[src.DocumentID] {
+			continue
+		}
+		seen[src.DocumentID] = true
+		titles = append(titles, src.Title)
+	}
+	if len(titles) == 0 {
+		return reply
+	}
+	return strings.TrimRight(reply, "\n ") + "\n\nSumber: " + strings.Join(titles, ", ")
+}
+
+func toResponse(row db.KnowledgeDocument) model.DocumentResponse {
+	resp := model.DocumentResponse{
+		ID:         row.ID,
+		Title:      row.Title,
+		Filename:   row.Filename,
+		Format:     row.Format,
+		SizeBytes:  row.SizeBytes,
+		ChunkCount: row.ChunkCount,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+	if row.CreatedBy.Valid {
+		resp.CreatedBy = &row.CreatedBy.Int32
+	}
+	return resp
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"shofy/modules/knowledge/model"
+	"strings"
+	"testing"
+)
+
+func TestSplitChunksMarkdownHeadings(t *testing.T) {
+	text := "# Pengiriman\nDikirim dalam 2 hari kerja.\n\nGratis ongkir di atas Rp 200.000.\n\n## Retur\nRetur maksimal 7 hari."
+	chunks := splitChunks(model.FormatMarkdown, text, 500)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2: %+v", len(chunks), chunks)
+	}
+	if chunks[0].Heading != "Pengiriman" || !strings.Contains(chunks[0].Content, "Gratis ongkir") {
+		t.Errorf("first chunk = %+v", chunks[0])
+	}
+	if chunks[1].Heading != "Retur" || chunks[1].Content != "Retur maksimal 7 hari." {
+		t.Errorf("second chunk = %+v", chunks[1])
+	}
+}
+
+func TestSplitChunksRespectsSize(t *testing.T) {
+	text := strings.Repeat("Kalimat yang cukup panjang untuk diuji. ", 100)
+	for _, c := range splitChunks(model.FormatText, text, 300) {
+		if len(c.Content) > 300 {
+			t.Fatalf("chunk of %d bytes exceeds size", len(c.Content))
+		}
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	content := "BT /F1 12 Tf 72 712 Td (Jam buka 09.00) Tj 0 -14 Td [(Senin) -300 (sampai Jumat)] TJ ET"
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write([]byte(content))
+	w.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Font /Subtype /Type1 >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", z.Len())
+	pdf.Write(z.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF")
+
+	got, err := extractPDF(pdf.Bytes(), 1<<20)
+	if err != nil || !strings.Contains(got, "Jam buka 09.00\nSenin sampai Jumat") {
+		t.Errorf("extractPDF = %q, %v", got, err)
+	}
+	if got, _ := extractPDF([]byte("not a pdf"), 1<<20); got != "" {
+		t.Error("non-PDF data should give no text")
+	}
+	if _, err := extractPDF(pdf.Bytes(), int64(len(content)-1)); !errors.Is(err, errInflateLimit) {
+		t.Errorf("extractPDF past the limit = %v, want errInflateLimit", err)
+	}
+}
+
+func TestAppendCitations(t *testing.T) {
+	sources := []model.Source{
+		{Ref: 1, DocumentID: 10, Title: "Kebijakan Retur"},
+		{Ref: 2, DocumentID: 10, Title: "Kebijakan Retur"},
+		{Ref: 3, DocumentID: 11, Title: "Pengiriman"},
+	}
+
+	got := AppendCitations("Retur 7 hari [1][2], ongkir gratis [3]. [9]", sources)
+	if !strings.HasSuffix(got, "\n\nSumber: Kebijakan Retur, Pengiriman") {
+		t.Errorf("AppendCitations = %q", got)
+	}
+	if got := AppendCitations("Tanpa kutipan", sources); got != "Tanpa kutipan" {
+		t.Errorf("reply without markers changed to %q", got)
+	}
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// errInflateLimit is returned for PDFs whose streams decompress past the
+// configured maximum, such as zip bombs.
+var errInflateLimit = errors.New("PDF streams inflate past the limit")
+
+// extractPDF pulls the text out of the page content streams of a PDF. It
+// understands uncompressed and FlateDecode streams with simple font
+// encodings, which covers documents exported from word processors. Scanned
+// or encrypted PDFs give no text. The streams may inflate to at most
+// maxInflated bytes in total.
+func extractPDF(data []byte, maxInflated int64) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", nil
+	}
+
+	var out strings.Builder
+	rest := data
+	for {
+		i := bytes.Index(rest, []byte("stream"))
+		if i < 0 {
+			break
+		}
+		// Skip "endstream" matches.
+		if i >= 3 && string(rest[i-3:i]) == "end" {
+			rest = rest[i+len("stream"):]
+			continue
+		}
+
+		dict := streamDict(rest[:i])
+		start := i + len("stream")
+		if start < len(rest) && rest[start] == '\r' {
+			start++
+		}
+		if start < len(rest) && rest[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(rest[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := rest[start : start+end]
+		rest = rest[start+end+len("endstream"):]
+
+		content, ok, err := decodeStream(dict, raw, maxInflated)
+		if err != nil {
+			return "", err
+		}
+		maxInflated -= int64(len(content))
+		if !ok || !bytes.Contains(content, []byte("BT")) {
+			continue
+		}
+		out.WriteString(contentText(content))
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+// streamDict returns the dictionary written just before a stream keyword.
+func streamDict(before []byte) string {
+	i := bytes.LastIndex(before, []byte("obj"))
+	if i < 0 {
+		return ""
+	}
+	return string(before[i:])
+}
+
+// decodeStream inflates raw when needed, to at most limit bytes. Images,
+// fonts and other filters are skipped.
+func decodeStream(dict string, raw []byte, limit int64) ([]byte, bool, error) {
+	if strings.Contains(dict, "/Subtype/Image") || strings.Contains(dict, "/Subtype /Image") ||
+		strings.Contains(dict, "/Length1") || strings.Contains(dict, "/Length2") {
+		return nil, false, nil
+	}
+	switch {
+	case strings.Contains(dict, "/FlateDecode"):
+		if strings.Count(dict, "Decode") > 1 {
+			return nil, false, nil
+		}
+		r, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false, nil
+		}
+		defer r.Close()
+		// Read one byte past the limit to tell a full stream from a cut one.
+		content, err := io.ReadAll(io.LimitReader(r, limit+1))
+		if int64(len(content)) > limit {
+			return nil, false, fmt.Errorf("%w of %d bytes", errInflateLimit, limit)
+		}
+		if err != nil && len(content) == 0 {
+			return nil, false, nil
+		}
+		return content, true, nil
+	case strings.Contains(dict, "/Filter"):
+		return nil, false, nil
+	default:
+		return raw, true, nil
+	}
+}
+
+// contentText runs the text-showing operators of a content stream.
+func contentText(content []byte) string {
+	var (
+		out      strings.Builder
+		operands []string
+		numbers  []float64
+		array    []string
+		inArray  bool
+	)
+	newline := func() {
+		s := out.String()
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			out.WriteString("\n")
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := literalString(content[i:])
+			i += n
+			if inArray {
+				array = append(array, s)
+			} else {
+				operands = append(operands, s)
+			}
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return out.String()
+			}
+			s := hexString(content[i+1 : i+end])
+			i += end + 1
+			if inArray {
+				array = append(array, s)
+			} else {
+				operands = append(operands, s)
+			}
+		case c == '[':
+			inArray, array = true, array[:0]
+			i++
+		case c == ']':
+			inArray = false
+			operands = append(operands, strings.Join(array, ""))
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(content) && (content[j] == '.' || (content[j] >= '0' && content[j] <= '9')) {
+				j++
+			}
+			n := parseNumber(string(content[i:j]))
+			// Wide negative kerning inside TJ arrays separates words.
+			if inArray && n < -200 {
+				array = append(array, " ")
+			}
+			numbers = append(numbers, n)
+			i = j
+		case isRegular(c):
+			j := i
+			for j < len(content) && isRegular(content[j]) && content[j] != '-' {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			op := string(content[i:j])
+			i = j
+			switch op {
+			case "Tj", "TJ":
+				if len(operands) > 0 {
+					out.WriteString(operands[len(operands)-1])
+				}
+			case "'", "\"":
+				newline()
+				if len(operands) > 0 {
+					out.WriteString(operands[len(operands)-1])
+				}
+			case "T*", "ET":
+				newline()
+			case "Td", "TD":
+				if len(numbers) >= 1 && numbers[len(numbers)-1] != 0 {
+					newline()
+				} else {
+					out.WriteString(" ")
+				}
+			}
+			if !inArray {
+				operands, numbers = operands[:0], numbers[:0]
+			}
+		default:
+			i++
+		}
+	}
+	return out.String()
+}
+
+func isRegular(c byte) bool {
+	return c > ' ' && !strings.ContainsRune("()<>[]{}/%", rune(c)) && c < 0x7f
+}
+
+func parseNumber(s string) float64 {
+	var (
+		n, frac float64
+		div     = 1.0
+		neg     bool
+		dot     bool
+	)
+	for _, c := range s {
+		switch {
+		case c == '-':
+			neg = true
+		case c == '.':
+			dot = true
+		case c >= '0' && c <= '9':
+			if dot {
+				div *= 10
+				frac += float64(c-'0') / div
+			} else {
+				n = n*10 + float64(c-'0')
+			}
+		}
+	}
+	if neg {
+		return -(n + frac)
+	}
+	return n + frac
+}
+
+// literalString decodes a (...) string starting at b[0] and returns it
+// with the number of bytes consumed.
+func literalString(b []byte) (string, int) {
+	var buf []byte
+	depth := 0
+	i := 0
+	for i < len(b) {
+		c := b[i]
+		switch {
+		case c == '\\' && i+1 < len(b):
+			i++
+			switch e := b[i]; e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation.
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for k := 0; k < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7'; k++ {
+						v = v*8 + int(b[i]-'0')
+						i++
+					}
+					buf = append(buf, byte(v))
+					continue
+				}
+				buf = append(buf, e)
+			}
+		case c == '(':
+			if depth > 0 {
+				buf = append(buf, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodeText(buf), i + 1
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+		i++
+	}
+	return decodeText(buf), i
+}
+
+func hexString(b []byte) string {
+	var buf []byte
+	var hi byte
+	half := false
+	for _, c := range b {
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if half {
+			buf = append(buf, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	if half {
+		buf = append(buf, hi<<4)
+	}
+	return decodeText(buf)
+}
+
+// decodeText reads UTF-16BE strings (with BOM or two-byte codes below
+// U+0100) and otherwise treats bytes as Latin-1. Control bytes, which come
+// from font-specific encodings, are dropped.
+func decodeText(b []byte) string {
+	var runes []rune
+	if len(b) >= 2 && len(b)%2 == 0 && (b[0] == 0xFE && b[1] == 0xFF || allHighZero(b)) {
+		if b[0] == 0xFE && b[1] == 0xFF {
+			b = b[2:]
+		}
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		runes = utf16.Decode(units)
+	} else {
+		runes = make([]rune, 0, len(b))
+		for _, c := range b {
+			runes = append(runes, rune(c))
+		}
+	}
+
+	var out strings.Builder
+	for _, r := range runes {
+		if unicode.IsPrint(r) || r == '\n' || r == '\t' {
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+func allHighZero(b []byte) bool {
+	for i := 0; i < len(b); i += 2 {
+		if b[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
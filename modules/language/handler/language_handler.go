@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"shofy/middleware"
+	"shofy/modules/language/model"
+	"shofy/modules/language/service"
+	"shofy/utils/apperror"
+	"shofy/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LanguageHandler struct {
+	languageService service.LanguageService
+}
+
+func NewLanguageHandler(languageService service.LanguageService) *LanguageHandler {
+	return &LanguageHandler{
+		languageService: languageService,
+	}
+}
+
+// InitRoutes registers the language endpoints. rg must run AuthMiddleware
+// and RequireRole; staff choose the languages the assistant answers in for
+// the shop in their token.
+func (h *LanguageHandler) InitRoutes(rg *gin.RouterGroup) {
+	rg.GET("", h.ListLanguages)
+	rg.GET("/settings", h.GetSettings)
+	rg.PUT("/settings", h.UpdateSettings)
+	rg.DELETE("/translations", h.ClearTranslations)
+}
+
+func (h *LanguageHandler) ListLanguages(c *gin.Context) {
+	response.Success(c, http.StatusOK, "Languages fetched successfully", h.languageService.Languages())
+}
+
+func (h *LanguageHandler) GetSettings(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	settings, err := h.languageService.GetSettings(c.Request.Context(), shopID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Language settings fetched successfully", settings)
+}
+
+// UpdateSettings replaces the default and supported languages of the shop.
+func (h *LanguageHandler) UpdateSettings(c *gin.Context) {
+	shopID, staffID, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req model.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	settings, err := h.languageService.UpdateSettings(c.Request.Context(), shopID, staffID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Language settings updated successfully", settings)
+}
+
+// ClearTranslations drops the cached product translations of the shop, so
+// they are translated again on the next chat that needs them.
+func (h *LanguageHandler) ClearTranslations(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	deleted, err := h.languageService.ClearTranslations(c.Request.Context(), shopID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Product translations cleared successfully", gin.H{"deleted": deleted})
+}
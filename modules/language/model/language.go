@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Language is one language a shop can serve.
+type Language struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Settings are the languages of a shop. Shops that never saved any serve
+// Bahasa Indonesia only.
+type Settings struct {
+	ShopID int32 `json:"shop_id"`
+	// DefaultLanguage is the language product data is written in and the
+	// reply language for customers writing in an unsupported one.
+	DefaultLanguage    string     `json:"default_language"`
+	SupportedLanguages []string   `json:"supported_languages"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`
+}
+
+type UpdateSettingsRequest struct {
+	DefaultLanguage    string   `json:"default_language" binding:"required,len=2"`
+	SupportedLanguages []string `json:"supported_languages" binding:"required,min=1,max=15,dive,len=2"`
+}
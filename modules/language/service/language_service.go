@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	db "shofy/db/sqlc"
+	chatModel "shofy/modules/chat/model"
+	"shofy/modules/language/model"
+	llmService "shofy/modules/llm/service"
+	"shofy/utils"
+	"shofy/utils/apperror"
+	"shofy/utils/language"
+	"shofy/utils/metrics"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrUnknownLanguage    = apperror.Validation("unknown_language", "Bahasa tidak dikenal")
+	ErrDefaultUnsupported = apperror.Validation("default_language_unsupported", "Bahasa utama harus termasuk bahasa yang didukung")
+)
+
+// translateBatch is how many products one translation request carries.
+const translateBatch = 20
+
+var translationSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "items": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "name": {"type": "string"},
+          "description": {"type": "string"}
+        },
+        "required": ["id", "name", "description"]
+      }
+    }
+  },
+  "required": ["items"]
+}`)
+
+const translationPrompt = `Terjemahkan nama dan deskripsi produk dalam JSON dari pengguna ke %s (%s).
+Jangan ubah merek, nomor model, ukuran, satuan dan id. Deskripsi kosong tetap kosong.
+Balas hanya dengan JSON: {"items": [{"id": "...", "name": "...", "description": "..."}]}`
+
+type LanguageService interface {
+	// Languages lists every language a shop can choose.
+	Languages() []model.Language
+	GetSettings(ctx context.Context, shopID int32) (*model.Settings, error)
+	UpdateSettings(ctx context.Context, shopID, staffID int32, req model.UpdateSettingsRequest) (*model.Settings, error)
+	// Resolve picks the reply language for a customer message: the language
+	// it is written in when the shop supports it, the default language for
+	// unsupported ones, and current when the message is too short to tell.
+	// It also returns the shop's default language.
+	Resolve(ctx context.Context, shopID int32, current, message string) (string, string)
+	// TranslateProducts returns products with their name and description
+	// in lang from the translation cache. Products without a cached
+	// translation keep the original text and are translated in the
+	// background for later messages.
+	TranslateProducts(ctx context.Context, shopID int32, lang string, products []db.GetProductsByShopIDRow) []db.GetProductsByShopIDRow
+	// ClearTranslations drops the cached translations of a shop's products.
+	ClearTranslations(ctx context.Context, shopID int32) (int64, error)
+}
+
+// NewLanguageService manages shop languages. llm translates product data;
+// with a nil llm products are never translated.
+func NewLanguageService(dbPool *pgxpool.Pool, llm llmService.Completer) LanguageService {
+	return &languageService{
+		queries:     db.New(dbPool),
+		llm:         llm,
+		translating: map[string]bool{},
+	}
+}
+
+type languageService struct {
+	queries *db.Queries
+	llm     llmService.Completer
+
+	// translating holds the shop and language pairs being translated in
+	// the background, so that concurrent messages start one run.
+	mu          sync.Mutex
+	translating map[string]bool
+}
+
+func (s *languageService) Languages() []model.Language {
+	codes := language.Codes()
+	languages := make([]model.Language, 0, len(codes))
+	for _, code := range codes {
+		languages = append(languages, model.Language{Code: code, Name: language.Name(code)})
+	}
+	return languages
+}
+
+func (s *languageService) GetSettings(ctx context.Context, shopID int32) (*model.Settings, error) {
+	row, err := s.queries.GetLanguageSettings(ctx, shopID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &model.Settings{ShopID: shopID, DefaultLanguage: language.Default, SupportedLanguages: []string{language.Default}}, nil
+		}
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	return toSettings(row), nil
+}
+
+func (s *languageService) UpdateSettings(ctx context.Context, shopID, staffID int32, req model.UpdateSettingsRequest) (*model.Settings, error) {
+	supported := []string{}
+	for _, code := range req.SupportedLanguages {
+		code = strings.ToLower(code)
+		if !language.Known(code) {
+			return nil, ErrUnknownLanguage.Wrap(fmt.Errorf("%q", code))
+		}
+		if !slices.Contains(supported, code) {
+			supported = append(supported, code)
+		}
+	}
+	def := strings.ToLower(req.DefaultLanguage)
+	if !slices.Contains(supported, def) {
+		return nil, ErrDefaultUnsupported
+	}
+
+	row, err := s.queries.UpsertLanguageSettings(ctx, db.UpsertLanguageSettingsParams{
+		ShopID:             shopID,
+		DefaultLanguage:    def,
+		SupportedLanguages: supported,
+		UpdatedBy:          pgtype.Int4{Int32: staffID, Valid: staffID != 0},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	// Cached chat replies are only served in the default language
+	if _, err := s.queries.DeleteResponseCacheByShop(ctx, shopID); err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate chat cache", "shop_id", shopID, "error", err)
+	}
+	return toSettings(row), nil
+}
+
+func (s *languageService) Resolve(ctx context.Context, shopID int32, current, message string) (string, string) {
+	settings, err := s.GetSettings(ctx, shopID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load language settings, using the default language", "shop_id", shopID, "error", err)
+		return language.Default, language.Default
+	}
+	def := settings.DefaultLanguage
+
+	detected, ok := language.Detect(message)
+	switch {
+	case ok && slices.Contains(settings.SupportedLanguages, detected):
+		return detected, def
+	case ok:
+		return def, def
+	case slices.Contains(settings.SupportedLanguages, current):
+		return current, def
+	}
+	return def, def
+}
+
+func (s *languageService) TranslateProducts(ctx context.Context, shopID int32, lang string, products []db.GetProductsByShopIDRow) []db.GetProductsByShopIDRow {
+	if s.llm == nil || lang == "" || len(products) == 0 {
+		return products
+	}
+	settings, err := s.GetSettings(ctx, shopID)
+	if err != nil || settings.DefaultLanguage == lang {
+		return products
+	}
+
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	rows, err := s.queries.ListProductTranslations(ctx, db.ListProductTranslationsParams{Language: lang, ProductIds: ids})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load product translations", "shop_id", shopID, "language", lang, "error", err)
+		return products
+	}
+	cached := make(map[string]db.ProductTranslation, len(rows))
+	for _, row := range rows {
+		cached[row.ProductID] = row
+	}
+
+	translated := slices.Clone(products)
+	var missing []db.GetProductsByShopIDRow
+	for i, p := range translated {
+		if t, ok := cached[p.ID]; ok && t.SourceHash == sourceHash(p) {
+			apply(&translated[i], t.Name, t.Description)
+			continue
+		}
+		missing = append(missing, p)
+	}
+	metrics.ProductTranslations.WithLabelValues("cached").Add(float64(len(products) - len(missing)))
+
+	if len(missing) > 0 {
+		s.translateLater(ctx, shopID, lang, missing)
+	}
+	return translated
+}
+
+// translateLater translates and caches products in the background unless
+// the shop's products are already being translated into lang.
+func (s *languageService) translateLater(ctx context.Context, shopID int32, lang string, products []db.GetProductsByShopIDRow) {
+	key := fmt.Sprintf("%d:%s", shopID, lang)
+	s.mu.Lock()
+	if s.translating[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.translating[key] = true
+	s.mu.Unlock()
+
+	go func(ctx context.Context) {
+		defer func() {
+			s.mu.Lock()
+			delete(s.translating, key)
+			s.mu.Unlock()
+		}()
+		for start := 0; start < len(products); start += translateBatch {
+			batch := products[start:min(start+translateBatch, len(products))]
+			if failed, err := s.translate(ctx, lang, batch); err != nil {
+				slog.WarnContext(ctx, "Product translation failed, keeping the original text", "shop_id", shopID, "language", lang, "error", err)
+				metrics.ProductTranslations.WithLabelValues("failed").Add(float64(failed))
+			}
+		}
+	}(context.WithoutCancel(ctx))
+}
+
+// translate asks the model to translate products and caches the result.
+// It returns how many products were left untranslated.
+func (s *languageService) translate(ctx context.Context, lang string, products []db.GetProductsByShopIDRow) (int, error) {
+	type item struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	items := make([]item, 0, len(products))
+	byID := make(map[string]db.GetProductsByShopIDRow, len(products))
+	for _, p := range products {
+		items = append(items, item{ID: p.ID, Name: p.Name, Description: p.Description.String})
+		byID[p.ID] = p
+	}
+	input, err := json.Marshal(items)
+	if err != nil {
+		return len(products), err
+	}
+
+	resp, _, err := s.llm.ChatCompletion(ctx, []chatModel.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(translationPrompt, language.Name(lang), lang)},
+		{Role: "user", Content: string(input)},
+	}, chatModel.WithJSONSchema("product_translations", translationSchema))
+	if err != nil {
+		return len(products), err
+	}
+
+	answer, _ := utils.SplitReasoning(resp.Message)
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return len(products), errors.New("no JSON object in translation")
+	}
+	var out struct {
+		Items []item `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &out); err != nil {
+		return len(products), fmt.Errorf("invalid translation: %w", err)
+	}
+
+	for _, t := range out.Items {
+		p, ok := byID[t.ID]
+		if !ok || strings.TrimSpace(t.Name) == "" {
+			continue
+		}
+		delete(byID, t.ID)
+		metrics.ProductTranslations.WithLabelValues("translated").Inc()
+
+		err := s.queries.UpsertProductTranslation(ctx, db.UpsertProductTranslationParams{
+			ProductID:   t.ID,
+			Language:    lang,
+			SourceHash:  sourceHash(p),
+			Name:        t.Name,
+			Description: t.Description,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to cache product translation", "product_id", t.ID, "language", lang, "error", err)
+		}
+	}
+	if len(byID) > 0 {
+		return len(byID), fmt.Errorf("%d products missing from translation", len(byID))
+	}
+	return 0, nil
+}
+
+func (s *languageService) ClearTranslations(ctx context.Context, shopID int32) (int64, error) {
+	n, err := s.queries.DeleteProductTranslationsByShop(ctx, shopID)
+	if err != nil {
+		return 0, fmt.Errorf("Error Database: %w", err)
+	}
+	return n, nil
+}
+
+// sourceHash identifies the text a translation was made from, so that
+// editing a product invalidates its translations.
+func sourceHash(p db.GetProductsByShopIDRow) string {
+	sum := sha256.Sum256([]byte(p.Name + "\x00" + p.Description.String))
+	return hex.EncodeToString(sum[:])
+}
+
+func apply(p *db.GetProductsByShopIDRow, name, description string) {
+	p.Name = name
+	if p.Description.Valid || description != "" {
+		p.Description = pgtype.Text{String: description, Valid: true}
+	}
+}
+
+func toSettings(row db.LanguageSetting) *model.Settings {
+	updatedAt := row.UpdatedAt.Time
+	return &model.Settings{
+		ShopID:             row.ShopID,
+		DefaultLanguage:    row.DefaultLanguage,
+		SupportedLanguages: row.SupportedLanguages,
+		UpdatedAt:          &updatedAt,
+	}
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"shofy/app/api/config"
+	azureService "shofy/modules/azure/service"
+	"shofy/modules/chat/model"
+	deepinfraService "shofy/modules/deepinfra/service"
+	"shofy/utils"
+	"shofy/utils/apperror"
+	"shofy/utils/metrics"
+	"strings"
+	"time"
+)
+
+// Typed errors returned once every provider has failed. Their messages are
+// safe to show to customers.
+var (
+	ErrUnavailable = apperror.Unavailable("llm_unavailable", "Asisten sedang sibuk, silakan coba lagi sebentar lagi")
+	ErrTimeout     = apperror.Unavailable("llm_timeout", "Asisten terlalu lama menjawab, silakan coba lagi")
+	ErrRejected    = apperror.Upstream("llm_rejected", "Gagal mendapatkan jawaban dari AI")
+	ErrNoProviders = apperror.Unavailable("llm_not_configured", "Asisten AI belum dikonfigurasi")
+)
+
+// Completer is one chat completion backend.
+type Completer interface {
+	ChatCompletion(ctx context.Context, messages []model.ChatMessage, opts ...model.CompletionOption) (model.ChatResponse, int, error)
+}
+
+// Provider is a Completer guarded by its own circuit breaker.
+type Provider struct {
+	Name      string
+	Model     string
+	Completer Completer
+	Breaker   *utils.Breaker
+}
+
+// ProviderError is the failure of one attempt against one provider.
+type ProviderError struct {
+	Provider string
+	Model    string
+	Status   int
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s/%s: status %d: %v", e.Provider, e.Model, e.Status, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether another attempt may succeed: timeouts,
+// transport errors, throttling and server errors are; other client errors
+// are not.
+func (e *ProviderError) Retryable() bool {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+	switch {
+	case e.Status == http.StatusRequestTimeout, e.Status == http.StatusTooManyRequests:
+		return true
+	case e.Status >= 500:
+		return true
+	}
+	return false
+}
+
+// Client tries each provider in order, retrying retryable failures with
+// jittered exponential backoff and skipping providers whose circuit is open.
+type Client struct {
+	Providers []*Provider
+	Config    config.LLMConfig
+
+	// sleep waits between attempts; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient builds the primary DeepInfra model followed by the configured
+// fallbacks. Fallbacks that cannot be set up are logged and left out; the
+// list itself was checked when the config was loaded.
+func NewClient(ctx context.Context, cfg *config.Config) *Client {
+	c := newClient(cfg.LLM)
+
+	if cfg.DeepInfra.Enabled {
+		c.Add("deepinfra", cfg.DeepInfra.Model, deepinfraService.NewOpenAIService(ctx, cfg.DeepInfra))
+	}
+
+	fallbacks, _ := cfg.LLM.FallbackList()
+	for _, f := range fallbacks {
+		model, completer, err := newCompleter(ctx, cfg, f)
+		if err != nil {
+			slog.ErrorContext(ctx, "Skipping LLM fallback", "provider", f.Provider, "error", err)
+			continue
+		}
+		c.Add(f.Provider, model, completer)
+	}
+	return c
+}
+
+// NewProviderClient builds a client for a single provider, without
+// fallbacks, e.g. to compare models against each other.
+func NewProviderClient(ctx context.Context, cfg *config.Config, provider config.LLMFallback) (*Client, error) {
+	model, completer, err := newCompleter(ctx, cfg, provider)
+	if err != nil {
+		return nil, err
+	}
+	c := newClient(cfg.LLM)
+	c.Add(provider.Provider, model, completer)
+	return c, nil
+}
+
+// NewClassifierClient builds the client of the configured classifier
+// model, or returns nil when LLM_CLASSIFIER is not set. It makes a single
+// attempt bounded by LLM_CLASSIFIER_TIMEOUT, since the customer waits for
+// it before the chat model starts.
+func NewClassifierClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	if strings.TrimSpace(cfg.LLM.Classifier) == "" {
+		return nil, nil
+	}
+	provider, err := config.ParseLLMProvider(cfg.LLM.Classifier)
+	if err != nil {
+		return nil, fmt.Errorf("LLM_CLASSIFIER: %w", err)
+	}
+	model, completer, err := newCompleter(ctx, cfg, provider)
+	if err != nil {
+		return nil, err
+	}
+	llmConfig := cfg.LLM
+	llmConfig.MaxAttempts = 1
+	llmConfig.AttemptTimeout = cfg.LLM.ClassifierTimeout
+	c := newClient(llmConfig)
+	c.Add(provider.Provider, model, completer)
+	return c, nil
+}
+
+// newCompleter sets up provider and returns the model it uses. Azure uses
+// its configured deployment when no model is given.
+func newCompleter(ctx context.Context, cfg *config.Config, provider config.LLMFallback) (string, Completer, error) {
+	switch provider.Provider {
+	case "deepinfra":
+		diCfg := cfg.DeepInfra
+		if provider.Model != "" {
+			diCfg.Model = provider.Model
+		}
+		return diCfg.Model, deepinfraService.NewOpenAIService(ctx, diCfg), nil
+	case "azure":
+		azCfg := cfg.Azure
+		if provider.Model != "" {
+			azCfg.Deployment = provider.Model
+		}
+		azure, err := azureService.NewOpenAI(ctx, azCfg)
+		if err != nil {
+			return "", nil, err
+		}
+		return azCfg.Deployment, azure, nil
+	}
+	return "", nil, fmt.Errorf("unknown provider %q", provider.Provider)
+}
+
+func newClient(cfg config.LLMConfig) *Client {
+	return &Client{Config: cfg, sleep: sleepContext}
+}
+
+// Add appends a provider with a fresh circuit breaker.
+func (c *Client) Add(name, model string, completer Completer) {
+	c.Providers = append(c.Providers, &Provider{
+		Name:      name,
+		Model:     model,
+		Completer: completer,
+		Breaker:   utils.NewBreaker(c.Config.BreakerThreshold, c.Config.BreakerCooldown),
+	})
+}
+
+// ChatCompletion returns the first successful reply. When every provider
+// fails it returns one of the typed errors above wrapping the causes.
+func (c *Client) ChatCompletion(ctx context.Context, messages []model.ChatMessage, opts ...model.CompletionOption) (model.ChatResponse, int, error) {
+	if len(c.Providers) == 0 {
+		return model.ChatResponse{}, http.StatusServiceUnavailable, ErrNoProviders
+	}
+
+	var (
+		causes   []error
+		timeouts int
+		rejected bool
+	)
+	for i, p := range c.Providers {
+		if !p.Breaker.Allow() {
+			metrics.LLMCircuitSkips.WithLabelValues(p.Name, p.Model).Inc()
+			causes = append(causes, fmt.Errorf("%s/%s: circuit open", p.Name, p.Model))
+			continue
+		}
+
+		resp, err := c.callProvider(ctx, p, messages, opts)
+		if err == nil {
+			if i > 0 {
+				metrics.LLMFallbacks.WithLabelValues(p.Name, p.Model).Inc()
+				slog.WarnContext(ctx, "LLM reply served by fallback", "provider", p.Name, "model", p.Model)
+			}
+			return resp, http.StatusOK, nil
+		}
+
+		causes = append(causes, err)
+		var perr *ProviderError
+		if errors.As(err, &perr) && !perr.Retryable() {
+			rejected = true
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			timeouts++
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	cause := errors.Join(causes...)
+	slog.ErrorContext(ctx, "All LLM providers failed", "error", cause)
+	switch {
+	case timeouts == len(causes):
+		return model.ChatResponse{}, http.StatusGatewayTimeout, ErrTimeout.Wrap(cause)
+	case rejected:
+		return model.ChatResponse{}, http.StatusBadGateway, ErrRejected.Wrap(cause)
+	default:
+		return model.ChatResponse{}, http.StatusServiceUnavailable, ErrUnavailable.Wrap(cause).WithRetryAfter(c.Config.BreakerCooldown)
+	}
+}
+
+// callProvider makes up to MaxAttempts calls to p. It stops early on a
+// non-retryable error or when the circuit opens.
+func (c *Client) callProvider(ctx context.Context, p *Provider, messages []model.ChatMessage, opts []model.CompletionOption) (model.ChatResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt < max(c.Config.MaxAttempts, 1); attempt++ {
+		if attempt > 0 {
+			if err := c.sleep(ctx, c.backoff(attempt)); err != nil || !p.Breaker.Allow() {
+				break
+			}
+			metrics.LLMRetries.WithLabelValues(p.Name, p.Model).Inc()
+		}
+
+		resp, err := c.attempt(ctx, p, messages, opts)
+		if err == nil {
+			p.Breaker.Success()
+			return resp, nil
+		}
+		lastErr = err
+
+		var perr *ProviderError
+		if errors.As(err, &perr) && !perr.Retryable() {
+			// The provider is up; the request itself was refused.
+			p.Breaker.Success()
+			return model.ChatResponse{}, err
+		}
+		p.Breaker.Failure()
+		slog.WarnContext(ctx, "LLM attempt failed", "provider", p.Name, "model", p.Model, "attempt", attempt+1, "error", err)
+	}
+	return model.ChatResponse{}, lastErr
+}
+
+func (c *Client) attempt(ctx context.Context, p *Provider, messages []model.ChatMessage, opts []model.CompletionOption) (model.ChatResponse, error) {
+	if c.Config.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Config.AttemptTimeout)
+		defer cancel()
+	}
+
+	resp, status, err := p.Completer.ChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+		}
+		return model.ChatResponse{}, &ProviderError{Provider: p.Name, Model: p.Model, Status: status, Err: err}
+	}
+	return resp, nil
+}
+
+// backoff returns a random delay up to RetryBaseDelay * 2^(attempt-1),
+// capped at RetryMaxDelay ("full jitter").
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.Config.RetryBaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > c.Config.RetryMaxDelay {
+		ceiling = c.Config.RetryMaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling))) + 1
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
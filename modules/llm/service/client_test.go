@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"shofy/app/api/config"
+	"shofy/modules/chat/model"
+	"testing"
+	"time"
+)
+
+type fakeCompleter struct {
+	statuses []int // one per call; 200 succeeds, the last one repeats
+	calls    int
+}
+
+func (f *fakeCompleter) ChatCompletion(ctx context.Context, messages []model.ChatMessage, opts ...model.CompletionOption) (model.ChatResponse, int, error) {
+	status := f.statuses[min(f.calls, len(f.statuses)-1)]
+	f.calls++
+	if status == http.StatusOK {
+		return model.ChatResponse{Message: "ok"}, status, nil
+	}
+	return model.ChatResponse{}, status, errors.New("upstream failed")
+}
+
+func testClient(completers ...*fakeCompleter) *Client {
+	c := newClient(config.LLMConfig{
+		MaxAttempts:      3,
+		RetryBaseDelay:   time.Millisecond,
+		RetryMaxDelay:    time.Millisecond,
+		BreakerThreshold: 3,
+		BreakerCooldown:  time.Minute,
+	})
+	c.sleep = func(context.Context, time.Duration) error { return nil }
+	for i, f := range completers {
+		c.Add("fake", string(rune('a'+i)), f)
+	}
+	return c
+}
+
+func TestClientRetriesRetryableErrors(t *testing.T) {
+	primary := &fakeCompleter{statuses: []int{503, 502, 200}}
+	resp, status, err := testClient(primary).ChatCompletion(context.Background(), nil)
+	if err != nil || status != http.StatusOK || resp.Message != "ok" {
+		t.Fatalf("got %v, %d, %v", resp, status, err)
+	}
+	if primary.calls != 3 {
+		t.Errorf("primary called %d times, want 3", primary.calls)
+	}
+}
+
+func TestClientFallsBackAndOpensCircuit(t *testing.T) {
+	primary := &fakeCompleter{statuses: []int{500}}
+	fallback := &fakeCompleter{statuses: []int{200}}
+	c := testClient(primary, fallback)
+
+	if _, _, err := c.ChatCompletion(context.Background(), nil); err != nil {
+		t.Fatalf("fallback should answer: %v", err)
+	}
+	if primary.calls != 3 || fallback.calls != 1 {
+		t.Fatalf("calls = %d/%d, want 3/1", primary.calls, fallback.calls)
+	}
+
+	// Three failures opened the primary circuit, so it is skipped now.
+	if _, _, err := c.ChatCompletion(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls != 3 {
+		t.Errorf("primary called while its circuit is open")
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	primary := &fakeCompleter{statuses: []int{400}}
+	_, _, err := testClient(primary).ChatCompletion(context.Background(), nil)
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("err = %v, want ErrRejected", err)
+	}
+	if primary.calls != 1 {
+		t.Errorf("primary called %d times, want 1", primary.calls)
+	}
+}
+
+func TestClientUnavailable(t *testing.T) {
+	_, status, err := testClient(&fakeCompleter{statuses: []int{503}}, &fakeCompleter{statuses: []int{429}}).
+		ChatCompletion(context.Background(), nil)
+	if !errors.Is(err, ErrUnavailable) || status != http.StatusServiceUnavailable {
+		t.Fatalf("got %d, %v, want ErrUnavailable", status, err)
+	}
+}
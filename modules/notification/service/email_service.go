@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"shofy/app/api/config"
+	"shofy/utils/metrics"
+	"strconv"
+	"strings"
+)
+
+type EmailService struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewEmailService(cfg config.SMTPConfig) *EmailService {
+	return &EmailService{
+		host:     cfg.Host,
+		port:     strconv.Itoa(cfg.Port),
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+	}
+}
+
+// Enabled reports whether an SMTP host has been configured.
+func (s *EmailService) Enabled() bool {
+	return s.host != ""
+}
+
+func (s *EmailService) SendOTP(email string, otp string) error {
+	body := fmt.Sprintf("Your login code is %s.\r\n\r\nKeep your OTP private. Sharing it could let others access your account.\r\n", otp)
+	return s.send(email, "Your login code", body)
+}
+
+func (s *EmailService) SendMagicLink(email string, link string) error {
+	body := fmt.Sprintf("Use the link below to sign in. It can be used once and expires soon.\r\n\r\n%s\r\n", link)
+	return s.send(email, "Your sign-in link", body)
+}
+
+// SendNotice sends a plain-text notification, e.g. a staff alert.
+func (s *EmailService) SendNotice(email string, subject string, body string) error {
+	return s.send(email, subject, body)
+}
+
+func (s *EmailService) send(to string, subject string, body string) error {
+	err := s.sendMail(to, subject, body)
+	metrics.NotificationOutcome("email", err)
+	return err
+}
+
+func (s *EmailService) sendMail(to string, subject string, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient address")
+	}
+
+	msg := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	addr := net.JoinHostPort(s.host, s.port)
+	if err := smtp.SendMail(addr, auth, s.from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+
+	return nil
+}
+
+// Ping connects to the SMTP server and waits for its greeting.
+func (s *EmailService) Ping(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.host, s.port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return client.Quit()
+}
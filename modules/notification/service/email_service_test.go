@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpSink is a minimal local SMTP server that records each message body.
+type smtpSink struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start SMTP sink: %v", err)
+	}
+	sink := &smtpSink{listener: listener, messages: make(chan string, 10)}
+	go sink.serve()
+	t.Cleanup(func() { listener.Close() })
+	return sink
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost sink")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.messages <- data.String()
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func newSinkEmailService(sink *smtpSink) *EmailService {
+	host, port, _ := net.SplitHostPort(sink.listener.Addr().String())
+	return &EmailService{host: host, port: port, from: "no-reply@shofy.test"}
+}
+
+func TestEmailService_SendOTP(t *testing.T) {
+	sink := newSMTPSink(t)
+	service := newSinkEmailService(sink)
+
+	if err := service.SendOTP("customer@example.com", "123456"); err != nil {
+		t.Fatalf("SendOTP() error = %v", err)
+	}
+
+	msg := <-sink.messages
+	if !strings.Contains(msg, "To: customer@example.com") {
+		t.Errorf("message missing recipient header: %q", msg)
+	}
+	if !strings.Contains(msg, "123456") {
+		t.Errorf("message missing OTP: %q", msg)
+	}
+}
+
+func TestEmailService_SendMagicLink(t *testing.T) {
+	sink := newSMTPSink(t)
+	service := newSinkEmailService(sink)
+
+	link := "https://shop.example.com/login?token=abc.def"
+	if err := service.SendMagicLink("customer@example.com", link); err != nil {
+		t.Fatalf("SendMagicLink() error = %v", err)
+	}
+
+	msg := <-sink.messages
+	if !strings.Contains(msg, link) {
+		t.Errorf("message missing link: %q", msg)
+	}
+}
+
+func TestEmailService_RejectsHeaderInjection(t *testing.T) {
+	sink := newSMTPSink(t)
+	service := newSinkEmailService(sink)
+
+	if err := service.SendOTP("a@example.com\r\nBcc: b@example.com", "123456"); err == nil {
+		t.Error("SendOTP() expected error for recipient with CRLF")
+	}
+}
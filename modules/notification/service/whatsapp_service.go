@@ -2,24 +2,36 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"shofy/app/api/config"
+	"shofy/utils/metrics"
+	"shofy/utils/telemetry"
 )
 
 type WhatsAppService struct {
+	enabled       bool
 	accessToken   string
 	phoneNumberID string
 }
 
 type WhatsAppMessage struct {
-	MessagingProduct string   `json:"messaging_product"`
-	RecipientType    string   `json:"recipient_type"`
-	To               string   `json:"to"`
-	Type             string   `json:"type"`
-	Template         Template `json:"template"`
+	MessagingProduct string    `json:"messaging_product"`
+	RecipientType    string    `json:"recipient_type"`
+	To               string    `json:"to"`
+	Type             string    `json:"type"`
+	Template         *Template `json:"template,omitempty"`
+	Text             *Text     `json:"text,omitempty"`
+}
+
+// Text is a free-form message. WhatsApp only delivers these within 24 hours
+// of the customer's last message.
+type Text struct {
+	PreviewURL bool   `json:"preview_url"`
+	Body       string `json:"body"`
 }
 
 type Template struct {
@@ -42,23 +54,33 @@ type Parameter struct {
 	Text string `json:"text"`
 }
 
-func NewWhatsAppService() *WhatsAppService {
+func NewWhatsAppService(cfg config.WhatsAppConfig) *WhatsAppService {
 	return &WhatsAppService{
-		accessToken:   os.Getenv("WHATSAPP_ACCESS_TOKEN"),
-		phoneNumberID: os.Getenv("WHATSAPP_PHONE_NUMBER_ID"),
+		enabled:       cfg.Enabled,
+		accessToken:   cfg.AccessToken,
+		phoneNumberID: cfg.PhoneNumberID,
 	}
 }
 
+// Enabled reports whether WhatsApp delivery is switched on.
+func (s *WhatsAppService) Enabled() bool {
+	return s.enabled
+}
+
 func (s *WhatsAppService) SendOTP(phoneNumber string, otp string) error {
+	err := s.sendOTP(phoneNumber, otp)
+	metrics.NotificationOutcome("whatsapp", err)
+	return err
+}
 
-	log.Println("WHATSHAP 3" + phoneNumber + " " + otp)
+func (s *WhatsAppService) sendOTP(phoneNumber string, otp string) error {
 
 	message := WhatsAppMessage{
 		MessagingProduct: "whatsapp",
 		RecipientType:    "individual",
 		To:               phoneNumber,
 		Type:             "template",
-		Template: Template{
+		Template: &Template{
 			Name:     "otp_notification",
 			Language: Language{Code: "id"},
 			Components: []Component{
@@ -75,8 +97,24 @@ func (s *WhatsAppService) SendOTP(phoneNumber string, otp string) error {
 		},
 	}
 
+	return s.post(message)
+}
+
+// SendText sends a free-form text message, e.g. a staff reply in a chat.
+func (s *WhatsAppService) SendText(phoneNumber string, body string) error {
+	err := s.post(WhatsAppMessage{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               phoneNumber,
+		Type:             "text",
+		Text:             &Text{Body: body},
+	})
+	metrics.NotificationOutcome("whatsapp", err)
+	return err
+}
+
+func (s *WhatsAppService) post(message WhatsAppMessage) error {
 	jsonData, err := json.Marshal(message)
-	log.Println("jsonData:", jsonData)
 
 	if err != nil {
 		return fmt.Errorf("error marshaling message: %v", err)
@@ -92,13 +130,12 @@ func (s *WhatsAppService) SendOTP(phoneNumber string, otp string) error {
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := telemetry.NewHTTPClient().Do(req)
 	if err != nil {
 		return fmt.Errorf("error sending message: %v", err)
 	}
 	defer resp.Body.Close()
-	log.Println("Masuk resp.StatusCode:", resp.StatusCode)
+	slog.Debug("WhatsApp API responded", "status", resp.StatusCode)
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("error response from WhatsApp API: %d", resp.StatusCode)
@@ -106,3 +143,24 @@ func (s *WhatsAppService) SendOTP(phoneNumber string, otp string) error {
 
 	return nil
 }
+
+// Ping checks that the phone number ID and access token are accepted.
+func (s *WhatsAppService) Ping(ctx context.Context) error {
+	url := fmt.Sprintf("https://graph.facebook.com/v17.0/%s?fields=id", s.phoneNumberID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+
+	resp, err := telemetry.NewHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("WhatsApp API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
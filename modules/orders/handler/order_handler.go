@@ -1,16 +1,18 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
 	order_model "shofy/modules/orders/model"
 	"shofy/modules/orders/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+var errInvalidOrderID = apperror.Validation("invalid_order_id", "Invalid order ID")
+
 type OrderHandler struct {
 	orderService service.OrderService
 }
@@ -37,7 +39,7 @@ func (h *OrderHandler) GetOrdersList(c *gin.Context) {
 
 	// Bind query parameters
 	if err := c.BindQuery(&q); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
 		return
 	}
 
@@ -48,14 +50,11 @@ func (h *OrderHandler) GetOrdersList(c *gin.Context) {
 		q.CurrentPage = 1
 	}
 
-	fmt.Println("q.UserID", q.UserID)
-	fmt.Println("q.Status", q.Status)
-
 	offset := (q.CurrentPage - 1) * q.Limit
 
 	result, err := h.orderService.GetOrdersList(c.Request.Context(), int32(q.Limit), int32(offset), q.CurrentPage, int32(q.UserID), q.Status)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
@@ -72,20 +71,13 @@ func (h *OrderHandler) GetOrderById(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid order ID")
-		return
-	}
-
-	_, err = h.orderService.GetOrderById(c.Request.Context(), int32(id))
-	if err != nil {
-		fmt.Println("Error: ", err)
-		response.Error(c, http.StatusNotFound, "Order not found")
+		_ = c.Error(errInvalidOrderID)
 		return
 	}
 
 	order, err := h.orderService.GetOrderById(c.Request.Context(), int32(id))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to get order")
+		_ = c.Error(err)
 		return
 	}
 
@@ -95,13 +87,13 @@ func (h *OrderHandler) GetOrderById(c *gin.Context) {
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	var req service.CreateOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	order, err := h.orderService.CreateOrder(c.Request.Context(), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to create order")
+		_ = c.Error(err)
 		return
 	}
 
@@ -112,26 +104,26 @@ func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid order ID")
+		_ = c.Error(errInvalidOrderID)
 		return
 	}
 
 	_, err = h.orderService.GetOrderById(c.Request.Context(), int32(id))
 	if err != nil {
-		response.NotSuccess(c, http.StatusOK, "Order not found", nil)
+		_ = c.Error(err)
 		return
 	}
 
 	var req service.UpdateOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 	req.ID = int32(id)
 
 	order, err := h.orderService.UpdateOrder(c.Request.Context(), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, fmt.Sprintf("Failed to update order: %v", err))
+		_ = c.Error(err)
 		return
 	}
 
@@ -142,19 +134,19 @@ func (h *OrderHandler) DeleteOrder(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid order ID")
+		_ = c.Error(errInvalidOrderID)
 		return
 	}
 
 	_, err = h.orderService.GetOrderById(c.Request.Context(), int32(id))
 	if err != nil {
-		response.Error(c, http.StatusNotFound, "Order not found")
+		_ = c.Error(err)
 		return
 	}
 
 	err = h.orderService.DeleteOrder(c.Request.Context(), int32(id))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to delete order")
+		_ = c.Error(err)
 		return
 	}
 	response.Success(c, http.StatusOK, "Order deleted successfully", nil)
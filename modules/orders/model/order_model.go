@@ -1,8 +1,8 @@
 package order_model
 
 type OrderQuery struct {
-	Limit       int    `form:"limit" binding:"min=1"`
-	CurrentPage int    `form:"page" binding:"min=1"`
-	UserID      int    `form:"user_id"`
-	Status      string `form:"status"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int    `form:"page" binding:"omitempty,min=1"`
+	UserID      int    `form:"user_id" binding:"omitempty,gt=0"`
+	Status      string `form:"status" binding:"omitempty,order_status"`
 }
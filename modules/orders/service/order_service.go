@@ -2,15 +2,20 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 
 	db "shofy/db/sqlc"
+	"shofy/utils/apperror"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrOrderNotFound = apperror.NotFound("order_not_found", "Order not found")
+
 type OrderService interface {
 	CreateOrder(ctx context.Context, req *CreateOrderRequest) (*db.Order, error)
 	GetOrdersList(ctx context.Context, limit, offset int32, page int, userID int32, status string) (*PaginatedOrders, error)
@@ -32,17 +37,17 @@ func NewOrderService(dbPool *pgxpool.Pool) OrderService {
 type OrderItem struct {
 	ID        int32   `json:"id"`
 	OrderID   int32   `json:"order_id"`
-	ProductID string  `json:"product_id"`
-	Quantity  int32   `json:"quantity"`
-	UnitPrice float64 `json:"unit_price"`
+	ProductID string  `json:"product_id" binding:"required"`
+	Quantity  int32   `json:"quantity" binding:"required,gt=0"`
+	UnitPrice float64 `json:"unit_price" binding:"gte=0"`
 }
 
 type CreateOrderRequest struct {
-	ShopID int32       `json:"shop_id"`
-	UserID int32       `json:"user_id"`
-	Total  float64     `json:"total"`
-	Status string      `json:"status"`
-	Items  []OrderItem `json:"items"`
+	ShopID int32       `json:"shop_id" binding:"required,gt=0"`
+	UserID int32       `json:"user_id" binding:"required,gt=0"`
+	Total  float64     `json:"total" binding:"gte=0"`
+	Status string      `json:"status" binding:"required,order_status"`
+	Items  []OrderItem `json:"items" binding:"required,min=1,dive"`
 }
 
 type PaginatedOrders struct {
@@ -79,8 +84,8 @@ func mapToSnakeCase(rows []db.GetListOrdersRow) []ListOrdersRowSnake {
 
 type UpdateOrderRequest struct {
 	ID     int32   `json:"id"`
-	Total  float64 `json:"total"`
-	Status string  `json:"status"`
+	Total  float64 `json:"total" binding:"gte=0"`
+	Status string  `json:"status" binding:"required,order_status"`
 }
 
 func (s *orderService) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*db.Order, error) {
@@ -154,6 +159,9 @@ func (s *orderService) GetOrdersList(ctx context.Context, limit, offset int32, p
 func (s *orderService) GetOrderById(ctx context.Context, id int32) (*db.Order, error) {
 	order, err := s.queries.GetOrderById(ctx, id)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
 		return nil, fmt.Errorf("failed to get order by id: %w", err)
 	}
 	return &order, nil
@@ -1,16 +1,17 @@
 package handler
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 	product_model "shofy/modules/product/model"
 	"shofy/modules/product/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
 
 	"github.com/gin-gonic/gin"
 )
 
+var errInvalidProductID = apperror.Validation("invalid_product_id", "Invalid product ID")
+
 type ProductHandler struct {
 	productService service.ProductService
 }
@@ -38,13 +39,13 @@ func (h *ProductHandler) InitRoutes(router *gin.RouterGroup) {
 func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	var req service.CreateProductRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	product, err := h.productService.CreateProduct(c.Request.Context(), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, fmt.Sprintf("Failed to create product: %v", err))
+		_ = c.Error(err)
 		return
 	}
 
@@ -54,13 +55,13 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		response.Error(c, http.StatusBadRequest, "Invalid product ID")
+		_ = c.Error(errInvalidProductID)
 		return
 	}
 
 	var req service.UpdateProductRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
@@ -68,7 +69,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 
 	product, err := h.productService.UpdateProduct(c.Request.Context(), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to update product")
+		_ = c.Error(err)
 		return
 	}
 
@@ -88,13 +89,13 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 
 	id := c.Param("id")
 	if id == "" {
-		response.Error(c, http.StatusBadRequest, "Invalid product ID")
+		_ = c.Error(errInvalidProductID)
 		return
 	}
 
 	product, err := h.productService.GetProductByID(c.Request.Context(), id)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to get product by ID")
+		_ = c.Error(err)
 		return
 	}
 
@@ -109,7 +110,7 @@ func (h *ProductHandler) ListProducts(c *gin.Context) {
 
 	// Bind query parameters
 	if err := c.BindQuery(&q); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
 		return
 	}
 
@@ -125,8 +126,7 @@ func (h *ProductHandler) ListProducts(c *gin.Context) {
 
 	result, err := h.productService.ListProducts(c.Request.Context(), int32(q.Limit), int32(offset), q.CurrentPage)
 	if err != nil {
-		log.Print("Error listing products:", err)
-		response.Error(c, http.StatusInternalServerError, "Failed to ListProducts")
+		_ = c.Error(err)
 		return
 	}
 
@@ -149,7 +149,7 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 
 	products, err := h.productService.GetAllProducts(c.Request.Context())
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
@@ -162,7 +162,7 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 func (h *ProductHandler) DeleteProductByID(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		response.Error(c, http.StatusBadRequest, "Invalid product ID")
+		_ = c.Error(errInvalidProductID)
 		return
 	}
 
@@ -170,12 +170,7 @@ func (h *ProductHandler) DeleteProductByID(c *gin.Context) {
 	err := h.productService.DeleteProductByID(c.Request.Context(), id)
 
 	if err != nil {
-		if err.Error() == "Product not found" {
-			response.NotSuccess(c, http.StatusOK, "Product not found", nil)
-			return
-		}
-
-		response.Error(c, http.StatusInternalServerError, "Failed to delete product")
+		_ = c.Error(err)
 		return
 	}
 
@@ -1,6 +1,6 @@
 package product_model
 
 type ProductQuery struct {
-	Limit       int `form:"limit" binding:"min=1"`
-	CurrentPage int `form:"page" binding:"min=1"`
+	Limit       int `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int `form:"page" binding:"omitempty,min=1"`
 }
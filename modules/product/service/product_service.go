@@ -2,17 +2,21 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
 
 	db "shofy/db/sqlc"
-	"shofy/utils"
+	"shofy/utils/apperror"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrProductNotFound = apperror.NotFound("product_not_found", "Product not found")
+
 type ProductService interface {
 	GetProductByID(ctx context.Context, id string) (ListProductsRowSnake, error)
 	ListProducts(ctx context.Context, limit, offset int32, page int) (*PaginatedProducts, error)
@@ -29,13 +33,13 @@ func NewProductService(dbPool *pgxpool.Pool) ProductService {
 }
 
 type CreateProductRequest struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Stock       int32   `json:"stock"`
-	CategoryID  int32   `json:"category_id"`
-	ShopID      int32   `json:"shop_id"`
+	ID          string  `json:"id" binding:"required,max=64"`
+	Name        string  `json:"name" binding:"required,max=255"`
+	Description string  `json:"description" binding:"max=5000"`
+	Price       float64 `json:"price" binding:"gt=0"`
+	Stock       int32   `json:"stock" binding:"gte=0"`
+	CategoryID  int32   `json:"category_id" binding:"required,gt=0"`
+	ShopID      int32   `json:"shop_id" binding:"required,gt=0"`
 }
 
 type ListProductsRowSnake struct {
@@ -88,12 +92,12 @@ func (s *productService) CreateProduct(ctx context.Context, req *CreateProductRe
 
 type UpdateProductRequest struct {
 	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Stock       int32   `json:"stock"`
-	CategoryID  int32   `json:"category_id"`
-	ShopID      int32   `json:"shop_id"`
+	Name        string  `json:"name" binding:"required,max=255"`
+	Description string  `json:"description" binding:"max=5000"`
+	Price       float64 `json:"price" binding:"gt=0"`
+	Stock       int32   `json:"stock" binding:"gte=0"`
+	CategoryID  int32   `json:"category_id" binding:"required,gt=0"`
+	ShopID      int32   `json:"shop_id" binding:"required,gt=0"`
 }
 
 type productService struct {
@@ -145,7 +149,10 @@ func mapRowToSnakeCase(r db.GetProductByIDRow) ListProductsRowSnake {
 func (s *productService) GetProductByID(ctx context.Context, id string) (ListProductsRowSnake, error) {
 	product, err := s.queries.GetProductByID(ctx, id)
 	if err != nil {
-		return ListProductsRowSnake{}, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ListProductsRowSnake{}, ErrProductNotFound
+		}
+		return ListProductsRowSnake{}, fmt.Errorf("Error Database: %w", err)
 	}
 
 	getproduct := mapRowToSnakeCase(product)
@@ -161,7 +168,7 @@ func (s *productService) ListProducts(ctx context.Context, limit, offset int32,
 	})
 
 	if err != nil {
-		log.Print("Error listing products2:", err)
+		slog.ErrorContext(ctx, "Error listing products", "error", err)
 
 		return nil, err
 	}
@@ -196,10 +203,10 @@ func (s *productService) DeleteProductByID(ctx context.Context, id string) error
 	// Check if product exists
 	_, err := s.queries.GetProductByID(ctx, id)
 	if err != nil {
-		if utils.IsDBDown(err) {
-			return fmt.Errorf("database is unreachable")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProductNotFound
 		}
-		return fmt.Errorf("Product not found")
+		return fmt.Errorf("Error Database: %w", err)
 	}
 
 	// Use SQLC's DeleteProduct method with the provided context
@@ -207,6 +214,7 @@ func (s *productService) DeleteProductByID(ctx context.Context, id string) error
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %v", err)
 	}
+	s.invalidateChatCache(ctx, id)
 	return nil
 }
 
@@ -241,5 +249,14 @@ func (s *productService) UpdateProduct(ctx context.Context, req *UpdateProductRe
 	if err != nil {
 		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
+	s.invalidateChatCache(ctx, product.ID)
 	return &product, nil
 }
+
+// invalidateChatCache drops cached chat replies that mention the product so
+// customers never get a stale price or stock.
+func (s *productService) invalidateChatCache(ctx context.Context, productID string) {
+	if _, err := s.queries.DeleteResponseCacheByProduct(ctx, productID); err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate chat cache", "product_id", productID, "error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+	"shofy/middleware"
+	"shofy/modules/prompt/model"
+	"shofy/modules/prompt/service"
+	"shofy/utils/apperror"
+	"shofy/utils/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidVersion = apperror.Validation("invalid_prompt_version", "Invalid prompt version")
+)
+
+type PromptHandler struct {
+	promptService service.PromptService
+}
+
+func NewPromptHandler(promptService service.PromptService) *PromptHandler {
+	return &PromptHandler{
+		promptService: promptService,
+	}
+}
+
+// InitRoutes registers the template endpoints. rg must run AuthMiddleware
+// and RequireRole; staff manage the templates of the shop in their token.
+func (h *PromptHandler) InitRoutes(rg *gin.RouterGroup) {
+	rg.GET("", h.ListTemplates)
+	rg.POST("", h.CreateTemplate)
+	rg.GET("/active", h.ActiveTemplate)
+	rg.POST("/preview", h.Preview)
+	rg.POST("/:version/activate", h.ActivateVersion)
+}
+
+func (h *PromptHandler) ListTemplates(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var q model.TemplateListQuery
+	if err := c.BindQuery(&q); err != nil {
+		_ = c.Error(apperror.ErrInvalidQuery.Wrap(err))
+		return
+	}
+
+	limit, page, offset := response.PageBounds(q.Limit, q.CurrentPage)
+
+	templates, total, err := h.promptService.ListTemplates(c.Request.Context(), shopID, int32(limit), int32(offset))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Prompt templates fetched successfully", gin.H{
+		"data":         templates,
+		"total_items":  total,
+		"total_pages":  response.TotalPages(total, limit),
+		"current_page": page,
+		"limit":        limit,
+	})
+}
+
+func (h *PromptHandler) ActiveTemplate(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	template, err := h.promptService.ActiveTemplate(c.Request.Context(), shopID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Prompt template fetched successfully", template)
+}
+
+func (h *PromptHandler) CreateTemplate(c *gin.Context) {
+	shopID, staffID, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req model.CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	template, err := h.promptService.CreateTemplate(c.Request.Context(), shopID, staffID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Prompt template created successfully", template)
+}
+
+func (h *PromptHandler) ActivateVersion(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		_ = c.Error(errInvalidVersion)
+		return
+	}
+
+	template, err := h.promptService.ActivateVersion(c.Request.Context(), shopID, int32(version))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Prompt template activated", template)
+}
+
+func (h *PromptHandler) Preview(c *gin.Context) {
+	shopID, _, err := middleware.StaffIdentity(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req model.PreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	preview, err := h.promptService.Preview(c.Request.Context(), shopID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Prompt rendered", preview)
+}
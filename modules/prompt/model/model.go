@@ -0,0 +1,61 @@
+package model
+
+import (
+	knowledgeModel "shofy/modules/knowledge/model"
+	"time"
+)
+
+// MaxTemplateSize caps the length of a stored prompt template.
+const MaxTemplateSize = 20000
+
+type CreateTemplateRequest struct {
+	Content string `json:"content" binding:"required,max=20000"`
+	// Variables fill {{.Hours}}, {{.Policies}} and {{.Vars.<name>}}.
+	Variables map[string]string `json:"variables"`
+	Note      string            `json:"note" binding:"max=255"`
+	// Activate makes the new version live right away.
+	Activate bool `json:"activate"`
+}
+
+// PreviewRequest renders a prompt for a sample message. Content previews a
+// draft, Version a stored version; with neither the active one is used.
+// Language lists the products in that language, as for a customer writing
+// in it.
+type PreviewRequest struct {
+	Content   string            `json:"content" binding:"max=20000"`
+	Variables map[string]string `json:"variables"`
+	Version   int32             `json:"version" binding:"omitempty,min=1"`
+	Message   string            `json:"message" binding:"required,max=2000"`
+	Language  string            `json:"language" binding:"omitempty,len=2"`
+}
+
+type TemplateListQuery struct {
+	Limit       int `form:"limit" binding:"omitempty,min=1,max=100"`
+	CurrentPage int `form:"page" binding:"omitempty,min=1"`
+}
+
+type TemplateResponse struct {
+	ID        int32             `json:"id"`
+	ShopID    int32             `json:"shop_id"`
+	Version   int32             `json:"version"`
+	Content   string            `json:"content"`
+	Variables map[string]string `json:"variables"`
+	Note      string            `json:"note,omitempty"`
+	IsActive  bool              `json:"is_active"`
+	CreatedBy *int32            `json:"created_by,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type PreviewMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type PreviewResponse struct {
+	// Version is 0 when a draft or the built-in default was rendered.
+	Version      int32  `json:"version"`
+	SystemPrompt string `json:"system_prompt"`
+	// Sources are the knowledge base excerpts retrieved for the message.
+	Sources  []knowledgeModel.Source `json:"sources"`
+	Messages []PreviewMessage        `json:"messages"`
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	db "shofy/db/sqlc"
+	chatService "shofy/modules/chat/service"
+	knowledgeModel "shofy/modules/knowledge/model"
+	"shofy/modules/prompt/model"
+	"shofy/utils/apperror"
+	"shofy/utils/language"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTemplateNotFound = apperror.NotFound("prompt_template_not_found", "Prompt template not found")
+	ErrShopNotFound     = apperror.NotFound("shop_not_found", "Shop not found")
+	ErrInvalidTemplate  = apperror.Validation("invalid_prompt_template", "Prompt template is invalid")
+	ErrTemplateTooLarge = apperror.Validation("prompt_template_too_large", "Prompt template is too large")
+	ErrVersionConflict  = apperror.Conflict("prompt_version_conflict", "Another version was saved at the same time, please retry")
+)
+
+type PromptService interface {
+	ListTemplates(ctx context.Context, shopID, limit, offset int32) ([]model.TemplateResponse, int64, error)
+	ActiveTemplate(ctx context.Context, shopID int32) (*model.TemplateResponse, error)
+	CreateTemplate(ctx context.Context, shopID, staffID int32, req model.CreateTemplateRequest) (*model.TemplateResponse, error)
+	ActivateVersion(ctx context.Context, shopID, version int32) (*model.TemplateResponse, error)
+	Preview(ctx context.Context, shopID int32, req model.PreviewRequest) (*model.PreviewResponse, error)
+	// SystemPrompt renders the active template of a shop for message and
+	// returns the knowledge base sources it includes. Products are listed
+	// in lang when it is set. A template that fails at runtime falls back
+	// to DefaultTemplate.
+	SystemPrompt(ctx context.Context, shopID int32, message, lang string) (string, []knowledgeModel.Source, error)
+}
+
+// Retriever finds knowledge base excerpts for a customer message.
+type Retriever interface {
+	Retrieve(ctx context.Context, shopID int32, query string) ([]knowledgeModel.Source, error)
+}
+
+// Translator translates product names and descriptions into a language.
+type Translator interface {
+	TranslateProducts(ctx context.Context, shopID int32, lang string, products []db.GetProductsByShopIDRow) []db.GetProductsByShopIDRow
+}
+
+// NewPromptService renders shop prompts. knowledge may be nil, in which
+// case prompts carry no knowledge base excerpts, and translator may be
+// nil, in which case products are listed as stored.
+func NewPromptService(dbPool *pgxpool.Pool, knowledge Retriever, translator Translator) PromptService {
+	return &promptService{
+		db:         dbPool,
+		queries:    db.New(dbPool),
+		knowledge:  knowledge,
+		translator: translator,
+	}
+}
+
+type promptService struct {
+	db         *pgxpool.Pool
+	queries    *db.Queries
+	knowledge  Retriever
+	translator Translator
+}
+
+func (s *promptService) ListTemplates(ctx context.Context, shopID, limit, offset int32) ([]model.TemplateResponse, int64, error) {
+	rows, err := s.queries.ListPromptTemplates(ctx, db.ListPromptTemplatesParams{
+		ShopID: shopID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	total, err := s.queries.CountPromptTemplates(ctx, shopID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error Database: %w", err)
+	}
+
+	templates := make([]model.TemplateResponse, 0, len(rows))
+	for _, row := range rows {
+		templates = append(templates, toResponse(row))
+	}
+	return templates, total, nil
+}
+
+func (s *promptService) ActiveTemplate(ctx context.Context, shopID int32) (*model.TemplateResponse, error) {
+	row, err := s.queries.GetActivePromptTemplate(ctx, shopID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	resp := toResponse(row)
+	return &resp, nil
+}
+
+func (s *promptService) CreateTemplate(ctx context.Context, shopID, staffID int32, req model.CreateTemplateRequest) (*model.TemplateResponse, error) {
+	if err := validate(req.Content, req.Variables); err != nil {
+		return nil, err
+	}
+
+	vars, err := json.Marshal(nonNil(req.Variables))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.queries.WithTx(tx)
+
+	row, err := qtx.CreatePromptTemplate(ctx, db.CreatePromptTemplateParams{
+		ShopID:    shopID,
+		Content:   req.Content,
+		Variables: vars,
+		Note:      pgtype.Text{String: req.Note, Valid: req.Note != ""},
+		CreatedBy: pgtype.Int4{Int32: staffID, Valid: staffID != 0},
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrVersionConflict
+		}
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	if req.Activate {
+		row, err = activate(ctx, qtx, shopID, row.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	resp := toResponse(row)
+	return &resp, nil
+}
+
+// ActivateVersion makes version the live template. Activating an older
+// version is how a shop rolls back.
+func (s *promptService) ActivateVersion(ctx context.Context, shopID, version int32) (*model.TemplateResponse, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row, err := activate(ctx, s.queries.WithTx(tx), shopID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	resp := toResponse(row)
+	return &resp, nil
+}
+
+func activate(ctx context.Context, qtx *db.Queries, shopID, version int32) (db.PromptTemplate, error) {
+	if err := qtx.DeactivatePromptTemplates(ctx, shopID); err != nil {
+		return db.PromptTemplate{}, fmt.Errorf("Error Database: %w", err)
+	}
+
+	row, err := qtx.ActivatePromptTemplate(ctx, db.ActivatePromptTemplateParams{
+		ShopID:  shopID,
+		Version: version,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.PromptTemplate{}, ErrTemplateNotFound
+		}
+		return db.PromptTemplate{}, fmt.Errorf("Error Database: %w", err)
+	}
+
+	// Cached chat replies were written under the previous template
+	if _, err := qtx.DeleteResponseCacheByShop(ctx, shopID); err != nil {
+		return db.PromptTemplate{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return row, nil
+}
+
+func (s *promptService) Preview(ctx context.Context, shopID int32, req model.PreviewRequest) (*model.PreviewResponse, error) {
+	var (
+		content string
+		vars    map[string]string
+		version int32
+	)
+	switch {
+	case req.Content != "":
+		if err := validate(req.Content, req.Variables); err != nil {
+			return nil, err
+		}
+		content, vars = req.Content, req.Variables
+	case req.Version != 0:
+		row, err := s.queries.GetPromptTemplateByVersion(ctx, db.GetPromptTemplateByVersionParams{
+			ShopID:  shopID,
+			Version: req.Version,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrTemplateNotFound
+			}
+			return nil, fmt.Errorf("Error Database: %w", err)
+		}
+		content, vars, version = row.Content, decodeVars(row.Variables), row.Version
+	default:
+		row, err := s.activeOrDefault(ctx, shopID)
+		if err != nil {
+			return nil, err
+		}
+		content, vars, version = row.Content, decodeVars(row.Variables), row.Version
+	}
+	data, err := s.templateData(ctx, shopID, vars, req.Message, req.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := Render(content, data)
+	if err != nil {
+		return nil, invalidTemplate(err)
+	}
+
+	return &model.PreviewResponse{
+		Version:      version,
+		SystemPrompt: prompt,
+		Sources:      data.Sources,
+		Messages: []model.PreviewMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: req.Message},
+		},
+	}, nil
+}
+
+func (s *promptService) SystemPrompt(ctx context.Context, shopID int32, message, lang string) (string, []knowledgeModel.Source, error) {
+	row, err := s.activeOrDefault(ctx, shopID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := s.templateData(ctx, shopID, decodeVars(row.Variables), message, lang)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prompt, err := Render(row.Content, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "Prompt template failed, using default", "shop_id", shopID, "version", row.Version, "error", err)
+		prompt, err = Render(DefaultTemplate, data)
+	}
+	return prompt, data.Sources, err
+}
+
+// activeOrDefault returns the active template, or DefaultTemplate as
+// version 0 when the shop has none.
+func (s *promptService) activeOrDefault(ctx context.Context, shopID int32) (db.PromptTemplate, error) {
+	row, err := s.queries.GetActivePromptTemplate(ctx, shopID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.PromptTemplate{ShopID: shopID, Content: DefaultTemplate}, nil
+		}
+		return db.PromptTemplate{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return row, nil
+}
+
+func (s *promptService) templateData(ctx context.Context, shopID int32, vars map[string]string, message, lang string) (Data, error) {
+	shop, err := s.queries.GetShopsById(ctx, shopID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Data{}, ErrShopNotFound
+		}
+		return Data{}, fmt.Errorf("Error Database: %w", err)
+	}
+
+	products, err := s.queries.GetProductsByShopID(ctx, shopID)
+	if err != nil {
+		return Data{}, fmt.Errorf("Error Database: %w", err)
+	}
+	if s.translator != nil && lang != "" {
+		products = s.translator.TranslateProducts(ctx, shopID, lang, products)
+	}
+
+	// Answering without the knowledge base beats not answering at all.
+	var sources []knowledgeModel.Source
+	if s.knowledge != nil {
+		sources, err = s.knowledge.Retrieve(ctx, shopID, message)
+		if err != nil {
+			slog.WarnContext(ctx, "Knowledge retrieval failed", "shop_id", shopID, "error", err)
+		}
+	}
+
+	data := newData(shop, vars, products, sources, chatService.EscalateMarker, message)
+	if lang != "" {
+		data.Language = language.Name(lang)
+	}
+	return data, nil
+}
+
+// validate parses content and runs it against sample data so that
+// references to unknown fields are caught before the template is saved.
+func validate(content string, vars map[string]string) error {
+	if len(content) > model.MaxTemplateSize {
+		return ErrTemplateTooLarge
+	}
+	if _, err := Parse(content); err != nil {
+		return invalidTemplate(err)
+	}
+	sample := newData(db.Shop{Name: "Contoh"}, vars, nil, []knowledgeModel.Source{{Ref: 1, Title: "Contoh", Content: "Contoh"}}, chatService.EscalateMarker, "Halo")
+	if _, err := Render(content, sample); err != nil {
+		return invalidTemplate(err)
+	}
+	return nil
+}
+
+func toResponse(row db.PromptTemplate) model.TemplateResponse {
+	resp := model.TemplateResponse{
+		ID:        row.ID,
+		ShopID:    row.ShopID,
+		Version:   row.Version,
+		Content:   row.Content,
+		Variables: decodeVars(row.Variables),
+		Note:      row.Note.String,
+		IsActive:  row.IsActive,
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.CreatedBy.Valid {
+		resp.CreatedBy = &row.CreatedBy.Int32
+	}
+	return resp
+}
+
+func decodeVars(raw []byte) map[string]string {
+	vars := map[string]string{}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &vars)
+	}
+	return vars
+}
+
+func nonNil(vars map[string]string) map[string]string {
+	if vars == nil {
+		return map[string]string{}
+	}
+	return vars
+}
+
+// invalidTemplate reports a parse or execution error back to the author.
+func invalidTemplate(err error) error {
+	return ErrInvalidTemplate.Wrap(err).WithDetails(err.Error())
+}
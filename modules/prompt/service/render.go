@@ -0,0 +1,176 @@
+package service
+
+import (
+	"fmt"
+	db "shofy/db/sqlc"
+	knowledgeModel "shofy/modules/knowledge/model"
+	knowledgeService "shofy/modules/knowledge/service"
+	"shofy/utils"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplate is used for shops without an active template.
+const DefaultTemplate = `Kamu adalah asisten virtual dari sebuah toko online bernama "{{.Shop.Name}}".
+Tugas kamu:
+- Menjawab pertanyaan tentang produk yang dijual
+- Menjelaskan detail dan stok barang
+- Membantu pelanggan dalam proses pemesanan
+- Memberikan jawaban yang relevan dan informatif
+
+Berikan jawaban yang rapi dengan format seperti berikut:
+
+Produk "sepatu" tersedia di:
+
+1. Toko A
+- Stok: 10
+- Harga: Rp 100.000
+
+2. Toko B
+- Stok: 20
+- Harga: Rp 120.000
+
+Pisahkan setiap item dengan newline (\n) agar mudah dibaca di frontend.
+
+Jika pelanggan menulis "Nama saya [X]", anggap [X] sebagai nama aslinya,
+walaupun [X] terdengar seperti kata biasa.
+
+Jawab hanya berdasarkan data di bawah. Jika informasinya tidak ada, katakan
+dengan jujur bahwa kamu tidak tahu.
+
+Jika pelanggan meminta sesuatu yang tidak bisa kamu bantu (misalnya komplain,
+pengembalian dana, atau masalah pesanan), balas hanya dengan {{.EscalateMarker}}.
+
+Data toko:
+- Nama: {{.Shop.Name}}
+{{- with .Shop.Description}}
+- Deskripsi: {{.}}{{end}}
+- Alamat: {{.Shop.Address}}, {{.Shop.City}}, {{.Shop.Country}}
+{{- with .Shop.Phone}}
+- WhatsApp: {{.}}{{end}}
+{{- with .Shop.Email}}
+- Email: {{.}}{{end}}
+{{- with .Hours}}
+
+Jam operasional:
+{{.}}{{end}}
+{{- with .Policies}}
+
+Kebijakan toko:
+{{.}}{{end}}
+
+Data produk:
+{{.CatalogSummary}}
+{{- with .Knowledge}}
+
+Dokumen toko. Jika jawabanmu memakai dokumen ini, tulis nomor sumbernya,
+misalnya [1]:
+{{.}}{{end}}`
+
+// ShopInfo is the shop as seen by templates.
+type ShopInfo struct {
+	Name        string
+	Description string
+	Address     string
+	City        string
+	Country     string
+	Phone       string
+	Email       string
+	Website     string
+}
+
+// CatalogItem is one product line of the catalog summary.
+type CatalogItem struct {
+	Name        string
+	Category    string
+	Description string
+	Price       string
+	Stock       int32
+}
+
+// Data is what a prompt template is executed with.
+type Data struct {
+	Shop     ShopInfo
+	Hours    string
+	Policies string
+	// Vars holds every stored variable, including hours and policies.
+	Vars    map[string]string
+	Catalog []CatalogItem
+	// CatalogSummary is Catalog rendered one product per line.
+	CatalogSummary string
+	EscalateMarker string
+	// Knowledge is the knowledge base excerpts relevant to Message,
+	// numbered [1], [2], ... so that replies can cite them.
+	Knowledge string
+	Sources   []knowledgeModel.Source
+	// Message is the customer message being answered.
+	Message string
+	// Language is the name of the language the reply is written in, e.g.
+	// "English", or empty when it was not resolved.
+	Language string
+}
+
+// Parse compiles a template. Unknown variables render as empty strings.
+func Parse(content string) (*template.Template, error) {
+	return template.New("prompt").Option("missingkey=zero").Parse(content)
+}
+
+// Render executes content with data.
+func Render(content string, data Data) (string, error) {
+	tmpl, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func newData(shop db.Shop, vars map[string]string, products []db.GetProductsByShopIDRow, sources []knowledgeModel.Source, escalateMarker, message string) Data {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	data := Data{
+		Shop: ShopInfo{
+			Name:        shop.Name,
+			Description: shop.Description,
+			Address:     shop.Address,
+			City:        shop.City,
+			Country:     shop.Country,
+			Phone:       shop.WhatsappPhone.String,
+			Email:       shop.Email.String,
+			Website:     shop.WebsiteUrl.String,
+		},
+		Hours:          vars["hours"],
+		Policies:       vars["policies"],
+		Vars:           vars,
+		Knowledge:      knowledgeService.FormatSources(sources),
+		Sources:        sources,
+		EscalateMarker: escalateMarker,
+		Message:        message,
+	}
+
+	lines := make([]string, 0, len(products))
+	for _, p := range products {
+		item := CatalogItem{
+			Name:        p.Name,
+			Category:    p.CategoryID,
+			Description: p.Description.String,
+			Price:       "-",
+			Stock:       p.Stock.Int32,
+		}
+		if p.Price.Valid {
+			item.Price = utils.FormatRupiah(p.Price)
+		}
+		data.Catalog = append(data.Catalog, item)
+		lines = append(lines, fmt.Sprintf("- %s (%s) | Harga: %s | Stok: %d", item.Name, item.Category, item.Price, item.Stock))
+	}
+	if len(lines) == 0 {
+		data.CatalogSummary = "Belum ada produk."
+	} else {
+		data.CatalogSummary = strings.Join(lines, "\n")
+	}
+	return data
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	db "shofy/db/sqlc"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRenderDefaultTemplate(t *testing.T) {
+	var price pgtype.Numeric
+	if err := price.Scan("120000"); err != nil {
+		t.Fatal(err)
+	}
+	data := newData(
+		db.Shop{Name: "Toko Budi", City: "Bandung"},
+		map[string]string{"hours": "Senin-Jumat 09.00-17.00"},
+		[]db.GetProductsByShopIDRow{{Name: "Sepatu", CategoryID: "Alas Kaki", Price: price, Stock: pgtype.Int4{Int32: 3, Valid: true}}},
+		nil, "[ESCALATE]", "Halo",
+	)
+
+	got, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`bernama "Toko Budi"`, "Senin-Jumat 09.00-17.00", "- Sepatu (Alas Kaki) | Harga: Rp 120.000 | Stok: 3", "[ESCALATE]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered prompt misses %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Kebijakan toko") {
+		t.Error("empty policies should be left out")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validate("{{.Shop.Name}} {{.Vars.missing}} {{.Message}}", nil); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+	for _, content := range []string{"{{.Shop.Name", "{{.Unknown}}", strings.Repeat("x", 20001)} {
+		err := validate(content, nil)
+		if !errors.Is(err, ErrInvalidTemplate) && !errors.Is(err, ErrTemplateTooLarge) {
+			t.Errorf("validate(%.20q) = %v, want invalid template", content, err)
+		}
+	}
+}
@@ -1,16 +1,18 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 	model "shofy/modules/role/model"
 	"shofy/modules/role/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+var errInvalidRoleID = apperror.Validation("invalid_role_id", "Invalid role ID")
+
 type RoleHandler struct {
 	roleService service.RoleService
 }
@@ -32,7 +34,7 @@ func (h *RoleHandler) InitRoutes(router *gin.RouterGroup) {
 func (h *RoleHandler) ListRoles(c *gin.Context) {
 	roles, err := h.roleService.ListRole(c.Request.Context())
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		_ = c.Error(err)
 		return
 	}
 
@@ -42,17 +44,13 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 func (h *RoleHandler) RolesByID(c *gin.Context) {
 	idRole, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid role ID")
+		_ = c.Error(errInvalidRoleID)
 		return
 	}
 
 	role, err := h.roleService.RolesByID(c.Request.Context(), int32(idRole))
 	if err != nil {
-		if err.Error() == "Role not found" {
-			response.NotSuccess(c, http.StatusOK, "Role not found", nil)
-			return
-		}
-		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		_ = c.Error(err)
 		return
 	}
 
@@ -62,17 +60,13 @@ func (h *RoleHandler) RolesByID(c *gin.Context) {
 func (h *RoleHandler) DeleteRolesByID(c *gin.Context) {
 	idRole, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid role ID")
+		_ = c.Error(errInvalidRoleID)
 		return
 	}
 
 	err = h.roleService.DeleteRolesByID(c.Request.Context(), int32(idRole))
 	if err != nil {
-		if err.Error() == "role not found" {
-			response.NotSuccess(c, http.StatusOK, "Role not found", nil)
-			return
-		}
-		response.Error(c, http.StatusInternalServerError, "Failed to delete role")
+		_ = c.Error(err)
 		return
 	}
 
@@ -83,19 +77,14 @@ func (h *RoleHandler) UpdateRolesById(c *gin.Context) {
 	var roleUpdate model.UpdateRolesRequest
 
 	if err := c.ShouldBindJSON(&roleUpdate); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	err := h.roleService.UpdateRolesById(c.Request.Context(), &roleUpdate)
 
 	if err != nil {
-		if err.Error() == "role not found" {
-			response.NotSuccess(c, http.StatusOK, "Role not found", nil)
-			return
-		}
-		log.Printf("Error Failed to update Roles", err)
-		response.Error(c, http.StatusInternalServerError, "Failed to update user")
+		_ = c.Error(err)
 		return
 	}
 
@@ -106,14 +95,13 @@ func (h *RoleHandler) CreateRoles(c *gin.Context) {
 	var roleCreate model.CreateRolesRequest
 
 	if err := c.ShouldBindJSON(&roleCreate); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	role, err := h.roleService.CreateRoles(c.Request.Context(), &roleCreate)
 	if err != nil {
-		log.Printf("Error creating role: %v", err)
-		response.Error(c, http.StatusInternalServerError, "Failed to create role")
+		_ = c.Error(err)
 		return
 	}
 
@@ -1,9 +1,9 @@
 package service
 
 type UpdateRolesRequest struct {
-	Name      string `json:"name"`
+	Name      string `json:"name" binding:"required,max=50"`
 	Is_active bool   `json:"is_active"`
-	Id        int32  `json:"id"`
+	Id        int32  `json:"id" binding:"required,gt=0"`
 }
 
 type ListRoleResponse struct {
@@ -17,6 +17,6 @@ type RoleResponse struct {
 }
 
 type CreateRolesRequest struct {
-	Name     string `json:"name"`
+	Name     string `json:"name" binding:"required,max=50"`
 	IsActive bool   `json:"is_active"`
 }
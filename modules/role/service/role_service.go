@@ -2,15 +2,21 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	db "shofy/db/sqlc"
 	role_model "shofy/modules/role/model"
+	"shofy/utils/apperror"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrRoleNotFound = apperror.NotFound("role_not_found", "Role not found")
+	ErrRoleExists   = apperror.Conflict("role_exists", "Role already exists")
+)
+
 type RoleService interface {
 	ListRole(ctx context.Context) (*role_model.ListRoleResponse, error)
 	RolesByID(ctx context.Context, idRole int32) (*role_model.RoleResponse, error)
@@ -53,8 +59,8 @@ func (s *roleService) RolesByID(ctx context.Context, idRole int32) (*role_model.
 	rows, err := s.queries.GetRoleByID(ctx, idRole)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("Role not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRoleNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -69,8 +75,8 @@ func (s *roleService) DeleteRolesByID(ctx context.Context, idRole int32) error {
 	_, err := s.queries.GetRoleByID(ctx, idRole)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("role not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRoleNotFound
 		}
 		return fmt.Errorf("failed to get role: %w", err)
 	}
@@ -87,8 +93,8 @@ func (s *roleService) UpdateRolesById(ctx context.Context, req *role_model.Updat
 	_, err := s.queries.GetRoleByID(ctx, req.Id)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("role not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRoleNotFound
 		}
 		return fmt.Errorf("failed to get role: %w", err)
 	}
@@ -110,8 +116,8 @@ func (s *roleService) CreateRoles(ctx context.Context, req *role_model.CreateRol
 	_, err := s.queries.GetRoleByName(ctx, req.Name)
 	if err == nil {
 		// Role ditemukan => return error
-		return nil, fmt.Errorf("role with name %s already exists", req.Name)
-	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRoleExists
+	} else if !errors.Is(err, pgx.ErrNoRows) {
 		return nil, fmt.Errorf("failed to check existing role: %w", err)
 	}
 
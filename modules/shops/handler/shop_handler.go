@@ -1,16 +1,18 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 	model "shofy/modules/shops/model"
 	"shofy/modules/shops/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+var errInvalidShopID = apperror.Validation("invalid_shop_id", "Invalid shops ID")
+
 type ShopHandler struct {
 	shopService service.ShopService
 }
@@ -47,7 +49,7 @@ func (h *ShopHandler) ListShops(c *gin.Context) {
 
 	shopss, err := h.shopService.ListShops(c.Request.Context(), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to list shops:")
+		_ = c.Error(err)
 		return
 	}
 
@@ -59,18 +61,13 @@ func (h *ShopHandler) GetShopsByID(c *gin.Context) {
 
 	shopsIdInt, err := strconv.Atoi(shopsId)
 	if err != nil {
-		response.NotSuccess(c, http.StatusOK, "Invalid shops ID", nil)
+		_ = c.Error(errInvalidShopID)
 		return
 	}
 
 	shops, err := h.shopService.GetShopsByID(c.Request.Context(), int32(shopsIdInt))
 	if err != nil {
-		if err.Error() == "shops not found" {
-			response.NotSuccess(c, http.StatusOK, "Shops not found", nil)
-			return
-		}
-		log.Printf("Error getting shops by ID %d: %v", shopsIdInt, err)
-		response.Error(c, http.StatusInternalServerError, "Failed to GetShopsByID")
+		_ = c.Error(err)
 		return
 	}
 
@@ -78,33 +75,21 @@ func (h *ShopHandler) GetShopsByID(c *gin.Context) {
 }
 
 func (h *ShopHandler) UpdateShops(c *gin.Context) {
-	shopsId := c.Param("id")
-
-	if shopsId == "" {
-		response.Error(c, http.StatusBadRequest, "Shops ID is required")
-		return
-	}
-
-	shopsIdInt, err := strconv.Atoi(shopsId)
+	shopsIdInt, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid Shops ID")
+		_ = c.Error(errInvalidShopID)
 		return
 	}
 
 	var req model.ShopsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	shops, err := h.shopService.UpdateShops(c.Request.Context(), int32(shopsIdInt), &req)
 	if err != nil {
-		if err.Error() == "shops not found" {
-			response.Error(c, http.StatusNotFound, "shops not found")
-			return
-		}
-		log.Printf("Error UpdateShops shops by ID %d: %v", shopsIdInt, err)
-		response.Error(c, http.StatusInternalServerError, "Failed to update shops")
+		_ = c.Error(err)
 		return
 	}
 
@@ -112,25 +97,14 @@ func (h *ShopHandler) UpdateShops(c *gin.Context) {
 }
 
 func (h *ShopHandler) DeleteShopsByID(c *gin.Context) {
-	userId := c.Param("id")
-	if userId == "" {
-		response.NotSuccess(c, http.StatusOK, "Shops ID is required", nil)
-		return
-	}
-
-	userIdInt, err := strconv.Atoi(userId)
+	userIdInt, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		response.NotSuccess(c, http.StatusOK, "Invalid Shops ID", nil)
+		_ = c.Error(errInvalidShopID)
 		return
 	}
 
 	if err := h.shopService.DeleteShopsByID(c.Request.Context(), int32(userIdInt)); err != nil {
-		if err.Error() == "Shops not found" {
-			response.NotSuccess(c, http.StatusOK, "Shops not found", nil)
-			return
-		}
-		log.Printf("Error Delete Shops by ID %d: %v", userIdInt, err)
-		response.Error(c, http.StatusInternalServerError, "Failed DeleteShopsByID")
+		_ = c.Error(err)
 		return
 	}
 
@@ -140,18 +114,13 @@ func (h *ShopHandler) DeleteShopsByID(c *gin.Context) {
 func (h *ShopHandler) CreateShops(c *gin.Context) {
 	var req model.ShopsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	user, err := h.shopService.CreateShops(c.Request.Context(), &req)
 	if err != nil {
-		if err.Error() == "Phone already exist" {
-			response.Error(c, http.StatusConflict, "Phone already exist")
-			return
-		}
-		log.Printf("Error CreateShops", err)
-		response.Error(c, http.StatusInternalServerError, "Failed to create Shops")
+		_ = c.Error(err)
 		return
 	}
 
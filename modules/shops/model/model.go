@@ -21,6 +21,8 @@ type ShopsResponse struct {
 	Longitude     float32 `json:"longitude"`
 	ZipCode       string  `json:"zip_code"`
 	Country       string  `json:"country"`
+	// ChatResumeHours is how long an idle chat session is resumed.
+	ChatResumeHours int32 `json:"chat_resume_hours"`
 }
 
 type ListShopsResponse struct {
@@ -32,18 +34,20 @@ type ListShopsResponse struct {
 }
 
 type ShopsRequest struct {
-	Name          string  `json:"name"`
-	Description   string  `json:"description"`
-	LogoUrl       string  `json:"logo_url"`
-	WebsiteUrl    string  `json:"website_url"`
-	Email         string  `json:"email"`
-	WhatsappPhone string  `json:"whatsapp_phone"`
-	Address       string  `json:"address"`
-	City          string  `json:"city"`
-	State         string  `json:"state"`
+	Name          string  `json:"name" binding:"required,max=255"`
+	Description   string  `json:"description" binding:"max=5000"`
+	LogoUrl       string  `json:"logo_url" binding:"omitempty,url"`
+	WebsiteUrl    string  `json:"website_url" binding:"omitempty,url"`
+	Email         string  `json:"email" binding:"omitempty,email"`
+	WhatsappPhone string  `json:"whatsapp_phone" binding:"omitempty,phone"`
+	Address       string  `json:"address" binding:"max=500"`
+	City          string  `json:"city" binding:"max=100"`
+	State         string  `json:"state" binding:"max=100"`
 	IsActive      bool    `json:"is_active"`
-	Latitude      float32 `json:"latitude"`
-	Longitude     float32 `json:"longitude"`
-	ZipCode       string  `json:"zip_code"`
-	Country       string  `json:"country"`
+	Latitude      float32 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude     float32 `json:"longitude" binding:"min=-180,max=180"`
+	ZipCode       string  `json:"zip_code" binding:"max=20"`
+	Country       string  `json:"country" binding:"max=100"`
+	// ChatResumeHours is optional; zero keeps the current (or default) window.
+	ChatResumeHours int32 `json:"chat_resume_hours" binding:"omitempty,min=1,max=720"`
 }
@@ -2,20 +2,26 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
-	"log"
+	"log/slog"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"fmt"
 	db "shofy/db/sqlc"
 	model "shofy/modules/shops/model"
 	"shofy/utils"
+	"shofy/utils/apperror"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrShopNotFound = apperror.NotFound("shop_not_found", "Shops not found")
+	ErrShopExists   = apperror.Conflict("shop_exists", "Shops already exist")
+)
+
 type ShopService interface {
 	GetShopsByID(ctx context.Context, id int32) (model.ShopsResponse, error)
 	ListShops(ctx context.Context, req *model.ListShopRequest) (*model.ListShopsResponse, error)
@@ -66,21 +72,22 @@ func (s *shopService) ListShops(ctx context.Context, req *model.ListShopRequest)
 	shopsResponses := make([]model.ShopsResponse, 0, len(shops))
 	for _, shop := range shops {
 		shopsResponses = append(shopsResponses, model.ShopsResponse{
-			ID:            shop.ID,
-			Name:          shop.Name,
-			Description:   shop.Description,
-			LogoUrl:       shop.LogoUrl.String,
-			WebsiteUrl:    shop.WebsiteUrl.String,
-			Email:         shop.Email.String,
-			WhatsappPhone: shop.WhatsappPhone.String,
-			Address:       shop.Address,
-			City:          shop.City,
-			State:         shop.State,
-			IsActive:      shop.IsActive,
-			Latitude:      float32(shop.Latitude),
-			Longitude:     float32(shop.Longitude),
-			ZipCode:       shop.ZipCode,
-			Country:       shop.Country,
+			ID:              shop.ID,
+			Name:            shop.Name,
+			Description:     shop.Description,
+			LogoUrl:         shop.LogoUrl.String,
+			WebsiteUrl:      shop.WebsiteUrl.String,
+			Email:           shop.Email.String,
+			WhatsappPhone:   shop.WhatsappPhone.String,
+			Address:         shop.Address,
+			City:            shop.City,
+			State:           shop.State,
+			IsActive:        shop.IsActive,
+			Latitude:        float32(shop.Latitude),
+			Longitude:       float32(shop.Longitude),
+			ZipCode:         shop.ZipCode,
+			Country:         shop.Country,
+			ChatResumeHours: shop.ChatResumeHours,
 		})
 	}
 
@@ -96,28 +103,29 @@ func (s *shopService) ListShops(ctx context.Context, req *model.ListShopRequest)
 func (s *shopService) GetShopsByID(ctx context.Context, id int32) (model.ShopsResponse, error) {
 	shop, err := s.queries.GetShopsById(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return model.ShopsResponse{}, fmt.Errorf("shops not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ShopsResponse{}, ErrShopNotFound
 		}
 		return model.ShopsResponse{}, fmt.Errorf("failed to get shops: %w", err)
 	}
 
 	return model.ShopsResponse{
-		ID:            shop.ID,
-		Name:          shop.Name,
-		Description:   shop.Description,
-		LogoUrl:       shop.LogoUrl.String,
-		WebsiteUrl:    shop.WebsiteUrl.String,
-		Email:         shop.Email.String,
-		WhatsappPhone: shop.WhatsappPhone.String,
-		Address:       shop.Address,
-		City:          shop.City,
-		State:         shop.State,
-		IsActive:      shop.IsActive,
-		Latitude:      float32(shop.Latitude),
-		Longitude:     float32(shop.Longitude),
-		ZipCode:       shop.ZipCode,
-		Country:       shop.Country,
+		ID:              shop.ID,
+		Name:            shop.Name,
+		Description:     shop.Description,
+		LogoUrl:         shop.LogoUrl.String,
+		WebsiteUrl:      shop.WebsiteUrl.String,
+		Email:           shop.Email.String,
+		WhatsappPhone:   shop.WhatsappPhone.String,
+		Address:         shop.Address,
+		City:            shop.City,
+		State:           shop.State,
+		IsActive:        shop.IsActive,
+		Latitude:        float32(shop.Latitude),
+		Longitude:       float32(shop.Longitude),
+		ZipCode:         shop.ZipCode,
+		Country:         shop.Country,
+		ChatResumeHours: shop.ChatResumeHours,
 	}, nil
 }
 
@@ -125,8 +133,8 @@ func (s *shopService) UpdateShops(ctx context.Context, shopsId int32, req *model
 	// Get existing user
 	_, err := s.queries.GetShopsById(ctx, shopsId)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("shops not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrShopNotFound
 		}
 		return nil, fmt.Errorf("failed to get shops: %w", err)
 	}
@@ -160,27 +168,30 @@ func (s *shopService) UpdateShops(ctx context.Context, shopsId int32, req *model
 		Latitude:  float64(req.Latitude),
 		Longitude: float64(req.Longitude),
 		IsActive:  req.IsActive,
+		// Zero keeps the current window.
+		ChatResumeHours: pgtype.Int4{Int32: req.ChatResumeHours, Valid: req.ChatResumeHours != 0},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update Shops profile: %w", err)
 	}
 
 	return &model.ShopsResponse{
-		ID:            result.ID,
-		Name:          result.Name,
-		Description:   result.Description,
-		LogoUrl:       result.LogoUrl.String,
-		WebsiteUrl:    result.WebsiteUrl.String,
-		Email:         result.Email.String,
-		WhatsappPhone: result.WhatsappPhone.String,
-		Address:       result.Address,
-		City:          result.City,
-		State:         result.State,
-		IsActive:      result.IsActive,
-		Latitude:      float32(result.Latitude),
-		Longitude:     float32(result.Longitude),
-		ZipCode:       result.ZipCode,
-		Country:       result.Country,
+		ID:              result.ID,
+		Name:            result.Name,
+		Description:     result.Description,
+		LogoUrl:         result.LogoUrl.String,
+		WebsiteUrl:      result.WebsiteUrl.String,
+		Email:           result.Email.String,
+		WhatsappPhone:   result.WhatsappPhone.String,
+		Address:         result.Address,
+		City:            result.City,
+		State:           result.State,
+		IsActive:        result.IsActive,
+		Latitude:        float32(result.Latitude),
+		Longitude:       float32(result.Longitude),
+		ZipCode:         result.ZipCode,
+		Country:         result.Country,
+		ChatResumeHours: result.ChatResumeHours,
 	}, nil
 }
 
@@ -188,8 +199,8 @@ func (s *shopService) DeleteShopsByID(ctx context.Context, id int32) error {
 	// Check if user exists
 	_, err := s.queries.GetShopsById(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("Shops not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrShopNotFound
 		}
 		return fmt.Errorf("failed to get user: %w", err)
 	}
@@ -212,9 +223,12 @@ func (s *shopService) CreateShops(ctx context.Context, req *model.ShopsRequest)
 			Valid:  req.WhatsappPhone != "",
 		},
 	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
 
 	if result.ID != 0 {
-		return nil, fmt.Errorf("Shops already exist")
+		return nil, ErrShopExists
 	}
 
 	// Create user
@@ -245,28 +259,31 @@ func (s *shopService) CreateShops(ctx context.Context, req *model.ShopsRequest)
 		Latitude:  float64(req.Latitude),
 		Longitude: float64(req.Longitude),
 		IsActive:  true,
+		// Zero uses the default window.
+		ChatResumeHours: pgtype.Int4{Int32: req.ChatResumeHours, Valid: req.ChatResumeHours != 0},
 	})
 
 	// Check for errors
 	if err != nil {
-		log.Println("Error creating user:", err)
+		slog.ErrorContext(ctx, "Error creating user", "error", err)
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return &model.ShopsResponse{
-		Name:          shopResult.Name,
-		Description:   shopResult.Description,
-		LogoUrl:       shopResult.LogoUrl.String,
-		WebsiteUrl:    shopResult.WebsiteUrl.String,
-		Email:         shopResult.Email.String,
-		WhatsappPhone: shopResult.WhatsappPhone.String,
-		Address:       shopResult.Address,
-		City:          shopResult.City,
-		State:         shopResult.State,
-		IsActive:      result.IsActive,
-		Latitude:      float32(shopResult.Latitude),
-		Longitude:     float32(shopResult.Longitude),
-		ZipCode:       shopResult.ZipCode,
-		Country:       shopResult.Country,
+		Name:            shopResult.Name,
+		Description:     shopResult.Description,
+		LogoUrl:         shopResult.LogoUrl.String,
+		WebsiteUrl:      shopResult.WebsiteUrl.String,
+		Email:           shopResult.Email.String,
+		WhatsappPhone:   shopResult.WhatsappPhone.String,
+		Address:         shopResult.Address,
+		City:            shopResult.City,
+		State:           shopResult.State,
+		IsActive:        result.IsActive,
+		Latitude:        float32(shopResult.Latitude),
+		Longitude:       float32(shopResult.Longitude),
+		ZipCode:         shopResult.ZipCode,
+		Country:         shopResult.Country,
+		ChatResumeHours: shopResult.ChatResumeHours,
 	}, nil
 }
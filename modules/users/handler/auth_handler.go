@@ -1,12 +1,12 @@
 package handler
 
 import (
-	"log"
 	"net/http"
+	"shofy/middleware"
 	model "shofy/modules/users/model"
 	"shofy/modules/users/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,49 +21,47 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 	}
 }
 
-func (h *AuthHandler) InitRoutes(r *gin.RouterGroup) {
+// InitRoutes registers the auth routes. sendLimit runs before every route
+// that sends an OTP or login link.
+func (h *AuthHandler) InitRoutes(r *gin.RouterGroup, sendLimit gin.HandlerFunc) {
 	authRoutes := r.Group("/auth")
 	{
-		authRoutes.POST("/otp/send", h.SendOTP)
+		authRoutes.POST("/otp/send", sendLimit, h.SendOTP)
 		authRoutes.POST("/otp/verify", h.VerifyOTP)
+		authRoutes.POST("/register/otp/send", sendLimit, h.SendRegistrationOTP)
+		authRoutes.POST("/register", h.Register)
+		authRoutes.POST("/email/otp/send", sendLimit, h.SendEmailOTP)
+		authRoutes.POST("/email/otp/verify", h.VerifyEmailOTP)
+		authRoutes.POST("/email/link/send", sendLimit, h.SendMagicLink)
+		authRoutes.POST("/email/link/verify", h.VerifyMagicLink)
+	}
+
+	contactRoutes := authRoutes.Group("/contact")
+	contactRoutes.Use(middleware.AuthMiddleware())
+	{
+		contactRoutes.POST("/phone", sendLimit, h.RequestPhoneChange)
+		contactRoutes.POST("/email", sendLimit, h.RequestEmailChange)
+		contactRoutes.POST("/verify", h.ConfirmContactChange)
 	}
 }
 
 func (h *AuthHandler) SendOTP(c *gin.Context) {
-	log.Println("WHATSHAP")
-
 	var req model.SendOTPRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Validate phone number
-	if req.Phone == "" {
-		response.NotSuccess(c, http.StatusBadRequest, "Phone number is required", nil)
-		return
-	}
-
-	if req.Code == "" {
-		response.NotSuccess(c, http.StatusBadRequest, "Code is required", nil)
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	// Generate and send OTP
-	data, err := h.authService.GenerateAndSendOTP(c.Request.Context(), req)
+	data, err := h.authService.GenerateAndSendOTP(c.Request.Context(), req, c.ClientIP())
 	if err != nil {
-		// Check if the error is "Data Not Found"
-		if strings.Contains(err.Error(), "Data Not Found") {
-			response.Error(c, http.StatusNotFound, "Data not found")
-			return
-		}
-		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		_ = c.Error(err)
 		return
 	}
 
 	if data.Remarks == "User already logged" {
-		response.NotSuccess(c, http.StatusOK, "User already logged", nil)
+		_ = c.Error(service.ErrOTPUsed)
 		return
 	}
 
@@ -76,28 +74,183 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	var input model.VerifyOTP
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	// Verify OTP
-	isValid, err := h.authService.VerifyOTP(c.Request.Context(), input.Otp)
+	isValid, err := h.authService.VerifyOTP(c.Request.Context(), input)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "OTP conflict: multiple valid entries found") {
-			response.NotSuccess(c, http.StatusOK, "Multiple OTP", "OTP conflict: Please request a new OTP")
-			return
-		} else if strings.Contains(err.Error(), "OTP has expired") {
-			response.NotSuccess(c, http.StatusOK, "OTP Expired", "Please request a new OTP")
-			return
-		} else if strings.Contains(err.Error(), "OTP has already been used") {
-			response.NotSuccess(c, http.StatusOK, "User already logged", "User already logged")
-			return
-		}
-		response.NotSuccess(c, http.StatusOK, "System OTP Error", err.Error())
+		_ = c.Error(err)
 		return
 	}
 
 	response.Success(c, http.StatusOK, "OTP verified successfully", isValid)
 
 }
+
+func (h *AuthHandler) SendEmailOTP(c *gin.Context) {
+	var req model.EmailLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	data, err := h.authService.SendEmailOTP(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if data.Remarks == "User already logged" {
+		_ = c.Error(service.ErrOTPUsed)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OTP sent successfully", nil)
+}
+
+func (h *AuthHandler) VerifyEmailOTP(c *gin.Context) {
+	var input model.VerifyEmailOTP
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	result, err := h.authService.VerifyEmailOTP(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OTP verified successfully", result)
+}
+
+func (h *AuthHandler) SendMagicLink(c *gin.Context) {
+	var req model.EmailLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	err := h.authService.SendMagicLink(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Sign-in link sent successfully", nil)
+}
+
+func (h *AuthHandler) VerifyMagicLink(c *gin.Context) {
+	var req model.VerifyMagicLink
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	result, err := h.authService.VerifyMagicLink(c.Request.Context(), req.Token)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Signed in successfully", result)
+}
+
+func (h *AuthHandler) SendRegistrationOTP(c *gin.Context) {
+	var req model.RegisterOTPRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	err := h.authService.RequestRegistrationOTP(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OTP sent successfully", nil)
+}
+
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req model.RegisterRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	result, err := h.authService.Register(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "User registered successfully", result)
+}
+
+func (h *AuthHandler) RequestPhoneChange(c *gin.Context) {
+	var req model.ChangePhoneRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	err := h.authService.RequestPhoneChange(c.Request.Context(), currentUserID(c), req, c.ClientIP())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OTP sent successfully", nil)
+}
+
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	var req model.ChangeEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	err := h.authService.RequestEmailChange(c.Request.Context(), currentUserID(c), req, c.ClientIP())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OTP sent successfully", nil)
+}
+
+func (h *AuthHandler) ConfirmContactChange(c *gin.Context) {
+	var req model.ConfirmContactRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	err := h.authService.ConfirmContactChange(c.Request.Context(), currentUserID(c), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Contact updated successfully", nil)
+}
+
+// currentUserID returns the user ID set by the auth middleware.
+func currentUserID(c *gin.Context) int32 {
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(int32)
+	return id
+}
@@ -1,16 +1,18 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 	model "shofy/modules/users/model"
 	"shofy/modules/users/service"
+	"shofy/utils/apperror"
 	"shofy/utils/response"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+var errInvalidUserID = apperror.Validation("invalid_user_id", "Invalid user ID")
+
 type UserHandler struct {
 	userService service.UserService
 }
@@ -35,7 +37,7 @@ func (h *UserHandler) Logout(c *gin.Context) {
 	var req model.LogoutRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
@@ -54,20 +56,20 @@ func (h *UserHandler) Logout(c *gin.Context) {
 	}
 
 	if token == "" {
-		response.Error(c, http.StatusBadRequest, "No token provided")
+		_ = c.Error(apperror.ErrUnauthenticated)
 		return
 	}
 
 	userIdNumber, err := strconv.Atoi(req.UserID)
 
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid user ID")
+		_ = c.Error(errInvalidUserID)
 		return
 	}
 
 	// Invalidate the token
 	if err := h.userService.Logout(c.Request.Context(), token, userIdNumber); err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to logout")
+		_ = c.Error(err)
 		return
 	}
 
@@ -80,18 +82,13 @@ func (h *UserHandler) Logout(c *gin.Context) {
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req model.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	user, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
-		if err.Error() == "Phone already exist" {
-			response.Error(c, http.StatusConflict, "Phone already exist")
-			return
-		}
-		log.Printf("Error CreateUser", err)
-		response.Error(c, http.StatusInternalServerError, "Failed to create user")
+		_ = c.Error(err)
 		return
 	}
 
@@ -101,31 +98,21 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	userId := c.Param("id")
 
-	if userId == "" {
-		response.Error(c, http.StatusBadRequest, "User ID is required")
-		return
-	}
-
 	userIdInt, err := strconv.Atoi(userId)
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid user ID")
+		_ = c.Error(errInvalidUserID)
 		return
 	}
 
 	var req model.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		_ = c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	user, err := h.userService.UpdateUser(c.Request.Context(), int32(userIdInt), &req)
 	if err != nil {
-		if err.Error() == "user not found" {
-			response.Error(c, http.StatusNotFound, "User not found")
-			return
-		}
-		log.Printf("Error UpdateUser user by ID %d: %v", userIdInt, err)
-		response.Error(c, http.StatusInternalServerError, "Failed to update user")
+		_ = c.Error(err)
 		return
 	}
 
@@ -157,7 +144,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 
 	users, err := h.userService.ListUsers(c.Request.Context(), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to list users:")
+		_ = c.Error(err)
 		return
 	}
 
@@ -169,18 +156,13 @@ func (h *UserHandler) GetUsersByID(c *gin.Context) {
 
 	userIdInt, err := strconv.Atoi(userId)
 	if err != nil {
-		response.NotSuccess(c, http.StatusOK, "Invalid user ID", nil)
+		_ = c.Error(errInvalidUserID)
 		return
 	}
 
 	user, err := h.userService.GetUserByID(c.Request.Context(), int32(userIdInt))
 	if err != nil {
-		if err.Error() == "user not found" {
-			response.NotSuccess(c, http.StatusOK, "User not found", nil)
-			return
-		}
-		log.Printf("Error getting user by ID %d: %v", userIdInt, err)
-		response.Error(c, http.StatusInternalServerError, "Failed to GetUsersByID")
+		_ = c.Error(err)
 		return
 	}
 
@@ -188,25 +170,14 @@ func (h *UserHandler) GetUsersByID(c *gin.Context) {
 }
 
 func (h *UserHandler) DeleteUsersByID(c *gin.Context) {
-	userId := c.Param("id")
-	if userId == "" {
-		response.NotSuccess(c, http.StatusOK, "User ID is required", nil)
-		return
-	}
-
-	userIdInt, err := strconv.Atoi(userId)
+	userIdInt, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		response.NotSuccess(c, http.StatusOK, "Invalid user ID", nil)
+		_ = c.Error(errInvalidUserID)
 		return
 	}
 
 	if err := h.userService.DeleteUsersByID(c.Request.Context(), int32(userIdInt)); err != nil {
-		if err.Error() == "user not found" {
-			response.NotSuccess(c, http.StatusOK, "User not found", nil)
-			return
-		}
-		log.Printf("Error Delete user by ID %d: %v", userIdInt, err)
-		response.Error(c, http.StatusInternalServerError, "Failed DeleteUsersByID")
+		_ = c.Error(err)
 		return
 	}
 
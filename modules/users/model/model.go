@@ -3,25 +3,25 @@ package service
 import "time"
 
 type CreateUserRequest struct {
-	ShopID     int32  `json:"shop_id"`
-	Email      string `json:"email"`
-	Phone      string `json:"phone"`
-	FirstName  string `json:"first_name"`
-	LastName   string `json:"last_name"`
-	Address    string `json:"address"`
-	City       string `json:"city"`
-	Country    string `json:"country"`
-	PostalCode string `json:"postal_code"`
-	CodeArea   string `json:"code_area"` // Optional, can be empty
+	ShopID     int32  `json:"shop_id" binding:"required,gt=0"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	Phone      string `json:"phone" binding:"required,phone"`
+	FirstName  string `json:"first_name" binding:"max=100"`
+	LastName   string `json:"last_name" binding:"max=100"`
+	Address    string `json:"address" binding:"max=500"`
+	City       string `json:"city" binding:"max=100"`
+	Country    string `json:"country" binding:"max=100"`
+	PostalCode string `json:"postal_code" binding:"max=20"`
+	CodeArea   string `json:"code_area" binding:"omitempty,area_code"` // Optional, can be empty
 }
 
 type UpdateUserRequest struct {
-	FirstName  string `json:"first_name"`
-	LastName   string `json:"last_name"`
-	Address    string `json:"address"`
-	City       string `json:"city"`
-	Country    string `json:"country"`
-	PostalCode string `json:"postal_code"`
+	FirstName  string `json:"first_name" binding:"max=100"`
+	LastName   string `json:"last_name" binding:"max=100"`
+	Address    string `json:"address" binding:"max=500"`
+	City       string `json:"city" binding:"max=100"`
+	Country    string `json:"country" binding:"max=100"`
+	PostalCode string `json:"postal_code" binding:"max=20"`
 }
 
 type ListUsersRequest struct {
@@ -54,7 +54,7 @@ type ListUsersResponse struct {
 }
 
 type LogoutRequest struct {
-	UserID string `json:"user_id"`
+	UserID string `json:"user_id" binding:"required,numeric"`
 }
 
 type OTPData struct {
@@ -64,18 +64,21 @@ type OTPData struct {
 }
 
 type SendOTPRequest struct {
-	Code  string `json:"code"`
-	Phone string `json:"phone"`
+	ShopID int32  `json:"shop_id" binding:"required,gt=0"`
+	Code   string `json:"code" binding:"required,area_code"`
+	Phone  string `json:"phone" binding:"required,phone"`
 }
 
 type VerifyOTP struct {
-	Otp string `json:"otp"`
+	ShopID int32  `json:"shop_id" binding:"required,gt=0"`
+	Code   string `json:"code" binding:"required,area_code"`
+	Phone  string `json:"phone" binding:"required,phone"`
+	Otp    string `json:"otp" binding:"required,numeric"`
 }
 
 type PhoneResponse struct {
 	Phone_number string `json:"phone_number"`
 	Status       bool   `json:"status"`
-	Otp          string `json:"otp"`
 	Remarks      string `json:"remarks"`
 	UserID       string `json:"user_id"`
 }
@@ -84,3 +87,57 @@ type VerifyOTPResponse struct {
 	Token string   `json:"token"`
 	Role  []string `json:"role"`
 }
+
+type RegisterOTPRequest struct {
+	ShopID int32  `json:"shop_id" binding:"required,gt=0"`
+	Code   string `json:"code" binding:"required,area_code"`
+	Phone  string `json:"phone" binding:"required,phone"`
+}
+
+type RegisterRequest struct {
+	ShopID     int32  `json:"shop_id" binding:"required,gt=0"`
+	Code       string `json:"code" binding:"required,area_code"`
+	Phone      string `json:"phone" binding:"required,phone"`
+	Otp        string `json:"otp" binding:"required,numeric"`
+	FirstName  string `json:"first_name" binding:"max=100"`
+	LastName   string `json:"last_name" binding:"max=100"`
+	Address    string `json:"address" binding:"max=500"`
+	City       string `json:"city" binding:"max=100"`
+	Country    string `json:"country" binding:"max=100"`
+	PostalCode string `json:"postal_code" binding:"max=20"`
+}
+
+type ChangePhoneRequest struct {
+	Code  string `json:"code" binding:"required,area_code"`
+	Phone string `json:"phone" binding:"required,phone"`
+}
+
+type ChangeEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ConfirmContactRequest struct {
+	Type string `json:"type" binding:"required,oneof=phone email"`
+	Otp  string `json:"otp" binding:"required,numeric"`
+}
+
+type EmailLoginRequest struct {
+	ShopID int32  `json:"shop_id" binding:"required,gt=0"`
+	Email  string `json:"email" binding:"required,email"`
+}
+
+type VerifyEmailOTP struct {
+	ShopID int32  `json:"shop_id" binding:"required,gt=0"`
+	Email  string `json:"email" binding:"required,email"`
+	Otp    string `json:"otp" binding:"required,numeric"`
+}
+
+type VerifyMagicLink struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailResponse struct {
+	Email   string `json:"email"`
+	Status  bool   `json:"status"`
+	Remarks string `json:"remarks"`
+}
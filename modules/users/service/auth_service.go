@@ -2,160 +2,390 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
+	"shofy/app/api/config"
 	db "shofy/db/sqlc"
 	notificationService "shofy/modules/notification/service"
 	model "shofy/modules/users/model"
+	"shofy/utils/apperror"
 	"shofy/utils/jwt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrOTPInvalid         = apperror.Validation("otp_invalid", "Invalid OTP").WithDetails("Please check the code and try again")
+	ErrOTPExpired         = apperror.Validation("otp_expired", "OTP Expired").WithDetails("Please request a new OTP")
+	ErrOTPUsed            = apperror.Conflict("otp_used", "User already logged")
+	ErrOTPTooManyAttempts = apperror.RateLimited("otp_too_many_attempts", "Too many attempts").WithDetails("Please request a new OTP")
+	// ErrOTPRateLimited is returned with a specific message and RetryAfter
+	// when a send request hits a cooldown or quota.
+	ErrOTPRateLimited = apperror.RateLimited("otp_rate_limited", "Too many OTP requests")
+	ErrUserNotFound   = apperror.NotFound("user_not_found", "User not found")
+)
+
+// OTPPolicy holds the verify and send limits applied to login OTPs.
+type OTPPolicy struct {
+	MaxAttempts      int32
+	ResendCooldown   time.Duration
+	MaxSendsPerPhone int64
+	MaxSendsPerIP    int64
+	QuotaWindow      time.Duration
+}
+
+func newOTPPolicy(cfg config.AuthConfig) OTPPolicy {
+	return OTPPolicy{
+		MaxAttempts:      cfg.OTPMaxAttempts,
+		ResendCooldown:   cfg.OTPResendCooldown,
+		MaxSendsPerPhone: cfg.OTPMaxSendsPerPhone,
+		MaxSendsPerIP:    cfg.OTPMaxSendsPerIP,
+		QuotaWindow:      time.Hour,
+	}
+}
+
 type AuthService struct {
 	db              *pgxpool.Pool
 	whatsappService *notificationService.WhatsAppService
+	emailService    *notificationService.EmailService
 	otpStore        map[string]*model.OTPData // In-memory store for demo, should use Redis/DB in production
 	queries         *db.Queries
+	policy          OTPPolicy
+	otpKey          []byte
+	linkKey         []byte
+	linkURL         string
+	linkTTL         time.Duration
+	customerRole    string
+	devLogOTPs      bool
 }
 
-func NewAuthService(pool *pgxpool.Pool) *AuthService {
+func NewAuthService(pool *pgxpool.Pool, cfg *config.Config) *AuthService {
+	otpKey := cfg.Auth.OTPSecretKey
+	if otpKey == "" {
+		otpKey = cfg.JWT.SecretKey
+	}
+
+	linkKey := cfg.Auth.MagicLinkSecretKey
+	if linkKey == "" {
+		linkKey = cfg.JWT.SecretKey
+	}
+
 	return &AuthService{
 		db:              pool,
-		whatsappService: notificationService.NewWhatsAppService(),
+		whatsappService: notificationService.NewWhatsAppService(cfg.WhatsApp),
+		emailService:    notificationService.NewEmailService(cfg.SMTP),
 		otpStore:        make(map[string]*model.OTPData),
 		queries:         db.New(pool),
+		policy:          newOTPPolicy(cfg.Auth),
+		otpKey:          []byte(otpKey),
+		linkKey:         []byte(linkKey),
+		linkURL:         cfg.Auth.MagicLinkURL,
+		linkTTL:         cfg.Auth.MagicLinkTTL,
+		customerRole:    cfg.Auth.DefaultCustomerRole,
+		devLogOTPs:      cfg.Auth.DevLogOTPs,
 	}
 }
 
-func (s *AuthService) GenerateAndSendOTP(ctx context.Context, req model.SendOTPRequest) (*model.PhoneResponse, error) {
-	// Generate 6 digit OTP
-	otp, err := GenerateOTP(6)
+func (s *AuthService) GenerateAndSendOTP(ctx context.Context, req model.SendOTPRequest, clientIP string) (*model.PhoneResponse, error) {
+	if err := s.checkIPQuota(ctx, clientIP); err != nil {
+		return nil, err
+	}
 
-	checkPhone, err := s.queries.FindUserByPhoneAndCode(ctx, db.FindUserByPhoneAndCodeParams{
+	checkPhone, err := s.queries.FindActiveUserByShopAndPhone(ctx, db.FindActiveUserByShopAndPhoneParams{
+		ShopID:   req.ShopID,
 		Phone:    pgtype.Text{String: req.Phone, Valid: true},
 		CodeArea: pgtype.Text{String: req.Code, Valid: true},
 	})
 
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("Data Not Found: %w", err)
+	fullPhone := req.Code + req.Phone
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Unknown numbers get the same response and quotas as real ones so
+		// the endpoint cannot be used to find registered phones.
+		if err := s.logUnknownRecipient(ctx, fullPhone, clientIP); err != nil {
+			return nil, err
 		}
+		return &model.PhoneResponse{
+			Phone_number: req.Phone,
+			Status:       true,
+		}, nil
+	}
+	if err != nil {
 		return nil, fmt.Errorf("Error Database: %w", err)
 	}
 
-	dataUser, err := s.queries.FindUserLoginOtpByPhone(ctx, pgtype.Text{String: req.Phone, Valid: true})
+	otp, alreadyLogged, err := s.storeLoginOTP(ctx, checkPhone.ID, fullPhone, clientIP)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if alreadyLogged {
+		return &model.PhoneResponse{
+			Phone_number: checkPhone.Phone.String,
+			Status:       true,
+			Remarks:      "User already logged",
+		}, nil
+	}
 
-			// Jika tidak ada OTP sebelumnya, insert OTP baru
-			err = s.queries.InsertUserLoginOtp(ctx, db.InsertUserLoginOtpParams{
-				UserID: checkPhone.ID,
-				Otp:    otp,
-			})
-			if err != nil {
-				return nil, fmt.Errorf("Failed to store OTP: %w", err)
-			}
+	// Send OTP via WhatsApp
+	if err := s.deliverPhoneOTP(ctx, fullPhone, otp); err != nil {
+		return nil, err
+	}
+
+	return &model.PhoneResponse{
+		Phone_number: checkPhone.Phone.String,
+		Status:       true,
+	}, nil
+}
+
+// storeLoginOTP applies the recipient quota and stores a new login OTP for the
+// user. alreadyLogged is true when the user's last OTP is still marked used.
+func (s *AuthService) storeLoginOTP(ctx context.Context, userID int32, recipient string, clientIP string) (otp string, alreadyLogged bool, err error) {
+	// Generate 6 digit OTP
+	otp, err = GenerateOTP(6)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to generate OTP: %w", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	// Serialize sends for the same recipient across instances
+	if err := qtx.LockOtpPhone(ctx, recipient); err != nil {
+		return "", false, fmt.Errorf("Error Database: %w", err)
+	}
+
+	if err := s.checkRecipientQuota(ctx, qtx, recipient); err != nil {
+		return "", false, err
+	}
+
+	dataUser, err := qtx.FindUserLoginOtpByUserId(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", false, fmt.Errorf("Error Database: %w", err)
+		}
+
+		// Jika tidak ada OTP sebelumnya, insert OTP baru
+		err = qtx.InsertUserLoginOtp(ctx, db.InsertUserLoginOtpParams{
+			UserID:  userID,
+			OtpHash: s.hashOTP(otp),
+		})
+		if err != nil {
+			return "", false, fmt.Errorf("Failed to store OTP: %w", err)
 		}
 	} else {
 
 		if dataUser.IsUsed.Valid && !dataUser.IsUsed.Bool {
 			// Jika OTP sudah ada, update OTP
-			err = s.queries.UpdateOTPByUserId(ctx, db.UpdateOTPByUserIdParams{
-				UserID: int32(dataUser.UserID),
-				Otp:    otp,
+			err = qtx.UpdateOTPByUserId(ctx, db.UpdateOTPByUserIdParams{
+				UserID:  dataUser.UserID,
+				OtpHash: s.hashOTP(otp),
 			})
 
 			if err != nil {
-				log.Println("Error updating OTP in database:", err)
-				return nil, fmt.Errorf("failed to update OTP: %w", err)
+				slog.ErrorContext(ctx, "Error updating OTP in database", "error", err)
+				return "", false, fmt.Errorf("failed to update OTP: %w", err)
 			}
 		} else {
-			return &model.PhoneResponse{
-				Phone_number: checkPhone.Phone.String,
-				Status:       true,
-				Remarks:      "User already logged",
-			}, nil
+			return "", true, nil
 		}
 	}
-	log.Println("WHATSHAP", err)
 
-	// Send OTP via WhatsApp
-	// err = s.whatsappService.SendOTP(checkPhone.CodeArea.String+checkPhone.Phone.String, otp)
-	// log.Println("WHATSHAP 4")
+	err = qtx.InsertOtpSendLog(ctx, db.InsertOtpSendLogParams{
+		Phone:     recipient,
+		IpAddress: clientIP,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to log OTP send: %w", err)
+	}
 
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed to send OTP: %v", err)
-	// }
+	if err := tx.Commit(ctx); err != nil {
+		return "", false, fmt.Errorf("Error Database: %w", err)
+	}
 
-	return &model.PhoneResponse{
-		Phone_number: checkPhone.Phone.String,
-		Status:       true,
-		Otp:          otp,
-	}, nil
+	return otp, false, nil
 }
 
-func (s *AuthService) VerifyOTP(ctx context.Context, inputOTP string) (*model.VerifyOTPResponse, error) {
+// logUnknownRecipient applies the recipient quota and logs a send for a
+// recipient without an account, so lookups count against the IP quota.
+func (s *AuthService) logUnknownRecipient(ctx context.Context, recipient string, clientIP string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.LockOtpPhone(ctx, recipient); err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
 
-	count, err := s.queries.CountValidOtps(ctx, inputOTP)
+	if err := s.checkRecipientQuota(ctx, qtx, recipient); err != nil {
+		return err
+	}
 
+	err = qtx.InsertOtpSendLog(ctx, db.InsertOtpSendLogParams{
+		Phone:     recipient,
+		IpAddress: clientIP,
+	})
 	if err != nil {
-		log.Println("Error counting OTP:", err)
-		return nil, err
+		return fmt.Errorf("failed to log OTP send: %w", err)
 	}
-	log.Print("Count Valid OTPs: ", count)
-	if count > 1 {
-		return nil, fmt.Errorf("OTP conflict: multiple valid entries found")
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Error Database: %w", err)
 	}
+	return nil
+}
 
-	otpData, err := s.queries.VerifyOtp(ctx, inputOTP)
+// checkIPQuota enforces the per-IP send quota.
+func (s *AuthService) checkIPQuota(ctx context.Context, clientIP string) error {
+	ipSends, err := s.queries.CountOtpSendsByIPSince(ctx, db.CountOtpSendsByIPSinceParams{
+		IpAddress: clientIP,
+		CreatedAt: s.quotaWindowStart(),
+	})
+	if err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	if ipSends >= s.policy.MaxSendsPerIP {
+		return ErrOTPRateLimited.WithMessage("Too many OTP requests from this address").WithRetryAfter(s.policy.QuotaWindow)
+	}
+	return nil
+}
 
-	if otpData.Status == "EXPIRED" {
-		return nil, fmt.Errorf("OTP has expired")
-	} else if otpData.Status == "USED" {
-		return nil, fmt.Errorf("OTP has already been used")
+// checkRecipientQuota enforces the resend cooldown and the per-recipient send
+// quota. The recipient is a full phone number or an email address.
+func (s *AuthService) checkRecipientQuota(ctx context.Context, q *db.Queries, phone string) error {
+	lastSend, err := q.GetLastOtpSendByPhone(ctx, phone)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	if err == nil {
+		if wait := s.policy.ResendCooldown - time.Since(lastSend.CreatedAt.Time); wait > 0 {
+			return ErrOTPRateLimited.WithMessage("OTP was sent recently").WithRetryAfter(wait)
+		}
 	}
 
+	phoneSends, err := q.CountOtpSendsByPhoneSince(ctx, db.CountOtpSendsByPhoneSinceParams{
+		Phone:     phone,
+		CreatedAt: s.quotaWindowStart(),
+	})
 	if err != nil {
-		log.Println("Failed to verify OTP in User Login OTP:", err)
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	if phoneSends >= s.policy.MaxSendsPerPhone {
+		return ErrOTPRateLimited.WithMessage("Too many OTP requests for this phone").WithRetryAfter(s.policy.QuotaWindow)
+	}
+
+	return nil
+}
+
+func (s *AuthService) quotaWindowStart() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().Add(-s.policy.QuotaWindow), Valid: true}
+}
+
+func (s *AuthService) VerifyOTP(ctx context.Context, input model.VerifyOTP) (*model.VerifyOTPResponse, error) {
+
+	otpData, err := s.queries.VerifyOtp(ctx, db.VerifyOtpParams{
+		ShopID:   input.ShopID,
+		Phone:    pgtype.Text{String: input.Phone, Valid: true},
+		CodeArea: pgtype.Text{String: input.Code, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOTPInvalid
+		}
+		slog.ErrorContext(ctx, "Failed to verify OTP in User Login OTP", "error", err)
 		return nil, fmt.Errorf("Failed to verify OTP in User Login OTP: %w", err)
 	}
 
-	// Mark OTP as used
-	err = s.queries.UpdateIsUsed(ctx, db.UpdateIsUsedParams{
-		UserID: int32(otpData.UserID),
-		Otp:    inputOTP,
+	if err := s.consumeLoginOTP(ctx, otpData.Status, otpData.ID, otpData.OtpHash, input.Otp); err != nil {
+		return nil, err
+	}
+
+	return s.issueToken(ctx, otpData.UserID)
+}
+
+// consumeLoginOTP checks a code against a user_login_otp row and marks it used
+// on success. The attempt is counted before comparing so parallel guesses
+// share the budget.
+func (s *AuthService) consumeLoginOTP(ctx context.Context, status string, id int32, otpHash string, code string) error {
+	if status == "EXPIRED" {
+		return ErrOTPExpired
+	} else if status == "USED" {
+		return ErrOTPUsed
+	}
+
+	attempts, err := s.queries.IncrementOtpAttempts(ctx, db.IncrementOtpAttemptsParams{
+		ID:          id,
+		MaxAttempts: s.policy.MaxAttempts,
 	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOTPTooManyAttempts
+		}
+		return fmt.Errorf("failed to record OTP attempt: %w", err)
+	}
+
+	if !hmac.Equal([]byte(s.hashOTP(code)), []byte(otpHash)) {
+		if attempts >= s.policy.MaxAttempts {
+			return ErrOTPTooManyAttempts
+		}
+		return ErrOTPInvalid
+	}
 
+	// Mark OTP as used
+	updated, err := s.queries.UpdateIsUsed(ctx, id)
 	if err != nil {
-		log.Println("Error update OTP:", err)
-		return nil, fmt.Errorf("failed to update OTP: %w", err)
+		slog.ErrorContext(ctx, "Error update OTP", "error", err)
+		return fmt.Errorf("failed to update OTP: %w", err)
+	}
+	if updated == 0 {
+		return ErrOTPUsed
 	}
 
-	//GENERATE ROLE JWT
-	rolesFromDB, err := s.queries.ListUserRole(ctx, otpData.UserID)
+	return nil
+}
 
+// issueToken builds the JWT for a user from their shop and the roles
+// assigned in the database.
+func (s *AuthService) issueToken(ctx context.Context, userID int32) (*model.VerifyOTPResponse, error) {
+	user, err := s.queries.GetUser(ctx, userID)
 	if err != nil {
-		log.Println("failed to get user roles:", err)
+		slog.ErrorContext(ctx, "failed to get user", "error", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	rolesFromDB, err := s.queries.ListUserRole(ctx, userID)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get user roles", "error", err)
 		return nil, fmt.Errorf("failed to get user roles: %w", err)
 	}
-	fmt.Printf("Role DB: %+v\n", rolesFromDB)
 
 	var roleList []string
 	for _, r := range rolesFromDB {
 		roleList = append(roleList, r.Name)
 	}
 	// Generate JWT token
-	token, err := jwt.GenerateToken(otpData.UserID, roleList)
+	token, err := jwt.GenerateToken(userID, user.ShopID, roleList)
 
 	if err != nil {
-		log.Println("failed to generate token:", err)
+		slog.ErrorContext(ctx, "failed to generate token", "error", err)
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
@@ -165,6 +395,13 @@ func (s *AuthService) VerifyOTP(ctx context.Context, inputOTP string) (*model.Ve
 	}, nil
 }
 
+// hashOTP returns the hex HMAC-SHA256 of an OTP code keyed with the server secret.
+func (s *AuthService) hashOTP(otp string) string {
+	mac := hmac.New(sha256.New, s.otpKey)
+	mac.Write([]byte(otp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // GenerateOTP menghasilkan OTP numerik dengan panjang tertentu (4 atau 6 digit)
 func GenerateOTP(length int) (string, error) {
 	if length <= 0 {
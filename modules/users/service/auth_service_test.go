@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	db "shofy/db/sqlc"
+	"shofy/utils/apperror"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeOTPDB answers the user_login_otp and otp_send_log queries for a single
+// OTP row.
+type fakeOTPDB struct {
+	attempts   int32
+	used       bool
+	lastSend   time.Time
+	phoneSends int64
+	ipSends    int64
+}
+
+func (f *fakeOTPDB) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	if strings.Contains(sql, "SET is_used = TRUE") {
+		if f.used {
+			return pgconn.NewCommandTag("UPDATE 0"), nil
+		}
+		f.used = true
+		return pgconn.NewCommandTag("UPDATE 1"), nil
+	}
+	return pgconn.CommandTag{}, errors.New("unexpected exec: " + sql)
+}
+
+func (f *fakeOTPDB) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query: " + sql)
+}
+
+func (f *fakeOTPDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	switch {
+	case strings.Contains(sql, "SET attempts = attempts + 1"):
+		if f.attempts >= args[1].(int32) {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		f.attempts++
+		return fakeRow{vals: []any{f.attempts}}
+	case strings.Contains(sql, "WHERE ip_address = $1"):
+		return fakeRow{vals: []any{f.ipSends}}
+	case strings.Contains(sql, "COUNT(*)"):
+		return fakeRow{vals: []any{f.phoneSends}}
+	case strings.Contains(sql, "ORDER BY created_at DESC"):
+		if f.lastSend.IsZero() {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		return fakeRow{vals: []any{int64(1), args[0].(string), "10.0.0.1", pgtype.Timestamptz{Time: f.lastSend, Valid: true}}}
+	}
+	return fakeRow{err: errors.New("unexpected query row: " + sql)}
+}
+
+type fakeRow struct {
+	vals []any
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.vals[i]))
+	}
+	return nil
+}
+
+func newTestAuthService(f *fakeOTPDB) *AuthService {
+	return &AuthService{
+		queries: db.New(f),
+		otpKey:  []byte("test-secret"),
+		policy: OTPPolicy{
+			MaxAttempts:      3,
+			ResendCooldown:   time.Minute,
+			MaxSendsPerPhone: 5,
+			MaxSendsPerIP:    10,
+			QuotaWindow:      time.Hour,
+		},
+	}
+}
+
+func TestHashOTP(t *testing.T) {
+	s := newTestAuthService(&fakeOTPDB{})
+
+	hash := s.hashOTP("123456")
+	if hash != s.hashOTP("123456") {
+		t.Fatal("hashOTP is not deterministic")
+	}
+	if hash == s.hashOTP("123457") {
+		t.Fatal("different codes share a hash")
+	}
+	if strings.Contains(hash, "123456") {
+		t.Fatal("hash contains the code")
+	}
+
+	other := newTestAuthService(&fakeOTPDB{})
+	other.otpKey = []byte("other-secret")
+	if hash == other.hashOTP("123456") {
+		t.Fatal("hash does not depend on the key")
+	}
+}
+
+func TestConsumeLoginOTP(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("correct code", func(t *testing.T) {
+		f := &fakeOTPDB{}
+		s := newTestAuthService(f)
+		if err := s.consumeLoginOTP(ctx, "VALID", 1, s.hashOTP("123456"), "123456"); err != nil {
+			t.Fatalf("consumeLoginOTP() error = %v", err)
+		}
+		if !f.used {
+			t.Fatal("OTP was not marked used")
+		}
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		f := &fakeOTPDB{}
+		s := newTestAuthService(f)
+		err := s.consumeLoginOTP(ctx, "VALID", 1, s.hashOTP("123456"), "000000")
+		if !errors.Is(err, ErrOTPInvalid) {
+			t.Fatalf("error = %v, want ErrOTPInvalid", err)
+		}
+		if f.used || f.attempts != 1 {
+			t.Fatalf("used = %v, attempts = %d, want unused with 1 attempt", f.used, f.attempts)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		f := &fakeOTPDB{}
+		s := newTestAuthService(f)
+		hash := s.hashOTP("123456")
+		for i := int32(1); i < s.policy.MaxAttempts; i++ {
+			if err := s.consumeLoginOTP(ctx, "VALID", 1, hash, "000000"); !errors.Is(err, ErrOTPInvalid) {
+				t.Fatalf("attempt %d error = %v, want ErrOTPInvalid", i, err)
+			}
+		}
+		if err := s.consumeLoginOTP(ctx, "VALID", 1, hash, "000000"); !errors.Is(err, ErrOTPTooManyAttempts) {
+			t.Fatalf("last attempt error = %v, want ErrOTPTooManyAttempts", err)
+		}
+		// The right code no longer helps once the budget is spent.
+		if err := s.consumeLoginOTP(ctx, "VALID", 1, hash, "123456"); !errors.Is(err, ErrOTPTooManyAttempts) {
+			t.Fatalf("correct code after exhaustion error = %v, want ErrOTPTooManyAttempts", err)
+		}
+		if f.used {
+			t.Fatal("OTP was marked used after exhaustion")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		f := &fakeOTPDB{}
+		s := newTestAuthService(f)
+		err := s.consumeLoginOTP(ctx, "EXPIRED", 1, s.hashOTP("123456"), "123456")
+		if !errors.Is(err, ErrOTPExpired) {
+			t.Fatalf("error = %v, want ErrOTPExpired", err)
+		}
+		if f.attempts != 0 {
+			t.Fatalf("attempts = %d, want 0", f.attempts)
+		}
+	})
+
+	t.Run("reused", func(t *testing.T) {
+		f := &fakeOTPDB{}
+		s := newTestAuthService(f)
+		hash := s.hashOTP("123456")
+		if err := s.consumeLoginOTP(ctx, "VALID", 1, hash, "123456"); err != nil {
+			t.Fatalf("first use error = %v", err)
+		}
+		if err := s.consumeLoginOTP(ctx, "USED", 1, hash, "123456"); !errors.Is(err, ErrOTPUsed) {
+			t.Fatalf("second use error = %v, want ErrOTPUsed", err)
+		}
+		// A parallel request that read the row before it was marked used.
+		if err := s.consumeLoginOTP(ctx, "VALID", 1, hash, "123456"); !errors.Is(err, ErrOTPUsed) {
+			t.Fatalf("racing use error = %v, want ErrOTPUsed", err)
+		}
+	})
+}
+
+func TestCheckRecipientQuota(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		fake    fakeOTPDB
+		wantErr bool
+	}{
+		{"first send", fakeOTPDB{}, false},
+		{"after cooldown", fakeOTPDB{lastSend: time.Now().Add(-2 * time.Minute), phoneSends: 4}, false},
+		{"within cooldown", fakeOTPDB{lastSend: time.Now().Add(-10 * time.Second), phoneSends: 1}, true},
+		{"phone quota spent", fakeOTPDB{lastSend: time.Now().Add(-2 * time.Minute), phoneSends: 5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.fake
+			s := newTestAuthService(&f)
+			err := s.checkRecipientQuota(ctx, s.queries, "+628123456789")
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("checkRecipientQuota() error = %v", err)
+				}
+				return
+			}
+			var appErr *apperror.Error
+			if !errors.Is(err, ErrOTPRateLimited) || !errors.As(err, &appErr) || appErr.RetryAfter <= 0 {
+				t.Fatalf("error = %v, want ErrOTPRateLimited with RetryAfter", err)
+			}
+		})
+	}
+}
+
+func TestCheckIPQuota(t *testing.T) {
+	ctx := context.Background()
+
+	s := newTestAuthService(&fakeOTPDB{ipSends: 9})
+	if err := s.checkIPQuota(ctx, "10.0.0.1"); err != nil {
+		t.Fatalf("below quota error = %v", err)
+	}
+
+	s = newTestAuthService(&fakeOTPDB{ipSends: 10})
+	if err := s.checkIPQuota(ctx, "10.0.0.1"); !errors.Is(err, ErrOTPRateLimited) {
+		t.Fatalf("at quota error = %v, want ErrOTPRateLimited", err)
+	}
+}
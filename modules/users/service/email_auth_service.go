@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	db "shofy/db/sqlc"
+	model "shofy/modules/users/model"
+	"shofy/utils/apperror"
+	"shofy/utils/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrMagicLinkInvalid = apperror.Validation("magic_link_invalid", "Invalid link").WithDetails("Please request a new sign-in link")
+	ErrMagicLinkExpired = apperror.Validation("magic_link_expired", "Link Expired").WithDetails("Please request a new sign-in link")
+)
+
+// SendEmailOTP sends a login code to an email-only account in the shop.
+func (s *AuthService) SendEmailOTP(ctx context.Context, req model.EmailLoginRequest, clientIP string) (*model.EmailResponse, error) {
+	if err := s.checkIPQuota(ctx, clientIP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.findEmailUser(ctx, req.ShopID, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	otp, alreadyLogged, err := s.storeLoginOTP(ctx, user.ID, req.Email, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	if alreadyLogged {
+		return &model.EmailResponse{
+			Email:   user.Email.String,
+			Status:  true,
+			Remarks: "User already logged",
+		}, nil
+	}
+
+	if err := s.deliverEmailOTP(ctx, req.Email, otp); err != nil {
+		return nil, err
+	}
+
+	return &model.EmailResponse{
+		Email:  user.Email.String,
+		Status: true,
+	}, nil
+}
+
+// VerifyEmailOTP checks an email login code and issues a token.
+func (s *AuthService) VerifyEmailOTP(ctx context.Context, input model.VerifyEmailOTP) (*model.VerifyOTPResponse, error) {
+	otpData, err := s.queries.VerifyEmailOtp(ctx, db.VerifyEmailOtpParams{
+		ShopID: input.ShopID,
+		Email:  pgtype.Text{String: input.Email, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOTPInvalid
+		}
+		return nil, fmt.Errorf("Failed to verify OTP in User Login OTP: %w", err)
+	}
+
+	if err := s.consumeLoginOTP(ctx, otpData.Status, otpData.ID, otpData.OtpHash, input.Otp); err != nil {
+		return nil, err
+	}
+
+	return s.issueToken(ctx, otpData.UserID)
+}
+
+// SendMagicLink emails a signed, single-use sign-in link.
+func (s *AuthService) SendMagicLink(ctx context.Context, req model.EmailLoginRequest, clientIP string) error {
+	if s.linkURL == "" {
+		return fmt.Errorf("MAGIC_LINK_URL is not set")
+	}
+
+	if err := s.checkIPQuota(ctx, clientIP); err != nil {
+		return err
+	}
+
+	user, err := s.findEmailUser(ctx, req.ShopID, req.Email)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.linkTTL)
+	token, err := s.signMagicLink(user.ID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.LockOtpPhone(ctx, req.Email); err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+
+	if err := s.checkRecipientQuota(ctx, qtx, req.Email); err != nil {
+		return err
+	}
+
+	err = qtx.CreateUserLoginLink(ctx, db.CreateUserLoginLinkParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store magic link: %w", err)
+	}
+
+	err = qtx.InsertOtpSendLog(ctx, db.InsertOtpSendLogParams{
+		Phone:     req.Email,
+		IpAddress: clientIP,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to log OTP send: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+
+	link := s.linkURL + "?token=" + url.QueryEscape(token)
+
+	if !s.emailService.Enabled() {
+		metrics.NotificationSends.WithLabelValues("email", "skipped").Inc()
+		slog.WarnContext(ctx, "SMTP is not configured, skipping magic link send")
+		return nil
+	}
+	if err := s.emailService.SendMagicLink(req.Email, link); err != nil {
+		return fmt.Errorf("failed to send magic link: %w", err)
+	}
+	return nil
+}
+
+// VerifyMagicLink checks the link signature and expiry, consumes it and
+// issues a token.
+func (s *AuthService) VerifyMagicLink(ctx context.Context, token string) (*model.VerifyOTPResponse, error) {
+	userID, expiresAt, err := s.parseMagicLink(token)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(expiresAt) {
+		return nil, ErrMagicLinkExpired
+	}
+
+	linkUserID, err := s.queries.ConsumeUserLoginLink(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMagicLinkInvalid
+		}
+		return nil, fmt.Errorf("failed to consume magic link: %w", err)
+	}
+	if linkUserID != userID {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	return s.issueToken(ctx, userID)
+}
+
+func (s *AuthService) findEmailUser(ctx context.Context, shopID int32, email string) (db.User, error) {
+	user, err := s.queries.FindActiveUserByShopAndEmail(ctx, db.FindActiveUserByShopAndEmailParams{
+		ShopID: shopID,
+		Email:  pgtype.Text{String: email, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.User{}, ErrUserNotFound.Wrap(err)
+		}
+		return db.User{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return user, nil
+}
+
+// signMagicLink builds "<payload>.<signature>" where the payload carries the
+// user ID, expiry and a random nonce so every link is unique.
+func (s *AuthService) signMagicLink(userID int32, expiresAt time.Time) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate magic link: %w", err)
+	}
+
+	payload := fmt.Sprintf("%d.%d.%s", userID, expiresAt.Unix(), hex.EncodeToString(nonce))
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return encoded + "." + s.signLinkPayload(encoded), nil
+}
+
+func (s *AuthService) parseMagicLink(token string) (int32, time.Time, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(s.signLinkPayload(encoded))) {
+		return 0, time.Time{}, ErrMagicLinkInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, time.Time{}, ErrMagicLinkInvalid
+	}
+
+	parts := strings.Split(string(payload), ".")
+	if len(parts) != 3 {
+		return 0, time.Time{}, ErrMagicLinkInvalid
+	}
+
+	userID, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, time.Time{}, ErrMagicLinkInvalid
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, ErrMagicLinkInvalid
+	}
+
+	return int32(userID), time.Unix(expiresAt, 0), nil
+}
+
+func (s *AuthService) signLinkPayload(encoded string) string {
+	mac := hmac.New(sha256.New, s.linkKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hashToken is the lookup key stored for a magic link.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,451 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"log/slog"
+	db "shofy/db/sqlc"
+	model "shofy/modules/users/model"
+	"shofy/utils/apperror"
+	"shofy/utils/metrics"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	PurposeRegister    = "register"
+	PurposeChangePhone = "change_phone"
+	PurposeChangeEmail = "change_email"
+)
+
+var (
+	ErrShopNotFound        = apperror.NotFound("shop_not_found", "Shop not found")
+	ErrUserAlreadyExists   = apperror.Conflict("user_exists", "User already exists")
+	ErrContactTypeMismatch = apperror.Validation("contact_type_mismatch", "Account does not use this contact type")
+	ErrNoPendingChange     = apperror.NotFound("no_pending_change", "No pending change")
+)
+
+// RequestRegistrationOTP sends a registration code to a phone number that has
+// no account yet in the given shop.
+func (s *AuthService) RequestRegistrationOTP(ctx context.Context, req model.RegisterOTPRequest, clientIP string) error {
+	if err := s.checkIPQuota(ctx, clientIP); err != nil {
+		return err
+	}
+
+	if _, err := s.queries.GetShopsById(ctx, req.ShopID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrShopNotFound
+		}
+		return fmt.Errorf("Error Database: %w", err)
+	}
+
+	_, err := s.queries.FindUserByShopAndPhone(ctx, db.FindUserByShopAndPhoneParams{
+		ShopID: req.ShopID,
+		Phone:  pgtype.Text{String: req.Phone, Valid: true},
+	})
+	if err == nil {
+		return ErrUserAlreadyExists
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+
+	otp, err := s.createVerificationOTP(ctx, clientIP, db.CreateVerificationOtpParams{
+		Purpose:  PurposeRegister,
+		ShopID:   req.ShopID,
+		Target:   req.Phone,
+		CodeArea: pgtype.Text{String: req.Code, Valid: true},
+	}, req.Code+req.Phone)
+	if err != nil {
+		return err
+	}
+
+	return s.deliverPhoneOTP(ctx, req.Code+req.Phone, otp)
+}
+
+// Register verifies a registration code, creates the customer with its
+// profile and default role in the shop, and issues a token.
+func (s *AuthService) Register(ctx context.Context, req model.RegisterRequest) (*model.VerifyOTPResponse, error) {
+	otpData, err := s.queries.GetLatestVerificationOtpByTarget(ctx, db.GetLatestVerificationOtpByTargetParams{
+		Purpose:  PurposeRegister,
+		ShopID:   req.ShopID,
+		Target:   req.Phone,
+		CodeArea: pgtype.Text{String: req.Code, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOTPInvalid
+		}
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	if err := s.checkVerificationOTP(ctx, otpData, req.Otp); err != nil {
+		return nil, err
+	}
+
+	role, err := s.queries.GetRoleByName(ctx, s.customerRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default customer role: %w", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := markVerificationOTPUsed(ctx, qtx, otpData.ID); err != nil {
+		return nil, err
+	}
+
+	user, err := qtx.CreateUser(ctx, db.CreateUserParams{
+		ShopID:   req.ShopID,
+		Phone:    pgtype.Text{String: req.Phone, Valid: true},
+		CodeArea: pgtype.Text{String: req.Code, Valid: true},
+		IsActive: pgtype.Bool{Bool: true, Valid: true},
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrUserAlreadyExists.Wrap(err)
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	_, err = qtx.CreateUserProfile(ctx, db.CreateUserProfileParams{
+		UserID:     user.ID,
+		Phone:      pgtype.Text{String: req.Phone, Valid: true},
+		FirstName:  pgtype.Text{String: req.FirstName, Valid: req.FirstName != ""},
+		LastName:   pgtype.Text{String: req.LastName, Valid: req.LastName != ""},
+		Address:    pgtype.Text{String: req.Address, Valid: req.Address != ""},
+		City:       pgtype.Text{String: req.City, Valid: req.City != ""},
+		Country:    pgtype.Text{String: req.Country, Valid: req.Country != ""},
+		PostalCode: pgtype.Text{String: req.PostalCode, Valid: req.PostalCode != ""},
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrUserAlreadyExists.Wrap(err)
+		}
+		return nil, fmt.Errorf("failed to create user profile: %w", err)
+	}
+
+	err = qtx.CreateUserRole(ctx, db.CreateUserRoleParams{
+		UserID: user.ID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign customer role: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
+
+	return s.issueToken(ctx, user.ID)
+}
+
+// RequestPhoneChange stores the new phone as unconfirmed and sends a code to it.
+func (s *AuthService) RequestPhoneChange(ctx context.Context, userID int32, req model.ChangePhoneRequest, clientIP string) error {
+	if err := s.checkIPQuota(ctx, clientIP); err != nil {
+		return err
+	}
+
+	user, err := s.getActiveUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.Phone.Valid {
+		return ErrContactTypeMismatch
+	}
+
+	_, err = s.queries.FindUserByShopAndPhone(ctx, db.FindUserByShopAndPhoneParams{
+		ShopID: user.ShopID,
+		Phone:  pgtype.Text{String: req.Phone, Valid: true},
+	})
+	if err == nil {
+		return ErrUserAlreadyExists
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+
+	err = s.queries.SetUnconfirmedPhone(ctx, db.SetUnconfirmedPhoneParams{
+		ID:               userID,
+		UnconfirmedPhone: pgtype.Text{String: req.Phone, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store unconfirmed phone: %w", err)
+	}
+
+	otp, err := s.createVerificationOTP(ctx, clientIP, db.CreateVerificationOtpParams{
+		Purpose:  PurposeChangePhone,
+		ShopID:   user.ShopID,
+		UserID:   pgtype.Int4{Int32: userID, Valid: true},
+		Target:   req.Phone,
+		CodeArea: pgtype.Text{String: req.Code, Valid: true},
+	}, req.Code+req.Phone)
+	if err != nil {
+		return err
+	}
+
+	return s.deliverPhoneOTP(ctx, req.Code+req.Phone, otp)
+}
+
+// RequestEmailChange stores the new email as unconfirmed and sends a code to it.
+func (s *AuthService) RequestEmailChange(ctx context.Context, userID int32, req model.ChangeEmailRequest, clientIP string) error {
+	if err := s.checkIPQuota(ctx, clientIP); err != nil {
+		return err
+	}
+
+	user, err := s.getActiveUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.Email.Valid {
+		return ErrContactTypeMismatch
+	}
+
+	_, err = s.queries.FindUserByShopAndEmail(ctx, db.FindUserByShopAndEmailParams{
+		ShopID: user.ShopID,
+		Email:  pgtype.Text{String: req.Email, Valid: true},
+	})
+	if err == nil {
+		return ErrUserAlreadyExists
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+
+	err = s.queries.SetUnconfirmedEmail(ctx, db.SetUnconfirmedEmailParams{
+		ID:               userID,
+		UnconfirmedEmail: pgtype.Text{String: req.Email, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store unconfirmed email: %w", err)
+	}
+
+	otp, err := s.createVerificationOTP(ctx, clientIP, db.CreateVerificationOtpParams{
+		Purpose: PurposeChangeEmail,
+		ShopID:  user.ShopID,
+		UserID:  pgtype.Int4{Int32: userID, Valid: true},
+		Target:  req.Email,
+	}, req.Email)
+	if err != nil {
+		return err
+	}
+
+	return s.deliverEmailOTP(ctx, req.Email, otp)
+}
+
+// ConfirmContactChange verifies the code for a pending phone or email change
+// and moves the unconfirmed value into place.
+func (s *AuthService) ConfirmContactChange(ctx context.Context, userID int32, req model.ConfirmContactRequest) error {
+	purpose := PurposeChangePhone
+	if req.Type == "email" {
+		purpose = PurposeChangeEmail
+	}
+
+	otpData, err := s.queries.GetLatestVerificationOtpByUser(ctx, db.GetLatestVerificationOtpByUserParams{
+		Purpose: purpose,
+		UserID:  pgtype.Int4{Int32: userID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoPendingChange
+		}
+		return fmt.Errorf("Error Database: %w", err)
+	}
+
+	if err := s.checkVerificationOTP(ctx, otpData, req.Otp); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := markVerificationOTPUsed(ctx, qtx, otpData.ID); err != nil {
+		return err
+	}
+
+	if purpose == PurposeChangePhone {
+		_, err = qtx.ConfirmUserPhone(ctx, db.ConfirmUserPhoneParams{
+			ID:               userID,
+			CodeArea:         otpData.CodeArea,
+			UnconfirmedPhone: pgtype.Text{String: otpData.Target, Valid: true},
+		})
+	} else {
+		_, err = qtx.ConfirmUserEmail(ctx, db.ConfirmUserEmailParams{
+			ID:               userID,
+			UnconfirmedEmail: pgtype.Text{String: otpData.Target, Valid: true},
+		})
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoPendingChange
+		}
+		return fmt.Errorf("failed to confirm contact change: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *AuthService) getActiveUser(ctx context.Context, userID int32) (db.GetUserRow, error) {
+	user, err := s.queries.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.GetUserRow{}, ErrUserNotFound.Wrap(err)
+		}
+		return db.GetUserRow{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return user, nil
+}
+
+// createVerificationOTP applies the recipient quota, replaces any pending code
+// for the same target and returns the new plain code.
+func (s *AuthService) createVerificationOTP(ctx context.Context, clientIP string, params db.CreateVerificationOtpParams, recipient string) (string, error) {
+	otp, err := GenerateOTP(6)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate OTP: %w", err)
+	}
+	params.OtpHash = s.hashOTP(otp)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Error Database: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.LockOtpPhone(ctx, recipient); err != nil {
+		return "", fmt.Errorf("Error Database: %w", err)
+	}
+
+	if err := s.checkRecipientQuota(ctx, qtx, recipient); err != nil {
+		return "", err
+	}
+
+	err = qtx.InvalidateVerificationOtps(ctx, db.InvalidateVerificationOtpsParams{
+		Purpose: params.Purpose,
+		ShopID:  params.ShopID,
+		Target:  params.Target,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to invalidate previous OTP: %w", err)
+	}
+
+	if _, err := qtx.CreateVerificationOtp(ctx, params); err != nil {
+		return "", fmt.Errorf("Failed to store OTP: %w", err)
+	}
+
+	err = qtx.InsertOtpSendLog(ctx, db.InsertOtpSendLogParams{
+		Phone:     recipient,
+		IpAddress: clientIP,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to log OTP send: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("Error Database: %w", err)
+	}
+
+	return otp, nil
+}
+
+// checkVerificationOTP compares a code with a stored verification OTP. The
+// attempt is counted outside any caller transaction so a failed guess is kept.
+func (s *AuthService) checkVerificationOTP(ctx context.Context, otpData db.UserVerificationOtp, code string) error {
+	if otpData.IsUsed {
+		return ErrOTPUsed
+	}
+	if otpData.ExpiresAt.Time.Before(time.Now()) {
+		return ErrOTPExpired
+	}
+
+	attempts, err := s.queries.IncrementVerificationOtpAttempts(ctx, db.IncrementVerificationOtpAttemptsParams{
+		ID:          otpData.ID,
+		MaxAttempts: s.policy.MaxAttempts,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOTPTooManyAttempts
+		}
+		return fmt.Errorf("failed to record OTP attempt: %w", err)
+	}
+
+	if !hmac.Equal([]byte(s.hashOTP(code)), []byte(otpData.OtpHash)) {
+		if attempts >= s.policy.MaxAttempts {
+			return ErrOTPTooManyAttempts
+		}
+		return ErrOTPInvalid
+	}
+
+	return nil
+}
+
+// markVerificationOTPUsed makes a checked code single-use.
+func markVerificationOTPUsed(ctx context.Context, q *db.Queries, id int32) error {
+	updated, err := q.MarkVerificationOtpUsed(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to update OTP: %w", err)
+	}
+	if updated == 0 {
+		return ErrOTPUsed
+	}
+	return nil
+}
+
+// deliverPhoneOTP sends a code over WhatsApp when OTP delivery is enabled.
+func (s *AuthService) deliverPhoneOTP(ctx context.Context, phone string, otp string) error {
+	if !s.whatsappService.Enabled() {
+		metrics.NotificationSends.WithLabelValues("whatsapp", "skipped").Inc()
+		slog.WarnContext(ctx, "OTP delivery disabled, skipping WhatsApp send")
+		s.logDevOTP(ctx, phone, otp)
+		return nil
+	}
+
+	if err := s.whatsappService.SendOTP(phone, otp); err != nil {
+		return fmt.Errorf("failed to send OTP: %w", err)
+	}
+	return nil
+}
+
+// deliverEmailOTP sends a code over SMTP when an SMTP host is configured.
+func (s *AuthService) deliverEmailOTP(ctx context.Context, email string, otp string) error {
+	if !s.emailService.Enabled() {
+		metrics.NotificationSends.WithLabelValues("email", "skipped").Inc()
+		slog.WarnContext(ctx, "SMTP is not configured, skipping email OTP send")
+		s.logDevOTP(ctx, email, otp)
+		return nil
+	}
+
+	if err := s.emailService.SendOTP(email, otp); err != nil {
+		return fmt.Errorf("failed to send OTP: %w", err)
+	}
+	return nil
+}
+
+// logDevOTP logs an undelivered code when OTP_DEV_LOG_CODES is on, so that
+// local setups without WhatsApp or SMTP can still log in.
+func (s *AuthService) logDevOTP(ctx context.Context, recipient string, otp string) {
+	if !s.devLogOTPs {
+		return
+	}
+	slog.WarnContext(ctx, "Development OTP, never enable in production", "recipient", recipient, "dev_code", otp)
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation,
+// e.g. a contact registered by a concurrent request.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -2,18 +2,24 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	db "shofy/db/sqlc"
 	model "shofy/modules/users/model"
+	"shofy/utils/apperror"
 	"shofy/utils/jwt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrPhoneExists         = apperror.Conflict("phone_exists", "Phone already exist")
+	ErrUserProfileNotFound = apperror.NotFound("user_profile_not_found", "User profile not found")
+)
+
 type UserService interface {
 	Logout(ctx context.Context, token string, userId int) error
 	CreateUser(ctx context.Context, req *model.CreateUserRequest) (*model.UserResponse, error)
@@ -36,15 +42,15 @@ type userService struct {
 func (s *userService) Logout(ctx context.Context, token string, userId int) error {
 	// Invalidate the token
 	if err := jwt.InvalidateToken(token); err != nil {
-		log.Println("Error invalidating token:", err)
+		slog.ErrorContext(ctx, "Error invalidating token", "error", err)
 		return fmt.Errorf("failed to invalidate token: %w", err)
 	}
 
 	err := s.queries.UpdateIsUsedFalse(ctx, int32(userId))
 
 	if err != nil {
-		log.Println("Error update is active UserLoginOtp:", err)
-		return fmt.Errorf("Error update is active UserLoginOtp", err)
+		slog.ErrorContext(ctx, "Error update is active UserLoginOtp", "error", err)
+		return fmt.Errorf("Error update is active UserLoginOtp: %w", err)
 	}
 
 	return nil
@@ -53,9 +59,12 @@ func (s *userService) Logout(ctx context.Context, token string, userId int) erro
 func (s *userService) CreateUser(ctx context.Context, req *model.CreateUserRequest) (*model.UserResponse, error) {
 
 	checkPhone, err := s.queries.FindUserByPhone(ctx, pgtype.Text{String: req.Phone, Valid: true})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("Error Database: %w", err)
+	}
 
 	if checkPhone.ID != 0 {
-		return nil, fmt.Errorf("Phone already exist")
+		return nil, ErrPhoneExists
 	}
 
 	// Create user
@@ -69,17 +78,23 @@ func (s *userService) CreateUser(ctx context.Context, req *model.CreateUserReque
 			String: req.Phone,
 			Valid:  req.Phone != "",
 		},
+		CodeArea: pgtype.Text{
+			String: req.CodeArea,
+			Valid:  req.CodeArea != "",
+		},
 		IsActive: pgtype.Bool{
 			Bool:  true,
 			Valid: true,
 		},
 	})
 
-	log.Println("DATA USER:", user.ID, user.ShopID, user.Email.String, user.Phone.String)
 	// Check for errors
 
 	if err != nil {
-		log.Println("Error creating user:", err)
+		if isUniqueViolation(err) {
+			return nil, ErrUserAlreadyExists.Wrap(err)
+		}
+		slog.ErrorContext(ctx, "Error creating user", "error", err)
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -116,7 +131,10 @@ func (s *userService) CreateUser(ctx context.Context, req *model.CreateUserReque
 		},
 	})
 	if err != nil {
-		log.Println("Error creating user:", err)
+		if isUniqueViolation(err) {
+			return nil, ErrUserAlreadyExists.Wrap(err)
+		}
+		slog.ErrorContext(ctx, "Error creating user", "error", err)
 
 		return nil, fmt.Errorf("failed to create user profile: %w", err)
 	}
@@ -140,8 +158,8 @@ func (s *userService) UpdateUser(ctx context.Context, userId int32, req *model.U
 	// Get existing user
 	user, err := s.queries.GetUser(ctx, userId)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("user not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -226,12 +244,12 @@ func (s *userService) ListUsers(ctx context.Context, req *model.ListUsersRequest
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
 
-	log.Println("Total users:", users)
+	slog.DebugContext(ctx, "Listed users", "count", len(users))
 	// Get user profiles for all users
 	userResponses := make([]model.UserResponse, 0, len(users))
 	for _, user := range users {
 		profile, err := s.queries.GetUserProfile(ctx, user.ID)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("failed to get user profile: %w", err)
 		}
 
@@ -263,16 +281,16 @@ func (s *userService) ListUsers(ctx context.Context, req *model.ListUsersRequest
 func (s *userService) GetUserByID(ctx context.Context, id int32) (model.UserResponse, error) {
 	user, err := s.queries.GetUser(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return model.UserResponse{}, fmt.Errorf("user not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserResponse{}, ErrUserNotFound
 		}
 		return model.UserResponse{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	profile, err := s.queries.GetUserProfile(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return model.UserResponse{}, fmt.Errorf("user profile not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserResponse{}, ErrUserProfileNotFound
 		}
 		return model.UserResponse{}, fmt.Errorf("failed to get user profile: %w", err)
 	}
@@ -297,8 +315,8 @@ func (s *userService) DeleteUsersByID(ctx context.Context, id int32) error {
 	// Check if user exists
 	_, err := s.queries.GetUser(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("user not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
 		}
 		return fmt.Errorf("failed to get user: %w", err)
 	}
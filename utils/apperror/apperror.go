@@ -0,0 +1,141 @@
+// Package apperror defines the typed errors returned by services. Each error
+// has a kind, which decides the HTTP status, and a stable snake_case code
+// that clients can match on instead of the message.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindValidation    Kind = "validation"
+	KindUnauthorized  Kind = "unauthorized"
+	KindForbidden     Kind = "forbidden"
+	KindNotFound      Kind = "not_found"
+	KindConflict      Kind = "conflict"
+	KindUnprocessable Kind = "unprocessable"
+	KindRateLimited   Kind = "rate_limited"
+	KindUpstream      Kind = "upstream"
+	KindUnavailable   Kind = "unavailable"
+	KindInternal      Kind = "internal"
+)
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Details is sent as the envelope data, e.g. a hint or per-field errors.
+	Details any
+	// RetryAfter is sent as the Retry-After header when set.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error    { return New(KindValidation, code, message) }
+func Unauthorized(code, message string) *Error  { return New(KindUnauthorized, code, message) }
+func Forbidden(code, message string) *Error     { return New(KindForbidden, code, message) }
+func NotFound(code, message string) *Error      { return New(KindNotFound, code, message) }
+func Conflict(code, message string) *Error      { return New(KindConflict, code, message) }
+func Unprocessable(code, message string) *Error { return New(KindUnprocessable, code, message) }
+func RateLimited(code, message string) *Error   { return New(KindRateLimited, code, message) }
+func Upstream(code, message string) *Error      { return New(KindUpstream, code, message) }
+func Unavailable(code, message string) *Error   { return New(KindUnavailable, code, message) }
+func Internal(code, message string) *Error      { return New(KindInternal, code, message) }
+
+// Common errors shared by every handler.
+var (
+	ErrInvalidRequest  = Validation("invalid_request", "Invalid request body")
+	ErrInvalidQuery    = Validation("invalid_query", "Invalid query parameters")
+	ErrUnauthenticated = Unauthorized("unauthenticated", "No authentication token provided")
+	ErrInvalidToken    = Unauthorized("invalid_token", "Invalid or expired token")
+	ErrForbidden       = Forbidden("forbidden", "Forbidden")
+	ErrDatabaseDown    = Unavailable("database_unavailable", "Database is unavailable")
+	ErrInternal        = Internal("internal_error", "Internal Server Error")
+)
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches on the code, so copies made by Wrap or WithDetails still match
+// the sentinel they came from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e carrying err as its cause.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithDetails returns a copy of e with the given envelope data.
+func (e *Error) WithDetails(details any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// WithMessage returns a copy of e with a different message.
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithRetryAfter returns a copy of e that asks the client to wait d.
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = d
+	return &c
+}
+
+// As returns the first *Error in err's chain.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// HTTPStatus maps an error kind to its response status.
+func HTTPStatus(kind Kind) int {
+	switch kind {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	case KindUpstream:
+		return http.StatusBadGateway
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// Breaker is a consecutive-failure circuit breaker. After Threshold failures
+// it opens for Cooldown, then lets calls through again (half-open) until the
+// next success closes it or the next failure re-opens it.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// DBBreaker tracks connection-level database failures reported through
+// RecordDBError.
+var DBBreaker = NewBreaker(5, 30*time.Second)
+
+// RecordDBError feeds DBBreaker: connection failures count against it and
+// any other outcome, including query errors, proves the database is up.
+func RecordDBError(err error) {
+	if IsDBDown(err) {
+		DBBreaker.Failure()
+		return
+	}
+	DBBreaker.Success()
+}
+
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state()
+}
+
+func (b *Breaker) state() string {
+	if b.failures < b.Threshold {
+		return CircuitClosed
+	}
+	if time.Since(b.openedAt) < b.Cooldown {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+// Allow reports whether a call may proceed.
+func (b *Breaker) Allow() bool {
+	return b.State() != CircuitOpen
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.Threshold {
+		b.openedAt = time.Now()
+	}
+}
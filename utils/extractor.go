@@ -1,26 +1,57 @@
 package utils
 
 import (
+	"regexp"
 	"strings"
 )
 
-func ExtractThinkingProcess(response string) string {
-	startTag := "<think>"
-	endTag := "</think>"
+// reasoningBlock matches one closed reasoning block. Reasoning models use
+// <think>, some fine-tunes <thinking> or <reasoning>.
+var reasoningBlock = regexp.MustCompile(`(?is)<(think|thinking|reasoning)>(.*?)</(?:think|thinking|reasoning)>`)
+
+var (
+	reasoningOpen  = regexp.MustCompile(`(?i)<(think|thinking|reasoning)>`)
+	reasoningClose = regexp.MustCompile(`(?i)</(think|thinking|reasoning)>`)
+)
+
+// SplitReasoning separates the reasoning of a model response from the
+// answer. It handles any number of reasoning blocks, a closing tag without
+// an opening one (R1 templates put <think> in the prompt) and a block cut
+// off before its closing tag.
+func SplitReasoning(response string) (answer, reasoning string) {
+	var parts []string
 
-	startPos := strings.Index(response, startTag)
-	if startPos == -1 {
-		return response // No think tags found, return original response
+	// R1 style: the response starts inside the reasoning block.
+	if closing := reasoningClose.FindStringIndex(response); closing != nil {
+		if opening := reasoningOpen.FindStringIndex(response); opening == nil || opening[0] > closing[0] {
+			parts = append(parts, strings.TrimSpace(response[:closing[0]]))
+			response = response[closing[1]:]
+		}
 	}
 
-	endPos := strings.Index(response, endTag)
-	if endPos == -1 {
-		return response // No end tag found, return original response
+	response = reasoningBlock.ReplaceAllStringFunc(response, func(block string) string {
+		parts = append(parts, strings.TrimSpace(reasoningBlock.FindStringSubmatch(block)[2]))
+		return ""
+	})
+
+	// A block the model never closed, e.g. after hitting the token limit.
+	if opening := reasoningOpen.FindStringIndex(response); opening != nil {
+		parts = append(parts, strings.TrimSpace(response[opening[1]:]))
+		response = response[:opening[0]]
 	}
 
-	// Remove the think section and any newlines before the actual response
-	cleanedResponse := response[endPos+len(endTag):]
-	cleanedResponse = strings.TrimSpace(cleanedResponse)
+	kept := parts[:0]
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.TrimSpace(response), strings.Join(kept, "\n\n")
+}
 
-	return cleanedResponse
+// ExtractThinkingProcess returns the answer of a model response without its
+// reasoning.
+func ExtractThinkingProcess(response string) string {
+	answer, _ := SplitReasoning(response)
+	return answer
 }
@@ -0,0 +1,22 @@
+package utils
+
+import "testing"
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		name, response, answer, reasoning string
+	}{
+		{"none", "Halo kak", "Halo kak", ""},
+		{"single", "<think>cek stok</think>\n\nStok ada 5", "Stok ada 5", "cek stok"},
+		{"several", "<think>a</think>Satu <THINKING>b</THINKING>dua", "Satu dua", "a\n\nb"},
+		{"r1 without opening tag", "pelanggan tanya harga\n</think>\nHarganya Rp 10.000", "Harganya Rp 10.000", "pelanggan tanya harga"},
+		{"unclosed", "Sebentar ya <think>menghitung ongkir", "Sebentar ya", "menghitung ongkir"},
+		{"only reasoning", "<think>hmm</think>", "", "hmm"},
+	}
+	for _, tt := range tests {
+		answer, reasoning := SplitReasoning(tt.response)
+		if answer != tt.answer || reasoning != tt.reasoning {
+			t.Errorf("%s: SplitReasoning = (%q, %q), want (%q, %q)", tt.name, answer, reasoning, tt.answer, tt.reasoning)
+		}
+	}
+}
@@ -1,20 +1,46 @@
 package jwt
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type JWTClaim struct {
 	UserID int32    `json:"user_id"`
+	ShopID int32    `json:"shop_id,omitempty"`
 	Role   []string `json:"role"`
 	jwt.RegisteredClaims
 }
 
+// GuestClaim identifies an anonymous chat visitor of one shop.
+type GuestClaim struct {
+	GuestID string `json:"guest_id"`
+	ShopID  int32  `json:"shop_id"`
+	jwt.RegisteredClaims
+}
+
+var secretKeyOverride string
+
+// SetSecretKey sets the signing key from configuration. When unset the
+// JWT_SECRET_KEY environment variable is used.
+func SetSecretKey(key string) {
+	secretKeyOverride = key
+}
+
+func signingKey() string {
+	if secretKeyOverride != "" {
+		return secretKeyOverride
+	}
+	return os.Getenv("JWT_SECRET_KEY")
+}
+
 // TokenBlacklist stores invalidated tokens
 var (
 	blacklistedTokens = make(map[string]time.Time)
@@ -43,9 +69,9 @@ func cleanupBlacklist() {
 	}
 }
 
-func GenerateToken(userID int32, role []string) (string, error) {
+func GenerateToken(userID, shopID int32, role []string) (string, error) {
 	// Get secret key from environment variable
-	secretKey := os.Getenv("JWT_SECRET_KEY")
+	secretKey := signingKey()
 	if secretKey == "" {
 		return "", fmt.Errorf("JWT_SECRET_KEY is not set")
 	}
@@ -53,6 +79,7 @@ func GenerateToken(userID int32, role []string) (string, error) {
 	// Create claims with user ID and standard claims
 	claims := JWTClaim{
 		UserID: userID,
+		ShopID: shopID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
@@ -83,7 +110,7 @@ func ValidateToken(tokenString string) (*JWTClaim, error) {
 	blacklistMutex.RUnlock()
 
 	// Get secret key from environment variable
-	secretKey := os.Getenv("JWT_SECRET_KEY")
+	secretKey := signingKey()
 	if secretKey == "" {
 		return nil, fmt.Errorf("JWT_SECRET_KEY is not set")
 	}
@@ -108,11 +135,64 @@ func ValidateToken(tokenString string) (*JWTClaim, error) {
 	return nil, fmt.Errorf("invalid token claims")
 }
 
+// guestSigningKey is derived from the signing key so guest tokens are never
+// accepted as user tokens and the other way round.
+func guestSigningKey() []byte {
+	mac := hmac.New(sha256.New, []byte(signingKey()))
+	mac.Write([]byte("chat-guest"))
+	return mac.Sum(nil)
+}
+
+// GenerateGuestToken issues a token for a new anonymous visitor of shopID.
+func GenerateGuestToken(shopID int32, ttl time.Duration) (string, *GuestClaim, error) {
+	if signingKey() == "" {
+		return "", nil, fmt.Errorf("JWT_SECRET_KEY is not set")
+	}
+
+	now := time.Now()
+	claims := &GuestClaim{
+		GuestID: uuid.NewString(),
+		ShopID:  shopID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(guestSigningKey())
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign guest token: %w", err)
+	}
+	return token, claims, nil
+}
+
+func ValidateGuestToken(tokenString string) (*GuestClaim, error) {
+	if signingKey() == "" {
+		return nil, fmt.Errorf("JWT_SECRET_KEY is not set")
+	}
+
+	claims := &GuestClaim{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return guestSigningKey(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse guest token: %w", err)
+	}
+	if !token.Valid || claims.GuestID == "" || claims.ShopID == 0 {
+		return nil, fmt.Errorf("invalid guest token claims")
+	}
+	return claims, nil
+}
+
 // InvalidateToken adds a token to the blacklist
 func InvalidateToken(tokenString string) error {
 	claims := &JWTClaim{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		secretKey := os.Getenv("JWT_SECRET_KEY")
+		secretKey := signingKey()
 		return []byte(secretKey), nil
 	})
 
@@ -0,0 +1,170 @@
+// Package language detects the language customers write in and names the
+// languages shops can serve.
+package language
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Default is the language shops serve unless configured otherwise.
+const Default = "id"
+
+// names are the languages Detect can tell apart, by ISO 639-1 code, as
+// prompts refer to them.
+var names = map[string]string{
+	"id": "Bahasa Indonesia",
+	"en": "English",
+	"es": "Español",
+	"fr": "Français",
+	"de": "Deutsch",
+	"nl": "Nederlands",
+	"pt": "Português",
+	"it": "Italiano",
+	"vi": "Tiếng Việt",
+	"zh": "中文",
+	"ja": "日本語",
+	"ko": "한국어",
+	"th": "ภาษาไทย",
+	"ar": "العربية",
+	"ru": "Русский",
+}
+
+// stopwords are frequent words of the languages written in Latin script,
+// including the ones customers use when asking about products.
+var stopwords = map[string][]string{
+	"id": {"yang", "dan", "ini", "itu", "ada", "apa", "apakah", "berapa", "harga", "harganya", "stok", "saya", "aku", "kak", "kakak", "mau", "beli", "bisa", "tidak", "gak", "nggak", "dong", "ya", "untuk", "dengan", "dari", "masih", "terima", "kasih", "barang", "ongkir", "kirim", "sudah", "belum", "gimana", "bagaimana", "min", "tolong"},
+	"en": {"the", "is", "are", "what", "how", "much", "price", "do", "does", "you", "have", "stock", "i", "want", "buy", "can", "please", "thanks", "thank", "hello", "hi", "this", "that", "and", "of", "available", "for", "with", "my", "any", "still", "in", "it", "there", "ship", "shipping", "cost"},
+	"es": {"el", "la", "los", "las", "es", "qué", "que", "cuánto", "cuesta", "precio", "tienen", "hay", "quiero", "comprar", "gracias", "hola", "por", "favor", "para", "con", "de", "y", "disponible", "envío"},
+	"fr": {"le", "la", "les", "est", "quel", "quelle", "combien", "coûte", "prix", "vous", "avez", "je", "veux", "acheter", "merci", "bonjour", "pour", "avec", "et", "de", "du", "disponible", "livraison", "s'il"},
+	"de": {"der", "die", "das", "ist", "was", "wie", "viel", "kostet", "preis", "haben", "sie", "ich", "möchte", "kaufen", "danke", "hallo", "für", "mit", "und", "noch", "verfügbar", "versand", "bitte"},
+	"nl": {"de", "het", "een", "is", "wat", "hoeveel", "kost", "prijs", "hebben", "jullie", "ik", "wil", "kopen", "bedankt", "hallo", "voor", "met", "en", "nog", "beschikbaar", "verzending", "alstublieft"},
+	"pt": {"o", "os", "as", "é", "quanto", "custa", "preço", "vocês", "têm", "tem", "eu", "quero", "comprar", "obrigado", "obrigada", "olá", "para", "com", "e", "disponível", "envio", "por", "favor"},
+	"it": {"il", "lo", "gli", "è", "quanto", "costa", "prezzo", "avete", "voglio", "comprare", "grazie", "ciao", "per", "con", "e", "disponibile", "spedizione", "favore"},
+	"vi": {"không", "của", "và", "là", "có", "giá", "bao", "nhiêu", "tôi", "muốn", "mua", "còn", "hàng", "cảm", "ơn", "xin", "chào", "bạn", "được", "cho"},
+}
+
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwords))
+	for code, words := range stopwords {
+		sets[code] = make(map[string]bool, len(words))
+		for _, w := range words {
+			sets[code][w] = true
+		}
+	}
+	return sets
+}()
+
+// Codes lists the known language codes, sorted.
+func Codes() []string {
+	codes := make([]string, 0, len(names))
+	for code := range names {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// Known reports whether code is a language Detect can return.
+func Known(code string) bool {
+	_, ok := names[code]
+	return ok
+}
+
+// Name returns the name of a language in that language, or code itself
+// for unknown codes.
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// Detect guesses the language of text. ok is false when text is too short
+// or too mixed to tell, e.g. "ok" or a product name alone; callers then
+// keep the language they had.
+func Detect(text string) (code string, ok bool) {
+	if code, ok := detectScript(text); ok {
+		return code, true
+	}
+
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(tokens) == 0 {
+		return "", false
+	}
+
+	scores := map[string]int{}
+	for _, t := range tokens {
+		for code, set := range stopwordSets {
+			if set[t] {
+				scores[code]++
+			}
+		}
+	}
+
+	best, second := "", 0
+	for _, c := range Codes() {
+		switch s := scores[c]; {
+		case best == "" || s > scores[best]:
+			second = scores[best]
+			best = c
+		case s > second:
+			second = s
+		}
+	}
+	top := scores[best]
+	// One hit decides a greeting or one-word message; longer messages need
+	// two, and the runner-up must be clearly behind.
+	if top == 0 || top == second || (top < 2 && len(tokens) > 2) {
+		return "", false
+	}
+	return best, true
+}
+
+// detectScript recognises languages by their writing system.
+func detectScript(text string) (string, bool) {
+	var letters, han, kana, hangul, thai, arabic, cyrillic int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		}
+	}
+	if letters == 0 {
+		return "", false
+	}
+
+	// Japanese mixes kanji with kana; Chinese has no kana.
+	switch share := func(n int) bool { return n*10 >= letters*3 }; {
+	case kana > 0 && share(kana+han):
+		return "ja", true
+	case share(han):
+		return "zh", true
+	case share(hangul):
+		return "ko", true
+	case share(thai):
+		return "th", true
+	case share(arabic):
+		return "ar", true
+	case share(cyrillic):
+		return "ru", true
+	}
+	return "", false
+}
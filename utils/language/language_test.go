@@ -0,0 +1,33 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{"Kak, berapa harga sepatu merah?", "id", true},
+		{"Sepatu merah masih ada stok gak?", "id", true},
+		{"How much is the red shoe?", "en", true},
+		{"Do you still have it in stock?", "en", true},
+		{"Hello", "en", true},
+		{"¿Cuánto cuesta la camiseta?", "es", true},
+		{"Combien coûte le sac, s'il vous plaît?", "fr", true},
+		{"Was kostet die Jacke?", "de", true},
+		{"红色的鞋子多少钱？", "zh", true},
+		{"この靴はいくらですか", "ja", true},
+		{"이 신발 얼마예요?", "ko", true},
+		{"Сколько стоит куртка?", "ru", true},
+		{"ok", "", false},
+		{"Sepatu Lari Merah", "", false},
+		{"12345", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Detect(tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Detect(%q) = %q, %v; want %q, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
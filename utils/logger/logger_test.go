@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"shofy/app/api/config"
+	"strings"
+	"testing"
+)
+
+func TestLogger_RedactsAndAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, config.LogConfig{Level: "info", Format: "json"})
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	log.InfoContext(ctx, "sending to +6281234567890",
+		"otp", "123456",
+		"phone", "6281234567890",
+		"message", "hello there",
+		"error", errors.New("bad token eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl"),
+	)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON log line %q: %v", buf.String(), err)
+	}
+
+	if entry["request_id"] != "req-123" {
+		t.Errorf("request_id = %v, want req-123", entry["request_id"])
+	}
+	if entry["otp"] != redacted {
+		t.Errorf("otp = %v, want redacted", entry["otp"])
+	}
+	if entry["phone"] != "***********90" {
+		t.Errorf("phone = %v, want masked", entry["phone"])
+	}
+	if entry["message"] != "[REDACTED len=11]" {
+		t.Errorf("message = %v, want length only", entry["message"])
+	}
+
+	line := buf.String()
+	for _, leaked := range []string{"123456", "81234567", "eyJhbGciOi", "hello there"} {
+		if strings.Contains(line, leaked) {
+			t.Errorf("log line leaks %q: %s", leaked, line)
+		}
+	}
+}
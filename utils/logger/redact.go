@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// Values under these keys are never logged.
+	secretKeys = map[string]bool{
+		"otp":           true,
+		"otp_code":      true,
+		"token":         true,
+		"access_token":  true,
+		"refresh_token": true,
+		"authorization": true,
+		"password":      true,
+		"secret":        true,
+		"api_key":       true,
+	}
+
+	// Values under these keys are replaced by their length only.
+	contentKeys = map[string]bool{
+		"message":  true,
+		"content":  true,
+		"prompt":   true,
+		"response": true,
+		"body":     true,
+	}
+
+	contactKeys = map[string]bool{
+		"phone":             true,
+		"phone_number":      true,
+		"whatsapp_phone":    true,
+		"recipient":         true,
+		"to":                true,
+		"email":             true,
+		"unconfirmed_email": true,
+	}
+
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	bearerRegexp = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]+`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s-]{7,}\d`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// redactAttr is the slog ReplaceAttr hook. Sensitive keys are masked and
+// every other string value is scrubbed for phone numbers, emails and tokens.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.MessageKey || a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.SourceKey {
+		return a
+	}
+
+	key := strings.ToLower(a.Key)
+	switch {
+	case secretKeys[key]:
+		return slog.String(a.Key, redacted)
+	case contentKeys[key]:
+		return slog.String(a.Key, fmt.Sprintf("[REDACTED len=%d]", len(a.Value.String())))
+	case contactKeys[key]:
+		return slog.String(a.Key, Mask(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, scrubString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, scrubString(err.Error()))
+		}
+	}
+	return a
+}
+
+// Mask keeps the last two characters of a contact value.
+func Mask(value string) string {
+	if len(value) <= 2 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-2) + value[len(value)-2:]
+}
+
+// scrubString masks tokens, phone numbers and email addresses found in free
+// text such as log messages and error strings.
+func scrubString(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = bearerRegexp.ReplaceAllString(s, "Bearer "+redacted)
+	s = emailPattern.ReplaceAllStringFunc(s, Mask)
+	s = phonePattern.ReplaceAllStringFunc(s, maskPhone)
+	return s
+}
+
+// maskPhone only masks runs with enough digits to be a phone number, so
+// dates and IDs stay readable.
+func maskPhone(s string) string {
+	digits := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	if digits < 9 {
+		return s
+	}
+	return Mask(s)
+}
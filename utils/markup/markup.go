@@ -0,0 +1,106 @@
+// Package markup renders assistant replies, written in Markdown, for the
+// channel that shows them.
+package markup
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Formats a reply can be rendered in.
+const (
+	// Markdown keeps the reply as Markdown, for web clients that render it.
+	Markdown = "markdown"
+	// HTML escapes the reply and turns bold text and newlines into tags,
+	// for older web clients that insert the reply as HTML.
+	HTML = "html"
+	// WhatsApp uses WhatsApp's own *bold*, _italic_ and ~strike~ markers.
+	WhatsApp = "whatsapp"
+	// Plain strips all formatting, for SMS and channels without markup.
+	Plain = "plain"
+)
+
+var (
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+	heading     = regexp.MustCompile(`(?m)^#{1,6}[ \t]+(.+?)[ \t]*#*$`)
+	bullet      = regexp.MustCompile(`(?m)^([ \t]*)[*+][ \t]+`)
+	link        = regexp.MustCompile(`\[([^\]\n]+)\]\((https?://[^)\s]+)\)`)
+	bold        = regexp.MustCompile(`\*\*([^*\n]+)\*\*|__([^_\n]+)__`)
+	italicStar  = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*\n]*?)\*([^*\w]|$)`)
+	italicUnder = regexp.MustCompile(`(^|[^_\w])_([^_\s][^_\n]*?)_([^_\w]|$)`)
+	strike      = regexp.MustCompile(`~~([^~\n]+)~~`)
+	inlineCode  = regexp.MustCompile("`([^`\n]+)`")
+	codeFence   = regexp.MustCompile("(?m)^```[a-zA-Z]*\\s*$\\n?")
+	escapedLine = strings.NewReplacer(`\r\n`, "\n", `\n`, "\n")
+)
+
+// Render converts a Markdown reply to format. Unknown formats fall back to
+// Markdown.
+func Render(text, format string) string {
+	text = normalize(text)
+	switch format {
+	case HTML:
+		return toHTML(text)
+	case WhatsApp:
+		return toWhatsApp(text)
+	case Plain:
+		return toPlain(text)
+	}
+	return text
+}
+
+// normalize fixes what models commonly get wrong regardless of format:
+// literal "\n" sequences, Windows line ends and runs of blank lines.
+func normalize(text string) string {
+	text = escapedLine.Replace(text)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+func toHTML(text string) string {
+	text = html.EscapeString(text)
+	text = bold.ReplaceAllString(text, "<strong>$1$2</strong>")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+func toWhatsApp(text string) string {
+	text = codeFence.ReplaceAllString(text, "```\n")
+	text = bullet.ReplaceAllString(text, "$1- ")
+	text = link.ReplaceAllString(text, "$1 ($2)")
+	// Italic first: WhatsApp uses the single asterisk for bold.
+	text = italicStar.ReplaceAllString(text, "${1}_${2}_${3}")
+	text = bold.ReplaceAllString(text, "*$1$2*")
+	text = heading.ReplaceAllString(text, "*$1*")
+	return strike.ReplaceAllString(text, "~$1~")
+}
+
+func toPlain(text string) string {
+	text = codeFence.ReplaceAllString(text, "")
+	text = bullet.ReplaceAllString(text, "$1- ")
+	text = link.ReplaceAllString(text, "$1 ($2)")
+	text = bold.ReplaceAllString(text, "$1$2")
+	text = italicStar.ReplaceAllString(text, "$1$2$3")
+	text = italicUnder.ReplaceAllString(text, "$1$2$3")
+	text = heading.ReplaceAllString(text, "$1")
+	text = strike.ReplaceAllString(text, "$1")
+	return inlineCode.ReplaceAllString(text, "$1")
+}
+
+// ForChannel returns the format for a channel name from the channel
+// table. web is the format for the web widget ("system").
+func ForChannel(channel, web string) string {
+	switch channel {
+	case "whatsapp":
+		return WhatsApp
+	case "sms", "facebook", "instagram":
+		return Plain
+	case "telegram":
+		return Markdown
+	}
+	if web == "" {
+		return Markdown
+	}
+	return web
+}
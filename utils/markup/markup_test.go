@@ -0,0 +1,29 @@
+package markup
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	reply := "## Sepatu Merah\\n\\n* **Harga:** Rp 100.000\n* Stok: _10_\n\n\n\nLihat [katalog](https://toko.id/katalog)"
+	tests := []struct {
+		format, want string
+	}{
+		{Markdown, "## Sepatu Merah\n\n* **Harga:** Rp 100.000\n* Stok: _10_\n\nLihat [katalog](https://toko.id/katalog)"},
+		{WhatsApp, "*Sepatu Merah*\n\n- *Harga:* Rp 100.000\n- Stok: _10_\n\nLihat katalog (https://toko.id/katalog)"},
+		{Plain, "Sepatu Merah\n\n- Harga: Rp 100.000\n- Stok: 10\n\nLihat katalog (https://toko.id/katalog)"},
+		{HTML, "## Sepatu Merah<br><br>* <strong>Harga:</strong> Rp 100.000<br>* Stok: _10_<br><br>Lihat [katalog](https://toko.id/katalog)"},
+	}
+	for _, tt := range tests {
+		if got := Render(reply, tt.format); got != tt.want {
+			t.Errorf("Render(%s) =\n%q\nwant\n%q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestRenderItalicToWhatsApp(t *testing.T) {
+	if got, want := Render("Ini *penting* dan **tebal**", WhatsApp), "Ini _penting_ dan *tebal*"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+	if got, want := Render("kode_produk_1 <b>", HTML), "kode_produk_1 &lt;b&gt;"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shofy"
+
+// Registry holds every application metric plus the Go and process
+// collectors. It is served by Handler.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	LLMDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "LLM call latency by provider and model.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"provider", "model"})
+
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "LLM tokens used by provider, model and type (prompt or completion).",
+	}, []string{"provider", "model", "type"})
+
+	LLMFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_failures_total",
+		Help:      "Failed LLM calls by provider, model and reason.",
+	}, []string{"provider", "model", "reason"})
+
+	LLMRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_retries_total",
+		Help:      "LLM calls retried after a retryable failure by provider and model.",
+	}, []string{"provider", "model"})
+
+	LLMFallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_fallbacks_total",
+		Help:      "Chat replies served by a fallback provider, by provider and model.",
+	}, []string{"provider", "model"})
+
+	LLMCircuitSkips = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_circuit_skips_total",
+		Help:      "LLM calls skipped because the provider circuit was open.",
+	}, []string{"provider", "model"})
+
+	ChatCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_cache_requests_total",
+		Help:      "Chat response cache lookups by outcome (hit, miss, bypass).",
+	}, []string{"outcome"})
+
+	ChatIntents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_intents_total",
+		Help:      "Chat messages by intent, how it was found (rule, classifier, default) and who answered (router, staff, model).",
+	}, []string{"intent", "source", "answered_by"})
+
+	ChatStructuredReplies = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_structured_replies_total",
+		Help:      "Structured chat replies by outcome (valid, repaired, fallback).",
+	}, []string{"outcome"})
+
+	ProductTranslations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "product_translations_total",
+		Help:      "Product translations served from cache, translated by the LLM or failed.",
+	}, []string{"result"})
+
+	GuardrailBlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "guardrail_blocks_total",
+		Help:      "Chat messages and replies blocked by a guardrail, by direction, rule and action.",
+	}, []string{"direction", "rule", "action"})
+
+	NotificationSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notification_sends_total",
+		Help:      "Notification send attempts by channel and outcome (sent, failed, skipped).",
+	}, []string{"channel", "outcome"})
+
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter by policy.",
+	}, []string{"policy"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		LLMDuration,
+		LLMTokens,
+		LLMFailures,
+		LLMRetries,
+		LLMFallbacks,
+		LLMCircuitSkips,
+		ChatCacheRequests,
+		ChatIntents,
+		ChatStructuredReplies,
+		ProductTranslations,
+		GuardrailBlocks,
+		NotificationSends,
+		RateLimitRejections,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// NotificationOutcome counts a send attempt as sent or failed.
+func NotificationOutcome(channel string, err error) {
+	outcome := "sent"
+	if err != nil {
+		outcome = "failed"
+	}
+	NotificationSends.WithLabelValues(channel, outcome).Inc()
+}
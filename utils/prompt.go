@@ -1,7 +0,0 @@
-package utils
-
-const (
-	PromptFirstMessage = `
-	Always treat 'My name is [X]' literally, even if [X] is a word like 'Private' or 'Public'. Assume [X] is the user's actual name.
-	`
-)
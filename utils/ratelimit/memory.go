@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryLimiter keeps buckets in process memory. Each instance limits on
+// its own, so use the Postgres backend when running more than one.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (l *memoryLimiter) Take(_ context.Context, key string, p Policy) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(p.Limit), updatedAt: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = min(float64(p.Limit), b.tokens+elapsed*p.refillRate())
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(p, b.tokens, allowed), nil
+}
+
+func (l *memoryLimiter) Purge(_ context.Context, idle time.Duration) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var deleted int64
+	cutoff := l.now().Add(-idle)
+	for key, b := range l.buckets {
+		if b.updatedAt.Before(cutoff) {
+			delete(l.buckets, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
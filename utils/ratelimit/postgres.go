@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	db "shofy/db/sqlc"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type postgresLimiter struct {
+	queries *db.Queries
+}
+
+// NewPostgresLimiter keeps buckets in the rate_limit_buckets table so every
+// instance shares them. Each request costs one round trip.
+func NewPostgresLimiter(dbPool *pgxpool.Pool) Limiter {
+	return &postgresLimiter{queries: db.New(dbPool)}
+}
+
+func (l *postgresLimiter) Take(ctx context.Context, key string, p Policy) (Result, error) {
+	row, err := l.queries.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		BucketKey:  key,
+		Capacity:   float64(p.Limit),
+		RefillRate: p.refillRate(),
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("Error Database: %w", err)
+	}
+	return newResult(p, row.Tokens, row.Allowed), nil
+}
+
+func (l *postgresLimiter) Purge(ctx context.Context, idle time.Duration) (int64, error) {
+	return l.queries.DeleteIdleRateLimitBuckets(ctx, pgtype.Timestamptz{Time: time.Now().Add(-idle), Valid: true})
+}
@@ -0,0 +1,124 @@
+// Package ratelimit implements token-bucket rate limiting with an in-memory
+// backend for single instances and a Postgres backend shared by every
+// instance.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key kinds a policy can be keyed by.
+const (
+	KeyIP   = "ip"
+	KeyUser = "user"
+	KeyShop = "shop"
+)
+
+// Policy allows Limit requests per Period for each key. The bucket holds
+// Limit tokens, so a quiet client may burst up to Limit at once.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Key    string
+}
+
+// ParsePolicy reads a spec such as "5/10m by ip": a limit, a period and
+// optionally the key kind, which defaults to ip.
+func ParsePolicy(name, spec string) (Policy, error) {
+	p := Policy{Name: name, Key: KeyIP}
+
+	rate, key, hasKey := strings.Cut(strings.TrimSpace(spec), " by ")
+	if hasKey {
+		p.Key = strings.TrimSpace(key)
+	}
+	limit, period, ok := strings.Cut(strings.TrimSpace(rate), "/")
+	if !ok {
+		return p, fmt.Errorf("rate limit %q must look like 10/1m", spec)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return p, fmt.Errorf("rate limit %q needs a positive limit", spec)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return p, fmt.Errorf("rate limit %q needs a positive period", spec)
+	}
+	p.Limit, p.Period = n, d
+
+	switch p.Key {
+	case KeyIP, KeyUser, KeyShop:
+	default:
+		return p, fmt.Errorf("rate limit %q must be keyed by ip, user or shop", spec)
+	}
+	return p, nil
+}
+
+// refillRate is the number of tokens added per second.
+func (p Policy) refillRate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Result describes the bucket after a request took (or failed to take) a
+// token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token when the request was denied.
+	RetryAfter time.Duration
+}
+
+func newResult(p Policy, tokens float64, allowed bool) Result {
+	rate := p.refillRate()
+	r := Result{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     time.Duration((float64(p.Limit) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		r.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return r
+}
+
+type Limiter interface {
+	// Take takes one token from the bucket for key under policy p.
+	Take(ctx context.Context, key string, p Policy) (Result, error)
+	// Purge forgets buckets untouched for idle. Such buckets are full again
+	// once idle is at least the longest policy period.
+	Purge(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+// RunPurger purges buckets idle for longer than idle every interval until
+// ctx is done.
+func RunPurger(ctx context.Context, l Limiter, interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := l.Purge(ctx, idle)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to purge rate limit buckets", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.DebugContext(ctx, "Purged rate limit buckets", "count", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("chat", "20/1m by user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Limit != 20 || p.Period != time.Minute || p.Key != KeyUser {
+		t.Fatalf("got %+v", p)
+	}
+
+	if p, _ := ParsePolicy("otp", "5/10m"); p.Key != KeyIP {
+		t.Fatalf("default key = %q, want ip", p.Key)
+	}
+
+	for _, spec := range []string{"5", "0/1m", "5/soon", "5/1m by phone"} {
+		if _, err := ParsePolicy("bad", spec); err == nil {
+			t.Errorf("ParsePolicy(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestMemoryLimiterRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := &memoryLimiter{buckets: map[string]*bucket{}, now: func() time.Time { return now }}
+	p := Policy{Name: "otp", Limit: 2, Period: 10 * time.Second, Key: KeyIP}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if res, _ := l.Take(ctx, "k", p); !res.Allowed {
+			t.Fatalf("request %d denied", i)
+		}
+	}
+
+	res, _ := l.Take(ctx, "k", p)
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("third request got %+v, want denied", res)
+	}
+	if res.RetryAfter != 5*time.Second {
+		t.Fatalf("RetryAfter = %v, want 5s", res.RetryAfter)
+	}
+
+	now = now.Add(5 * time.Second)
+	if res, _ := l.Take(ctx, "k", p); !res.Allowed {
+		t.Fatal("request after refill denied")
+	}
+
+	now = now.Add(time.Minute)
+	if n, _ := l.Purge(ctx, 10*time.Second); n != 1 {
+		t.Fatalf("purged %d buckets, want 1", n)
+	}
+}
@@ -0,0 +1,18 @@
+package response
+
+// PageBounds applies the default limit of 10 and first page to list query
+// values and returns the limit, page and row offset.
+func PageBounds(limit, page int) (int, int, int) {
+	if limit == 0 {
+		limit = 10
+	}
+	if page == 0 {
+		page = 1
+	}
+	return limit, page, (page - 1) * limit
+}
+
+// TotalPages returns how many pages of limit rows hold total rows.
+func TotalPages(total int64, limit int) int {
+	return int((total + int64(limit) - 1) / int64(limit))
+}
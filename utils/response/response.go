@@ -4,6 +4,7 @@ import "github.com/gin-gonic/gin"
 
 type Response struct {
 	Status  bool        `json:"status"`
+	Code    string      `json:"code,omitempty"` // stable error code, see utils/apperror
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
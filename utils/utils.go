@@ -3,7 +3,10 @@ package utils
 
 import (
 	"context"
+	"strconv"
 	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func IsDBDown(err error) bool {
@@ -27,3 +30,21 @@ func StringOrEmpty(s *string) string {
 	}
 	return *s
 }
+
+// FormatRupiah renders a numeric price as whole rupiah with dotted
+// thousands, e.g. "Rp 120.000".
+func FormatRupiah(n pgtype.Numeric) string {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return "Rp 0"
+	}
+	digits := strconv.FormatInt(int64(f.Float64+0.5), 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return "Rp " + b.String()
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestFormatRupiah(t *testing.T) {
+	tests := map[string]string{
+		"0":          "Rp 0",
+		"950":        "Rp 950",
+		"150000":     "Rp 150.000",
+		"1250000.00": "Rp 1.250.000",
+	}
+	for in, want := range tests {
+		var n pgtype.Numeric
+		if err := n.Scan(in); err != nil {
+			t.Fatalf("Scan(%q) error = %v", in, err)
+		}
+		if got := FormatRupiah(n); got != want {
+			t.Errorf("FormatRupiah(%s) = %q, want %q", in, got, want)
+		}
+	}
+	if got := FormatRupiah(pgtype.Numeric{}); got != "Rp 0" {
+		t.Errorf("FormatRupiah(NULL) = %q, want Rp 0", got)
+	}
+}
@@ -0,0 +1,210 @@
+// Package validation configures the validator behind gin's binding tags and
+// turns bind failures into per-field messages in English or Indonesian.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	id_translations "github.com/go-playground/validator/v10/translations/id"
+)
+
+const (
+	LocaleEN = "en"
+	LocaleID = "id"
+)
+
+// OrderStatuses are the values allowed by the orders.status check constraint.
+var OrderStatuses = []string{"pending", "paid", "shipped", "cancelled"}
+
+var (
+	phonePattern    = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+	areaCodePattern = regexp.MustCompile(`^\+?[0-9]{1,4}$`)
+)
+
+// customRules are the tags added on top of the validator built-ins, with
+// their messages per locale.
+var customRules = []struct {
+	tag      string
+	fn       validator.Func
+	messages map[string]string
+}{
+	{
+		tag: "phone",
+		fn:  func(fl validator.FieldLevel) bool { return phonePattern.MatchString(fl.Field().String()) },
+		messages: map[string]string{
+			LocaleEN: "{0} must be a phone number of 6 to 15 digits",
+			LocaleID: "{0} harus berupa nomor telepon 6 sampai 15 digit",
+		},
+	},
+	{
+		tag: "area_code",
+		fn:  func(fl validator.FieldLevel) bool { return areaCodePattern.MatchString(fl.Field().String()) },
+		messages: map[string]string{
+			LocaleEN: "{0} must be a country calling code such as 62 or +62",
+			LocaleID: "{0} harus berupa kode negara seperti 62 atau +62",
+		},
+	},
+	{
+		tag: "order_status",
+		fn: func(fl validator.FieldLevel) bool {
+			for _, status := range OrderStatuses {
+				if fl.Field().String() == status {
+					return true
+				}
+			}
+			return false
+		},
+		messages: map[string]string{
+			LocaleEN: "{0} must be one of " + strings.Join(OrderStatuses, ", "),
+			LocaleID: "{0} harus salah satu dari " + strings.Join(OrderStatuses, ", "),
+		},
+	},
+}
+
+// invalidTypeMessages are used when the JSON value does not fit the field.
+var invalidTypeMessages = map[string]string{
+	LocaleEN: "%s has an invalid type",
+	LocaleID: "%s memiliki tipe yang tidak valid",
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var (
+	setupOnce  sync.Once
+	setupErr   error
+	translator *ut.UniversalTranslator
+)
+
+// Setup registers the custom rules and translations on gin's validator. It
+// is safe to call more than once.
+func Setup() error {
+	setupOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			setupErr = errors.New("gin validator is not go-playground/validator")
+			return
+		}
+		setupErr = register(v)
+	})
+	return setupErr
+}
+
+func register(v *validator.Validate) error {
+	// Report fields by the name clients send.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+
+	for _, rule := range customRules {
+		if err := v.RegisterValidation(rule.tag, rule.fn); err != nil {
+			return fmt.Errorf("register %s rule: %w", rule.tag, err)
+		}
+	}
+
+	english := en.New()
+	translator = ut.New(english, english, id.New())
+
+	defaults := map[string]func(*validator.Validate, ut.Translator) error{
+		LocaleEN: en_translations.RegisterDefaultTranslations,
+		LocaleID: id_translations.RegisterDefaultTranslations,
+	}
+	for locale, registerDefaults := range defaults {
+		trans, _ := translator.GetTranslator(locale)
+		if err := registerDefaults(v, trans); err != nil {
+			return fmt.Errorf("register %s translations: %w", locale, err)
+		}
+
+		for _, rule := range customRules {
+			message := rule.messages[locale]
+			err := v.RegisterTranslation(rule.tag, trans,
+				func(t ut.Translator) error { return t.Add(rule.tag, message, true) },
+				func(t ut.Translator, fe validator.FieldError) string {
+					msg, _ := t.T(fe.Tag(), fe.Field())
+					return msg
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("register %s translation for %s: %w", locale, rule.tag, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Locale picks the message language from an Accept-Language header.
+// Indonesian is used when it is the first preference, English otherwise.
+func Locale(acceptLanguage string) string {
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	first, _, _ = strings.Cut(strings.TrimSpace(first), ";")
+	lang, _, _ := strings.Cut(strings.ToLower(first), "-")
+	if lang == LocaleID || lang == "in" {
+		return LocaleID
+	}
+	return LocaleEN
+}
+
+// FieldErrors lists the per-field problems in a bind error, or returns nil
+// when err is not about individual fields (e.g. malformed JSON).
+func FieldErrors(err error, locale string) []FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		format, ok := invalidTypeMessages[locale]
+		if !ok {
+			format = invalidTypeMessages[LocaleEN]
+		}
+		return []FieldError{{Field: typeErr.Field, Message: fmt.Sprintf(format, typeErr.Field)}}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	var trans ut.Translator
+	if translator != nil {
+		trans, _ = translator.GetTranslator(locale)
+	}
+
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		message := fe.Error()
+		if trans != nil {
+			message = fe.Translate(trans)
+		}
+		fields = append(fields, FieldError{Field: fieldPath(fe), Message: message})
+	}
+	return fields
+}
+
+// fieldPath drops the struct name from the namespace, so nested fields read
+// like "items[0].quantity".
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+type orderItem struct {
+	Quantity int32 `json:"quantity" binding:"required,gt=0"`
+}
+
+type orderRequest struct {
+	Phone    string      `json:"phone" binding:"required,phone"`
+	Status   string      `json:"status" binding:"required,order_status"`
+	Latitude float32     `json:"latitude" binding:"min=-90,max=90"`
+	Items    []orderItem `json:"items" binding:"required,min=1,dive"`
+}
+
+func bindJSON(t *testing.T, body string) error {
+	t.Helper()
+	if err := Setup(); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	var dst orderRequest
+	return binding.JSON.Bind(req, &dst)
+}
+
+func TestFieldErrorsLocalized(t *testing.T) {
+	err := bindJSON(t, `{"phone":"12ab","status":"lost","latitude":91,"items":[{"quantity":0}]}`)
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+
+	en := FieldErrors(err, LocaleEN)
+	fields := map[string]string{}
+	for _, f := range en {
+		fields[f.Field] = f.Message
+	}
+	for _, name := range []string{"phone", "status", "latitude", "items[0].quantity"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("missing error for %s in %+v", name, en)
+		}
+	}
+	if !strings.Contains(fields["status"], "pending, paid, shipped, cancelled") {
+		t.Errorf("status message = %q", fields["status"])
+	}
+
+	id := FieldErrors(err, LocaleID)
+	if len(id) != len(en) || id[0].Message == en[0].Message {
+		t.Errorf("expected Indonesian messages, got %+v", id)
+	}
+}
+
+func TestFieldErrorsInvalidType(t *testing.T) {
+	err := bindJSON(t, `{"phone":"812345678","status":"paid","latitude":"north","items":[{"quantity":1}]}`)
+	fields := FieldErrors(err, LocaleEN)
+	if len(fields) != 1 || fields[0].Field != "latitude" {
+		t.Fatalf("unexpected errors %+v", fields)
+	}
+}
+
+func TestFieldErrorsMalformedJSON(t *testing.T) {
+	if fields := FieldErrors(bindJSON(t, `{"phone":`), LocaleEN); fields != nil {
+		t.Fatalf("expected no field errors, got %+v", fields)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	cases := map[string]string{
+		"":                        LocaleEN,
+		"id-ID,id;q=0.9,en;q=0.8": LocaleID,
+		"en-US,id;q=0.5":          LocaleEN,
+		"in":                      LocaleID,
+	}
+	for header, want := range cases {
+		if got := Locale(header); got != want {
+			t.Errorf("Locale(%q) = %q, want %q", header, got, want)
+		}
+	}
+}